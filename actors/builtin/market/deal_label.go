@@ -0,0 +1,194 @@
+package market
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"golang.org/x/xerrors"
+)
+
+// A deal label is either a UTF-8 string or raw bytes.
+// The two forms are distinguished in CBOR by major type (text string or byte string), so a string label
+// encodes identically to the plain string labels of earlier versions.
+// The zero value is the empty string label.
+type DealLabel struct {
+	s       string
+	bs      []byte
+	isBytes bool
+}
+
+var EmptyDealLabel = DealLabel{}
+
+// Returns a string label, or an error if the string is not valid UTF-8.
+func NewLabelFromString(s string) (DealLabel, error) {
+	if !utf8.ValidString(s) {
+		return EmptyDealLabel, xerrors.Errorf("label string is not valid UTF-8")
+	}
+	return DealLabel{s: s}, nil
+}
+
+// Returns a bytes label. The bytes are copied.
+func NewLabelFromBytes(b []byte) DealLabel {
+	return DealLabel{bs: append([]byte{}, b...), isBytes: true}
+}
+
+// Converts a label from a proposal encoded before labels could hold bytes.
+// Such labels were encoded as strings without validation, so any that are not valid UTF-8
+// become bytes labels.
+func LabelFromLegacyString(s string) DealLabel {
+	if l, err := NewLabelFromString(s); err == nil {
+		return l
+	}
+	return NewLabelFromBytes([]byte(s))
+}
+
+func (l DealLabel) IsString() bool {
+	return !l.isBytes
+}
+
+func (l DealLabel) IsBytes() bool {
+	return l.isBytes
+}
+
+// Returns the label string, or an error if the label holds bytes.
+func (l DealLabel) ToString() (string, error) {
+	if l.isBytes {
+		return "", xerrors.Errorf("label is bytes, not a string")
+	}
+	return l.s, nil
+}
+
+// Returns the label's content as bytes, for either form.
+func (l DealLabel) ToBytes() []byte {
+	if l.isBytes {
+		return append([]byte{}, l.bs...)
+	}
+	return []byte(l.s)
+}
+
+// Returns the size of the label's content in bytes.
+func (l DealLabel) Length() int {
+	if l.isBytes {
+		return len(l.bs)
+	}
+	return len(l.s)
+}
+
+func (l DealLabel) Equals(o DealLabel) bool {
+	if l.isBytes != o.isBytes {
+		return false
+	}
+	if l.isBytes {
+		return bytes.Equal(l.bs, o.bs)
+	}
+	return l.s == o.s
+}
+
+func (l DealLabel) String() string {
+	if l.isBytes {
+		return fmt.Sprintf("0x%x", l.bs)
+	}
+	return l.s
+}
+
+func (l *DealLabel) MarshalCBOR(w io.Writer) error {
+	scratch := make([]byte, 9)
+	if l.isBytes {
+		if len(l.bs) > cbg.ByteArrayMaxLen {
+			return xerrors.Errorf("label bytes too long: %d", len(l.bs))
+		}
+		if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajByteString, uint64(len(l.bs))); err != nil {
+			return err
+		}
+		_, err := w.Write(l.bs)
+		return err
+	}
+	if len(l.s) > cbg.MaxLength {
+		return xerrors.Errorf("label string too long: %d", len(l.s))
+	}
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len(l.s))); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, l.s)
+	return err
+}
+
+func (l *DealLabel) UnmarshalCBOR(r io.Reader) error {
+	*l = DealLabel{}
+
+	br := cbg.GetPeeker(r)
+	scratch := make([]byte, 8)
+
+	maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+	switch maj {
+	case cbg.MajTextString:
+		if extra > cbg.MaxLength {
+			return xerrors.Errorf("label string too long: %d", extra)
+		}
+		buf := make([]byte, extra)
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return err
+		}
+		if !utf8.Valid(buf) {
+			return xerrors.Errorf("label string is not valid UTF-8")
+		}
+		l.s = string(buf)
+	case cbg.MajByteString:
+		if extra > cbg.ByteArrayMaxLen {
+			return xerrors.Errorf("label bytes too long: %d", extra)
+		}
+		l.bs = make([]byte, extra)
+		if _, err := io.ReadFull(br, l.bs); err != nil {
+			return err
+		}
+		l.isBytes = true
+	default:
+		return xerrors.Errorf("label must be a text or byte string, got major type %d", maj)
+	}
+	return nil
+}
+
+// A bytes label is encoded in JSON as an object tagging its base64 content, so that it
+// can't be confused with a string label, which is encoded as a plain JSON string.
+type dealLabelJSONBytes struct {
+	Bytes []byte `json:"bytes"`
+}
+
+func (l DealLabel) MarshalJSON() ([]byte, error) {
+	if l.isBytes {
+		return json.Marshal(dealLabelJSONBytes{Bytes: l.bs})
+	}
+	return json.Marshal(l.s)
+}
+
+func (l *DealLabel) UnmarshalJSON(b []byte) error {
+	*l = DealLabel{}
+
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		label, err := NewLabelFromString(s)
+		if err != nil {
+			return err
+		}
+		*l = label
+		return nil
+	}
+	var tagged dealLabelJSONBytes
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&tagged); err != nil {
+		return xerrors.Errorf("label must be a JSON string or bytes object: %w", err)
+	}
+	if tagged.Bytes == nil {
+		return xerrors.Errorf("label bytes object has no bytes field")
+	}
+	*l = NewLabelFromBytes(tagged.Bytes)
+	return nil
+}
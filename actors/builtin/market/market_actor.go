@@ -6,6 +6,7 @@ import (
 	"sort"
 
 	addr "github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/go-state-types/big"
 	"github.com/filecoin-project/go-state-types/cbor"
@@ -40,6 +41,9 @@ func (a Actor) Exports() []interface{} {
 		7:                         a.OnMinerSectorsTerminate,
 		8:                         a.ComputeDataCommitment,
 		9:                         a.CronTick,
+		10:                        a.CancelDeal,
+		11:                        a.RenewDeal,
+		12:                        a.GetBalanceSchedule,
 	}
 }
 
@@ -140,17 +144,24 @@ func (a Actor) AddBalance(rt Runtime, providerOrClientAddress *addr.Address) *ab
 	return nil
 }
 
-//type PublishStorageDealsParams struct {
-//	Deals []ClientDealProposal
-//}
-type PublishStorageDealsParams = market0.PublishStorageDealsParams
+// Changed since v2:
+// - Deals are v3 ClientDealProposals
+type PublishStorageDealsParams struct {
+	Deals []ClientDealProposal
+}
 
-//type PublishStorageDealsReturn struct {
-//	IDs []abi.DealID
-//}
-type PublishStorageDealsReturn = market0.PublishStorageDealsReturn
+// Changed since v2:
+// - ValidDeals bitfield
+type PublishStorageDealsReturn struct {
+	IDs []abi.DealID
+	// Indices of the input deals which were published, in the same order as IDs.
+	ValidDeals bitfield.BitField
+}
 
 // Publish a new set of storage deals (not yet included in a sector).
+// Deals which fail validation are skipped, and the remainder published. Deals are considered in order, and any
+// deal for which the client or provider has insufficient balance after locking funds for the preceding valid deals
+// is also skipped. The message aborts only if no deal is valid.
 func (a Actor) PublishStorageDeals(rt Runtime, params *PublishStorageDealsParams) *PublishStorageDealsReturn {
 
 	// Deal message must have a From field identical to the provider of all the deals.
@@ -186,48 +197,122 @@ func (a Actor) PublishStorageDeals(rt Runtime, params *PublishStorageDealsParams
 		rt.Abortf(exitcode.ErrForbidden, "caller %v is not worker or control address of provider %v", caller, provider)
 	}
 
-	resolvedAddrs := make(map[addr.Address]addr.Address, len(params.Deals))
 	baselinePower := requestCurrentBaselinePower(rt)
 	networkRawPower, networkQAPower := requestCurrentNetworkPower(rt)
 
-	var newDealIds []abi.DealID
+	// Validate deals against the current state, dropping any that are invalid.
+	// Verified deals also consume the client's data cap here, which cannot be done within a state transaction,
+	// so every other check must be complete before a deal's data cap is used.
 	var st State
+	rt.StateReadonly(&st)
+	msm, err := st.mutator(adt.AsStore(rt)).withPendingProposals(ReadOnlyPermission).
+		withEscrowTable(ReadOnlyPermission).withLockedTable(ReadOnlyPermission).build()
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load state")
+
+	var validDeals []ClientDealProposal
+	validInput := bitfield.New()
+	proposalCids := make(map[cid.Cid]struct{}, len(params.Deals))
+	totalClientLockup := make(map[addr.Address]abi.TokenAmount)
+	totalProviderLockup := big.Zero()
+	for di, deal := range params.Deals {
+		if err := validateDeal(rt, deal, networkRawPower, networkQAPower, baselinePower); err != nil {
+			rt.Log(rtt.INFO, "invalid deal %d: %s", di, err)
+			continue
+		}
+		if deal.Proposal.Provider != provider && deal.Proposal.Provider != providerRaw {
+			rt.Log(rtt.INFO, "invalid deal %d: cannot publish deals from different providers at the same time", di)
+			continue
+		}
+
+		client, ok := rt.ResolveAddress(deal.Proposal.Client)
+		if !ok {
+			rt.Log(rtt.INFO, "invalid deal %d: failed to resolve client address %v", di, deal.Proposal.Client)
+			continue
+		}
+		// Normalise provider and client addresses in the proposal stored on chain (after signature verification).
+		deal.Proposal.Provider = provider
+		deal.Proposal.Client = client
+
+		pcid, err := deal.Proposal.Cid()
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "failed to take cid of proposal %d", di)
+		if _, duplicate := proposalCids[pcid]; duplicate {
+			rt.Log(rtt.INFO, "invalid deal %d: cannot publish duplicate deals", di)
+			continue
+		}
+		has, err := msm.pendingDeals.Has(abi.CidKey(pcid))
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to check for existence of deal proposal")
+		if has {
+			rt.Log(rtt.INFO, "invalid deal %d: cannot publish duplicate deals", di)
+			continue
+		}
+
+		clientLockup, ok := totalClientLockup[client]
+		if !ok {
+			clientLockup = big.Zero()
+		}
+		clientLockup = big.Add(clientLockup, deal.Proposal.ClientBalanceRequirement())
+		covered, err := msm.balanceCovered(client, clientLockup)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to check client balance coverage")
+		if !covered {
+			rt.Log(rtt.INFO, "invalid deal %d: insufficient client funds to lock", di)
+			continue
+		}
+
+		providerLockup := big.Add(totalProviderLockup, deal.Proposal.ProviderCollateral)
+		covered, err = msm.balanceCovered(provider, providerLockup)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to check provider balance coverage")
+		if !covered {
+			rt.Log(rtt.INFO, "invalid deal %d: insufficient provider funds to lock", di)
+			continue
+		}
+
+		// Check VerifiedClient allowed cap and deduct PieceSize from cap.
+		// The DealSize must be within the available DataCap of the VerifiedClient,
+		// otherwise the deal is dropped. We do not allow a deal that is partially verified.
+		if deal.Proposal.VerifiedDeal {
+			code := rt.Send(
+				builtin.VerifiedRegistryActorAddr,
+				builtin.MethodsVerifiedRegistry.UseBytes,
+				&verifreg.UseBytesParams{
+					Address:  client,
+					DealSize: big.NewIntUnsigned(uint64(deal.Proposal.PieceSize)),
+				},
+				abi.NewTokenAmount(0),
+				&builtin.Discard{},
+			)
+			if !code.IsSuccess() {
+				rt.Log(rtt.INFO, "invalid deal %d: failed to use data cap for client %v, exit code %v", di, client, code)
+				continue
+			}
+		}
+
+		totalClientLockup[client] = clientLockup
+		totalProviderLockup = providerLockup
+		proposalCids[pcid] = struct{}{}
+		validDeals = append(validDeals, deal)
+		validInput.Set(uint64(di))
+	}
+
+	if len(validDeals) == 0 {
+		rt.Abortf(exitcode.ErrIllegalArgument, "all deal proposals invalid")
+	}
+
+	var newDealIds []abi.DealID
 	rt.StateTransaction(&st, func() {
 		msm, err := st.mutator(adt.AsStore(rt)).withPendingProposals(WritePermission).
 			withDealProposals(WritePermission).withDealsByEpoch(WritePermission).withEscrowTable(WritePermission).
 			withLockedTable(WritePermission).build()
 		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load state")
 
-		// All storage dealProposals will be added in an atomic transaction; this operation will be unrolled if any of them fails.
-		for di, deal := range params.Deals {
-			validateDeal(rt, deal, networkRawPower, networkQAPower, baselinePower)
-
-			if deal.Proposal.Provider != provider && deal.Proposal.Provider != providerRaw {
-				rt.Abortf(exitcode.ErrIllegalArgument, "cannot publish deals from different providers at the same time")
-			}
-
-			client, ok := rt.ResolveAddress(deal.Proposal.Client)
-			if !ok {
-				rt.Abortf(exitcode.ErrNotFound, "failed to resolve client address %v", deal.Proposal.Client)
-			}
-			// Normalise provider and client addresses in the proposal stored on chain (after signature verification).
-			deal.Proposal.Provider = provider
-			resolvedAddrs[deal.Proposal.Client] = client
-			deal.Proposal.Client = client
-
+		// All valid storage dealProposals will be added in an atomic transaction.
+		for _, deal := range validDeals {
 			err := msm.lockClientAndProviderBalances(&deal.Proposal)
 			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to lock balance")
 
 			id := msm.generateStorageDealID()
 
 			pcid, err := deal.Proposal.Cid()
-			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "failed to take cid of proposal %d", di)
-
-			has, err := msm.pendingDeals.Has(abi.CidKey(pcid))
-			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to check for existence of deal proposal")
-			if has {
-				rt.Abortf(exitcode.ErrIllegalArgument, "cannot publish duplicate deals")
-			}
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to take cid of proposal %d", id)
 
 			err = msm.pendingDeals.Put(abi.CidKey(pcid))
 			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to set pending deal")
@@ -250,29 +335,7 @@ func (a Actor) PublishStorageDeals(rt Runtime, params *PublishStorageDealsParams
 		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to flush state")
 	})
 
-	for _, deal := range params.Deals {
-		// Check VerifiedClient allowed cap and deduct PieceSize from cap.
-		// Either the DealSize is within the available DataCap of the VerifiedClient
-		// or this message will fail. We do not allow a deal that is partially verified.
-		if deal.Proposal.VerifiedDeal {
-			resolvedClient, ok := resolvedAddrs[deal.Proposal.Client]
-			builtin.RequireParam(rt, ok, "could not get resolvedClient client address")
-
-			code := rt.Send(
-				builtin.VerifiedRegistryActorAddr,
-				builtin.MethodsVerifiedRegistry.UseBytes,
-				&verifreg.UseBytesParams{
-					Address:  resolvedClient,
-					DealSize: big.NewIntUnsigned(uint64(deal.Proposal.PieceSize)),
-				},
-				abi.NewTokenAmount(0),
-				&builtin.Discard{},
-			)
-			builtin.RequireSuccess(rt, code, "failed to add verified deal for client: %v", deal.Proposal.Client)
-		}
-	}
-
-	return &PublishStorageDealsReturn{IDs: newDealIds}
+	return &PublishStorageDealsReturn{IDs: newDealIds, ValidDeals: validInput}
 }
 
 // Changed since v2:
@@ -305,32 +368,45 @@ type SectorWeights struct {
 // This method performs some light validation on the way in order to fail early if deals can be
 // determined to be invalid for the proposed sector properties.
 // Full deal validation is deferred to deal activation since it depends on the activation epoch.
+//
+// The miner calls this when precommitting sectors, so the deals are recorded as precommitted,
+// after which their clients may no longer cancel them alone.
+// The record isn't cleared if the precommit later expires, so the client's window to cancel alone closes
+// permanently at the first precommit attempt; thereafter cancellation needs the provider's signature.
 func (a Actor) VerifyDealsForActivation(rt Runtime, params *VerifyDealsForActivationParams) *VerifyDealsForActivationReturn {
 	rt.ValidateImmediateCallerType(builtin.StorageMinerActorCodeID)
 	minerAddr := rt.Caller()
 	currEpoch := rt.CurrEpoch()
 
 	var st State
-	rt.StateReadonly(&st)
-	store := adt.AsStore(rt)
-
-	proposals, err := AsDealProposalArray(store, st.Proposals)
-	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deal proposals")
-
 	weights := make([]SectorWeights, len(params.Sectors))
-	for i, sector := range params.Sectors {
-		// Pass the current epoch as the activation epoch for validation.
-		// The sector activation epoch isn't yet known, but it's still more helpful to fail now if the deal
-		// is so late that a sector activating now couldn't include it.
-		dealWeight, verifiedWeight, dealSpace, err := validateAndComputeDealWeight(proposals, sector.DealIDs, minerAddr, sector.SectorExpiry, currEpoch)
-		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to validate deal proposals for activation")
+	rt.StateTransaction(&st, func() {
+		msm, err := st.mutator(adt.AsStore(rt)).withDealProposals(ReadOnlyPermission).
+			withPrecommittedDeals(WritePermission).build()
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load state")
 
-		weights[i] = SectorWeights{
-			DealSpace:          dealSpace,
-			DealWeight:         dealWeight,
-			VerifiedDealWeight: verifiedWeight,
+		for i, sector := range params.Sectors {
+			// Pass the current epoch as the activation epoch for validation.
+			// The sector activation epoch isn't yet known, but it's still more helpful to fail now if the deal
+			// is so late that a sector activating now couldn't include it.
+			dealWeight, verifiedWeight, dealSpace, err := validateAndComputeDealWeight(msm.dealProposals, sector.DealIDs, minerAddr, sector.SectorExpiry, currEpoch)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to validate deal proposals for activation")
+
+			weights[i] = SectorWeights{
+				DealSpace:          dealSpace,
+				DealWeight:         dealWeight,
+				VerifiedDealWeight: verifiedWeight,
+			}
+
+			for _, dealID := range sector.DealIDs {
+				err = msm.precommittedDeals.Put(dealKey(dealID))
+				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to record precommitted deal %d", dealID)
+			}
 		}
-	}
+
+		err = msm.commitState()
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to flush state")
+	})
 
 	return &VerifyDealsForActivationReturn{
 		Sectors: weights,
@@ -359,7 +435,8 @@ func (a Actor) ActivateDeals(rt Runtime, params *ActivateDealsParams) *abi.Empty
 		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to validate dealProposals for activation")
 
 		msm, err := st.mutator(adt.AsStore(rt)).withDealStates(WritePermission).
-			withPendingProposals(ReadOnlyPermission).withDealProposals(ReadOnlyPermission).build()
+			withPendingProposals(ReadOnlyPermission).withDealProposals(ReadOnlyPermission).
+			withPrecommittedDeals(WritePermission).build()
 		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load state")
 
 		for _, dealID := range params.DealIDs {
@@ -390,6 +467,10 @@ func (a Actor) ActivateDeals(rt Runtime, params *ActivateDealsParams) *abi.Empty
 				SlashEpoch:       epochUndefined,
 			})
 			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to set deal state %d", dealID)
+
+			// Deals precommitted before precommits were recorded may be absent.
+			_, err = msm.precommittedDeals.TryDelete(dealKey(dealID))
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to delete precommitted deal %d", dealID)
 		}
 
 		err = msm.commitState()
@@ -504,7 +585,8 @@ func (a Actor) CronTick(rt Runtime, _ *abi.EmptyValue) *abi.EmptyValue {
 
 		msm, err := st.mutator(adt.AsStore(rt)).withDealStates(WritePermission).
 			withLockedTable(WritePermission).withEscrowTable(WritePermission).withDealsByEpoch(WritePermission).
-			withDealProposals(WritePermission).withPendingProposals(WritePermission).build()
+			withDealProposals(WritePermission).withPendingProposals(WritePermission).
+			withPrecommittedDeals(WritePermission).build()
 		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load state")
 
 		for i := st.LastCron + 1; i <= rt.CurrEpoch(); i++ {
@@ -539,6 +621,9 @@ func (a Actor) CronTick(rt Runtime, _ *abi.EmptyValue) *abi.EmptyValue {
 
 					pdErr := msm.pendingDeals.Delete(abi.CidKey(dcid))
 					builtin.RequireNoErr(rt, pdErr, exitcode.ErrIllegalState, "failed to delete pending proposal %v", dcid)
+
+					_, pcErr := msm.precommittedDeals.TryDelete(dealKey(dealID))
+					builtin.RequireNoErr(rt, pcErr, exitcode.ErrIllegalState, "failed to delete precommitted deal %d", dealID)
 					return nil
 				}
 
@@ -624,6 +709,300 @@ func (a Actor) CronTick(rt Runtime, _ *abi.EmptyValue) *abi.EmptyValue {
 	return nil
 }
 
+type CancelDealParams struct {
+	DealID abi.DealID
+	// The epoch at which the deal's first cron operation is scheduled.
+	// This is a random epoch chosen at publication, which can be found with State.FindPendingDealOpEpoch.
+	ProcessEpoch abi.ChainEpoch
+	// The signature of the deal's other party over the DealCancellation's signing bytes.
+	// The client may omit this to cancel the deal alone.
+	CounterpartySignature *crypto.Signature
+}
+
+// The message signed by a deal's party to consent to its cancellation.
+// It names the deal's proposal as well as its ID, so the consent can't be applied to any other deal.
+type DealCancellation struct {
+	DealID   abi.DealID
+	Proposal cid.Cid
+}
+
+// Prefixes the bytes signed to consent to a deal's cancellation, so that they can't be mistaken for
+// any other message the party signs.
+var DealCancellationSignaturePrefix = []byte("fil_market_deal_cancellation:")
+
+// Returns the bytes a party signs to consent to the cancellation: the prefix followed by the serialized cancellation.
+func (c *DealCancellation) SigningBytes() ([]byte, error) {
+	buf := bytes.NewBuffer(append([]byte{}, DealCancellationSignaturePrefix...))
+	if err := c.MarshalCBOR(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Cancels a published deal that has not been activated, before its start epoch.
+// The caller must be the deal's client, or the worker or a control address of its provider.
+//
+// A cancellation signed by both parties (the caller and the signer of the counterparty signature) unlocks the
+// storage fee and both collaterals, with no fee. The client may instead cancel alone until the deal is first included
+// in a precommitted sector (even if that precommit later expires), in which case the client's collateral is paid to the provider and the storage fee and
+// provider collateral are unlocked. Either way, the deal proposal, its pending proposal and its scheduled cron
+// operation are removed, and any data cap used by a verified deal is restored to the client.
+func (a Actor) CancelDeal(rt Runtime, params *CancelDealParams) *abi.EmptyValue {
+	rt.ValidateImmediateCallerType(builtin.CallerTypesSignable...)
+	caller := rt.Caller()
+	dealID := params.DealID
+
+	var st State
+	rt.StateReadonly(&st)
+	proposals, err := AsDealProposalArray(adt.AsStore(rt), st.Proposals)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deal proposals")
+	proposal, err := getDealProposal(proposals, dealID)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to get deal %d", dealID)
+
+	joint := params.CounterpartySignature != nil
+	if caller == proposal.Client {
+		if joint {
+			_, worker, _ := builtin.RequestMinerControlAddrs(rt, proposal.Provider)
+			verifyDealCancellationSignature(rt, *params.CounterpartySignature, worker, dealID, proposal)
+		}
+	} else {
+		_, worker, controllers := builtin.RequestMinerControlAddrs(rt, proposal.Provider)
+		callerOk := caller == worker
+		for _, controller := range controllers {
+			if callerOk {
+				break
+			}
+			callerOk = caller == controller
+		}
+		if !callerOk {
+			rt.Abortf(exitcode.ErrForbidden, "caller %v is neither the client nor a worker or control address of provider %v",
+				caller, proposal.Provider)
+		}
+		if !joint {
+			rt.Abortf(exitcode.ErrForbidden, "provider cannot cancel deal %d without the client's signature", dealID)
+		}
+		verifyDealCancellationSignature(rt, *params.CounterpartySignature, proposal.Client, dealID, proposal)
+	}
+
+	if rt.CurrEpoch() >= proposal.StartEpoch {
+		rt.Abortf(exitcode.ErrIllegalArgument, "cannot cancel deal %d at or after its start epoch %d", dealID, proposal.StartEpoch)
+	}
+
+	rt.StateTransaction(&st, func() {
+		msm, err := st.mutator(adt.AsStore(rt)).withDealProposals(WritePermission).withDealStates(ReadOnlyPermission).
+			withPendingProposals(WritePermission).withDealsByEpoch(WritePermission).withPrecommittedDeals(WritePermission).
+			withEscrowTable(WritePermission).withLockedTable(WritePermission).build()
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load state")
+
+		_, found, err := msm.dealStates.Get(dealID)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to get state for deal %d", dealID)
+		if found {
+			rt.Abortf(exitcode.ErrIllegalArgument, "cannot cancel activated deal %d", dealID)
+		}
+
+		precommitted, err := msm.precommittedDeals.TryDelete(dealKey(dealID))
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to delete precommitted deal %d", dealID)
+		if precommitted && !joint {
+			rt.Abortf(exitcode.ErrForbidden, "client cannot cancel precommitted deal %d without the provider's signature", dealID)
+		}
+
+		removed, err := msm.dealsByEpoch.Remove(params.ProcessEpoch, dealID)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to remove deal op for deal %d", dealID)
+		if !removed {
+			rt.Abortf(exitcode.ErrIllegalArgument, "deal %d has no operation scheduled at epoch %d", dealID, params.ProcessEpoch)
+		}
+
+		pcid, err := proposal.Cid()
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to calculate CID for proposal %d", dealID)
+		err = msm.pendingDeals.Delete(abi.CidKey(pcid))
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to delete pending proposal %v", pcid)
+
+		err = msm.dealProposals.Delete(dealID)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to delete proposal %d", dealID)
+
+		msm.processDealCancelled(rt, proposal, !joint)
+
+		err = msm.commitState()
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to flush state")
+	})
+
+	if proposal.VerifiedDeal {
+		code := rt.Send(
+			builtin.VerifiedRegistryActorAddr,
+			builtin.MethodsVerifiedRegistry.RestoreBytes,
+			&verifreg.RestoreBytesParams{
+				Address:  proposal.Client,
+				DealSize: big.NewIntUnsigned(uint64(proposal.PieceSize)),
+			},
+			abi.NewTokenAmount(0),
+			&builtin.Discard{},
+		)
+		builtin.RequireSuccess(rt, code, "failed to restore data cap for cancelled deal %d", dealID)
+	}
+	return nil
+}
+
+// New terms for an active deal, agreed by its client and provider.
+type DealRenewal struct {
+	DealID                       abi.DealID
+	NewEndEpoch                  abi.ChainEpoch
+	NewStoragePricePerEpoch      abi.TokenAmount
+	AdditionalClientCollateral   abi.TokenAmount
+	AdditionalProviderCollateral abi.TokenAmount
+}
+
+// A deal renewal signed by the client.
+// The provider's agreement is implicit in the authenticity of the on-chain message submitting it.
+type ClientDealRenewal struct {
+	Renewal         DealRenewal
+	ClientSignature crypto.Signature
+}
+
+type RenewDealParams struct {
+	Renewal      ClientDealRenewal
+	SectorExpiry abi.ChainEpoch // Expiration of the sector hosting the deal.
+}
+
+type RenewDealReturn struct {
+	DealWeightDelta         abi.DealWeight // Space*time added to the deal, if not verified.
+	VerifiedDealWeightDelta abi.DealWeight // Space*time added to the deal, if verified.
+}
+
+// Extends an active deal to a new end epoch, at a new price per epoch and with additional collateral.
+// Called by the deal's provider, which is responsible for checking that the deal is hosted by a sector
+// expiring at SectorExpiry.
+//
+// Payment for the elapsed epochs is first settled at the old price, and the new price applies from the current epoch.
+// The client's locked storage fee is adjusted to cover the remaining term at the new price, and the additional
+// collateral is locked. The deal's scheduled cron operation is kept, since cron reschedules it until the end epoch.
+// Returns the weight added to the deal, for the provider to add to the hosting sector.
+func (a Actor) RenewDeal(rt Runtime, params *RenewDealParams) *RenewDealReturn {
+	rt.ValidateImmediateCallerType(builtin.StorageMinerActorCodeID)
+	minerAddr := rt.Caller()
+	currEpoch := rt.CurrEpoch()
+	renewal := &params.Renewal.Renewal
+	dealID := renewal.DealID
+
+	if renewal.AdditionalClientCollateral.LessThan(big.Zero()) || renewal.AdditionalProviderCollateral.LessThan(big.Zero()) {
+		rt.Abortf(exitcode.ErrIllegalArgument, "negative additional collateral for deal %d", dealID)
+	}
+
+	var st State
+	rt.StateReadonly(&st)
+	proposals, err := AsDealProposalArray(adt.AsStore(rt), st.Proposals)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deal proposals")
+	proposal, err := getDealProposal(proposals, dealID)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to get deal %d", dealID)
+	if proposal.Provider != minerAddr {
+		rt.Abortf(exitcode.ErrForbidden, "caller %v is not the provider of deal %d", minerAddr, dealID)
+	}
+
+	buf := bytes.Buffer{}
+	err = renewal.MarshalCBOR(&buf)
+	builtin.RequireNoErr(rt, err, exitcode.ErrSerialization, "failed to marshal renewal of deal %d", dealID)
+	err = rt.VerifySignature(params.Renewal.ClientSignature, proposal.Client, buf.Bytes())
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "invalid client signature for renewal of deal %d", dealID)
+
+	ret := RenewDealReturn{DealWeightDelta: big.Zero(), VerifiedDealWeightDelta: big.Zero()}
+	rt.StateTransaction(&st, func() {
+		msm, err := st.mutator(adt.AsStore(rt)).withDealProposals(WritePermission).withDealStates(WritePermission).
+			withEscrowTable(WritePermission).withLockedTable(WritePermission).build()
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load state")
+
+		state, found, err := msm.dealStates.Get(dealID)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to get state for deal %d", dealID)
+		if !found {
+			rt.Abortf(exitcode.ErrIllegalArgument, "deal %d is not active", dealID)
+		}
+		if state.SlashEpoch != epochUndefined {
+			rt.Abortf(exitcode.ErrIllegalArgument, "deal %d was slashed at epoch %d", dealID, state.SlashEpoch)
+		}
+		// Until its first cron operation, the deal's proposal is held in the pending set by its CID,
+		// which the renewal would change.
+		if state.LastUpdatedEpoch == epochUndefined {
+			rt.Abortf(exitcode.ErrIllegalArgument, "deal %d cannot be renewed before its first payment", dealID)
+		}
+		if currEpoch >= proposal.EndEpoch {
+			rt.Abortf(exitcode.ErrIllegalArgument, "deal %d ended at epoch %d", dealID, proposal.EndEpoch)
+		}
+		if renewal.NewEndEpoch <= proposal.EndEpoch {
+			rt.Abortf(exitcode.ErrIllegalArgument, "new end epoch %d for deal %d is not after its end epoch %d",
+				renewal.NewEndEpoch, dealID, proposal.EndEpoch)
+		}
+		if renewal.NewEndEpoch > params.SectorExpiry {
+			rt.Abortf(exitcode.ErrIllegalArgument, "new end epoch %d for deal %d is after sector expiration %d",
+				renewal.NewEndEpoch, dealID, params.SectorExpiry)
+		}
+
+		renewed := *proposal
+		renewed.EndEpoch = renewal.NewEndEpoch
+		renewed.StoragePricePerEpoch = renewal.NewStoragePricePerEpoch
+		renewed.ClientCollateral = big.Add(proposal.ClientCollateral, renewal.AdditionalClientCollateral)
+		renewed.ProviderCollateral = big.Add(proposal.ProviderCollateral, renewal.AdditionalProviderCollateral)
+		err = validateDealRenewal(&renewed)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "invalid renewal of deal %d", dealID)
+
+		// Settle payment at the old price up to the current epoch.
+		slashed, _, removed := msm.updatePendingDealState(rt, state, proposal, currEpoch)
+		builtin.RequireState(rt, slashed.IsZero() && !removed, "deal %d unexpectedly slashed or removed on renewal", dealID)
+		state.LastUpdatedEpoch = currEpoch
+		err = msm.dealStates.Set(dealID, state)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to set state for deal %d", dealID)
+
+		err = msm.lockRenewalBalances(proposal, &renewed, currEpoch)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to lock balances for renewal of deal %d", dealID)
+
+		err = msm.dealProposals.Set(dealID, &renewed)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to set deal %d", dealID)
+
+		weightDelta := big.Sub(DealWeight(&renewed), DealWeight(proposal))
+		if renewed.VerifiedDeal {
+			ret.VerifiedDealWeightDelta = weightDelta
+		} else {
+			ret.DealWeightDelta = weightDelta
+		}
+
+		err = msm.commitState()
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to flush state")
+	})
+	return &ret
+}
+
+// Funds removed from an address's locked balance by cron at an epoch.
+type BalanceUnlock struct {
+	Epoch              abi.ChainEpoch
+	ClientStorageFee   abi.TokenAmount // Paid to providers, or unlocked if a deal was slashed.
+	ClientCollateral   abi.TokenAmount // Unlocked.
+	ProviderCollateral abi.TokenAmount // Unlocked, or burnt if a deal was slashed.
+}
+
+type GetBalanceScheduleReturn struct {
+	Escrow             abi.TokenAmount
+	Locked             abi.TokenAmount
+	ClientStorageFee   abi.TokenAmount // Part of the locked balance reserved for deals' remaining storage fees.
+	ClientCollateral   abi.TokenAmount // Part of the locked balance held as client collateral.
+	ProviderCollateral abi.TokenAmount // Part of the locked balance held as provider collateral.
+	Unlocks            []BalanceUnlock // Projected removals from the locked balance, ordered by epoch.
+	Unscheduled        []abi.DealID    // Deals with no scheduled cron operation, whose funds are omitted from Unlocks.
+}
+
+// Reports the escrow and locked balances of a client or provider, with the locked balance split by reason,
+// and projects when cron will remove the locked funds, which are not available to WithdrawBalance until then.
+// This method does not mutate state.
+func (a Actor) GetBalanceSchedule(rt Runtime, providerOrClientAddress *addr.Address) *GetBalanceScheduleReturn {
+	rt.ValidateImmediateCallerAcceptAny()
+	nominal, ok := rt.ResolveAddress(*providerOrClientAddress)
+	if !ok {
+		rt.Abortf(exitcode.ErrIllegalArgument, "failed to resolve address %v", providerOrClientAddress)
+	}
+
+	var st State
+	rt.StateReadonly(&st)
+	ret, err := st.GetBalanceSchedule(adt.AsStore(rt), nominal)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to compute balance schedule for %v", nominal)
+	return ret
+}
+
 func genRandNextEpoch(currEpoch abi.ChainEpoch, deal *DealProposal, rbF func(crypto.DomainSeparationTag, abi.ChainEpoch, []byte) abi.Randomness) (abi.ChainEpoch, error) {
 	buf := bytes.Buffer{}
 	if err := deal.MarshalCBOR(&buf); err != nil {
@@ -726,57 +1105,84 @@ func validateDealCanActivate(proposal *DealProposal, minerAddr addr.Address, sec
 	return nil
 }
 
-func validateDeal(rt Runtime, deal ClientDealProposal, networkRawPower, networkQAPower, baselinePower abi.StoragePower) {
+func validateDeal(rt Runtime, deal ClientDealProposal, networkRawPower, networkQAPower, baselinePower abi.StoragePower) error {
 	if err := dealProposalIsInternallyValid(rt, deal); err != nil {
-		rt.Abortf(exitcode.ErrIllegalArgument, "Invalid deal proposal: %s", err)
+		return exitcode.ErrIllegalArgument.Wrapf("Invalid deal proposal: %s", err)
 	}
 
 	proposal := deal.Proposal
 
-	if len(proposal.Label) > DealMaxLabelSize {
-		rt.Abortf(exitcode.ErrIllegalArgument, "deal label can be at most %d bytes, is %d", DealMaxLabelSize, len(proposal.Label))
+	if proposal.Label.Length() > DealMaxLabelSize {
+		return exitcode.ErrIllegalArgument.Wrapf("deal label can be at most %d bytes, is %d", DealMaxLabelSize, proposal.Label.Length())
 	}
 
 	if err := proposal.PieceSize.Validate(); err != nil {
-		rt.Abortf(exitcode.ErrIllegalArgument, "proposal piece size is invalid: %v", err)
+		return exitcode.ErrIllegalArgument.Wrapf("proposal piece size is invalid: %v", err)
 	}
 
 	if !proposal.PieceCID.Defined() {
-		rt.Abortf(exitcode.ErrIllegalArgument, "proposal PieceCID undefined")
+		return exitcode.ErrIllegalArgument.Wrapf("proposal PieceCID undefined")
 	}
 
 	if proposal.PieceCID.Prefix() != PieceCIDPrefix {
-		rt.Abortf(exitcode.ErrIllegalArgument, "proposal PieceCID had wrong prefix")
+		return exitcode.ErrIllegalArgument.Wrapf("proposal PieceCID had wrong prefix")
 	}
 
 	if proposal.EndEpoch <= proposal.StartEpoch {
-		rt.Abortf(exitcode.ErrIllegalArgument, "proposal end before proposal start")
+		return exitcode.ErrIllegalArgument.Wrapf("proposal end before proposal start")
 	}
 
 	if rt.CurrEpoch() > proposal.StartEpoch {
-		rt.Abortf(exitcode.ErrIllegalArgument, "Deal start epoch has already elapsed.")
+		return exitcode.ErrIllegalArgument.Wrapf("Deal start epoch has already elapsed.")
 	}
 
 	minDuration, maxDuration := DealDurationBounds(proposal.PieceSize)
 	if proposal.Duration() < minDuration || proposal.Duration() > maxDuration {
-		rt.Abortf(exitcode.ErrIllegalArgument, "Deal duration out of bounds.")
+		return exitcode.ErrIllegalArgument.Wrapf("Deal duration out of bounds.")
 	}
 
 	minPrice, maxPrice := DealPricePerEpochBounds(proposal.PieceSize, proposal.Duration())
 	if proposal.StoragePricePerEpoch.LessThan(minPrice) || proposal.StoragePricePerEpoch.GreaterThan(maxPrice) {
-		rt.Abortf(exitcode.ErrIllegalArgument, "Storage price out of bounds.")
+		return exitcode.ErrIllegalArgument.Wrapf("Storage price out of bounds.")
 	}
 
 	minProviderCollateral, maxProviderCollateral := DealProviderCollateralBounds(proposal.PieceSize, proposal.VerifiedDeal,
 		networkRawPower, networkQAPower, baselinePower, rt.TotalFilCircSupply())
 	if proposal.ProviderCollateral.LessThan(minProviderCollateral) || proposal.ProviderCollateral.GreaterThan(maxProviderCollateral) {
-		rt.Abortf(exitcode.ErrIllegalArgument, "Provider collateral out of bounds.")
+		return exitcode.ErrIllegalArgument.Wrapf("Provider collateral out of bounds.")
 	}
 
 	minClientCollateral, maxClientCollateral := DealClientCollateralBounds(proposal.PieceSize, proposal.Duration())
 	if proposal.ClientCollateral.LessThan(minClientCollateral) || proposal.ClientCollateral.GreaterThan(maxClientCollateral) {
-		rt.Abortf(exitcode.ErrIllegalArgument, "Client collateral out of bounds.")
+		return exitcode.ErrIllegalArgument.Wrapf("Client collateral out of bounds.")
 	}
+	return nil
+}
+
+// Validates the terms of a renewed deal proposal which are not fixed by its original proposal.
+func validateDealRenewal(proposal *DealProposal) error {
+	_, maxDuration := DealDurationBounds(proposal.PieceSize)
+	if proposal.Duration() > maxDuration {
+		return exitcode.ErrIllegalArgument.Wrapf("deal duration %d exceeds maximum %d", proposal.Duration(), maxDuration)
+	}
+
+	minPrice, maxPrice := DealPricePerEpochBounds(proposal.PieceSize, proposal.Duration())
+	if proposal.StoragePricePerEpoch.LessThan(minPrice) || proposal.StoragePricePerEpoch.GreaterThan(maxPrice) {
+		return exitcode.ErrIllegalArgument.Wrapf("storage price out of bounds")
+	}
+
+	// Collateral only grows, so a renewal can only exceed the maximum.
+	_, maxProviderCollateral := DealProviderCollateralBounds(proposal.PieceSize, proposal.VerifiedDeal,
+		big.Zero(), big.Zero(), big.Zero(), big.Zero())
+	if proposal.ProviderCollateral.GreaterThan(maxProviderCollateral) {
+		return exitcode.ErrIllegalArgument.Wrapf("provider collateral out of bounds")
+	}
+
+	_, maxClientCollateral := DealClientCollateralBounds(proposal.PieceSize, proposal.Duration())
+	if proposal.ClientCollateral.GreaterThan(maxClientCollateral) {
+		return exitcode.ErrIllegalArgument.Wrapf("client collateral out of bounds")
+	}
+	return nil
 }
 
 //
@@ -806,6 +1212,16 @@ func escrowAddress(rt Runtime, address addr.Address) (nominal addr.Address, reci
 	return nominal, nominal, []addr.Address{nominal}
 }
 
+// Verifies a party's signature over the cancellation of a deal.
+func verifyDealCancellationSignature(rt Runtime, sig crypto.Signature, signer addr.Address, dealID abi.DealID, proposal *DealProposal) {
+	pcid, err := proposal.Cid()
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to calculate CID for proposal %d", dealID)
+	toVerify, err := (&DealCancellation{DealID: dealID, Proposal: pcid}).SigningBytes()
+	builtin.RequireNoErr(rt, err, exitcode.ErrSerialization, "failed to marshal cancellation of deal %d", dealID)
+	err = rt.VerifySignature(sig, signer, toVerify)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "invalid signature for cancellation of deal %d", dealID)
+}
+
 func getDealProposal(proposals *DealArray, dealID abi.DealID) (*DealProposal, error) {
 	proposal, found, err := proposals.Get(dealID)
 	if err != nil {
@@ -22,6 +22,37 @@ func (m *marketStateMutation) lockClientAndProviderBalances(proposal *DealPropos
 	return nil
 }
 
+// Locks the additional collateral of a renewed deal and adjusts the client's locked storage fee to the remaining term
+// at the renewed price. The deal's payments must be settled up to the current epoch.
+func (m *marketStateMutation) lockRenewalBalances(deal, renewed *DealProposal, currEpoch abi.ChainEpoch) error {
+	remainingFee := big.Mul(big.NewInt(int64(deal.EndEpoch-currEpoch)), deal.StoragePricePerEpoch)
+	renewedFee := big.Mul(big.NewInt(int64(renewed.EndEpoch-currEpoch)), renewed.StoragePricePerEpoch)
+	feeDelta := big.Sub(renewedFee, remainingFee)
+	clientCollateralDelta := big.Sub(renewed.ClientCollateral, deal.ClientCollateral)
+	providerCollateralDelta := big.Sub(renewed.ProviderCollateral, deal.ProviderCollateral)
+
+	clientLock := clientCollateralDelta
+	if feeDelta.LessThan(big.Zero()) {
+		if err := m.unlockBalance(deal.Client, feeDelta.Neg(), ClientStorageFee); err != nil {
+			return xerrors.Errorf("failed to unlock client storage fee: %w", err)
+		}
+	} else {
+		clientLock = big.Add(clientLock, feeDelta)
+		m.totalClientStorageFee = big.Add(m.totalClientStorageFee, feeDelta)
+	}
+
+	if err := m.maybeLockBalance(deal.Client, clientLock); err != nil {
+		return xerrors.Errorf("failed to lock client funds: %w", err)
+	}
+	if err := m.maybeLockBalance(deal.Provider, providerCollateralDelta); err != nil {
+		return xerrors.Errorf("failed to lock provider funds: %w", err)
+	}
+
+	m.totalClientLockedCollateral = big.Add(m.totalClientLockedCollateral, clientCollateralDelta)
+	m.totalProviderLockedCollateral = big.Add(m.totalProviderLockedCollateral, providerCollateralDelta)
+	return nil
+}
+
 func (m *marketStateMutation) unlockBalance(addr addr.Address, amount abi.TokenAmount, lockReason BalanceLockingReason) error {
 	if amount.LessThan(big.Zero()) {
 		return xerrors.Errorf("unlock negative amount %v", amount)
@@ -45,14 +76,14 @@ func (m *marketStateMutation) unlockBalance(addr addr.Address, amount abi.TokenA
 }
 
 // move funds from locked in client to available in provider
-func (m *marketStateMutation) transferBalance(fromAddr addr.Address, toAddr addr.Address, amount abi.TokenAmount) error {
+func (m *marketStateMutation) transferBalance(fromAddr addr.Address, toAddr addr.Address, amount abi.TokenAmount, lockReason BalanceLockingReason) error {
 	if amount.LessThan(big.Zero()) {
 		return xerrors.Errorf("transfer negative amount %v", amount)
 	}
 	if err := m.escrowTable.MustSubtract(fromAddr, amount); err != nil {
 		return xerrors.Errorf("subtract from escrow: %w", err)
 	}
-	if err := m.unlockBalance(fromAddr, amount, ClientStorageFee); err != nil {
+	if err := m.unlockBalance(fromAddr, amount, lockReason); err != nil {
 		return xerrors.Errorf("subtract from locked: %w", err)
 	}
 	if err := m.escrowTable.Add(toAddr, amount); err != nil {
@@ -73,6 +104,19 @@ func (m *marketStateMutation) slashBalance(addr addr.Address, amount abi.TokenAm
 	return m.unlockBalance(addr, amount, reason)
 }
 
+// Checks whether an address's escrow balance covers its locked balance plus an additional amount.
+func (m *marketStateMutation) balanceCovered(addr addr.Address, amountToLock abi.TokenAmount) (bool, error) {
+	prevLocked, err := m.lockedTable.Get(addr)
+	if err != nil {
+		return false, xerrors.Errorf("failed to get locked balance: %w", err)
+	}
+	escrowBalance, err := m.escrowTable.Get(addr)
+	if err != nil {
+		return false, xerrors.Errorf("failed to get escrow balance: %w", err)
+	}
+	return big.Add(prevLocked, amountToLock).LessThanEqual(escrowBalance), nil
+}
+
 func (m *marketStateMutation) maybeLockBalance(addr addr.Address, amount abi.TokenAmount) error {
 	if amount.LessThan(big.Zero()) {
 		return xerrors.Errorf("cannot lock negative amount %v", amount)
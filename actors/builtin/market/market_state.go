@@ -2,11 +2,14 @@ package market
 
 import (
 	"bytes"
+	"sort"
 
+	addr "github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/go-state-types/big"
 	"github.com/filecoin-project/go-state-types/exitcode"
 	"github.com/ipfs/go-cid"
+	cbg "github.com/whyrusleeping/cbor-gen"
 	xerrors "golang.org/x/xerrors"
 
 	"github.com/filecoin-project/specs-actors/v3/actors/builtin"
@@ -62,6 +65,12 @@ type State struct {
 	TotalProviderLockedCollateral abi.TokenAmount
 	// Total storage fee that is locked in escrow -> unlocked when payments are made
 	TotalClientStorageFee abi.TokenAmount
+
+	// PrecommittedDeals tracks deals that have been included in a precommitted sector but not yet activated.
+	// A client may cancel a deal alone only until it is first precommitted.
+	// The market isn't told when a precommit expires, so a deal stays here until it is activated, cancelled
+	// or times out, even if the sector including it is never proven.
+	PrecommittedDeals cid.Cid // Set[DealID]
 }
 
 func ConstructState(store adt.Store) (*State, error) {
@@ -78,6 +87,10 @@ func ConstructState(store adt.Store) (*State, error) {
 	if err != nil {
 		return nil, xerrors.Errorf("failed to create empty map: %w", err)
 	}
+	emptyPrecommittedDealsMapCid, err := adt.StoreEmptyMap(store, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create empty map: %w", err)
+	}
 	emptyDealOpsHamtCid, err := StoreEmptySetMultimap(store, builtin.DefaultHamtBitwidth)
 	if err != nil {
 		return nil, xerrors.Errorf("failed to create empty multiset: %w", err)
@@ -100,9 +113,199 @@ func ConstructState(store adt.Store) (*State, error) {
 		TotalClientLockedCollateral:   abi.NewTokenAmount(0),
 		TotalProviderLockedCollateral: abi.NewTokenAmount(0),
 		TotalClientStorageFee:         abi.NewTokenAmount(0),
+
+		PrecommittedDeals: emptyPrecommittedDealsMapCid,
 	}, nil
 }
 
+// Finds the epoch at which a pending deal's first cron operation is scheduled.
+// Publishing schedules it at a random epoch within DealUpdatesInterval of the deal's start epoch,
+// so this searches that interval. Returns false if the deal has no scheduled operation there.
+func (st *State) FindPendingDealOpEpoch(store adt.Store, dealID abi.DealID) (abi.ChainEpoch, bool, error) {
+	proposals, err := AsDealProposalArray(store, st.Proposals)
+	if err != nil {
+		return epochUndefined, false, xerrors.Errorf("failed to load deal proposals: %w", err)
+	}
+	proposal, err := getDealProposal(proposals, dealID)
+	if err != nil {
+		return epochUndefined, false, err
+	}
+	dealOps, err := AsSetMultimap(store, st.DealOpsByEpoch, builtin.DefaultHamtBitwidth, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return epochUndefined, false, xerrors.Errorf("failed to load deal ops: %w", err)
+	}
+
+	for epoch := proposal.StartEpoch; epoch < proposal.StartEpoch+DealUpdatesInterval; epoch++ {
+		found := false
+		err = dealOps.ForEach(epoch, func(id abi.DealID) error {
+			found = found || id == dealID
+			return nil
+		})
+		if err != nil {
+			return epochUndefined, false, xerrors.Errorf("failed to iterate deal ops for epoch %d: %w", epoch, err)
+		}
+		if found {
+			return epoch, true, nil
+		}
+	}
+	return epochUndefined, false, nil
+}
+
+// Reports an address's escrow and locked balances, with the locked balance split by reason, and projects
+// the epochs at which cron will remove its locked funds. The address must be an ID address.
+//
+// The projection assumes every deal runs to its end epoch: pending deals are activated in time, and active deals
+// are neither terminated nor renewed. A deal's storage fee is paid from the client's locked balance at each of its
+// cron operations, the first being the one scheduled in DealOpsByEpoch and the rest following every
+// DealUpdatesInterval, and its collateral is unlocked at the first operation at or after its end epoch.
+// A slashed deal's remaining storage fee and collateral are removed at its next operation.
+// A deal with no scheduled operation is reported in Unscheduled, and its funds counted in the locked balance
+// but not projected to unlock.
+//
+// This iterates all deal proposals and deal operations.
+func (st *State) GetBalanceSchedule(store adt.Store, a addr.Address) (*GetBalanceScheduleReturn, error) {
+	escrowTable, err := adt.AsBalanceTable(store, st.EscrowTable)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to load escrow table: %w", err)
+	}
+	lockedTable, err := adt.AsBalanceTable(store, st.LockedTable)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to load locked table: %w", err)
+	}
+	proposals, err := AsDealProposalArray(store, st.Proposals)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to load deal proposals: %w", err)
+	}
+	states, err := AsDealStateArray(store, st.States)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to load deal states: %w", err)
+	}
+	dealOps, err := AsSetMultimap(store, st.DealOpsByEpoch, builtin.DefaultHamtBitwidth, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to load deal ops: %w", err)
+	}
+
+	ret := &GetBalanceScheduleReturn{
+		ClientStorageFee:   big.Zero(),
+		ClientCollateral:   big.Zero(),
+		ProviderCollateral: big.Zero(),
+	}
+	if ret.Escrow, err = escrowTable.Get(a); err != nil {
+		return nil, xerrors.Errorf("failed to get escrow balance: %w", err)
+	}
+	if ret.Locked, err = lockedTable.Get(a); err != nil {
+		return nil, xerrors.Errorf("failed to get locked balance: %w", err)
+	}
+
+	deals := make(map[abi.DealID]*DealProposal)
+	var dealIDs []abi.DealID
+	var proposal DealProposal
+	if err = proposals.ForEach(&proposal, func(id int64) error {
+		if proposal.Client == a || proposal.Provider == a {
+			p := proposal
+			deals[abi.DealID(id)] = &p
+			dealIDs = append(dealIDs, abi.DealID(id))
+		}
+		return nil
+	}); err != nil {
+		return nil, xerrors.Errorf("failed to iterate deal proposals: %w", err)
+	}
+
+	// Find the next scheduled operation for each deal.
+	nextOps := make(map[abi.DealID]abi.ChainEpoch)
+	var setRoot cbg.CborCid
+	if err = dealOps.mp.ForEach(&setRoot, func(key string) error {
+		epoch, err := abi.ParseUIntKey(key)
+		if err != nil {
+			return xerrors.Errorf("failed to parse deal ops epoch %s: %w", key, err)
+		}
+		return dealOps.ForEach(abi.ChainEpoch(epoch), func(id abi.DealID) error {
+			if _, ok := deals[id]; ok {
+				nextOps[id] = abi.ChainEpoch(epoch)
+			}
+			return nil
+		})
+	}); err != nil {
+		return nil, xerrors.Errorf("failed to iterate deal ops: %w", err)
+	}
+
+	unlocks := make(map[abi.ChainEpoch]*BalanceUnlock)
+	addUnlock := func(deal *DealProposal, epoch abi.ChainEpoch, fee abi.TokenAmount, collateral bool) {
+		u, ok := unlocks[epoch]
+		if !ok {
+			u = &BalanceUnlock{
+				Epoch:              epoch,
+				ClientStorageFee:   big.Zero(),
+				ClientCollateral:   big.Zero(),
+				ProviderCollateral: big.Zero(),
+			}
+		}
+		if deal.Client == a {
+			u.ClientStorageFee = big.Add(u.ClientStorageFee, fee)
+			if collateral {
+				u.ClientCollateral = big.Add(u.ClientCollateral, deal.ClientCollateral)
+			}
+		}
+		if deal.Provider == a && collateral {
+			u.ProviderCollateral = big.Add(u.ProviderCollateral, deal.ProviderCollateral)
+		}
+		if !u.ClientStorageFee.IsZero() || !u.ClientCollateral.IsZero() || !u.ProviderCollateral.IsZero() {
+			unlocks[epoch] = u
+		}
+	}
+
+	for _, id := range dealIDs {
+		deal := deals[id]
+		state, activated, err := states.Get(id)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to get state for deal %d: %w", id, err)
+		}
+		nextOp, scheduled := nextOps[id]
+
+		// Payment is due from the later of the start and last update epochs.
+		paidEpoch := deal.StartEpoch
+		if activated && state.LastUpdatedEpoch > paidEpoch {
+			paidEpoch = state.LastUpdatedEpoch
+		}
+		lockedFee := big.Mul(big.NewInt(int64(deal.EndEpoch-paidEpoch)), deal.StoragePricePerEpoch)
+		if deal.Client == a {
+			ret.ClientStorageFee = big.Add(ret.ClientStorageFee, lockedFee)
+			ret.ClientCollateral = big.Add(ret.ClientCollateral, deal.ClientCollateral)
+		}
+		if deal.Provider == a {
+			ret.ProviderCollateral = big.Add(ret.ProviderCollateral, deal.ProviderCollateral)
+		}
+
+		if !scheduled {
+			ret.Unscheduled = append(ret.Unscheduled, id)
+			continue
+		}
+		if activated && state.SlashEpoch != epochUndefined {
+			addUnlock(deal, nextOp, lockedFee, true)
+			continue
+		}
+		for epoch := nextOp; ; epoch += DealUpdatesInterval {
+			if epoch >= deal.EndEpoch {
+				fee := big.Mul(big.NewInt(int64(deal.EndEpoch-paidEpoch)), deal.StoragePricePerEpoch)
+				addUnlock(deal, epoch, fee, true)
+				break
+			}
+			if epoch > paidEpoch {
+				addUnlock(deal, epoch, big.Mul(big.NewInt(int64(epoch-paidEpoch)), deal.StoragePricePerEpoch), false)
+				paidEpoch = epoch
+			}
+		}
+	}
+
+	for _, u := range unlocks { //nolint:nomaprange
+		ret.Unlocks = append(ret.Unlocks, *u)
+	}
+	sort.Slice(ret.Unlocks, func(i, j int) bool {
+		return ret.Unlocks[i].Epoch < ret.Unlocks[j].Epoch
+	})
+	return ret, nil
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // Deal state operations
 ////////////////////////////////////////////////////////////////////////////////
@@ -143,7 +346,7 @@ func (m *marketStateMutation) updatePendingDealState(rt Runtime, state *DealStat
 
 		// the transfer amount can be less than or equal to zero if a deal is slashed before or at the deal's start epoch.
 		if totalPayment.GreaterThan(big.Zero()) {
-			err := m.transferBalance(deal.Client, deal.Provider, totalPayment)
+			err := m.transferBalance(deal.Client, deal.Provider, totalPayment, ClientStorageFee)
 			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to transfer %v from %v to %v",
 				totalPayment, deal.Client, deal.Provider)
 		}
@@ -206,6 +409,24 @@ func (m *marketStateMutation) processDealInitTimedOut(rt Runtime, deal *DealProp
 	return amountSlashed
 }
 
+// Deal cancelled before activation. Unlock collaterals and storage fee for both provider and client,
+// except that a client cancelling alone forfeits its collateral to the provider.
+func (m *marketStateMutation) processDealCancelled(rt Runtime, deal *DealProposal, forfeitClientCollateral bool) {
+	err := m.unlockBalance(deal.Client, deal.TotalStorageFee(), ClientStorageFee)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed unlocking client storage fee")
+
+	if forfeitClientCollateral {
+		err = m.transferBalance(deal.Client, deal.Provider, deal.ClientCollateral, ClientCollateral)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed transferring client collateral to provider")
+	} else {
+		err = m.unlockBalance(deal.Client, deal.ClientCollateral, ClientCollateral)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed unlocking client collateral")
+	}
+
+	err = m.unlockBalance(deal.Provider, deal.ProviderCollateral, ProviderCollateral)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed unlocking deal provider balance")
+}
+
 // Normal expiration. Unlock collaterals for both provider and client.
 func (m *marketStateMutation) processDealExpired(rt Runtime, deal *DealProposal, state *DealState) {
 	builtin.RequireState(rt, state.SectorStartEpoch != epochUndefined, "sector start epoch undefined")
@@ -292,6 +513,9 @@ type marketStateMutation struct {
 	dpePermit    MarketStateMutationPermission
 	dealsByEpoch *SetMultimap
 
+	precommitPermit   MarketStateMutationPermission
+	precommittedDeals *adt.Set
+
 	lockedPermit                  MarketStateMutationPermission
 	lockedTable                   *adt.BalanceTable
 	totalClientLockedCollateral   abi.TokenAmount
@@ -357,6 +581,14 @@ func (m *marketStateMutation) build() (*marketStateMutation, error) {
 		m.dealsByEpoch = dbe
 	}
 
+	if m.precommitPermit != Invalid {
+		precommitted, err := adt.AsSet(m.store, m.st.PrecommittedDeals, builtin.DefaultHamtBitwidth)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to load precommitted deals: %w", err)
+		}
+		m.precommittedDeals = precommitted
+	}
+
 	m.nextDealId = m.st.NextID
 
 	return m, nil
@@ -392,6 +624,11 @@ func (m *marketStateMutation) withDealsByEpoch(permit MarketStateMutationPermiss
 	return m
 }
 
+func (m *marketStateMutation) withPrecommittedDeals(permit MarketStateMutationPermission) *marketStateMutation {
+	m.precommitPermit = permit
+	return m
+}
+
 func (m *marketStateMutation) commitState() error {
 	var err error
 	if m.proposalPermit == WritePermission {
@@ -433,6 +670,12 @@ func (m *marketStateMutation) commitState() error {
 		}
 	}
 
+	if m.precommitPermit == WritePermission {
+		if m.st.PrecommittedDeals, err = m.precommittedDeals.Root(); err != nil {
+			return xerrors.Errorf("failed to flush precommitted deals: %w", err)
+		}
+	}
+
 	m.st.NextID = m.nextDealId
 	return nil
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"strings"
 	"testing"
@@ -762,20 +763,20 @@ func TestPublishStorageDealsFailures(t *testing.T) {
 					a.addParticipantFunds(rt, client, big.Sub(d.ClientBalanceRequirement(), big.NewInt(1)))
 					a.addProviderFunds(rt, d.ProviderCollateral, mAddrs)
 				},
-				exitCode: exitcode.ErrInsufficientFunds,
+				exitCode: exitcode.ErrIllegalArgument,
 			},
 			"provider does not have enough balance for collateral": {
 				setup: func(rt *mock.Runtime, a *marketActorTestHarness, d *market.DealProposal) {
 					a.addParticipantFunds(rt, client, d.ClientBalanceRequirement())
 					a.addProviderFunds(rt, big.Sub(d.ProviderCollateral, big.NewInt(1)), mAddrs)
 				},
-				exitCode: exitcode.ErrInsufficientFunds,
+				exitCode: exitcode.ErrIllegalArgument,
 			},
 			"unable to resolve client address": {
 				setup: func(_ *mock.Runtime, a *marketActorTestHarness, d *market.DealProposal) {
 					d.Client = tutil.NewBLSAddr(t, 1)
 				},
-				exitCode: exitcode.ErrNotFound,
+				exitCode: exitcode.ErrIllegalArgument,
 			},
 			"signature is invalid": {
 				setup: func(_ *mock.Runtime, a *marketActorTestHarness, d *market.DealProposal) {
@@ -788,13 +789,13 @@ func TestPublishStorageDealsFailures(t *testing.T) {
 				setup: func(rt *mock.Runtime, a *marketActorTestHarness, d *market.DealProposal) {
 					a.addProviderFunds(rt, d.ProviderCollateral, mAddrs)
 				},
-				exitCode: exitcode.ErrInsufficientFunds,
+				exitCode: exitcode.ErrIllegalArgument,
 			},
 			"no entry for provider in locked  balance table": {
 				setup: func(rt *mock.Runtime, a *marketActorTestHarness, d *market.DealProposal) {
 					a.addParticipantFunds(rt, client, d.ClientBalanceRequirement())
 				},
-				exitCode: exitcode.ErrInsufficientFunds,
+				exitCode: exitcode.ErrIllegalArgument,
 			},
 			"bad piece CID": {
 				setup: func(_ *mock.Runtime, _ *marketActorTestHarness, d *market.DealProposal) {
@@ -863,7 +864,7 @@ func TestPublishStorageDealsFailures(t *testing.T) {
 			expectQueryNetworkInfo(rt, actor)
 			rt.SetCaller(worker, builtin.AccountActorCodeID)
 			rt.ExpectVerifySignature(crypto.Signature{}, deal1.Client, mustCbor(&deal1), nil)
-			rt.ExpectAbort(exitcode.ErrInsufficientFunds, func() {
+			rt.ExpectAbort(exitcode.ErrIllegalArgument, func() {
 				rt.Call(actor.PublishStorageDeals, params)
 			})
 
@@ -885,7 +886,7 @@ func TestPublishStorageDealsFailures(t *testing.T) {
 			expectQueryNetworkInfo(rt, actor)
 			rt.SetCaller(worker, builtin.AccountActorCodeID)
 			rt.ExpectVerifySignature(crypto.Signature{}, deal1.Client, mustCbor(&deal1), nil)
-			rt.ExpectAbort(exitcode.ErrInsufficientFunds, func() {
+			rt.ExpectAbort(exitcode.ErrIllegalArgument, func() {
 				rt.Call(actor.PublishStorageDeals, params)
 			})
 
@@ -894,9 +895,9 @@ func TestPublishStorageDealsFailures(t *testing.T) {
 		})
 	}
 
-	// fail when deals have different providers
+	// deals for a different provider are dropped
 	{
-		t.Run("fail when deals have different providers", func(t *testing.T) {
+		t.Run("drop deals with a different provider", func(t *testing.T) {
 			rt, actor := basicMarketSetup(t, owner, provider, worker, client)
 			deal1 := actor.generateDealAndAddFunds(rt, client, mAddrs, startEpoch, endEpoch)
 			m2 := &minerAddrs{owner, worker, tutil.NewIDAddr(t, 1000), nil}
@@ -914,11 +915,12 @@ func TestPublishStorageDealsFailures(t *testing.T) {
 
 			actor.expectGetRandom(rt, &deal1, abi.ChainEpoch(100))
 
-			rt.ExpectAbort(exitcode.ErrIllegalArgument, func() {
-				rt.Call(actor.PublishStorageDeals, params)
-			})
-
+			ret := rt.Call(actor.PublishStorageDeals, params).(*market.PublishStorageDealsReturn)
 			rt.Verify()
+			require.Len(t, ret.IDs, 1)
+			valid, err := ret.ValidDeals.All(uint64(len(params.Deals)))
+			require.NoError(t, err)
+			assert.Equal(t, []uint64{0}, valid)
 			actor.checkState(rt)
 		})
 
@@ -996,6 +998,233 @@ func TestPublishStorageDealsFailures(t *testing.T) {
 	})
 }
 
+func TestPublishStorageDealsPartialSuccess(t *testing.T) {
+	owner := tutil.NewIDAddr(t, 101)
+	provider := tutil.NewIDAddr(t, 102)
+	worker := tutil.NewIDAddr(t, 103)
+	client := tutil.NewIDAddr(t, 104)
+	unfundedClient := tutil.NewIDAddr(t, 105)
+	mAddrs := &minerAddrs{owner, worker, provider, nil}
+	startEpoch := abi.ChainEpoch(42)
+	endEpoch := startEpoch + 200*builtin.EpochsInDay
+
+	t.Run("publishes valid deals and drops invalid ones", func(t *testing.T) {
+		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
+
+		valid1 := actor.generateDealAndAddFunds(rt, client, mAddrs, startEpoch, endEpoch)
+		badSignature := actor.generateDealAndAddFunds(rt, client, mAddrs, startEpoch+1, endEpoch)
+		unfunded := generateDealProposal(unfundedClient, provider, startEpoch+2, endEpoch)
+		actor.addProviderFunds(rt, unfunded.ProviderCollateral, mAddrs)
+		noDataCap := actor.generateDealAndAddFunds(rt, client, mAddrs, startEpoch+3, endEpoch)
+		noDataCap.VerifiedDeal = true
+		duplicate := valid1
+		valid2 := actor.generateDealAndAddFunds(rt, client, mAddrs, startEpoch+4, endEpoch)
+
+		params := mkPublishStorageParams(valid1, badSignature, unfunded, noDataCap, duplicate, valid2)
+		rt.SetCaller(worker, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerType(builtin.CallerTypesSignable...)
+		rt.ExpectSend(provider, builtin.MethodsMiner.ControlAddresses, nil, abi.NewTokenAmount(0), &miner.GetControlAddressesReturn{Worker: worker, Owner: owner}, 0)
+		expectQueryNetworkInfo(rt, actor)
+		rt.ExpectVerifySignature(crypto.Signature{}, client, mustCbor(&valid1), nil)
+		rt.ExpectVerifySignature(crypto.Signature{}, client, mustCbor(&badSignature), errors.New("bad signature"))
+		rt.ExpectVerifySignature(crypto.Signature{}, unfundedClient, mustCbor(&unfunded), nil)
+		rt.ExpectVerifySignature(crypto.Signature{}, client, mustCbor(&noDataCap), nil)
+		rt.ExpectSend(builtin.VerifiedRegistryActorAddr, builtin.MethodsVerifiedRegistry.UseBytes, &verifreg.UseBytesParams{
+			Address:  client,
+			DealSize: big.NewIntUnsigned(uint64(noDataCap.PieceSize)),
+		}, abi.NewTokenAmount(0), nil, exitcode.ErrIllegalArgument)
+		rt.ExpectVerifySignature(crypto.Signature{}, client, mustCbor(&duplicate), nil)
+		rt.ExpectVerifySignature(crypto.Signature{}, client, mustCbor(&valid2), nil)
+		actor.expectGetRandom(rt, &valid1, abi.ChainEpoch(100))
+		actor.expectGetRandom(rt, &valid2, abi.ChainEpoch(100))
+
+		ret := rt.Call(actor.PublishStorageDeals, params).(*market.PublishStorageDealsReturn)
+		rt.Verify()
+
+		require.Len(t, ret.IDs, 2)
+		valid, err := ret.ValidDeals.All(uint64(len(params.Deals)))
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{0, 5}, valid)
+		assert.Equal(t, valid1.StartEpoch, actor.getDealProposal(rt, ret.IDs[0]).StartEpoch)
+		assert.Equal(t, valid2.StartEpoch, actor.getDealProposal(rt, ret.IDs[1]).StartEpoch)
+
+		// Only the published deals' balances are locked.
+		assert.Equal(t, big.Add(valid1.ClientBalanceRequirement(), valid2.ClientBalanceRequirement()), actor.getLockedBalance(rt, client))
+		assert.Equal(t, big.Add(valid1.ProviderCollateral, valid2.ProviderCollateral), actor.getLockedBalance(rt, provider))
+		actor.checkState(rt)
+	})
+
+	t.Run("fails when no deal is valid", func(t *testing.T) {
+		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
+		unfunded := generateDealProposal(unfundedClient, provider, startEpoch, endEpoch)
+		actor.addProviderFunds(rt, unfunded.ProviderCollateral, mAddrs)
+		badSignature := actor.generateDealAndAddFunds(rt, client, mAddrs, startEpoch+1, endEpoch)
+
+		params := mkPublishStorageParams(unfunded, badSignature)
+		rt.SetCaller(worker, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerType(builtin.CallerTypesSignable...)
+		rt.ExpectSend(provider, builtin.MethodsMiner.ControlAddresses, nil, abi.NewTokenAmount(0), &miner.GetControlAddressesReturn{Worker: worker, Owner: owner}, 0)
+		expectQueryNetworkInfo(rt, actor)
+		rt.ExpectVerifySignature(crypto.Signature{}, unfundedClient, mustCbor(&unfunded), nil)
+		rt.ExpectVerifySignature(crypto.Signature{}, client, mustCbor(&badSignature), errors.New("bad signature"))
+		rt.ExpectAbortContainsMessage(exitcode.ErrIllegalArgument, "all deal proposals invalid", func() {
+			rt.Call(actor.PublishStorageDeals, params)
+		})
+		rt.Verify()
+		actor.checkState(rt)
+	})
+
+	t.Run("drops deals the provider cannot cover", func(t *testing.T) {
+		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
+		deal1 := actor.generateDealAndAddFunds(rt, client, mAddrs, startEpoch, endEpoch)
+		deal2 := generateDealProposal(client, provider, startEpoch+1, endEpoch)
+		actor.addParticipantFunds(rt, client, deal2.ClientBalanceRequirement())
+
+		params := mkPublishStorageParams(deal1, deal2)
+		rt.SetCaller(worker, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerType(builtin.CallerTypesSignable...)
+		rt.ExpectSend(provider, builtin.MethodsMiner.ControlAddresses, nil, abi.NewTokenAmount(0), &miner.GetControlAddressesReturn{Worker: worker, Owner: owner}, 0)
+		expectQueryNetworkInfo(rt, actor)
+		rt.ExpectVerifySignature(crypto.Signature{}, client, mustCbor(&deal1), nil)
+		rt.ExpectVerifySignature(crypto.Signature{}, client, mustCbor(&deal2), nil)
+		actor.expectGetRandom(rt, &deal1, abi.ChainEpoch(100))
+
+		ret := rt.Call(actor.PublishStorageDeals, params).(*market.PublishStorageDealsReturn)
+		rt.Verify()
+
+		require.Len(t, ret.IDs, 1)
+		valid, err := ret.ValidDeals.All(uint64(len(params.Deals)))
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{0}, valid)
+		assert.Equal(t, deal1.ProviderCollateral, actor.getLockedBalance(rt, provider))
+		assert.Equal(t, deal1.ClientBalanceRequirement(), actor.getLockedBalance(rt, client))
+		actor.checkState(rt)
+	})
+
+	t.Run("fails when provider cannot cover any deal", func(t *testing.T) {
+		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
+		deal := generateDealProposal(client, provider, startEpoch, endEpoch)
+		actor.addParticipantFunds(rt, client, deal.ClientBalanceRequirement())
+
+		params := mkPublishStorageParams(deal)
+		rt.SetCaller(worker, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerType(builtin.CallerTypesSignable...)
+		rt.ExpectSend(provider, builtin.MethodsMiner.ControlAddresses, nil, abi.NewTokenAmount(0), &miner.GetControlAddressesReturn{Worker: worker, Owner: owner}, 0)
+		expectQueryNetworkInfo(rt, actor)
+		rt.ExpectVerifySignature(crypto.Signature{}, client, mustCbor(&deal), nil)
+		rt.ExpectAbortContainsMessage(exitcode.ErrIllegalArgument, "all deal proposals invalid", func() {
+			rt.Call(actor.PublishStorageDeals, params)
+		})
+		rt.Verify()
+		actor.checkState(rt)
+	})
+}
+
+func TestDealLabel(t *testing.T) {
+	roundTrip := func(t *testing.T, label market.DealLabel) market.DealLabel {
+		buf := bytes.Buffer{}
+		require.NoError(t, label.MarshalCBOR(&buf))
+		var out market.DealLabel
+		require.NoError(t, out.UnmarshalCBOR(&buf))
+		return out
+	}
+
+	t.Run("string label round trips and encodes as a plain string", func(t *testing.T) {
+		label := mustLabel("hello")
+		out := roundTrip(t, label)
+		assert.True(t, out.IsString())
+		assert.True(t, label.Equals(out))
+		str, err := out.ToString()
+		require.NoError(t, err)
+		assert.Equal(t, "hello", str)
+
+		labelBuf := bytes.Buffer{}
+		require.NoError(t, label.MarshalCBOR(&labelBuf))
+		strBuf := bytes.Buffer{}
+		require.NoError(t, cbg.WriteMajorTypeHeader(&strBuf, cbg.MajTextString, 5))
+		strBuf.WriteString("hello")
+		assert.Equal(t, strBuf.Bytes(), labelBuf.Bytes())
+	})
+
+	t.Run("bytes label round trips", func(t *testing.T) {
+		label := market.NewLabelFromBytes([]byte{0xff, 0x00, 0xfe})
+		out := roundTrip(t, label)
+		assert.True(t, out.IsBytes())
+		assert.True(t, label.Equals(out))
+		assert.Equal(t, []byte{0xff, 0x00, 0xfe}, out.ToBytes())
+		assert.Equal(t, 3, out.Length())
+		_, err := out.ToString()
+		assert.Error(t, err)
+	})
+
+	t.Run("empty label is an empty string", func(t *testing.T) {
+		out := roundTrip(t, market.EmptyDealLabel)
+		assert.True(t, out.IsString())
+		assert.Equal(t, 0, out.Length())
+		assert.False(t, out.Equals(market.NewLabelFromBytes(nil)))
+	})
+
+	t.Run("rejects strings that are not valid UTF-8", func(t *testing.T) {
+		_, err := market.NewLabelFromString("\xff\xfe")
+		assert.Error(t, err)
+
+		buf := bytes.Buffer{}
+		require.NoError(t, cbg.WriteMajorTypeHeader(&buf, cbg.MajTextString, 2))
+		buf.Write([]byte{0xff, 0xfe})
+		var out market.DealLabel
+		assert.Error(t, out.UnmarshalCBOR(&buf))
+	})
+
+	t.Run("legacy strings that are not valid UTF-8 become bytes", func(t *testing.T) {
+		assert.True(t, market.LabelFromLegacyString("valid").IsString())
+		label := market.LabelFromLegacyString("\xff\xfe")
+		assert.True(t, label.IsBytes())
+		assert.Equal(t, []byte{0xff, 0xfe}, label.ToBytes())
+	})
+
+	t.Run("labels round trip through JSON", func(t *testing.T) {
+		for _, label := range []market.DealLabel{
+			mustLabel("hello"),
+			market.EmptyDealLabel,
+			market.NewLabelFromBytes([]byte{0xff, 0x00, 0xfe}),
+			market.NewLabelFromBytes(nil),
+		} {
+			b, err := json.Marshal(label)
+			require.NoError(t, err)
+			var out market.DealLabel
+			require.NoError(t, json.Unmarshal(b, &out))
+			assert.True(t, label.Equals(out), "label %s decoded as %s", label, out)
+		}
+
+		b, err := json.Marshal(mustLabel("hello"))
+		require.NoError(t, err)
+		assert.Equal(t, `"hello"`, string(b))
+		b, err = json.Marshal(market.NewLabelFromBytes([]byte("hello")))
+		require.NoError(t, err)
+		assert.Equal(t, `{"bytes":"aGVsbG8="}`, string(b))
+
+		var out market.DealLabel
+		assert.Error(t, json.Unmarshal([]byte(`{"other":"aGVsbG8="}`), &out))
+		assert.Error(t, json.Unmarshal([]byte(`42`), &out))
+	})
+
+	t.Run("proposal with bytes label can be published", func(t *testing.T) {
+		owner := tutil.NewIDAddr(t, 101)
+		provider := tutil.NewIDAddr(t, 102)
+		worker := tutil.NewIDAddr(t, 103)
+		client := tutil.NewIDAddr(t, 104)
+		mAddrs := &minerAddrs{owner, worker, provider, nil}
+		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
+
+		deal := actor.generateDealAndAddFunds(rt, client, mAddrs, abi.ChainEpoch(42), abi.ChainEpoch(42)+200*builtin.EpochsInDay)
+		deal.Label = market.NewLabelFromBytes([]byte{0xff, 0xfe})
+		rt.SetCaller(worker, builtin.AccountActorCodeID)
+		ids := actor.publishDeals(rt, mAddrs, publishDealReq{deal: deal})
+		assert.True(t, actor.getDealProposal(rt, ids[0]).Label.IsBytes())
+		actor.checkState(rt)
+	})
+}
+
 func TestActivateDeals(t *testing.T) {
 
 	owner := tutil.NewIDAddr(t, 101)
@@ -1830,6 +2059,22 @@ func TestCronTickTimedoutDeals(t *testing.T) {
 		actor.checkState(rt)
 	})
 
+	t.Run("timed out precommitted deal is no longer recorded as precommitted", func(t *testing.T) {
+		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
+		dealId := actor.generateAndPublishDeal(rt, client, mAddrs, startEpoch, endEpoch, startEpoch)
+		d := actor.getDealProposal(rt, dealId)
+		actor.verifyDealsForActivation(rt, provider, []market.SectorDeals{{SectorExpiry: endEpoch + 100, DealIDs: []abi.DealID{dealId}}})
+		actor.assertPrecommitted(rt, dealId, true)
+
+		rt.SetEpoch(startEpoch)
+		rt.ExpectSend(builtin.BurntFundsActorAddr, builtin.MethodSend, nil, d.ProviderCollateral, nil, exitcode.Ok)
+		actor.cronTick(rt)
+
+		actor.assertDealDeleted(rt, dealId, d)
+		actor.assertPrecommitted(rt, dealId, false)
+		actor.checkState(rt)
+	})
+
 	t.Run("timed out and verified deals are slashed, deleted AND sent to the Registry actor", func(t *testing.T) {
 		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
 		// deal1 and deal2 are verified
@@ -2389,7 +2634,7 @@ func TestMarketActorDeals(t *testing.T) {
 		rt.Verify()
 	}
 
-	dealProposal.Label = "foo"
+	dealProposal.Label = mustLabel("foo")
 
 	// Same deal with a different label should work
 	{
@@ -2417,7 +2662,7 @@ func TestMaxDealLabelSize(t *testing.T) {
 	actor.addParticipantFunds(rt, client, abi.NewTokenAmount(20000000))
 
 	dealProposal := generateDealProposal(client, provider, abi.ChainEpoch(1), abi.ChainEpoch(200*builtin.EpochsInDay))
-	dealProposal.Label = string(make([]byte, market.DealMaxLabelSize))
+	dealProposal.Label = market.NewLabelFromBytes(make([]byte, market.DealMaxLabelSize))
 	params := &market.PublishStorageDealsParams{Deals: []market.ClientDealProposal{{Proposal: dealProposal}}}
 
 	// Label at max size should work.
@@ -2426,7 +2671,7 @@ func TestMaxDealLabelSize(t *testing.T) {
 		actor.publishDeals(rt, minerAddrs, publishDealReq{deal: dealProposal})
 	}
 
-	dealProposal.Label = string(make([]byte, market.DealMaxLabelSize+1))
+	dealProposal.Label = mustLabel(string(make([]byte, market.DealMaxLabelSize+1)))
 
 	// Label greater than max size should fail.
 	{
@@ -2510,6 +2755,564 @@ func TestComputeDataCommitment(t *testing.T) {
 	})
 }
 
+func TestCancelDeal(t *testing.T) {
+	owner := tutil.NewIDAddr(t, 101)
+	provider := tutil.NewIDAddr(t, 102)
+	worker := tutil.NewIDAddr(t, 103)
+	client := tutil.NewIDAddr(t, 104)
+	other := tutil.NewIDAddr(t, 105)
+	mAddrs := &minerAddrs{owner, worker, provider, nil}
+
+	startEpoch := abi.ChainEpoch(50)
+	endEpoch := startEpoch + 200*builtin.EpochsInDay
+	processEpoch := startEpoch + 5
+	sectorExpiry := endEpoch + 100
+	sig := crypto.Signature{Type: crypto.SigTypeBLS, Data: []byte("cancel")}
+
+	t.Run("client cancels alone before precommit and forfeits collateral to provider", func(t *testing.T) {
+		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
+		dealID := actor.generateAndPublishDeal(rt, client, mAddrs, startEpoch, endEpoch, processEpoch)
+		d := actor.getDealProposal(rt, dealID)
+		clientEscrow := actor.getEscrowBalance(rt, client)
+		providerEscrow := actor.getEscrowBalance(rt, provider)
+
+		var st market.State
+		rt.GetState(&st)
+		foundEpoch, found, err := st.FindPendingDealOpEpoch(rt.AdtStore(), dealID)
+		require.NoError(t, err)
+		require.True(t, found)
+		assert.Equal(t, processEpoch, foundEpoch)
+
+		actor.cancelDeal(rt, client, &market.CancelDealParams{DealID: dealID, ProcessEpoch: processEpoch})
+
+		assert.Equal(t, big.Sub(clientEscrow, d.ClientCollateral), actor.getEscrowBalance(rt, client))
+		assert.Equal(t, big.Zero(), actor.getLockedBalance(rt, client))
+		assert.Equal(t, big.Add(providerEscrow, d.ClientCollateral), actor.getEscrowBalance(rt, provider))
+		assert.Equal(t, big.Zero(), actor.getLockedBalance(rt, provider))
+		actor.assertLockedFundStates(rt, big.Zero(), big.Zero(), big.Zero())
+		actor.assertDealDeleted(rt, dealID, d)
+		actor.assertNoDealOp(rt, processEpoch, dealID)
+
+		// Nothing is slashed when the deal would have started.
+		rt.SetEpoch(processEpoch)
+		actor.cronTick(rt)
+		actor.checkState(rt)
+	})
+
+	t.Run("provider cancels jointly with client signature", func(t *testing.T) {
+		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
+		dealID := actor.generateAndPublishDeal(rt, client, mAddrs, startEpoch, endEpoch, processEpoch)
+		d := actor.getDealProposal(rt, dealID)
+		clientEscrow := actor.getEscrowBalance(rt, client)
+		providerEscrow := actor.getEscrowBalance(rt, provider)
+
+		expectGetControlAddresses(rt, provider, owner, worker)
+		rt.ExpectVerifySignature(sig, client, actor.dealCancellationBytes(rt, dealID), nil)
+		actor.cancelDeal(rt, worker, &market.CancelDealParams{DealID: dealID, ProcessEpoch: processEpoch, CounterpartySignature: &sig})
+
+		assert.Equal(t, clientEscrow, actor.getEscrowBalance(rt, client))
+		assert.Equal(t, big.Zero(), actor.getLockedBalance(rt, client))
+		assert.Equal(t, providerEscrow, actor.getEscrowBalance(rt, provider))
+		assert.Equal(t, big.Zero(), actor.getLockedBalance(rt, provider))
+		actor.assertDealDeleted(rt, dealID, d)
+		actor.assertNoDealOp(rt, processEpoch, dealID)
+		actor.checkState(rt)
+	})
+
+	t.Run("client cancels precommitted deal jointly with provider signature", func(t *testing.T) {
+		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
+		dealID := actor.generateAndPublishDeal(rt, client, mAddrs, startEpoch, endEpoch, processEpoch)
+		d := actor.getDealProposal(rt, dealID)
+		clientEscrow := actor.getEscrowBalance(rt, client)
+		actor.verifyDealsForActivation(rt, provider, []market.SectorDeals{{SectorExpiry: sectorExpiry, DealIDs: []abi.DealID{dealID}}})
+		actor.assertPrecommitted(rt, dealID, true)
+
+		expectGetControlAddresses(rt, provider, owner, worker)
+		rt.ExpectVerifySignature(sig, worker, actor.dealCancellationBytes(rt, dealID), nil)
+		actor.cancelDeal(rt, client, &market.CancelDealParams{DealID: dealID, ProcessEpoch: processEpoch, CounterpartySignature: &sig})
+
+		assert.Equal(t, clientEscrow, actor.getEscrowBalance(rt, client))
+		assert.Equal(t, big.Zero(), actor.getLockedBalance(rt, client))
+		actor.assertPrecommitted(rt, dealID, false)
+		actor.assertDealDeleted(rt, dealID, d)
+		actor.checkState(rt)
+	})
+
+	t.Run("cancelling verified deal restores data cap", func(t *testing.T) {
+		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
+		deal := actor.generateDealAndAddFunds(rt, client, mAddrs, startEpoch, endEpoch)
+		deal.VerifiedDeal = true
+		rt.SetCaller(worker, builtin.AccountActorCodeID)
+		dealID := actor.publishDeals(rt, mAddrs, publishDealReq{deal, processEpoch})[0]
+
+		rt.ExpectSend(builtin.VerifiedRegistryActorAddr, builtin.MethodsVerifiedRegistry.RestoreBytes, &verifreg.RestoreBytesParams{
+			Address:  client,
+			DealSize: big.NewIntUnsigned(uint64(deal.PieceSize)),
+		}, abi.NewTokenAmount(0), nil, exitcode.Ok)
+		actor.cancelDeal(rt, client, &market.CancelDealParams{DealID: dealID, ProcessEpoch: processEpoch})
+
+		actor.assertDealDeleted(rt, dealID, &deal)
+		actor.checkState(rt)
+	})
+
+	t.Run("client cannot cancel precommitted deal alone", func(t *testing.T) {
+		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
+		dealID := actor.generateAndPublishDeal(rt, client, mAddrs, startEpoch, endEpoch, processEpoch)
+		actor.verifyDealsForActivation(rt, provider, []market.SectorDeals{{SectorExpiry: sectorExpiry, DealIDs: []abi.DealID{dealID}}})
+
+		actor.expectCancelDealAbort(rt, exitcode.ErrForbidden, client, &market.CancelDealParams{DealID: dealID, ProcessEpoch: processEpoch})
+		actor.assertPrecommitted(rt, dealID, true)
+		actor.checkState(rt)
+	})
+
+	t.Run("provider cannot cancel without client signature", func(t *testing.T) {
+		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
+		dealID := actor.generateAndPublishDeal(rt, client, mAddrs, startEpoch, endEpoch, processEpoch)
+
+		expectGetControlAddresses(rt, provider, owner, worker)
+		actor.expectCancelDealAbort(rt, exitcode.ErrForbidden, worker, &market.CancelDealParams{DealID: dealID, ProcessEpoch: processEpoch})
+		actor.checkState(rt)
+	})
+
+	t.Run("fails when caller is not a party to the deal", func(t *testing.T) {
+		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
+		dealID := actor.generateAndPublishDeal(rt, client, mAddrs, startEpoch, endEpoch, processEpoch)
+
+		expectGetControlAddresses(rt, provider, owner, worker)
+		actor.expectCancelDealAbort(rt, exitcode.ErrForbidden, other, &market.CancelDealParams{DealID: dealID, ProcessEpoch: processEpoch, CounterpartySignature: &sig})
+		actor.checkState(rt)
+	})
+
+	t.Run("fails with invalid counterparty signature", func(t *testing.T) {
+		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
+		dealID := actor.generateAndPublishDeal(rt, client, mAddrs, startEpoch, endEpoch, processEpoch)
+
+		expectGetControlAddresses(rt, provider, owner, worker)
+		rt.ExpectVerifySignature(sig, client, actor.dealCancellationBytes(rt, dealID), errors.New("bad signature"))
+		actor.expectCancelDealAbort(rt, exitcode.ErrIllegalArgument, worker, &market.CancelDealParams{DealID: dealID, ProcessEpoch: processEpoch, CounterpartySignature: &sig})
+		actor.checkState(rt)
+	})
+
+	t.Run("cancellation signature is domain separated and bound to the proposal", func(t *testing.T) {
+		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
+		dealID := actor.generateAndPublishDeal(rt, client, mAddrs, startEpoch, endEpoch, processEpoch)
+		otherID := actor.generateAndPublishDeal(rt, client, mAddrs, startEpoch, endEpoch+1, processEpoch)
+
+		toSign := actor.dealCancellationBytes(rt, dealID)
+		assert.True(t, bytes.HasPrefix(toSign, market.DealCancellationSignaturePrefix))
+		assert.NotEqual(t, actor.dealCancellationBytes(rt, otherID), toSign)
+
+		// Consent to cancel the other deal can't be used to cancel this one.
+		expectGetControlAddresses(rt, provider, owner, worker)
+		rt.ExpectVerifySignature(sig, client, toSign, errors.New("signed bytes differ"))
+		actor.expectCancelDealAbort(rt, exitcode.ErrIllegalArgument, worker, &market.CancelDealParams{DealID: dealID, ProcessEpoch: processEpoch, CounterpartySignature: &sig})
+		actor.checkState(rt)
+	})
+
+	t.Run("fails with wrong process epoch", func(t *testing.T) {
+		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
+		dealID := actor.generateAndPublishDeal(rt, client, mAddrs, startEpoch, endEpoch, processEpoch)
+
+		actor.expectCancelDealAbort(rt, exitcode.ErrIllegalArgument, client, &market.CancelDealParams{DealID: dealID, ProcessEpoch: processEpoch + 1})
+		actor.checkState(rt)
+	})
+
+	t.Run("fails at start epoch", func(t *testing.T) {
+		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
+		dealID := actor.generateAndPublishDeal(rt, client, mAddrs, startEpoch, endEpoch, processEpoch)
+
+		rt.SetEpoch(startEpoch)
+		actor.expectCancelDealAbort(rt, exitcode.ErrIllegalArgument, client, &market.CancelDealParams{DealID: dealID, ProcessEpoch: processEpoch})
+		actor.checkState(rt)
+	})
+
+	t.Run("fails for activated deal", func(t *testing.T) {
+		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
+		dealID := actor.generateAndPublishDeal(rt, client, mAddrs, startEpoch, endEpoch, processEpoch)
+		actor.verifyDealsForActivation(rt, provider, []market.SectorDeals{{SectorExpiry: sectorExpiry, DealIDs: []abi.DealID{dealID}}})
+		actor.activateDeals(rt, sectorExpiry, provider, rt.Epoch(), dealID)
+		actor.assertPrecommitted(rt, dealID, false)
+
+		expectGetControlAddresses(rt, provider, owner, worker)
+		rt.ExpectVerifySignature(sig, worker, actor.dealCancellationBytes(rt, dealID), nil)
+		actor.expectCancelDealAbort(rt, exitcode.ErrIllegalArgument, client, &market.CancelDealParams{DealID: dealID, ProcessEpoch: processEpoch, CounterpartySignature: &sig})
+		actor.checkState(rt)
+	})
+
+	t.Run("fails for unknown deal", func(t *testing.T) {
+		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
+
+		actor.expectCancelDealAbort(rt, exitcode.ErrNotFound, client, &market.CancelDealParams{DealID: 42, ProcessEpoch: processEpoch})
+		actor.checkState(rt)
+	})
+}
+
+func TestRenewDeal(t *testing.T) {
+	owner := tutil.NewIDAddr(t, 101)
+	provider := tutil.NewIDAddr(t, 102)
+	worker := tutil.NewIDAddr(t, 103)
+	client := tutil.NewIDAddr(t, 104)
+	mAddrs := &minerAddrs{owner, worker, provider, nil}
+
+	startEpoch := abi.ChainEpoch(50)
+	endEpoch := startEpoch + 200*builtin.EpochsInDay
+	sectorExpiry := endEpoch + 2000
+	renewEpoch := startEpoch + 100
+	sig := crypto.Signature{Type: crypto.SigTypeBLS, Data: []byte("renew")}
+
+	// Publishes and activates a deal, and processes it once so it is no longer pending.
+	setup := func(t *testing.T) (*mock.Runtime, *marketActorTestHarness, abi.DealID) {
+		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
+		dealID := actor.publishAndActivateDeal(rt, client, mAddrs, startEpoch, endEpoch, 0, sectorExpiry, startEpoch)
+		rt.SetEpoch(startEpoch)
+		actor.cronTick(rt)
+		return rt, actor, dealID
+	}
+
+	renewal := func(dealID abi.DealID, newEnd abi.ChainEpoch, price, clientCollateral, providerCollateral int64) market.ClientDealRenewal {
+		return market.ClientDealRenewal{
+			Renewal: market.DealRenewal{
+				DealID:                       dealID,
+				NewEndEpoch:                  newEnd,
+				NewStoragePricePerEpoch:      big.NewInt(price),
+				AdditionalClientCollateral:   big.NewInt(clientCollateral),
+				AdditionalProviderCollateral: big.NewInt(providerCollateral),
+			},
+			ClientSignature: sig,
+		}
+	}
+
+	t.Run("renewal settles payment and locks fees and collateral for the new term", func(t *testing.T) {
+		rt, actor, dealID := setup(t)
+		d := actor.getDealProposal(rt, dealID)
+		newEnd := endEpoch + 1000
+		oldRemainingFee := big.Mul(big.NewInt(int64(endEpoch-renewEpoch)), d.StoragePricePerEpoch)
+		newRemainingFee := big.Mul(big.NewInt(int64(newEnd-renewEpoch)), big.NewInt(20))
+		actor.addParticipantFunds(rt, client, big.Add(big.Sub(newRemainingFee, oldRemainingFee), big.NewInt(5)))
+		actor.addProviderFunds(rt, big.NewInt(7), mAddrs)
+
+		clientEscrow := actor.getEscrowBalance(rt, client)
+		clientLocked := actor.getLockedBalance(rt, client)
+		providerEscrow := actor.getEscrowBalance(rt, provider)
+		providerLocked := actor.getLockedBalance(rt, provider)
+
+		rt.SetEpoch(renewEpoch)
+		r := renewal(dealID, newEnd, 20, 5, 7)
+		rt.ExpectVerifySignature(sig, client, mustCbor(&r.Renewal), nil)
+		ret := actor.renewDeal(rt, provider, &market.RenewDealParams{Renewal: r, SectorExpiry: sectorExpiry})
+		assert.Equal(t, big.NewInt(int64(d.PieceSize)*1000), ret.DealWeightDelta)
+		assert.Equal(t, big.Zero(), ret.VerifiedDealWeightDelta)
+
+		// Elapsed epochs are paid at the old price.
+		payment := big.Mul(big.NewInt(int64(renewEpoch-startEpoch)), d.StoragePricePerEpoch)
+		assert.Equal(t, big.Sub(clientEscrow, payment), actor.getEscrowBalance(rt, client))
+		assert.Equal(t, big.Add(providerEscrow, payment), actor.getEscrowBalance(rt, provider))
+		assert.Equal(t, big.Sum(clientLocked, payment.Neg(), big.Sub(newRemainingFee, oldRemainingFee), big.NewInt(5)),
+			actor.getLockedBalance(rt, client))
+		assert.Equal(t, big.Add(providerLocked, big.NewInt(7)), actor.getLockedBalance(rt, provider))
+		actor.assertLockedFundStates(rt, newRemainingFee, big.Add(d.ProviderCollateral, big.NewInt(7)), big.Add(d.ClientCollateral, big.NewInt(5)))
+
+		renewed := actor.getDealProposal(rt, dealID)
+		assert.Equal(t, newEnd, renewed.EndEpoch)
+		assert.Equal(t, big.NewInt(20), renewed.StoragePricePerEpoch)
+		assert.Equal(t, renewEpoch, actor.getDealState(rt, dealID).LastUpdatedEpoch)
+		actor.checkState(rt)
+
+		// The deal is paid at the new price past its old end, until it expires at the new end.
+		current := endEpoch + 10
+		rt.SetEpoch(current)
+		pay, slashed := actor.cronTickAndAssertBalances(rt, client, provider, current, dealID)
+		assert.Equal(t, big.Mul(big.NewInt(int64(current-renewEpoch)), big.NewInt(20)), pay)
+		assert.Equal(t, big.Zero(), slashed)
+
+		rt.SetEpoch(newEnd + market.DealUpdatesInterval)
+		actor.cronTick(rt)
+		actor.assertDealDeleted(rt, dealID, renewed)
+		actor.assertLockedFundStates(rt, big.Zero(), big.Zero(), big.Zero())
+		actor.checkState(rt)
+	})
+
+	t.Run("renewal at a lower price unlocks storage fee", func(t *testing.T) {
+		rt, actor, dealID := setup(t)
+		d := actor.getDealProposal(rt, dealID)
+		newEnd := endEpoch + 1000
+		clientLocked := actor.getLockedBalance(rt, client)
+
+		rt.SetEpoch(renewEpoch)
+		r := renewal(dealID, newEnd, 1, 0, 0)
+		rt.ExpectVerifySignature(sig, client, mustCbor(&r.Renewal), nil)
+		actor.renewDeal(rt, provider, &market.RenewDealParams{Renewal: r, SectorExpiry: sectorExpiry})
+
+		payment := big.Mul(big.NewInt(int64(renewEpoch-startEpoch)), d.StoragePricePerEpoch)
+		oldRemainingFee := big.Mul(big.NewInt(int64(endEpoch-renewEpoch)), d.StoragePricePerEpoch)
+		newRemainingFee := big.NewInt(int64(newEnd - renewEpoch))
+		assert.Equal(t, big.Sum(clientLocked, payment.Neg(), big.Sub(newRemainingFee, oldRemainingFee)), actor.getLockedBalance(rt, client))
+		actor.assertLockedFundStates(rt, newRemainingFee, d.ProviderCollateral, d.ClientCollateral)
+		actor.checkState(rt)
+	})
+
+	t.Run("verified deal renewal returns verified weight", func(t *testing.T) {
+		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
+		deal := actor.generateDealAndAddFunds(rt, client, mAddrs, startEpoch, endEpoch)
+		deal.VerifiedDeal = true
+		rt.SetCaller(worker, builtin.AccountActorCodeID)
+		dealID := actor.publishDeals(rt, mAddrs, publishDealReq{deal: deal, requiredProcessEpoch: startEpoch})[0]
+		actor.activateDeals(rt, sectorExpiry, provider, 0, dealID)
+		rt.SetEpoch(startEpoch)
+		actor.cronTick(rt)
+
+		rt.SetEpoch(renewEpoch)
+		r := renewal(dealID, endEpoch+1000, 0, 0, 0)
+		rt.ExpectVerifySignature(sig, client, mustCbor(&r.Renewal), nil)
+		ret := actor.renewDeal(rt, provider, &market.RenewDealParams{Renewal: r, SectorExpiry: sectorExpiry})
+		assert.Equal(t, big.Zero(), ret.DealWeightDelta)
+		assert.Equal(t, big.NewInt(int64(deal.PieceSize)*1000), ret.VerifiedDealWeightDelta)
+		actor.checkState(rt)
+	})
+
+	t.Run("fails beyond sector expiration", func(t *testing.T) {
+		rt, actor, dealID := setup(t)
+		rt.SetEpoch(renewEpoch)
+		r := renewal(dealID, sectorExpiry+1, 0, 0, 0)
+		rt.ExpectVerifySignature(sig, client, mustCbor(&r.Renewal), nil)
+		actor.expectRenewDealAbort(rt, exitcode.ErrIllegalArgument, provider, &market.RenewDealParams{Renewal: r, SectorExpiry: sectorExpiry})
+		actor.checkState(rt)
+	})
+
+	t.Run("fails if new end epoch is not later", func(t *testing.T) {
+		rt, actor, dealID := setup(t)
+		rt.SetEpoch(renewEpoch)
+		r := renewal(dealID, endEpoch, 0, 0, 0)
+		rt.ExpectVerifySignature(sig, client, mustCbor(&r.Renewal), nil)
+		actor.expectRenewDealAbort(rt, exitcode.ErrIllegalArgument, provider, &market.RenewDealParams{Renewal: r, SectorExpiry: sectorExpiry})
+		actor.checkState(rt)
+	})
+
+	t.Run("fails if duration exceeds maximum", func(t *testing.T) {
+		rt, actor, dealID := setup(t)
+		rt.SetEpoch(renewEpoch)
+		newEnd := startEpoch + market.DealMaxDuration + 1
+		r := renewal(dealID, newEnd, 0, 0, 0)
+		rt.ExpectVerifySignature(sig, client, mustCbor(&r.Renewal), nil)
+		actor.expectRenewDealAbort(rt, exitcode.ErrIllegalArgument, provider, &market.RenewDealParams{Renewal: r, SectorExpiry: newEnd})
+		actor.checkState(rt)
+	})
+
+	t.Run("fails with negative collateral", func(t *testing.T) {
+		rt, actor, dealID := setup(t)
+		rt.SetEpoch(renewEpoch)
+		r := renewal(dealID, endEpoch+1000, 0, -1, 0)
+		actor.expectRenewDealAbort(rt, exitcode.ErrIllegalArgument, provider, &market.RenewDealParams{Renewal: r, SectorExpiry: sectorExpiry})
+		actor.checkState(rt)
+	})
+
+	t.Run("fails with insufficient client funds", func(t *testing.T) {
+		rt, actor, dealID := setup(t)
+		rt.SetEpoch(renewEpoch)
+		r := renewal(dealID, endEpoch+1000, 10, 0, 0)
+		rt.ExpectVerifySignature(sig, client, mustCbor(&r.Renewal), nil)
+		actor.expectRenewDealAbort(rt, exitcode.ErrInsufficientFunds, provider, &market.RenewDealParams{Renewal: r, SectorExpiry: sectorExpiry})
+		actor.checkState(rt)
+	})
+
+	t.Run("fails with invalid client signature", func(t *testing.T) {
+		rt, actor, dealID := setup(t)
+		rt.SetEpoch(renewEpoch)
+		r := renewal(dealID, endEpoch+1000, 0, 0, 0)
+		rt.ExpectVerifySignature(sig, client, mustCbor(&r.Renewal), errors.New("bad signature"))
+		actor.expectRenewDealAbort(rt, exitcode.ErrIllegalArgument, provider, &market.RenewDealParams{Renewal: r, SectorExpiry: sectorExpiry})
+		actor.checkState(rt)
+	})
+
+	t.Run("fails if caller is not the provider", func(t *testing.T) {
+		rt, actor, dealID := setup(t)
+		rt.SetEpoch(renewEpoch)
+		r := renewal(dealID, endEpoch+1000, 0, 0, 0)
+		actor.expectRenewDealAbort(rt, exitcode.ErrForbidden, tutil.NewIDAddr(t, 501), &market.RenewDealParams{Renewal: r, SectorExpiry: sectorExpiry})
+		actor.checkState(rt)
+	})
+
+	t.Run("fails before the deal's first payment", func(t *testing.T) {
+		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
+		dealID := actor.publishAndActivateDeal(rt, client, mAddrs, startEpoch, endEpoch, 0, sectorExpiry, startEpoch)
+		r := renewal(dealID, endEpoch+1000, 0, 0, 0)
+		rt.ExpectVerifySignature(sig, client, mustCbor(&r.Renewal), nil)
+		actor.expectRenewDealAbort(rt, exitcode.ErrIllegalArgument, provider, &market.RenewDealParams{Renewal: r, SectorExpiry: sectorExpiry})
+		actor.checkState(rt)
+	})
+
+	t.Run("fails for slashed deal", func(t *testing.T) {
+		rt, actor, dealID := setup(t)
+		rt.SetEpoch(renewEpoch)
+		actor.terminateDeals(rt, provider, dealID)
+		r := renewal(dealID, endEpoch+1000, 0, 0, 0)
+		rt.ExpectVerifySignature(sig, client, mustCbor(&r.Renewal), nil)
+		actor.expectRenewDealAbort(rt, exitcode.ErrIllegalArgument, provider, &market.RenewDealParams{Renewal: r, SectorExpiry: sectorExpiry})
+		actor.checkState(rt)
+	})
+}
+
+func TestGetBalanceSchedule(t *testing.T) {
+	owner := tutil.NewIDAddr(t, 101)
+	provider := tutil.NewIDAddr(t, 102)
+	worker := tutil.NewIDAddr(t, 103)
+	client := tutil.NewIDAddr(t, 104)
+	mAddrs := &minerAddrs{owner, worker, provider, nil}
+
+	startEpoch := abi.ChainEpoch(50)
+	endEpoch := startEpoch + 200*builtin.EpochsInDay
+	sectorExpiry := endEpoch + 100
+
+	// Checks that the locked balance is split exactly and removed in full by the schedule.
+	assertScheduleCovers := func(t *testing.T, ret *market.GetBalanceScheduleReturn) {
+		assert.Equal(t, ret.Locked, big.Sum(ret.ClientStorageFee, ret.ClientCollateral, ret.ProviderCollateral))
+		total := big.Zero()
+		for i, u := range ret.Unlocks {
+			if i > 0 {
+				assert.Greater(t, u.Epoch, ret.Unlocks[i-1].Epoch)
+			}
+			total = big.Sum(total, u.ClientStorageFee, u.ClientCollateral, u.ProviderCollateral)
+		}
+		assert.Equal(t, ret.Locked, total)
+	}
+
+	t.Run("active deal pays fees at each cron operation and unlocks collateral at expiry", func(t *testing.T) {
+		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
+		dealID := actor.publishAndActivateDeal(rt, client, mAddrs, startEpoch, endEpoch, 0, sectorExpiry, startEpoch)
+		d := actor.getDealProposal(rt, dealID)
+		rt.SetEpoch(startEpoch)
+		actor.cronTick(rt)
+
+		ret := actor.getBalanceSchedule(rt, client)
+		assert.Equal(t, actor.getEscrowBalance(rt, client), ret.Escrow)
+		assert.Equal(t, actor.getLockedBalance(rt, client), ret.Locked)
+		assert.Equal(t, d.TotalStorageFee(), ret.ClientStorageFee)
+		assert.Equal(t, d.ClientCollateral, ret.ClientCollateral)
+		assert.Equal(t, big.Zero(), ret.ProviderCollateral)
+		assertScheduleCovers(t, ret)
+
+		dayFee := big.Mul(big.NewInt(int64(market.DealUpdatesInterval)), d.StoragePricePerEpoch)
+		require.Len(t, ret.Unlocks, 200)
+		assert.Equal(t, market.BalanceUnlock{
+			Epoch:              startEpoch + market.DealUpdatesInterval,
+			ClientStorageFee:   dayFee,
+			ClientCollateral:   big.Zero(),
+			ProviderCollateral: big.Zero(),
+		}, ret.Unlocks[0])
+		assert.Equal(t, market.BalanceUnlock{
+			Epoch:              endEpoch,
+			ClientStorageFee:   dayFee,
+			ClientCollateral:   d.ClientCollateral,
+			ProviderCollateral: big.Zero(),
+		}, ret.Unlocks[199])
+
+		ret = actor.getBalanceSchedule(rt, provider)
+		assert.Equal(t, actor.getEscrowBalance(rt, provider), ret.Escrow)
+		assert.Equal(t, d.ProviderCollateral, ret.ProviderCollateral)
+		assert.Equal(t, []market.BalanceUnlock{{
+			Epoch:              endEpoch,
+			ClientStorageFee:   big.Zero(),
+			ClientCollateral:   big.Zero(),
+			ProviderCollateral: d.ProviderCollateral,
+		}}, ret.Unlocks)
+		assertScheduleCovers(t, ret)
+		actor.checkState(rt)
+	})
+
+	t.Run("pending deal schedule starts at its first cron operation", func(t *testing.T) {
+		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
+		processEpoch := startEpoch + 5
+		dealID := actor.generateAndPublishDeal(rt, client, mAddrs, startEpoch, endEpoch, processEpoch)
+		d := actor.getDealProposal(rt, dealID)
+
+		ret := actor.getBalanceSchedule(rt, client)
+		assert.Equal(t, d.TotalStorageFee(), ret.ClientStorageFee)
+		assertScheduleCovers(t, ret)
+		require.NotEmpty(t, ret.Unlocks)
+		assert.Equal(t, processEpoch, ret.Unlocks[0].Epoch)
+		assert.Equal(t, big.Mul(big.NewInt(5), d.StoragePricePerEpoch), ret.Unlocks[0].ClientStorageFee)
+
+		// The deal expires at the first operation after its end epoch.
+		last := ret.Unlocks[len(ret.Unlocks)-1]
+		assert.Equal(t, endEpoch+5, last.Epoch)
+		assert.Equal(t, d.ClientCollateral, last.ClientCollateral)
+		actor.checkState(rt)
+	})
+
+	t.Run("slashed deal is removed at its next cron operation", func(t *testing.T) {
+		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
+		dealID := actor.publishAndActivateDeal(rt, client, mAddrs, startEpoch, endEpoch, 0, sectorExpiry, startEpoch)
+		d := actor.getDealProposal(rt, dealID)
+		rt.SetEpoch(startEpoch)
+		actor.cronTick(rt)
+		rt.SetEpoch(startEpoch + 100)
+		actor.terminateDeals(rt, provider, dealID)
+
+		remainingFee := big.Mul(big.NewInt(int64(endEpoch-startEpoch)), d.StoragePricePerEpoch)
+		ret := actor.getBalanceSchedule(rt, client)
+		assert.Equal(t, []market.BalanceUnlock{{
+			Epoch:              startEpoch + market.DealUpdatesInterval,
+			ClientStorageFee:   remainingFee,
+			ClientCollateral:   d.ClientCollateral,
+			ProviderCollateral: big.Zero(),
+		}}, ret.Unlocks)
+		assertScheduleCovers(t, ret)
+
+		ret = actor.getBalanceSchedule(rt, provider)
+		require.Len(t, ret.Unlocks, 1)
+		assert.Equal(t, d.ProviderCollateral, ret.Unlocks[0].ProviderCollateral)
+		actor.checkState(rt)
+	})
+
+	t.Run("address without deals has nothing locked", func(t *testing.T) {
+		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
+		actor.addParticipantFunds(rt, client, big.NewInt(100))
+
+		ret := actor.getBalanceSchedule(rt, client)
+		assert.Equal(t, big.NewInt(100), ret.Escrow)
+		assert.Equal(t, big.Zero(), ret.Locked)
+		assert.Equal(t, big.Zero(), ret.ClientStorageFee)
+		assert.Empty(t, ret.Unlocks)
+		actor.checkState(rt)
+	})
+
+	t.Run("deal without a scheduled operation is reported", func(t *testing.T) {
+		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
+		processEpoch := startEpoch + 5
+		dealID := actor.generateAndPublishDeal(rt, client, mAddrs, startEpoch, endEpoch, processEpoch)
+		d := actor.getDealProposal(rt, dealID)
+
+		var st market.State
+		rt.GetState(&st)
+		dealOps, err := market.AsSetMultimap(adt.AsStore(rt), st.DealOpsByEpoch, builtin.DefaultHamtBitwidth, builtin.DefaultHamtBitwidth)
+		require.NoError(t, err)
+		removed, err := dealOps.Remove(processEpoch, dealID)
+		require.NoError(t, err)
+		require.True(t, removed)
+		st.DealOpsByEpoch, err = dealOps.Root()
+		require.NoError(t, err)
+		rt.ReplaceState(&st)
+
+		ret := actor.getBalanceSchedule(rt, client)
+		assert.Equal(t, []abi.DealID{dealID}, ret.Unscheduled)
+		assert.Equal(t, d.TotalStorageFee(), ret.ClientStorageFee)
+		assert.Equal(t, d.ClientCollateral, ret.ClientCollateral)
+		assert.Empty(t, ret.Unlocks)
+	})
+
+	t.Run("fails for unresolvable address", func(t *testing.T) {
+		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
+		unknown := tutil.NewBLSAddr(t, 1)
+
+		rt.ExpectValidateCallerAny()
+		rt.ExpectAbort(exitcode.ErrIllegalArgument, func() {
+			rt.Call(actor.GetBalanceSchedule, &unknown)
+		})
+		rt.Verify()
+		actor.checkState(rt)
+	})
+}
+
 func TestVerifyDealsForActivation(t *testing.T) {
 	owner := tutil.NewIDAddr(t, 101)
 	provider := tutil.NewIDAddr(t, 102)
@@ -2708,6 +3511,86 @@ func (h *marketActorTestHarness) verifyDealsForActivation(rt *mock.Runtime, prov
 	return val
 }
 
+func (h *marketActorTestHarness) cancelDeal(rt *mock.Runtime, caller address.Address, params *market.CancelDealParams) {
+	rt.SetCaller(caller, builtin.AccountActorCodeID)
+	rt.ExpectValidateCallerType(builtin.CallerTypesSignable...)
+
+	ret := rt.Call(h.CancelDeal, params)
+	rt.Verify()
+	require.Nil(h.t, ret)
+}
+
+func (h *marketActorTestHarness) expectCancelDealAbort(rt *mock.Runtime, code exitcode.ExitCode, caller address.Address, params *market.CancelDealParams) {
+	rt.SetCaller(caller, builtin.AccountActorCodeID)
+	rt.ExpectValidateCallerType(builtin.CallerTypesSignable...)
+
+	rt.ExpectAbort(code, func() {
+		rt.Call(h.CancelDeal, params)
+	})
+	rt.Verify()
+}
+
+func (h *marketActorTestHarness) renewDeal(rt *mock.Runtime, provider address.Address, params *market.RenewDealParams) *market.RenewDealReturn {
+	rt.SetCaller(provider, builtin.StorageMinerActorCodeID)
+	rt.ExpectValidateCallerType(builtin.StorageMinerActorCodeID)
+
+	ret := rt.Call(h.RenewDeal, params)
+	rt.Verify()
+	val, ok := ret.(*market.RenewDealReturn)
+	require.True(h.t, ok)
+	return val
+}
+
+func (h *marketActorTestHarness) expectRenewDealAbort(rt *mock.Runtime, code exitcode.ExitCode, provider address.Address, params *market.RenewDealParams) {
+	rt.SetCaller(provider, builtin.StorageMinerActorCodeID)
+	rt.ExpectValidateCallerType(builtin.StorageMinerActorCodeID)
+
+	rt.ExpectAbort(code, func() {
+		rt.Call(h.RenewDeal, params)
+	})
+	rt.Verify()
+}
+
+func (h *marketActorTestHarness) getBalanceSchedule(rt *mock.Runtime, a address.Address) *market.GetBalanceScheduleReturn {
+	rt.ExpectValidateCallerAny()
+
+	ret := rt.Call(h.GetBalanceSchedule, &a)
+	rt.Verify()
+	val, ok := ret.(*market.GetBalanceScheduleReturn)
+	require.True(h.t, ok)
+
+	// The method reports the same as the state function.
+	var st market.State
+	rt.GetState(&st)
+	fromState, err := st.GetBalanceSchedule(adt.AsStore(rt), a)
+	require.NoError(h.t, err)
+	assert.Equal(h.t, fromState, val)
+	return val
+}
+
+func (h *marketActorTestHarness) assertPrecommitted(rt *mock.Runtime, dealID abi.DealID, expected bool) {
+	var st market.State
+	rt.GetState(&st)
+
+	precommitted, err := adt.AsSet(adt.AsStore(rt), st.PrecommittedDeals, builtin.DefaultHamtBitwidth)
+	require.NoError(h.t, err)
+	found, err := precommitted.Has(abi.UIntKey(uint64(dealID)))
+	require.NoError(h.t, err)
+	require.Equal(h.t, expected, found)
+}
+
+func (h *marketActorTestHarness) assertNoDealOp(rt *mock.Runtime, epoch abi.ChainEpoch, dealID abi.DealID) {
+	var st market.State
+	rt.GetState(&st)
+
+	dealOps, err := market.AsSetMultimap(adt.AsStore(rt), st.DealOpsByEpoch, builtin.DefaultHamtBitwidth, builtin.DefaultHamtBitwidth)
+	require.NoError(h.t, err)
+	require.NoError(h.t, dealOps.ForEach(epoch, func(id abi.DealID) error {
+		require.NotEqual(h.t, dealID, id)
+		return nil
+	}))
+}
+
 type minerAddrs struct {
 	owner    address.Address
 	worker   address.Address
@@ -2923,6 +3806,9 @@ func (h *marketActorTestHarness) publishDeals(rt *mock.Runtime, minerAddrs *mine
 	resp, ok := ret.(*market.PublishStorageDealsReturn)
 	require.True(h.t, ok, "unexpected type returned from call to PublishStorageDeals")
 	require.Len(h.t, resp.IDs, len(publishDealReqs))
+	validCount, err := resp.ValidDeals.Count()
+	require.NoError(h.t, err)
+	require.Equal(h.t, uint64(len(publishDealReqs)), validCount)
 
 	// assert state after publishing the deals
 	dealIds := resp.IDs
@@ -2936,7 +3822,7 @@ func (h *marketActorTestHarness) publishDeals(rt *mock.Runtime, minerAddrs *mine
 		require.Equal(h.t, expected.PieceSize, p.PieceSize)
 		require.Equal(h.t, expected.Client, p.Client)
 		require.Equal(h.t, expected.Provider, p.Provider)
-		require.Equal(h.t, expected.Label, p.Label)
+		require.True(h.t, expected.Label.Equals(p.Label))
 		require.Equal(h.t, expected.VerifiedDeal, p.VerifiedDeal)
 		require.Equal(h.t, expected.StoragePricePerEpoch, p.StoragePricePerEpoch)
 		require.Equal(h.t, expected.ClientCollateral, p.ClientCollateral)
@@ -2977,6 +3863,14 @@ func (h *marketActorTestHarness) activateDeals(rt *mock.Runtime, sectorExpiry ab
 	}
 }
 
+func (h *marketActorTestHarness) dealCancellationBytes(rt *mock.Runtime, dealID abi.DealID) []byte {
+	pcid, err := h.getDealProposal(rt, dealID).Cid()
+	require.NoError(h.t, err)
+	toSign, err := (&market.DealCancellation{DealID: dealID, Proposal: pcid}).SigningBytes()
+	require.NoError(h.t, err)
+	return toSign
+}
+
 func (h *marketActorTestHarness) getDealProposal(rt *mock.Runtime, dealID abi.DealID) *market.DealProposal {
 	var st market.State
 	rt.GetState(&st)
@@ -3187,7 +4081,7 @@ func generateDealProposalWithCollateral(client, provider address.Address, provid
 	pieceSize := abi.PaddedPieceSize(2048)
 	storagePerEpoch := big.NewInt(10)
 
-	return market.DealProposal{PieceCID: pieceCid, PieceSize: pieceSize, Client: client, Provider: provider, Label: "label", StartEpoch: startEpoch,
+	return market.DealProposal{PieceCID: pieceCid, PieceSize: pieceSize, Client: client, Provider: provider, Label: mustLabel("label"), StartEpoch: startEpoch,
 		EndEpoch: endEpoch, StoragePricePerEpoch: storagePerEpoch, ProviderCollateral: providerCollateral, ClientCollateral: clientCollateral}
 }
 
@@ -3219,6 +4113,14 @@ func basicMarketSetup(t *testing.T, owner, provider, worker, client address.Addr
 	return rt, &actor
 }
 
+func mustLabel(s string) market.DealLabel {
+	label, err := market.NewLabelFromString(s)
+	if err != nil {
+		panic(err)
+	}
+	return label
+}
+
 func mkPublishStorageParams(proposals ...market.DealProposal) *market.PublishStorageDealsParams {
 	m := &market.PublishStorageDealsParams{}
 	for _, p := range proposals {
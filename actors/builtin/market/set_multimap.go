@@ -118,6 +118,39 @@ func (mm *SetMultimap) PutMany(epoch abi.ChainEpoch, vs []abi.DealID) error {
 	return nil
 }
 
+// Removes a value for a key, returning whether it was present.
+// An emptied set is left in place, to be removed with the rest of its key's values.
+func (mm *SetMultimap) Remove(epoch abi.ChainEpoch, v abi.DealID) (bool, error) {
+	k := abi.UIntKey(uint64(epoch))
+	set, found, err := mm.get(k)
+	if err != nil {
+		return false, err
+	}
+	if !found {
+		return false, nil
+	}
+
+	removed, err := set.TryDelete(dealKey(v))
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to remove key from set %v", epoch)
+	}
+	if !removed {
+		return false, nil
+	}
+
+	src, err := set.Root()
+	if err != nil {
+		return false, xerrors.Errorf("failed to flush set root: %w", err)
+	}
+	// Store the new set root under key.
+	newSetRoot := cbg.CborCid(src)
+	err = mm.mp.Put(k, &newSetRoot)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to store set")
+	}
+	return true, nil
+}
+
 // Removes all values for a key.
 func (mm *SetMultimap) RemoveAll(key abi.ChainEpoch) error {
 	if _, err := mm.mp.TryDelete(abi.UIntKey(uint64(key))); err != nil {
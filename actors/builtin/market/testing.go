@@ -28,6 +28,7 @@ type DealSummary struct {
 type StateSummary struct {
 	Deals                map[abi.DealID]*DealSummary
 	PendingProposalCount uint64
+	PrecommittedCount    uint64
 	DealStateCount       uint64
 	LockTableCount       uint64
 	DealOpEpochCount     uint64
@@ -168,6 +169,32 @@ func CheckStateInvariants(st *State, store adt.Store, balance abi.TokenAmount, c
 		acc.RequireNoError(err, "error iterating pending proposals")
 	}
 
+	//
+	// Precommitted Deals
+	//
+
+	precommittedDealCount := uint64(0)
+	if precommittedDeals, err := adt.AsSet(store, st.PrecommittedDeals, builtin.DefaultHamtBitwidth); err != nil {
+		acc.Addf("error loading precommitted deals: %v", err)
+	} else {
+		err = precommittedDeals.ForEach(func(key string) error {
+			dealID, err := parseDealKey(key)
+			if err != nil {
+				return err
+			}
+
+			stats, found := proposalStats[dealID]
+			acc.Require(found, "precommitted deal %d not found within proposals", dealID)
+			if found {
+				acc.Require(stats.SectorStartEpoch == epochUndefined, "precommitted deal %d has been activated", dealID)
+			}
+
+			precommittedDealCount++
+			return nil
+		})
+		acc.RequireNoError(err, "error iterating precommitted deals")
+	}
+
 	//
 	// Escrow Table and Locked Table
 	//
@@ -248,6 +275,7 @@ func CheckStateInvariants(st *State, store adt.Store, balance abi.TokenAmount, c
 	return &StateSummary{
 		Deals:                proposalStats,
 		PendingProposalCount: pendingProposalCount,
+		PrecommittedCount:    precommittedDealCount,
 		DealStateCount:       dealStateCount,
 		LockTableCount:       lockTableCount,
 		DealOpEpochCount:     dealOpEpochCount,
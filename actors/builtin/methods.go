@@ -61,7 +61,10 @@ var MethodsMarket = struct {
 	OnMinerSectorsTerminate  abi.MethodNum
 	ComputeDataCommitment    abi.MethodNum
 	CronTick                 abi.MethodNum
-}{MethodConstructor, 2, 3, 4, 5, 6, 7, 8, 9}
+	CancelDeal               abi.MethodNum
+	RenewDeal                abi.MethodNum
+	GetBalanceSchedule       abi.MethodNum
+}{MethodConstructor, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
 
 var MethodsPower = struct {
 	Constructor              abi.MethodNum
@@ -76,31 +79,42 @@ var MethodsPower = struct {
 }{MethodConstructor, 2, 3, 4, 5, 6, 7, 8, 9}
 
 var MethodsMiner = struct {
-	Constructor              abi.MethodNum
-	ControlAddresses         abi.MethodNum
-	ChangeWorkerAddress      abi.MethodNum
-	ChangePeerID             abi.MethodNum
-	SubmitWindowedPoSt       abi.MethodNum
-	PreCommitSector          abi.MethodNum
-	ProveCommitSector        abi.MethodNum
-	ExtendSectorExpiration   abi.MethodNum
-	TerminateSectors         abi.MethodNum
-	DeclareFaults            abi.MethodNum
-	DeclareFaultsRecovered   abi.MethodNum
-	OnDeferredCronEvent      abi.MethodNum
-	CheckSectorProven        abi.MethodNum
-	ApplyRewards             abi.MethodNum
-	ReportConsensusFault     abi.MethodNum
-	WithdrawBalance          abi.MethodNum
-	ConfirmSectorProofsValid abi.MethodNum
-	ChangeMultiaddrs         abi.MethodNum
-	CompactPartitions        abi.MethodNum
-	CompactSectorNumbers     abi.MethodNum
-	ConfirmUpdateWorkerKey   abi.MethodNum
-	RepayDebt                abi.MethodNum
-	ChangeOwnerAddress       abi.MethodNum
-	DisputeWindowedPoSt      abi.MethodNum
-}{MethodConstructor, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24}
+	Constructor                  abi.MethodNum
+	ControlAddresses             abi.MethodNum
+	ChangeWorkerAddress          abi.MethodNum
+	ChangePeerID                 abi.MethodNum
+	SubmitWindowedPoSt           abi.MethodNum
+	PreCommitSector              abi.MethodNum
+	ProveCommitSector            abi.MethodNum
+	ExtendSectorExpiration       abi.MethodNum
+	TerminateSectors             abi.MethodNum
+	DeclareFaults                abi.MethodNum
+	DeclareFaultsRecovered       abi.MethodNum
+	OnDeferredCronEvent          abi.MethodNum
+	CheckSectorProven            abi.MethodNum
+	ApplyRewards                 abi.MethodNum
+	ReportConsensusFault         abi.MethodNum
+	WithdrawBalance              abi.MethodNum
+	ConfirmSectorProofsValid     abi.MethodNum
+	ChangeMultiaddrs             abi.MethodNum
+	CompactPartitions            abi.MethodNum
+	CompactSectorNumbers         abi.MethodNum
+	ConfirmUpdateWorkerKey       abi.MethodNum
+	RepayDebt                    abi.MethodNum
+	ChangeOwnerAddress           abi.MethodNum
+	DisputeWindowedPoSt          abi.MethodNum
+	PreCommitSectorBatch         abi.MethodNum
+	ProveCommitAggregate         abi.MethodNum
+	ChangeBeneficiary            abi.MethodNum
+	GetBeneficiary               abi.MethodNum
+	ProveReplicaUpdates          abi.MethodNum
+	ExtendSectorExpiration2      abi.MethodNum
+	PreviewTerminateSectors      abi.MethodNum
+	CancelChangeWorkerAddress    abi.MethodNum
+	PreviewWindowedPoSt          abi.MethodNum
+	RenewSectorDeal              abi.MethodNum
+	ChangeWorkerAddressWithRoles abi.MethodNum
+}{MethodConstructor, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31, 32, 33, 34, 35}
 
 var MethodsVerifiedRegistry = struct {
 	Constructor       abi.MethodNum
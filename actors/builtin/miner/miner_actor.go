@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"math"
+	"sort"
 
 	addr "github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-bitfield"
@@ -39,6 +40,9 @@ const (
 	// actually happen without programming error in the actor code.
 	//ErrToBeDetermined = exitcode.FirstActorSpecificExitCode + iota
 
+	// A worker key change was requested while a change to a different key is pending.
+	ErrWorkerKeyChangePending = exitcode.FirstActorSpecificExitCode + iota
+
 	// The following errors are particular cases of illegal state.
 	// They're not expected to ever happen, but if they do, distinguished codes can help us
 	// diagnose the problem.
@@ -73,6 +77,17 @@ func (a Actor) Exports() []interface{} {
 		22:                        a.RepayDebt,
 		23:                        a.ChangeOwnerAddress,
 		24:                        a.DisputeWindowedPoSt,
+		25:                        a.PreCommitSectorBatch,
+		26:                        a.ProveCommitAggregate,
+		27:                        a.ChangeBeneficiary,
+		28:                        a.GetBeneficiary,
+		29:                        a.ProveReplicaUpdates,
+		30:                        a.ExtendSectorExpiration2,
+		31:                        a.PreviewTerminateSectors,
+		32:                        a.CancelChangeWorkerAddress,
+		33:                        a.PreviewWindowedPoSt,
+		34:                        a.RenewSectorDeal,
+		35:                        a.ChangeWorkerAddressWithRoles,
 	}
 }
 
@@ -118,6 +133,7 @@ func (a Actor) Constructor(rt Runtime, params *ConstructorParams) *abi.EmptyValu
 
 	info, err := ConstructMinerInfo(owner, worker, controlAddrs, params.PeerId, params.Multiaddrs, params.WindowPoStProofType)
 	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to construct initial miner info")
+	info.recordWorkerKey(worker, currEpoch)
 	infoCid := rt.StorePut(info)
 
 	store := adt.AsStore(rt)
@@ -164,14 +180,51 @@ type ChangeWorkerAddressParams = miner0.ChangeWorkerAddressParams
 
 // ChangeWorkerAddress will ALWAYS overwrite the existing control addresses with the control addresses passed in the params.
 // If a nil addresses slice is passed, the control addresses will be cleared.
+// A control address that remains in the list keeps its roles, and a new one is granted all roles;
+// use ChangeWorkerAddressWithRoles to set them.
 // A worker change will be scheduled if the worker passed in the params is different from the existing worker.
+// If a change to a different worker is already pending, the call aborts with ErrWorkerKeyChangePending;
+// the pending change must first be cancelled with CancelChangeWorkerAddress.
 func (a Actor) ChangeWorkerAddress(rt Runtime, params *ChangeWorkerAddressParams) *abi.EmptyValue {
-	checkControlAddresses(rt, params.NewControlAddrs)
+	changeWorkerAddress(rt, params.NewWorker, params.NewControlAddrs, nil)
+	return nil
+}
 
-	newWorker := resolveWorkerAddress(rt, params.NewWorker)
+type ChangeWorkerAddressWithRolesParams struct {
+	NewWorker       addr.Address
+	NewControlAddrs []addr.Address
+	// Roles granted to each of the new control addresses, in the same order.
+	// If empty, every control address is granted all roles.
+	NewControlAddrRoles []ControlRoles
+}
+
+// Like ChangeWorkerAddress, but each control address is permitted to call only the methods covered by its roles.
+func (a Actor) ChangeWorkerAddressWithRoles(rt Runtime, params *ChangeWorkerAddressWithRolesParams) *abi.EmptyValue {
+	controlRoles := params.NewControlAddrRoles
+	if len(controlRoles) == 0 {
+		controlRoles = AllControlRoles(len(params.NewControlAddrs))
+	} else if len(controlRoles) != len(params.NewControlAddrs) {
+		rt.Abortf(exitcode.ErrIllegalArgument, "%d control address roles for %d control addresses",
+			len(controlRoles), len(params.NewControlAddrs))
+	}
+	for i, roles := range controlRoles {
+		if roles == 0 || roles&^ControlRolesAll != 0 {
+			rt.Abortf(exitcode.ErrIllegalArgument, "invalid roles %b for control address %v", roles, params.NewControlAddrs[i])
+		}
+	}
+	changeWorkerAddress(rt, params.NewWorker, params.NewControlAddrs, controlRoles)
+	return nil
+}
+
+// Changes the worker and control addresses. If controlRoles is nil, the roles of the existing control addresses
+// are retained.
+func changeWorkerAddress(rt Runtime, worker addr.Address, newControlAddrs []addr.Address, controlRoles []ControlRoles) {
+	checkControlAddresses(rt, newControlAddrs)
+
+	newWorker := resolveWorkerAddress(rt, worker)
 
 	var controlAddrs []addr.Address
-	for _, ca := range params.NewControlAddrs {
+	for _, ca := range newControlAddrs {
 		resolved := resolveControlAddress(rt, ca)
 		controlAddrs = append(controlAddrs, resolved)
 	}
@@ -184,21 +237,43 @@ func (a Actor) ChangeWorkerAddress(rt Runtime, params *ChangeWorkerAddressParams
 		rt.ValidateImmediateCallerIs(info.Owner)
 
 		// save the new control addresses
+		if controlRoles == nil {
+			controlRoles = retainedControlRoles(info, controlAddrs)
+		}
 		info.ControlAddresses = controlAddrs
+		info.ControlAddressRoles = controlRoles
 
 		// save newWorker addr key change request
-		if newWorker != info.Worker && info.PendingWorkerKey == nil {
-			info.PendingWorkerKey = &WorkerKeyChange{
-				NewWorker:   newWorker,
-				EffectiveAt: rt.CurrEpoch() + WorkerKeyChangeDelay,
+		if newWorker != info.Worker {
+			if info.PendingWorkerKey == nil {
+				info.PendingWorkerKey = &WorkerKeyChange{
+					NewWorker:   newWorker,
+					EffectiveAt: rt.CurrEpoch() + WorkerKeyChangeDelay,
+				}
+			} else if info.PendingWorkerKey.NewWorker != newWorker {
+				rt.Abortf(ErrWorkerKeyChangePending, "change to worker %v already pending, effective at %d",
+					info.PendingWorkerKey.NewWorker, info.PendingWorkerKey.EffectiveAt)
 			}
 		}
 
 		err := st.SaveInfo(adt.AsStore(rt), info)
 		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "could not save miner info")
 	})
+}
 
-	return nil
+// Returns the roles of new control addresses when none are given: an address that is already a control address
+// keeps its roles, and any other is granted all roles.
+func retainedControlRoles(info *MinerInfo, controlAddrs []addr.Address) []ControlRoles {
+	roles := AllControlRoles(len(controlAddrs))
+	for i, ca := range controlAddrs {
+		for j, existing := range info.ControlAddresses {
+			if ca == existing && j < len(info.ControlAddressRoles) {
+				roles[i] = info.ControlAddressRoles[j]
+				break
+			}
+		}
+	}
+	return roles
 }
 
 // Triggers a worker address change if a change has been requested and its effective epoch has arrived.
@@ -216,6 +291,26 @@ func (a Actor) ConfirmUpdateWorkerKey(rt Runtime, params *abi.EmptyValue) *abi.E
 	return nil
 }
 
+// Cancels a pending worker address change.
+func (a Actor) CancelChangeWorkerAddress(rt Runtime, _ *abi.EmptyValue) *abi.EmptyValue {
+	var st State
+	rt.StateTransaction(&st, func() {
+		info := getMinerInfo(rt, &st)
+
+		// Only the Owner is allowed to change the worker.
+		rt.ValidateImmediateCallerIs(info.Owner)
+
+		if info.PendingWorkerKey == nil {
+			rt.Abortf(exitcode.ErrNotFound, "no pending worker key change")
+		}
+		info.PendingWorkerKey = nil
+
+		err := st.SaveInfo(adt.AsStore(rt), info)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "could not save miner info")
+	})
+	return nil
+}
+
 // Proposes or confirms a change of owner address.
 // If invoked by the current owner, proposes a new owner address for confirmation. If the proposed address is the
 // current owner address, revokes any existing proposal.
@@ -243,7 +338,17 @@ func (a Actor) ChangeOwnerAddress(rt Runtime, newAddress *addr.Address) *abi.Emp
 				rt.Abortf(exitcode.ErrIllegalArgument, "expected confirmation of %v, got %v",
 					info.PendingOwnerAddress, newAddress)
 			}
+			// A beneficiary that was the old owner follows the owner, and a pending beneficiary change
+			// proposed by the old owner is discarded.
+			if info.Beneficiary == info.Owner {
+				info.Beneficiary = *info.PendingOwnerAddress
+			}
+			info.PendingBeneficiaryTerm = nil
 			info.Owner = *info.PendingOwnerAddress
+			// An owner as beneficiary has no quota or expiration, including a beneficiary becoming the owner.
+			if info.Beneficiary == info.Owner {
+				info.BeneficiaryTerm = NewBeneficiaryTerm(big.Zero(), 0)
+			}
 		}
 
 		// Clear any resulting no-op change.
@@ -257,6 +362,110 @@ func (a Actor) ChangeOwnerAddress(rt Runtime, newAddress *addr.Address) *abi.Emp
 	return nil
 }
 
+type ChangeBeneficiaryParams struct {
+	NewBeneficiary addr.Address
+	NewQuota       abi.TokenAmount
+	NewExpiration  abi.ChainEpoch
+}
+
+// Proposes or confirms a change of beneficiary address.
+// If invoked by the owner, proposes a new beneficiary with a withdrawal quota and expiration, replacing any existing
+// proposal. A proposal of the owner itself as beneficiary must have a zero quota and expiration.
+// If invoked by the current beneficiary or the proposed beneficiary, with the same proposal, approves it.
+// The change takes effect once approved by both the current beneficiary and the nominee. Approval by the current
+// beneficiary is implied if it has no remaining quota, e.g. if the owner is the beneficiary, and approval by the
+// nominee is implied if the nominee is the owner.
+func (a Actor) ChangeBeneficiary(rt Runtime, params *ChangeBeneficiaryParams) *abi.EmptyValue {
+	newBeneficiary := resolveControlAddress(rt, params.NewBeneficiary)
+	if params.NewQuota.LessThan(big.Zero()) {
+		rt.Abortf(exitcode.ErrIllegalArgument, "beneficiary quota %v must be non-negative", params.NewQuota)
+	}
+
+	var st State
+	rt.StateTransaction(&st, func() {
+		info := getMinerInfo(rt, &st)
+		if rt.Caller() == info.Owner || info.PendingBeneficiaryTerm == nil {
+			// Propose a new beneficiary.
+			rt.ValidateImmediateCallerIs(info.Owner)
+			if newBeneficiary == info.Owner {
+				if !params.NewQuota.IsZero() || params.NewExpiration != 0 {
+					rt.Abortf(exitcode.ErrIllegalArgument, "owner as beneficiary must have zero quota and expiration, got %v, %d",
+						params.NewQuota, params.NewExpiration)
+				}
+			} else if params.NewExpiration <= rt.CurrEpoch() {
+				rt.Abortf(exitcode.ErrIllegalArgument, "beneficiary expiration %d must be after the current epoch %d",
+					params.NewExpiration, rt.CurrEpoch())
+			}
+			info.PendingBeneficiaryTerm = &PendingBeneficiaryChange{
+				NewBeneficiary:        newBeneficiary,
+				NewQuota:              params.NewQuota,
+				NewExpiration:         params.NewExpiration,
+				ApprovedByBeneficiary: info.BeneficiaryTerm.Available(rt.CurrEpoch()).Equals(big.Zero()),
+				ApprovedByNominee:     newBeneficiary == info.Owner,
+			}
+		} else { // info.PendingBeneficiaryTerm != nil
+			// Approve the proposal.
+			pending := info.PendingBeneficiaryTerm
+			rt.ValidateImmediateCallerIs(info.Beneficiary, pending.NewBeneficiary)
+			if newBeneficiary != pending.NewBeneficiary || !params.NewQuota.Equals(pending.NewQuota) ||
+				params.NewExpiration != pending.NewExpiration {
+				rt.Abortf(exitcode.ErrIllegalArgument, "expected confirmation of %v with quota %v and expiration %d, got %v, %v, %d",
+					pending.NewBeneficiary, pending.NewQuota, pending.NewExpiration, newBeneficiary, params.NewQuota, params.NewExpiration)
+			}
+			if rt.Caller() == info.Beneficiary {
+				pending.ApprovedByBeneficiary = true
+			}
+			if rt.Caller() == pending.NewBeneficiary {
+				pending.ApprovedByNominee = true
+			}
+		}
+
+		// Apply a fully approved change.
+		if pending := info.PendingBeneficiaryTerm; pending.ApprovedByBeneficiary && pending.ApprovedByNominee {
+			usedQuota := big.Zero()
+			if pending.NewBeneficiary == info.Beneficiary {
+				usedQuota = info.BeneficiaryTerm.UsedQuota
+			}
+			info.Beneficiary = pending.NewBeneficiary
+			info.BeneficiaryTerm = BeneficiaryTerm{
+				Quota:      pending.NewQuota,
+				UsedQuota:  usedQuota,
+				Expiration: pending.NewExpiration,
+			}
+			info.PendingBeneficiaryTerm = nil
+		}
+
+		err := st.SaveInfo(adt.AsStore(rt), info)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to save miner info")
+	})
+	return nil
+}
+
+type ActiveBeneficiary struct {
+	Beneficiary addr.Address
+	Term        BeneficiaryTerm
+}
+
+type GetBeneficiaryReturn struct {
+	Active   ActiveBeneficiary
+	Proposed *PendingBeneficiaryChange
+}
+
+// Returns the current beneficiary and its term, and any proposed change of beneficiary.
+func (a Actor) GetBeneficiary(rt Runtime, _ *abi.EmptyValue) *GetBeneficiaryReturn {
+	rt.ValidateImmediateCallerAcceptAny()
+	var st State
+	rt.StateReadonly(&st)
+	info := getMinerInfo(rt, &st)
+	return &GetBeneficiaryReturn{
+		Active: ActiveBeneficiary{
+			Beneficiary: info.Beneficiary,
+			Term:        info.BeneficiaryTerm,
+		},
+		Proposed: info.PendingBeneficiaryTerm,
+	}
+}
+
 //type ChangePeerIDParams struct {
 //	NewID abi.PeerID
 //}
@@ -269,7 +478,7 @@ func (a Actor) ChangePeerID(rt Runtime, params *ChangePeerIDParams) *abi.EmptyVa
 	rt.StateTransaction(&st, func() {
 		info := getMinerInfo(rt, &st)
 
-		rt.ValidateImmediateCallerIs(append(info.ControlAddresses, info.Owner, info.Worker)...)
+		rt.ValidateImmediateCallerIs(info.CallersWithRole(ControlRoleMaintenance)...)
 
 		info.PeerId = params.NewID
 		err := st.SaveInfo(adt.AsStore(rt), info)
@@ -290,7 +499,7 @@ func (a Actor) ChangeMultiaddrs(rt Runtime, params *ChangeMultiaddrsParams) *abi
 	rt.StateTransaction(&st, func() {
 		info := getMinerInfo(rt, &st)
 
-		rt.ValidateImmediateCallerIs(append(info.ControlAddresses, info.Owner, info.Worker)...)
+		rt.ValidateImmediateCallerIs(info.CallersWithRole(ControlRoleMaintenance)...)
 
 		info.Multiaddrs = params.NewMultiaddrs
 		err := st.SaveInfo(adt.AsStore(rt), info)
@@ -350,7 +559,7 @@ func (a Actor) SubmitWindowedPoSt(rt Runtime, params *SubmitWindowedPoStParams)
 	rt.StateTransaction(&st, func() {
 		info = getMinerInfo(rt, &st)
 
-		rt.ValidateImmediateCallerIs(append(info.ControlAddresses, info.Owner, info.Worker)...)
+		rt.ValidateImmediateCallerIs(info.CallersWithRole(ControlRolePoSt)...)
 
 		// Verify that the miner has passed 0 or 1 proofs. If they've
 		// passed 1, verify that it's a good proof.
@@ -469,6 +678,57 @@ func (a Actor) SubmitWindowedPoSt(rt Runtime, params *SubmitWindowedPoStParams)
 	return nil
 }
 
+type PreviewWindowedPoStParams struct {
+	// The deadline index which the submission would target.
+	Deadline uint64
+	// The partitions being proven, with the sectors to be skipped.
+	Partitions []PoStPartition
+}
+
+type PreviewWindowedPoStReturn struct {
+	NewFaultyPower         PowerPair // Power of skipped sectors that would become faulty.
+	RetractedRecoveryPower PowerPair // Power of skipped recovering sectors that would remain faulty.
+	RecoveredPower         PowerPair // Power of recovering sectors that would be restored.
+	FaultyPower            PowerPair // The deadline's total faulty power after the submission.
+	// The fee that would be charged for the deadline's faulty power when the deadline closes,
+	// at current reward and power estimates.
+	ProjectedFaultFee abi.TokenAmount
+}
+
+// Computes the changes in power that a Window PoSt submission for the current deadline would make, and the
+// fault fee that would then be charged at the close of the deadline, assuming no further fault or recovery
+// declarations. This allows a miner to decide whether to retry a proof rather than skip sectors.
+// Proofs are not inspected and this method does not mutate state.
+func (a Actor) PreviewWindowedPoSt(rt Runtime, params *PreviewWindowedPoStParams) *PreviewWindowedPoStReturn {
+	rt.ValidateImmediateCallerAcceptAny()
+	if params.Deadline >= WPoStPeriodDeadlines {
+		rt.Abortf(exitcode.ErrIllegalArgument, "invalid deadline %d of %d", params.Deadline, WPoStPeriodDeadlines)
+	}
+
+	rewardStats := requestCurrentEpochBlockReward(rt)
+	pwrTotal := requestCurrentTotalPower(rt)
+
+	var st State
+	rt.StateReadonly(&st)
+	info := getMinerInfo(rt, &st)
+	submissionPartitionLimit := loadPartitionsSectorsMax(info.WindowPoStPartitionSectors)
+	if uint64(len(params.Partitions)) > submissionPartitionLimit {
+		rt.Abortf(exitcode.ErrIllegalArgument, "too many partitions %d, limit %d", len(params.Partitions), submissionPartitionLimit)
+	}
+
+	postResult, faultyPower, err := st.PreviewPoSt(adt.AsStore(rt), rt.CurrEpoch(), info.SectorSize, params.Deadline, params.Partitions)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to preview post for deadline %d", params.Deadline)
+
+	return &PreviewWindowedPoStReturn{
+		NewFaultyPower:         postResult.NewFaultyPower,
+		RetractedRecoveryPower: postResult.RetractedRecoveryPower,
+		RecoveredPower:         postResult.RecoveredPower,
+		FaultyPower:            faultyPower,
+		ProjectedFaultFee: PledgePenaltyForContinuedFault(rewardStats.ThisEpochRewardSmoothed,
+			pwrTotal.QualityAdjPowerSmoothed, faultyPower.QA),
+	}
+}
+
 type DisputeWindowedPoStParams struct {
 	Deadline  uint64
 	PoStIndex uint64 // only one is allowed at a time to avoid loading too many sector infos.
@@ -644,62 +904,92 @@ type PreCommitSectorParams = miner0.SectorPreCommitInfo
 // Proposals must be posted on chain via sma.PublishStorageDeals before PreCommitSector.
 // Optimization: PreCommitSector could contain a list of deals that are not published yet.
 func (a Actor) PreCommitSector(rt Runtime, params *PreCommitSectorParams) *abi.EmptyValue {
-	nv := rt.NetworkVersion()
-	if !CanPreCommitSealProof(params.SealProof, nv) {
-		rt.Abortf(exitcode.ErrIllegalArgument, "unsupported seal proof type %v at network version %v", params.SealProof, nv)
-	}
-	if params.SectorNumber > abi.MaxSectorNumber {
-		rt.Abortf(exitcode.ErrIllegalArgument, "sector number %d out of range 0..(2^63-1)", params.SectorNumber)
-	}
-	if !params.SealedCID.Defined() {
-		rt.Abortf(exitcode.ErrIllegalArgument, "sealed CID undefined")
-	}
-	if params.SealedCID.Prefix() != SealedCIDPrefix {
-		rt.Abortf(exitcode.ErrIllegalArgument, "sealed CID had wrong prefix")
-	}
-	if params.SealRandEpoch >= rt.CurrEpoch() {
-		rt.Abortf(exitcode.ErrIllegalArgument, "seal challenge epoch %v must be before now %v", params.SealRandEpoch, rt.CurrEpoch())
-	}
+	return a.PreCommitSectorBatch(rt, &PreCommitSectorBatchParams{Sectors: []PreCommitSectorParams{*params}})
+}
+
+type PreCommitSectorBatchParams struct {
+	Sectors []PreCommitSectorParams
+}
 
-	challengeEarliest := rt.CurrEpoch() - MaxPreCommitRandomnessLookback
-	if params.SealRandEpoch < challengeEarliest {
-		rt.Abortf(exitcode.ErrIllegalArgument, "seal challenge epoch %v too old, must be after %v", params.SealRandEpoch, challengeEarliest)
+// Pre-commits a batch of sectors in a single message.
+// Every pre-commitment is validated as for PreCommitSector. Deposits are computed against a single snapshot of the
+// network reward and power estimates, and all pre-commitments and their expiry entries are recorded in one state
+// transaction. Any invalid pre-commitment aborts the whole batch.
+func (a Actor) PreCommitSectorBatch(rt Runtime, params *PreCommitSectorBatchParams) *abi.EmptyValue {
+	currEpoch := rt.CurrEpoch()
+	if len(params.Sectors) == 0 {
+		rt.Abortf(exitcode.ErrIllegalArgument, "batch empty")
+	} else if len(params.Sectors) > PreCommitSectorBatchMaxSize {
+		rt.Abortf(exitcode.ErrIllegalArgument, "batch of %d too large, max %d", len(params.Sectors), PreCommitSectorBatchMaxSize)
 	}
 
-	// Require sector lifetime meets minimum by assuming activation happens at last epoch permitted for seal proof.
-	// This could make sector maximum lifetime validation more lenient if the maximum sector limit isn't hit first.
-	maxActivation := rt.CurrEpoch() + MaxProveCommitDuration[params.SealProof]
-	validateExpiration(rt, maxActivation, params.Expiration, params.SealProof)
+	// Check per-sector preconditions before opening the state transaction or sending other messages.
+	nv := rt.NetworkVersion()
+	challengeEarliest := currEpoch - MaxPreCommitRandomnessLookback
+	sectorDeals := make([]market.SectorDeals, len(params.Sectors))
+	sectorNumbers := bitfield.New()
+	for i := range params.Sectors {
+		precommit := &params.Sectors[i]
+		if !CanPreCommitSealProof(precommit.SealProof, nv) {
+			rt.Abortf(exitcode.ErrIllegalArgument, "unsupported seal proof type %v at network version %v", precommit.SealProof, nv)
+		}
+		if precommit.SectorNumber > abi.MaxSectorNumber {
+			rt.Abortf(exitcode.ErrIllegalArgument, "sector number %d out of range 0..(2^63-1)", precommit.SectorNumber)
+		}
+		// Bitfield.IsSet() is fast when there are only locally-set values.
+		set, err := sectorNumbers.IsSet(uint64(precommit.SectorNumber))
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "failed to check sector number %d", precommit.SectorNumber)
+		if set {
+			rt.Abortf(exitcode.ErrIllegalArgument, "duplicate sector number %d", precommit.SectorNumber)
+		}
+		sectorNumbers.Set(uint64(precommit.SectorNumber))
 
-	if params.ReplaceCapacity && len(params.DealIDs) == 0 {
-		rt.Abortf(exitcode.ErrIllegalArgument, "cannot replace sector without committing deals")
-	}
-	if params.ReplaceSectorDeadline >= WPoStPeriodDeadlines {
-		rt.Abortf(exitcode.ErrIllegalArgument, "invalid deadline %d", params.ReplaceSectorDeadline)
-	}
-	if params.ReplaceSectorNumber > abi.MaxSectorNumber {
-		rt.Abortf(exitcode.ErrIllegalArgument, "invalid sector number %d", params.ReplaceSectorNumber)
+		if !precommit.SealedCID.Defined() {
+			rt.Abortf(exitcode.ErrIllegalArgument, "sealed CID undefined")
+		}
+		if precommit.SealedCID.Prefix() != SealedCIDPrefix {
+			rt.Abortf(exitcode.ErrIllegalArgument, "sealed CID had wrong prefix")
+		}
+		if precommit.SealRandEpoch >= currEpoch {
+			rt.Abortf(exitcode.ErrIllegalArgument, "seal challenge epoch %v must be before now %v", precommit.SealRandEpoch, currEpoch)
+		}
+		if precommit.SealRandEpoch < challengeEarliest {
+			rt.Abortf(exitcode.ErrIllegalArgument, "seal challenge epoch %v too old, must be after %v", precommit.SealRandEpoch, challengeEarliest)
+		}
+
+		// Require sector lifetime meets minimum by assuming activation happens at last epoch permitted for seal proof.
+		// This could make sector maximum lifetime validation more lenient if the maximum sector limit isn't hit first.
+		maxActivation := currEpoch + MaxProveCommitDuration[precommit.SealProof]
+		validateExpiration(rt, maxActivation, precommit.Expiration, precommit.SealProof)
+
+		if precommit.ReplaceCapacity && len(precommit.DealIDs) == 0 {
+			rt.Abortf(exitcode.ErrIllegalArgument, "cannot replace sector without committing deals")
+		}
+		if precommit.ReplaceSectorDeadline >= WPoStPeriodDeadlines {
+			rt.Abortf(exitcode.ErrIllegalArgument, "invalid deadline %d", precommit.ReplaceSectorDeadline)
+		}
+		if precommit.ReplaceSectorNumber > abi.MaxSectorNumber {
+			rt.Abortf(exitcode.ErrIllegalArgument, "invalid sector number %d", precommit.ReplaceSectorNumber)
+		}
+
+		sectorDeals[i] = market.SectorDeals{
+			SectorExpiry: precommit.Expiration,
+			DealIDs:      precommit.DealIDs,
+		}
 	}
 
 	// gather information from other actors
 
 	rewardStats := requestCurrentEpochBlockReward(rt)
 	pwrTotal := requestCurrentTotalPower(rt)
-	dealWeights := requestDealWeights(rt, []market.SectorDeals{
-		{
-			SectorExpiry: params.Expiration,
-			DealIDs:      params.DealIDs,
-		},
-	})
-	if len(dealWeights.Sectors) == 0 {
-		rt.Abortf(exitcode.ErrIllegalState, "deal weight request returned no records")
+	dealWeights := requestDealWeights(rt, sectorDeals)
+	if len(dealWeights.Sectors) != len(params.Sectors) {
+		rt.Abortf(exitcode.ErrIllegalState, "deal weight request returned %d records, expected %d",
+			len(dealWeights.Sectors), len(params.Sectors))
 	}
-	dealWeight := dealWeights.Sectors[0]
 
 	store := adt.AsStore(rt)
 	var st State
-	var err error
-	newlyVested := big.Zero()
 	feeToBurn := abi.NewTokenAmount(0)
 	rt.StateTransaction(&st, func() {
 		// available balance already accounts for fee debt so it is correct to call
@@ -710,86 +1000,100 @@ func (a Actor) PreCommitSector(rt Runtime, params *PreCommitSectorParams) *abi.E
 		feeToBurn = RepayDebtsOrAbort(rt, &st)
 
 		info := getMinerInfo(rt, &st)
-		rt.ValidateImmediateCallerIs(append(info.ControlAddresses, info.Owner, info.Worker)...)
+		rt.ValidateImmediateCallerIs(info.CallersWithRole(ControlRoleSealing)...)
 
-		if ConsensusFaultActive(info, rt.CurrEpoch()) {
+		if ConsensusFaultActive(info, currEpoch) {
 			rt.Abortf(exitcode.ErrForbidden, "precommit not allowed during active consensus fault")
 		}
 
-		// From network version 7, the pre-commit seal type must have the same Window PoSt proof type as the miner,
-		// rather than be exactly the same seal type.
-		// This permits a transition window from V1 to V1_1 seal types (which share Window PoSt proof type).
-		sectorWPoStProof, err := params.SealProof.RegisteredWindowPoStProof()
-		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "failed to lookup Window PoSt proof type for sector seal proof %d", params.SealProof)
-		if sectorWPoStProof != info.WindowPoStProofType {
-			rt.Abortf(exitcode.ErrIllegalArgument, "sector Window PoSt proof type %d must match miner Window PoSt proof type %d (seal proof type %d)",
-				sectorWPoStProof, info.WindowPoStProofType, params.SealProof)
-		}
+		err = st.AllocateSectorNumbers(store, sectorNumbers)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to allocate sector ids %v", sectorNumbers)
+
+		// This sector check is redundant given the allocated sectors bitfield, but remains for safety.
+		sectors, err := LoadSectors(store, st.Sectors)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load sectors")
 
 		dealCountMax := SectorDealsMax(info.SectorSize)
-		if uint64(len(params.DealIDs)) > dealCountMax {
-			rt.Abortf(exitcode.ErrIllegalArgument, "too many deals for sector %d > %d", len(params.DealIDs), dealCountMax)
-		}
+		chainInfos := make([]*SectorPreCommitOnChainInfo, len(params.Sectors))
+		expiryEvents := map[abi.ChainEpoch][]uint64{}
+		totalDepositRequired := big.Zero()
+		for i := range params.Sectors {
+			precommit := &params.Sectors[i]
+			dealWeight := dealWeights.Sectors[i]
+
+			// From network version 7, the pre-commit seal type must have the same Window PoSt proof type as the miner,
+			// rather than be exactly the same seal type.
+			// This permits a transition window from V1 to V1_1 seal types (which share Window PoSt proof type).
+			sectorWPoStProof, err := precommit.SealProof.RegisteredWindowPoStProof()
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "failed to lookup Window PoSt proof type for sector seal proof %d", precommit.SealProof)
+			if sectorWPoStProof != info.WindowPoStProofType {
+				rt.Abortf(exitcode.ErrIllegalArgument, "sector Window PoSt proof type %d must match miner Window PoSt proof type %d (seal proof type %d)",
+					sectorWPoStProof, info.WindowPoStProofType, precommit.SealProof)
+			}
 
-		// Ensure total deal space does not exceed sector size.
-		if dealWeight.DealSpace > uint64(info.SectorSize) {
-			rt.Abortf(exitcode.ErrIllegalArgument, "deals too large to fit in sector %d > %d", dealWeight.DealSpace, info.SectorSize)
-		}
+			if uint64(len(precommit.DealIDs)) > dealCountMax {
+				rt.Abortf(exitcode.ErrIllegalArgument, "too many deals for sector %d > %d", len(precommit.DealIDs), dealCountMax)
+			}
 
-		err = st.AllocateSectorNumber(store, params.SectorNumber)
-		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to allocate sector id %d", params.SectorNumber)
+			// Ensure total deal space does not exceed sector size.
+			if dealWeight.DealSpace > uint64(info.SectorSize) {
+				rt.Abortf(exitcode.ErrIllegalArgument, "deals too large to fit in sector %d > %d", dealWeight.DealSpace, info.SectorSize)
+			}
 
-		// This sector check is redundant given the allocated sectors bitfield, but remains for safety.
-		sectorFound, err := st.HasSectorNo(store, params.SectorNumber)
-		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to check sector %v", params.SectorNumber)
-		if sectorFound {
-			rt.Abortf(exitcode.ErrIllegalState, "sector %v already committed", params.SectorNumber)
-		}
+			_, sectorFound, err := sectors.Get(precommit.SectorNumber)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to check sector %v", precommit.SectorNumber)
+			if sectorFound {
+				rt.Abortf(exitcode.ErrIllegalState, "sector %v already committed", precommit.SectorNumber)
+			}
+
+			if precommit.ReplaceCapacity {
+				validateReplaceSector(rt, &st, store, precommit)
+			}
+
+			duration := precommit.Expiration - currEpoch
+			sectorWeight := QAPowerForWeight(info.SectorSize, duration, dealWeight.DealWeight, dealWeight.VerifiedDealWeight)
+			depositReq := PreCommitDepositForPower(rewardStats.ThisEpochRewardSmoothed, pwrTotal.QualityAdjPowerSmoothed, sectorWeight)
+			totalDepositRequired = big.Add(totalDepositRequired, depositReq)
+
+			chainInfos[i] = &SectorPreCommitOnChainInfo{
+				Info:               SectorPreCommitInfo(*precommit),
+				PreCommitDeposit:   depositReq,
+				PreCommitEpoch:     currEpoch,
+				DealWeight:         dealWeight.DealWeight,
+				VerifiedDealWeight: dealWeight.VerifiedDealWeight,
+			}
 
-		if params.ReplaceCapacity {
-			validateReplaceSector(rt, &st, store, params)
+			// add precommit expiry to the queue
+			msd, ok := MaxProveCommitDuration[precommit.SealProof]
+			if !ok {
+				rt.Abortf(exitcode.ErrIllegalArgument, "no max seal duration set for proof type: %d", precommit.SealProof)
+			}
+			// The +1 here is critical for the batch verification of proofs. Without it, if a proof arrived exactly on the
+			// due epoch, ProveCommitSector would accept it, then the expiry event would remove it, and then
+			// ConfirmSectorProofsValid would fail to find it.
+			expiryBound := currEpoch + msd + 1
+			expiryEvents[expiryBound] = append(expiryEvents[expiryBound], uint64(precommit.SectorNumber))
 		}
 
-		duration := params.Expiration - rt.CurrEpoch()
-		sectorWeight := QAPowerForWeight(info.SectorSize, duration, dealWeight.DealWeight, dealWeight.VerifiedDealWeight)
-		depositReq := PreCommitDepositForPower(rewardStats.ThisEpochRewardSmoothed, pwrTotal.QualityAdjPowerSmoothed, sectorWeight)
-		if availableBalance.LessThan(depositReq) {
-			rt.Abortf(exitcode.ErrInsufficientFunds, "insufficient funds for pre-commit deposit: %v", depositReq)
+		if availableBalance.LessThan(totalDepositRequired) {
+			rt.Abortf(exitcode.ErrInsufficientFunds, "insufficient funds for pre-commit deposit: %v", totalDepositRequired)
 		}
 
-		err = st.AddPreCommitDeposit(depositReq)
-		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to add pre-commit deposit %v", depositReq)
+		err = st.AddPreCommitDeposit(totalDepositRequired)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to add pre-commit deposit %v", totalDepositRequired)
 
-		if err := st.PutPrecommittedSector(store, &SectorPreCommitOnChainInfo{
-			Info:               SectorPreCommitInfo(*params),
-			PreCommitDeposit:   depositReq,
-			PreCommitEpoch:     rt.CurrEpoch(),
-			DealWeight:         dealWeight.DealWeight,
-			VerifiedDealWeight: dealWeight.VerifiedDealWeight,
-		}); err != nil {
-			rt.Abortf(exitcode.ErrIllegalState, "failed to write pre-committed sector %v: %v", params.SectorNumber, err)
-		}
-		// add precommit expiry to the queue
-		msd, ok := MaxProveCommitDuration[params.SealProof]
-		if !ok {
-			rt.Abortf(exitcode.ErrIllegalArgument, "no max seal duration set for proof type: %d", params.SealProof)
-		}
-		// The +1 here is critical for the batch verification of proofs. Without it, if a proof arrived exactly on the
-		// due epoch, ProveCommitSector would accept it, then the expiry event would remove it, and then
-		// ConfirmSectorProofsValid would fail to find it.
-		expiryBound := rt.CurrEpoch() + msd + 1
+		err = st.PutPrecommittedSectors(store, chainInfos...)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to write pre-committed sectors")
 
-		err = st.AddPreCommitExpiry(store, expiryBound, params.SectorNumber)
-		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to add pre-commit expiry to queue")
+		err = st.AddPreCommitExpirations(store, expiryEvents)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to add pre-commit expiries to queue")
 	})
 
 	burnFunds(rt, feeToBurn)
 	rt.StateReadonly(&st)
-	err = st.CheckBalanceInvariants(rt.CurrentBalance())
+	err := st.CheckBalanceInvariants(rt.CurrentBalance())
 	builtin.RequireNoErr(rt, err, ErrBalanceInvariantBroken, "balance invariants broken")
 
-	notifyPledgeChanged(rt, newlyVested.Neg())
-
 	return nil
 }
 
@@ -857,6 +1161,95 @@ func (a Actor) ProveCommitSector(rt Runtime, params *ProveCommitSectorParams) *a
 	return nil
 }
 
+type ProveCommitAggregateParams struct {
+	SectorNumbers  bitfield.BitField
+	AggregateProof []byte
+}
+
+// Checks state of the corresponding sector pre-commitments and verifies a single aggregate proof of all
+// their seals synchronously, activating the sectors immediately rather than deferring to the power actor.
+func (a Actor) ProveCommitAggregate(rt Runtime, params *ProveCommitAggregateParams) *abi.EmptyValue {
+	aggSectorsCount, err := params.SectorNumbers.Count()
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "failed to count aggregated sectors")
+	if aggSectorsCount > MaxAggregatedSectors {
+		rt.Abortf(exitcode.ErrIllegalArgument, "too many sectors addressed, addressed %d want <= %d", aggSectorsCount, MaxAggregatedSectors)
+	} else if aggSectorsCount < MinAggregatedSectors {
+		rt.Abortf(exitcode.ErrIllegalArgument, "too few sectors addressed, addressed %d want >= %d", aggSectorsCount, MinAggregatedSectors)
+	}
+
+	if uint64(len(params.AggregateProof)) > MaxAggregateProofSize {
+		rt.Abortf(exitcode.ErrIllegalArgument, "sector prove-commit proof of size %d exceeds max size of %d",
+			len(params.AggregateProof), MaxAggregateProofSize)
+	}
+
+	store := adt.AsStore(rt)
+	var st State
+	rt.StateReadonly(&st)
+	info := getMinerInfo(rt, &st)
+	rt.ValidateImmediateCallerIs(info.CallersWithRole(ControlRoleSealing)...)
+
+	sectorNos, err := params.SectorNumbers.All(MaxAggregatedSectors)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "failed to expand aggregated sector numbers")
+	for _, sectorNo := range sectorNos {
+		if sectorNo > abi.MaxSectorNumber {
+			rt.Abortf(exitcode.ErrIllegalArgument, "sector number %d greater than maximum", sectorNo)
+		}
+	}
+	precommits, err := st.FindPrecommittedSectors(store, asSectorNumbers(sectorNos)...)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load pre-committed sectors")
+	if len(precommits) != len(sectorNos) {
+		rt.Abortf(exitcode.ErrNotFound, "found %d of %d pre-committed sectors", len(precommits), len(sectorNos))
+	}
+
+	minerActorID, err := addr.IDFromAddress(rt.Receiver())
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "runtime provided non-ID receiver address %v", rt.Receiver())
+
+	sealProof := precommits[0].Info.SealProof
+	svInfos := make([]proof.AggregateSealVerifyInfo, 0, len(precommits))
+	for _, precommit := range precommits {
+		if precommit.Info.SealProof != sealProof {
+			rt.Abortf(exitcode.ErrIllegalArgument, "aggregate contains mismatched seal proofs %d and %d", sealProof, precommit.Info.SealProof)
+		}
+
+		msd, ok := MaxProveCommitDuration[precommit.Info.SealProof]
+		if !ok {
+			rt.Abortf(exitcode.ErrIllegalState, "no max seal duration for proof type: %d", precommit.Info.SealProof)
+		}
+		proveCommitDue := precommit.PreCommitEpoch + msd
+		if rt.CurrEpoch() > proveCommitDue {
+			rt.Abortf(exitcode.ErrIllegalArgument, "commitment proof for %d too late at %d, due %d", precommit.Info.SectorNumber, rt.CurrEpoch(), proveCommitDue)
+		}
+
+		svi := getVerifyInfo(rt, &SealVerifyStuff{
+			SealedCID:           precommit.Info.SealedCID,
+			InteractiveEpoch:    precommit.PreCommitEpoch + PreCommitChallengeDelay,
+			SealRandEpoch:       precommit.Info.SealRandEpoch,
+			DealIDs:             precommit.Info.DealIDs,
+			SectorNumber:        precommit.Info.SectorNumber,
+			RegisteredSealProof: precommit.Info.SealProof,
+		})
+		svInfos = append(svInfos, proof.AggregateSealVerifyInfo{
+			Number:                svi.SectorID.Number,
+			Randomness:            svi.Randomness,
+			InteractiveRandomness: svi.InteractiveRandomness,
+			SealedCID:             svi.SealedCID,
+			UnsealedCID:           svi.UnsealedCID,
+		})
+	}
+
+	err = rt.VerifyAggregateSeals(proof.AggregateSealVerifyProofAndInfos{
+		Miner:          abi.ActorID(minerActorID),
+		SealProof:      sealProof,
+		AggregateProof: proof.RegisteredAggregationProof_SnarkPackV1,
+		Proof:          params.AggregateProof,
+		Infos:          svInfos,
+	})
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "aggregate seal verify failed")
+
+	confirmSectorProofsValidInternal(rt, precommits)
+	return nil
+}
+
 func (a Actor) ConfirmSectorProofsValid(rt Runtime, params *builtin.ConfirmSectorProofsParams) *abi.EmptyValue {
 	rt.ValidateImmediateCallerIs(builtin.StoragePowerActorAddr)
 
@@ -868,6 +1261,21 @@ func (a Actor) ConfirmSectorProofsValid(rt Runtime, params *builtin.ConfirmSecto
 		)
 	}
 
+	var st State
+	rt.StateReadonly(&st)
+	store := adt.AsStore(rt)
+
+	// This skips missing pre-commits.
+	precommittedSectors, err := st.FindPrecommittedSectors(store, params.Sectors...)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load pre-committed sectors")
+
+	confirmSectorProofsValidInternal(rt, precommittedSectors)
+	return nil
+}
+
+// Activates the deals of proven pre-committed sectors and adds them to the miner's sector set,
+// moving their pre-commit deposits to initial pledge.
+func confirmSectorProofsValidInternal(rt Runtime, precommittedSectors []*SectorPreCommitOnChainInfo) {
 	// get network stats from other actors
 	rewardStats := requestCurrentEpochBlockReward(rt)
 	pwrTotal := requestCurrentTotalPower(rt)
@@ -894,10 +1302,6 @@ func (a Actor) ConfirmSectorProofsValid(rt Runtime, params *builtin.ConfirmSecto
 	// Activate storage deals.
 	//
 
-	// This skips missing pre-commits.
-	precommittedSectors, err := st.FindPrecommittedSectors(store, params.Sectors...)
-	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load pre-committed sectors")
-
 	// Committed-capacity sectors licensed for early removal by new sectors being proven.
 	replaceSectors := make(DeadlineSectorMap)
 	// Pre-commits for new sectors.
@@ -1027,7 +1431,6 @@ func (a Actor) ConfirmSectorProofsValid(rt Runtime, params *builtin.ConfirmSecto
 
 	// Request pledge update for activated sector.
 	notifyPledgeChanged(rt, big.Sub(totalPledge, newlyVested))
-	return nil
 }
 
 //type CheckSectorProvenParams struct {
@@ -1059,6 +1462,257 @@ func (a Actor) CheckSectorProven(rt Runtime, params *CheckSectorProvenParams) *a
 // Sector Modification //
 /////////////////////////
 
+type ReplicaUpdate struct {
+	SectorNumber    abi.SectorNumber
+	Deadline        uint64
+	Partition       uint64
+	NewSealedCID    cid.Cid `checked:"true"` // CommR of the updated replica
+	NewUnsealedCID  cid.Cid `checked:"true"` // CommD of the deals' data
+	Deals           []abi.DealID
+	UpdateProofType proof.RegisteredUpdateProof
+	ReplicaProof    []byte
+}
+
+type ProveReplicaUpdatesParams struct {
+	Updates []ReplicaUpdate
+}
+
+// Updates committed-capacity sectors in place to hold the data of storage deals, without re-sealing.
+// Each sector must be active and without deals, in a deadline that is not currently being proven.
+// The sectors' deal weights, power and initial pledge are recomputed for their remaining lifetime,
+// and the deals are activated.
+// Aborts if any update is invalid.
+func (a Actor) ProveReplicaUpdates(rt Runtime, params *ProveReplicaUpdatesParams) *abi.EmptyValue {
+	currEpoch := rt.CurrEpoch()
+	if len(params.Updates) == 0 {
+		rt.Abortf(exitcode.ErrIllegalArgument, "no updates")
+	} else if len(params.Updates) > ProveReplicaUpdatesMaxSize {
+		rt.Abortf(exitcode.ErrIllegalArgument, "%d updates too many, max %d", len(params.Updates), ProveReplicaUpdatesMaxSize)
+	}
+
+	// Check per-update preconditions before loading state.
+	sectorNumbers := bitfield.New()
+	for i := range params.Updates {
+		update := &params.Updates[i]
+		set, err := sectorNumbers.IsSet(uint64(update.SectorNumber))
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "failed to check sector number %d", update.SectorNumber)
+		if set {
+			rt.Abortf(exitcode.ErrIllegalArgument, "duplicate sector number %d", update.SectorNumber)
+		}
+		sectorNumbers.Set(uint64(update.SectorNumber))
+
+		if update.Deadline >= WPoStPeriodDeadlines {
+			rt.Abortf(exitcode.ErrIllegalArgument, "deadline %d not in range 0..%d", update.Deadline, WPoStPeriodDeadlines)
+		}
+		if len(update.Deals) == 0 {
+			rt.Abortf(exitcode.ErrIllegalArgument, "no deals for sector %d", update.SectorNumber)
+		}
+		if !update.NewSealedCID.Defined() || update.NewSealedCID.Prefix() != SealedCIDPrefix {
+			rt.Abortf(exitcode.ErrIllegalArgument, "sealed CID %v for sector %d had wrong prefix", update.NewSealedCID, update.SectorNumber)
+		}
+		if !update.NewUnsealedCID.Defined() {
+			rt.Abortf(exitcode.ErrIllegalArgument, "unsealed CID undefined for sector %d", update.SectorNumber)
+		}
+		if len(update.ReplicaProof) > MaxReplicaUpdateProofSize {
+			rt.Abortf(exitcode.ErrIllegalArgument, "replica proof for sector %d size %d exceeds max %d",
+				update.SectorNumber, len(update.ReplicaProof), MaxReplicaUpdateProofSize)
+		}
+	}
+
+	var st State
+	rt.StateReadonly(&st)
+	store := adt.AsStore(rt)
+	info := getMinerInfo(rt, &st)
+	rt.ValidateImmediateCallerIs(info.CallersWithRole(ControlRoleSealing)...)
+
+	if ConsensusFaultActive(info, currEpoch) {
+		rt.Abortf(exitcode.ErrForbidden, "replica update not allowed during active consensus fault")
+	}
+
+	deadlines, err := st.LoadDeadlines(store)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadlines")
+	sectors, err := LoadSectors(store, st.Sectors)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load sectors")
+
+	oldSectors := make([]*SectorOnChainInfo, len(params.Updates))
+	sectorDeals := make([]market.SectorDeals, len(params.Updates))
+	for i := range params.Updates {
+		update := &params.Updates[i]
+		sector, found, err := sectors.Get(update.SectorNumber)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load sector %d", update.SectorNumber)
+		if !found {
+			rt.Abortf(exitcode.ErrNotFound, "no such sector %d", update.SectorNumber)
+		}
+		if len(sector.DealIDs) > 0 {
+			rt.Abortf(exitcode.ErrForbidden, "cannot update sector %d which already has deals", update.SectorNumber)
+		}
+		if sector.Expiration <= currEpoch {
+			rt.Abortf(exitcode.ErrForbidden, "cannot update sector %d expired at %d", update.SectorNumber, sector.Expiration)
+		}
+		if expected, ok := ReplicaUpdateProofTypes[sector.SealProof]; !ok {
+			rt.Abortf(exitcode.ErrForbidden, "cannot update sector %d with seal proof type %d", update.SectorNumber, sector.SealProof)
+		} else if update.UpdateProofType != expected {
+			rt.Abortf(exitcode.ErrIllegalArgument, "update proof type %d for sector %d does not match seal proof type, expected %d",
+				update.UpdateProofType, update.SectorNumber, expected)
+		}
+		// We assume that deadlines are immutable when being proven.
+		if !deadlineIsMutable(st.ProvingPeriodStart, update.Deadline, currEpoch) {
+			rt.Abortf(exitcode.ErrIllegalArgument, "cannot update sector %d in immutable deadline %d", update.SectorNumber, update.Deadline)
+		}
+
+		deadline, err := deadlines.LoadDeadline(store, update.Deadline)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadline %d", update.Deadline)
+		partition, err := deadline.LoadPartition(store, update.Partition)
+		builtin.RequireNoErr(rt, err, exitcode.ErrNotFound, "failed to load deadline %d partition %d", update.Deadline, update.Partition)
+		active, err := partition.ActiveSectors()
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load active sectors of deadline %d partition %d", update.Deadline, update.Partition)
+		if isActive, err := active.IsSet(uint64(update.SectorNumber)); err != nil {
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to check sector %d", update.SectorNumber)
+		} else if !isActive {
+			rt.Abortf(exitcode.ErrForbidden, "sector %d is not active in deadline %d partition %d",
+				update.SectorNumber, update.Deadline, update.Partition)
+		}
+
+		oldSectors[i] = sector
+		sectorDeals[i] = market.SectorDeals{
+			SectorExpiry: sector.Expiration,
+			DealIDs:      update.Deals,
+		}
+	}
+
+	// Check the deals can be activated, and their total size.
+	dealWeights := requestDealWeights(rt, sectorDeals)
+	if len(dealWeights.Sectors) != len(params.Updates) {
+		rt.Abortf(exitcode.ErrIllegalState, "deal weight request returned %d records, expected %d",
+			len(dealWeights.Sectors), len(params.Updates))
+	}
+	for i, weight := range dealWeights.Sectors {
+		if weight.DealSpace > uint64(info.SectorSize) {
+			rt.Abortf(exitcode.ErrIllegalArgument, "deals too large to fit in sector %d > %d", weight.DealSpace, info.SectorSize)
+		}
+
+		// Verify that the new data matches the deals, and the update proof.
+		update := &params.Updates[i]
+		unsealedCID := requestUnsealedSectorCID(rt, oldSectors[i].SealProof, update.Deals)
+		if !unsealedCID.Equals(update.NewUnsealedCID) {
+			rt.Abortf(exitcode.ErrIllegalArgument, "unsealed CID %v for sector %d does not match deals' data commitment %v",
+				update.NewUnsealedCID, update.SectorNumber, unsealedCID)
+		}
+		err := rt.VerifyReplicaUpdate(proof.ReplicaUpdateInfo{
+			UpdateProofType:      update.UpdateProofType,
+			NewSealedSectorCID:   update.NewSealedCID,
+			OldSealedSectorCID:   oldSectors[i].SealedCID,
+			NewUnsealedSectorCID: update.NewUnsealedCID,
+			Proof:                update.ReplicaProof,
+		})
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "failed to verify replica update for sector %d", update.SectorNumber)
+	}
+
+	for i := range params.Updates {
+		code := rt.Send(
+			builtin.StorageMarketActorAddr,
+			builtin.MethodsMarket.ActivateDeals,
+			&market.ActivateDealsParams{
+				DealIDs:      params.Updates[i].Deals,
+				SectorExpiry: oldSectors[i].Expiration,
+			},
+			abi.NewTokenAmount(0),
+			&builtin.Discard{},
+		)
+		builtin.RequireSuccess(rt, code, "failed to activate deals for sector %d", params.Updates[i].SectorNumber)
+	}
+
+	// get network stats from other actors
+	rewardStats := requestCurrentEpochBlockReward(rt)
+	pwrTotal := requestCurrentTotalPower(rt)
+	circulatingSupply := rt.TotalFilCircSupply()
+
+	powerDelta := NewPowerPairZero()
+	pledgeDelta := big.Zero()
+	rt.StateTransaction(&st, func() {
+		deadlines, err := st.LoadDeadlines(store)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadlines")
+		sectors, err := LoadSectors(store, st.Sectors)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load sectors")
+
+		newSectors := make([]*SectorOnChainInfo, len(params.Updates))
+		for i := range params.Updates {
+			update := &params.Updates[i]
+			oldSector := oldSectors[i]
+			weight := dealWeights.Sectors[i]
+
+			// The sector's power is computed over its remaining lifetime, so the update is treated as a new activation.
+			duration := oldSector.Expiration - currEpoch
+			pwr := QAPowerForWeight(info.SectorSize, duration, weight.DealWeight, weight.VerifiedDealWeight)
+			dayReward := ExpectedRewardForPower(rewardStats.ThisEpochRewardSmoothed, pwrTotal.QualityAdjPowerSmoothed, pwr, builtin.EpochsInDay)
+			storagePledge := ExpectedRewardForPower(rewardStats.ThisEpochRewardSmoothed, pwrTotal.QualityAdjPowerSmoothed, pwr, InitialPledgeProjectionPeriod)
+			initialPledge := InitialPledgeForPower(pwr, rewardStats.ThisEpochBaselinePower, rewardStats.ThisEpochRewardSmoothed,
+				pwrTotal.QualityAdjPowerSmoothed, circulatingSupply)
+
+			newSector := *oldSector
+			newSector.SealedCID = update.NewSealedCID
+			newSector.DealIDs = update.Deals
+			newSector.Activation = currEpoch
+			newSector.DealWeight = weight.DealWeight
+			newSector.VerifiedDealWeight = weight.VerifiedDealWeight
+			// Lower-bound the pledge by that of the sector before the update.
+			newSector.InitialPledge = big.Max(initialPledge, oldSector.InitialPledge)
+			newSector.ExpectedDayReward = dayReward
+			newSector.ExpectedStoragePledge = storagePledge
+			// Record the age and reward rate of the sector before the update for termination fee calculations.
+			newSector.ReplacedSectorAge = maxEpoch(0, currEpoch-oldSector.Activation)
+			newSector.ReplacedDayReward = oldSector.ExpectedDayReward
+			newSectors[i] = &newSector
+
+			deadline, err := deadlines.LoadDeadline(store, update.Deadline)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadline %d", update.Deadline)
+			partitions, err := deadline.PartitionsArray(store)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load partitions for deadline %d", update.Deadline)
+			var partition Partition
+			found, err := partitions.Get(update.Partition, &partition)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadline %d partition %d", update.Deadline, update.Partition)
+			if !found {
+				rt.Abortf(exitcode.ErrNotFound, "no such deadline %d partition %d", update.Deadline, update.Partition)
+			}
+
+			partitionPowerDelta, partitionPledgeDelta, err := partition.ReplaceSectors(store,
+				[]*SectorOnChainInfo{oldSector}, []*SectorOnChainInfo{&newSector}, info.SectorSize, st.QuantSpecForDeadline(update.Deadline))
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to replace sector %d at deadline %d partition %d",
+				update.SectorNumber, update.Deadline, update.Partition)
+			powerDelta = powerDelta.Add(partitionPowerDelta)
+			pledgeDelta = big.Add(pledgeDelta, partitionPledgeDelta)
+
+			err = partitions.Set(update.Partition, &partition)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to save deadline %d partition %d", update.Deadline, update.Partition)
+			deadline.Partitions, err = partitions.Root()
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to save partitions for deadline %d", update.Deadline)
+			err = deadlines.UpdateDeadline(store, update.Deadline, deadline)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to save deadline %d", update.Deadline)
+		}
+
+		err = sectors.Store(newSectors...)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to update sectors")
+		st.Sectors, err = sectors.Root()
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to save sectors")
+		err = st.SaveDeadlines(store, deadlines)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to save deadlines")
+
+		unlockedBalance, err := st.GetUnlockedBalance(rt.CurrentBalance())
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to calculate unlocked balance")
+		if unlockedBalance.LessThan(pledgeDelta) {
+			rt.Abortf(exitcode.ErrInsufficientFunds, "insufficient funds for increased initial pledge requirement %s, available: %s", pledgeDelta, unlockedBalance)
+		}
+		err = st.AddInitialPledge(pledgeDelta)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to add initial pledge %v", pledgeDelta)
+		err = st.CheckBalanceInvariants(rt.CurrentBalance())
+		builtin.RequireNoErr(rt, err, ErrBalanceInvariantBroken, "balance invariants broken")
+	})
+
+	requestUpdatePower(rt, powerDelta)
+	notifyPledgeChanged(rt, pledgeDelta)
+	return nil
+}
+
 //type ExtendSectorExpirationParams struct {
 //	Extensions []ExpirationExtension
 //}
@@ -1076,14 +1730,28 @@ type ExpirationExtension = miner0.ExpirationExtension
 // The sector must not be terminated or faulty.
 // The sector's power is recomputed for the new expiration.
 func (a Actor) ExtendSectorExpiration(rt Runtime, params *ExtendSectorExpirationParams) *abi.EmptyValue {
-	if uint64(len(params.Extensions)) > DeclarationsMax {
-		rt.Abortf(exitcode.ErrIllegalArgument, "too many declarations %d, max %d", len(params.Extensions), DeclarationsMax)
+	powerDelta, pledgeDelta := extendSectorExpirations(rt, params.Extensions, nil)
+
+	requestUpdatePower(rt, powerDelta)
+	// Note: the pledge delta is expected to be zero, since pledge is not re-calculated for the extension.
+	// But in case that ever changes, we can do the right thing here.
+	notifyPledgeChanged(rt, pledgeDelta)
+	return nil
+}
+
+// Extends the expiration of sectors, returning the resulting change in power and pledge.
+// If adjust is not nil, it is called with the index of each declaration, the sector, and the extended sector,
+// and may modify the extended sector's weights.
+func extendSectorExpirations(rt Runtime, extensions []ExpirationExtension,
+	adjust func(declIdx int, oldSector, newSector *SectorOnChainInfo, info *MinerInfo)) (PowerPair, abi.TokenAmount) {
+	if uint64(len(extensions)) > DeclarationsMax {
+		rt.Abortf(exitcode.ErrIllegalArgument, "too many declarations %d, max %d", len(extensions), DeclarationsMax)
 	}
 
 	// limit the number of sectors declared at once
 	// https://github.com/filecoin-project/specs-actors/issues/416
 	var sectorCount uint64
-	for _, decl := range params.Extensions {
+	for _, decl := range extensions {
 		if decl.Deadline >= WPoStPeriodDeadlines {
 			rt.Abortf(exitcode.ErrIllegalArgument, "deadline %d not in range 0..%d", decl.Deadline, WPoStPeriodDeadlines)
 		}
@@ -1113,24 +1781,21 @@ func (a Actor) ExtendSectorExpiration(rt Runtime, params *ExtendSectorExpiration
 	rt.StateTransaction(&st, func() {
 		info := getMinerInfo(rt, &st)
 
-		rt.ValidateImmediateCallerIs(append(info.ControlAddresses, info.Owner, info.Worker)...)
+		rt.ValidateImmediateCallerIs(info.CallersWithRole(ControlRoleSealing)...)
 
 		deadlines, err := st.LoadDeadlines(adt.AsStore(rt))
 		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadlines")
 
 		// Group declarations by deadline, and remember iteration order.
 		// This should be merged with the iteration outside the state transaction.
-		declsByDeadline := map[uint64][]*ExpirationExtension{}
+		declsByDeadline := map[uint64][]int{}
 		var deadlinesToLoad []uint64
-		for i := range params.Extensions {
-			// Take a pointer to the value inside the slice, don't
-			// take a reference to the temporary loop variable as it
-			// will be overwritten every iteration.
-			decl := &params.Extensions[i]
+		for i := range extensions {
+			decl := &extensions[i]
 			if _, ok := declsByDeadline[decl.Deadline]; !ok {
 				deadlinesToLoad = append(deadlinesToLoad, decl.Deadline)
 			}
-			declsByDeadline[decl.Deadline] = append(declsByDeadline[decl.Deadline], decl)
+			declsByDeadline[decl.Deadline] = append(declsByDeadline[decl.Deadline], i)
 		}
 
 		sectors, err := LoadSectors(store, st.Sectors)
@@ -1150,7 +1815,11 @@ func (a Actor) ExtendSectorExpiration(rt Runtime, params *ExtendSectorExpiration
 			// Remember iteration order of epochs.
 			var epochsToReschedule []abi.ChainEpoch
 
-			for _, decl := range declsByDeadline[dlIdx] {
+			for _, declIdx := range declsByDeadline[dlIdx] {
+				// Take a pointer to the value inside the slice, don't
+				// take a reference to the temporary loop variable as it
+				// will be overwritten every iteration.
+				decl := &extensions[declIdx]
 				var partition Partition
 				found, err := partitions.Get(decl.Partition, &partition)
 				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadline %v partition %v", dlIdx, decl.Partition)
@@ -1183,6 +1852,9 @@ func (a Actor) ExtendSectorExpiration(rt Runtime, params *ExtendSectorExpiration
 
 					newSector := *sector
 					newSector.Expiration = decl.NewExpiration
+					if adjust != nil {
+						adjust(declIdx, sector, &newSector, info)
+					}
 
 					newSectors[i] = &newSector
 				}
@@ -1230,10 +1902,243 @@ func (a Actor) ExtendSectorExpiration(rt Runtime, params *ExtendSectorExpiration
 		err = st.SaveDeadlines(store, deadlines)
 		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to save deadlines")
 	})
+	return powerDelta, pledgeDelta
+}
+
+type ExpirationExtension2 struct {
+	Deadline      uint64
+	Partition     uint64
+	Sectors       bitfield.BitField
+	NewExpiration abi.ChainEpoch
+	// Sectors, a subset of Sectors, whose verified deal weight is dropped by the extension.
+	DropVerified bitfield.BitField
+}
+
+type ExtendSectorExpiration2Params struct {
+	Extensions []ExpirationExtension2
+}
+
+// The effect of an extension on a single sector.
+type SectorExtension struct {
+	SectorNumber    abi.SectorNumber
+	VerifiedDropped bool
+	OldQAPower      abi.StoragePower
+	NewQAPower      abi.StoragePower
+	PledgeDelta     abi.TokenAmount // Change in the sector's initial pledge, zero since pledge is not re-calculated.
+}
+
+type ExtendSectorExpiration2Return struct {
+	Sectors []SectorExtension // Ordered by sector number.
+}
+
+// Changes the expiration epoch for sectors to new, later ones, like ExtendSectorExpiration, but retaining
+// the sectors' deal space rather than spreading their deal weights over the extended lifetime.
+// A sector's deal weight and verified deal weight are both scaled with its lifetime, so its quality-adjusted
+// power is unchanged, if its new expiration is within VerifiedDealMaxTerm of its activation. Beyond that, the
+// verified deal weight must be dropped explicitly by including the sector in DropVerified, and the sector's power
+// falls to that of a sector without verified deals.
+// As with ExtendSectorExpiration, pledge is not re-calculated for the extension.
+// Returns the power and pledge change of each extended sector. The pledge change is always zero, and is
+// reported so that callers needn't assume so.
+func (a Actor) ExtendSectorExpiration2(rt Runtime, params *ExtendSectorExpiration2Params) *ExtendSectorExpiration2Return {
+	extensions := make([]ExpirationExtension, len(params.Extensions))
+	for i, decl := range params.Extensions {
+		contained, err := BitFieldContainsAll(decl.Sectors, decl.DropVerified)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "failed to check sectors to drop verified weight")
+		if !contained {
+			rt.Abortf(exitcode.ErrIllegalArgument, "sectors to drop verified weight are not all extended in deadline %d partition %d",
+				decl.Deadline, decl.Partition)
+		}
+		extensions[i] = ExpirationExtension{
+			Deadline:      decl.Deadline,
+			Partition:     decl.Partition,
+			Sectors:       decl.Sectors,
+			NewExpiration: decl.NewExpiration,
+		}
+	}
+
+	var results []SectorExtension
+	powerDelta, pledgeDelta := extendSectorExpirations(rt, extensions, func(declIdx int, oldSector, newSector *SectorOnChainInfo, info *MinerInfo) {
+		drop, err := params.Extensions[declIdx].DropVerified.IsSet(uint64(oldSector.SectorNumber))
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "failed to check sector %d to drop verified weight", oldSector.SectorNumber)
+
+		// Scale the weights with the sector's lifetime, retaining the deal space.
+		newSector.DealWeight = scaleDealWeight(oldSector.DealWeight, oldSector, newSector)
+		if drop {
+			newSector.VerifiedDealWeight = big.Zero()
+		} else if !oldSector.VerifiedDealWeight.IsZero() {
+			termEnd := oldSector.Activation + VerifiedDealMaxTerm
+			if newSector.Expiration > termEnd {
+				rt.Abortf(exitcode.ErrForbidden, "cannot extend sector %d with verified deals to %d beyond verified term ending %d without dropping verified weight",
+					oldSector.SectorNumber, newSector.Expiration, termEnd)
+			}
+			newSector.VerifiedDealWeight = scaleDealWeight(oldSector.VerifiedDealWeight, oldSector, newSector)
+		}
+
+		results = append(results, SectorExtension{
+			SectorNumber:    oldSector.SectorNumber,
+			VerifiedDropped: drop && !oldSector.VerifiedDealWeight.IsZero(),
+			OldQAPower:      QAPowerForSector(info.SectorSize, oldSector),
+			NewQAPower:      QAPowerForSector(info.SectorSize, newSector),
+			PledgeDelta:     big.Sub(newSector.InitialPledge, oldSector.InitialPledge),
+		})
+	})
+
+	requestUpdatePower(rt, powerDelta)
+	notifyPledgeChanged(rt, pledgeDelta)
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].SectorNumber < results[j].SectorNumber
+	})
+	return &ExtendSectorExpiration2Return{Sectors: results}
+}
+
+// Scales a deal weight of a sector from its old lifetime to its new one.
+func scaleDealWeight(weight abi.DealWeight, oldSector, newSector *SectorOnChainInfo) abi.DealWeight {
+	oldDuration := big.NewInt(int64(oldSector.Expiration - oldSector.Activation))
+	newDuration := big.NewInt(int64(newSector.Expiration - newSector.Activation))
+	return big.Div(big.Mul(weight, newDuration), oldDuration)
+}
+
+type RenewSectorDealParams struct {
+	Deadline  uint64
+	Partition uint64
+	Sector    abi.SectorNumber
+	Renewal   market.ClientDealRenewal // Renewal of one of the sector's deals, signed by the deal's client.
+}
+
+// Renews a deal hosted by an active sector, extending it to a new end epoch no later than the sector's expiration.
+// The renewal's price and collateral are settled by the market actor.
+// The sector's deal weight grows with the deal's duration, and its power is recomputed.
+// The sector's initial pledge and expected rewards are recomputed for the new power, as at activation,
+// but never reduced. Any additional pledge is locked from the miner's unlocked balance.
+func (a Actor) RenewSectorDeal(rt Runtime, params *RenewSectorDealParams) *abi.EmptyValue {
+	if params.Deadline >= WPoStPeriodDeadlines {
+		rt.Abortf(exitcode.ErrIllegalArgument, "deadline %d not in range 0..%d", params.Deadline, WPoStPeriodDeadlines)
+	}
+	dealID := params.Renewal.Renewal.DealID
+
+	var st State
+	rt.StateReadonly(&st)
+	store := adt.AsStore(rt)
+	info := getMinerInfo(rt, &st)
+	rt.ValidateImmediateCallerIs(info.CallersWithRole(ControlRoleSealing)...)
+
+	sectors, err := LoadSectors(store, st.Sectors)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load sectors")
+	sector, found, err := sectors.Get(params.Sector)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load sector %d", params.Sector)
+	if !found {
+		rt.Abortf(exitcode.ErrNotFound, "no such sector %d", params.Sector)
+	}
+	hosted := false
+	for _, id := range sector.DealIDs {
+		if id == dealID {
+			hosted = true
+			break
+		}
+	}
+	if !hosted {
+		rt.Abortf(exitcode.ErrIllegalArgument, "deal %d is not in sector %d", dealID, params.Sector)
+	}
+
+	deadlines, err := st.LoadDeadlines(store)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadlines")
+	deadline, err := deadlines.LoadDeadline(store, params.Deadline)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadline %d", params.Deadline)
+	partition, err := deadline.LoadPartition(store, params.Partition)
+	builtin.RequireNoErr(rt, err, exitcode.ErrNotFound, "failed to load deadline %d partition %d", params.Deadline, params.Partition)
+	active, err := partition.ActiveSectors()
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load active sectors of deadline %d partition %d", params.Deadline, params.Partition)
+	if isActive, err := active.IsSet(uint64(params.Sector)); err != nil {
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to check sector %d", params.Sector)
+	} else if !isActive {
+		rt.Abortf(exitcode.ErrForbidden, "sector %d is not active in deadline %d partition %d",
+			params.Sector, params.Deadline, params.Partition)
+	}
+
+	var renewed market.RenewDealReturn
+	code := rt.Send(
+		builtin.StorageMarketActorAddr,
+		builtin.MethodsMarket.RenewDeal,
+		&market.RenewDealParams{
+			Renewal:      params.Renewal,
+			SectorExpiry: sector.Expiration,
+		},
+		abi.NewTokenAmount(0),
+		&renewed,
+	)
+	builtin.RequireSuccess(rt, code, "failed to renew deal %d", dealID)
+
+	rewardStats := requestCurrentEpochBlockReward(rt)
+	pwrTotal := requestCurrentTotalPower(rt)
+	circulatingSupply := rt.TotalFilCircSupply()
+
+	powerDelta := NewPowerPairZero()
+	pledgeDelta := big.Zero()
+	rt.StateTransaction(&st, func() {
+		deadlines, err := st.LoadDeadlines(store)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadlines")
+		sectors, err := LoadSectors(store, st.Sectors)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load sectors")
+
+		newSector := *sector
+		newSector.DealWeight = big.Add(sector.DealWeight, renewed.DealWeightDelta)
+		newSector.VerifiedDealWeight = big.Add(sector.VerifiedDealWeight, renewed.VerifiedDealWeightDelta)
+
+		// Recompute pledge and expected rewards for the new power, lower-bounded by their current values
+		// so that the renewal can't reduce the sector's collateral or termination fee.
+		pwr := QAPowerForSector(info.SectorSize, &newSector)
+		dayReward := ExpectedRewardForPower(rewardStats.ThisEpochRewardSmoothed, pwrTotal.QualityAdjPowerSmoothed, pwr, builtin.EpochsInDay)
+		storagePledge := ExpectedRewardForPower(rewardStats.ThisEpochRewardSmoothed, pwrTotal.QualityAdjPowerSmoothed, pwr, InitialPledgeProjectionPeriod)
+		initialPledge := InitialPledgeForPower(pwr, rewardStats.ThisEpochBaselinePower, rewardStats.ThisEpochRewardSmoothed,
+			pwrTotal.QualityAdjPowerSmoothed, circulatingSupply)
+		newSector.ExpectedDayReward = big.Max(sector.ExpectedDayReward, dayReward)
+		newSector.ExpectedStoragePledge = big.Max(sector.ExpectedStoragePledge, storagePledge)
+		newSector.InitialPledge = big.Max(sector.InitialPledge, initialPledge)
+
+		deadline, err := deadlines.LoadDeadline(store, params.Deadline)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadline %d", params.Deadline)
+		partitions, err := deadline.PartitionsArray(store)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load partitions for deadline %d", params.Deadline)
+		var partition Partition
+		found, err := partitions.Get(params.Partition, &partition)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadline %d partition %d", params.Deadline, params.Partition)
+		if !found {
+			rt.Abortf(exitcode.ErrNotFound, "no such deadline %d partition %d", params.Deadline, params.Partition)
+		}
+
+		powerDelta, pledgeDelta, err = partition.ReplaceSectors(store,
+			[]*SectorOnChainInfo{sector}, []*SectorOnChainInfo{&newSector}, info.SectorSize, st.QuantSpecForDeadline(params.Deadline))
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to replace sector %d at deadline %d partition %d",
+			params.Sector, params.Deadline, params.Partition)
+
+		err = partitions.Set(params.Partition, &partition)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to save deadline %d partition %d", params.Deadline, params.Partition)
+		deadline.Partitions, err = partitions.Root()
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to save partitions for deadline %d", params.Deadline)
+		err = deadlines.UpdateDeadline(store, params.Deadline, deadline)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to save deadline %d", params.Deadline)
+
+		err = sectors.Store(&newSector)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to update sector %d", params.Sector)
+		st.Sectors, err = sectors.Root()
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to save sectors")
+		err = st.SaveDeadlines(store, deadlines)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to save deadlines")
+
+		unlockedBalance, err := st.GetUnlockedBalance(rt.CurrentBalance())
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to calculate unlocked balance")
+		if unlockedBalance.LessThan(pledgeDelta) {
+			rt.Abortf(exitcode.ErrInsufficientFunds, "insufficient funds for increased initial pledge requirement %s, available: %s", pledgeDelta, unlockedBalance)
+		}
+		err = st.AddInitialPledge(pledgeDelta)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to add initial pledge %v", pledgeDelta)
+		err = st.CheckBalanceInvariants(rt.CurrentBalance())
+		builtin.RequireNoErr(rt, err, ErrBalanceInvariantBroken, "balance invariants broken")
+	})
 
 	requestUpdatePower(rt, powerDelta)
-	// Note: the pledge delta is expected to be zero, since pledge is not re-calculated for the extension.
-	// But in case that ever changes, we can do the right thing here.
 	notifyPledgeChanged(rt, pledgeDelta)
 	return nil
 }
@@ -1307,7 +2212,7 @@ func (a Actor) TerminateSectors(rt Runtime, params *TerminateSectorsParams) *Ter
 		hadEarlyTerminations = havePendingEarlyTerminations(rt, &st)
 
 		info := getMinerInfo(rt, &st)
-		rt.ValidateImmediateCallerIs(append(info.ControlAddresses, info.Owner, info.Worker)...)
+		rt.ValidateImmediateCallerIs(info.CallersWithRole(ControlRoleTermination)...)
 
 		deadlines, err := st.LoadDeadlines(adt.AsStore(rt))
 		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadlines")
@@ -1366,6 +2271,52 @@ func (a Actor) TerminateSectors(rt Runtime, params *TerminateSectorsParams) *Ter
 	return &TerminateSectorsReturn{Done: !more}
 }
 
+// The projected cost of terminating a single sector.
+type SectorTerminationFee struct {
+	SectorNumber abi.SectorNumber
+	Fee          abi.TokenAmount // Termination fee that would be burnt.
+	Pledge       abi.TokenAmount // Initial pledge that would be released.
+}
+
+type PreviewTerminateSectorsReturn struct {
+	Sectors     []SectorTerminationFee // Ordered by sector number.
+	TotalFee    abi.TokenAmount
+	TotalPledge abi.TokenAmount
+}
+
+// Computes the fees that would be charged, and the pledge released, by terminating sectors at the current epoch
+// with TerminateSectors. The fees are computed from current reward and power estimates, but are not limited by
+// the miner's available funds. This method does not mutate state.
+func (a Actor) PreviewTerminateSectors(rt Runtime, params *TerminateSectorsParams) *PreviewTerminateSectorsReturn {
+	rt.ValidateImmediateCallerAcceptAny()
+	if len(params.Terminations) > DeclarationsMax {
+		rt.Abortf(exitcode.ErrIllegalArgument,
+			"too many declarations when previewing sector termination: %d > %d",
+			len(params.Terminations), DeclarationsMax,
+		)
+	}
+
+	toProcess := make(DeadlineSectorMap)
+	for _, term := range params.Terminations {
+		err := toProcess.Add(term.Deadline, term.Partition, term.Sectors)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument,
+			"failed to process deadline %d, partition %d", term.Deadline, term.Partition,
+		)
+	}
+	err := toProcess.Check(AddressedPartitionsMax, AddressedSectorsMax)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "cannot process requested parameters")
+
+	rewardStats := requestCurrentEpochBlockReward(rt)
+	pwrTotal := requestCurrentTotalPower(rt)
+
+	var st State
+	rt.StateReadonly(&st)
+	ret, err := PreviewTerminationFees(adt.AsStore(rt), &st, rt.CurrEpoch(),
+		rewardStats.ThisEpochRewardSmoothed, pwrTotal.QualityAdjPowerSmoothed, params)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to preview termination fees")
+	return ret
+}
+
 ////////////
 // Faults //
 ////////////
@@ -1408,7 +2359,7 @@ func (a Actor) DeclareFaults(rt Runtime, params *DeclareFaultsParams) *abi.Empty
 	powerDelta := NewPowerPairZero()
 	rt.StateTransaction(&st, func() {
 		info := getMinerInfo(rt, &st)
-		rt.ValidateImmediateCallerIs(append(info.ControlAddresses, info.Owner, info.Worker)...)
+		rt.ValidateImmediateCallerIs(info.CallersWithRole(ControlRoleFaults)...)
 
 		deadlines, err := st.LoadDeadlines(store)
 		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadlines")
@@ -1494,7 +2445,7 @@ func (a Actor) DeclareFaultsRecovered(rt Runtime, params *DeclareFaultsRecovered
 		feeToBurn = RepayDebtsOrAbort(rt, &st)
 
 		info := getMinerInfo(rt, &st)
-		rt.ValidateImmediateCallerIs(append(info.ControlAddresses, info.Owner, info.Worker)...)
+		rt.ValidateImmediateCallerIs(info.CallersWithRole(ControlRoleFaults)...)
 		if ConsensusFaultActive(info, rt.CurrEpoch()) {
 			rt.Abortf(exitcode.ErrForbidden, "recovery not allowed during active consensus fault")
 		}
@@ -1564,7 +2515,7 @@ func (a Actor) CompactPartitions(rt Runtime, params *CompactPartitionsParams) *a
 	var st State
 	rt.StateTransaction(&st, func() {
 		info := getMinerInfo(rt, &st)
-		rt.ValidateImmediateCallerIs(append(info.ControlAddresses, info.Owner, info.Worker)...)
+		rt.ValidateImmediateCallerIs(info.CallersWithRole(ControlRoleMaintenance)...)
 
 		if !deadlineAvailableForCompaction(st.ProvingPeriodStart, params.Deadline, rt.CurrEpoch()) {
 			rt.Abortf(exitcode.ErrForbidden,
@@ -1634,7 +2585,7 @@ func (a Actor) CompactSectorNumbers(rt Runtime, params *CompactSectorNumbersPara
 	var st State
 	rt.StateTransaction(&st, func() {
 		info := getMinerInfo(rt, &st)
-		rt.ValidateImmediateCallerIs(append(info.ControlAddresses, info.Owner, info.Worker)...)
+		rt.ValidateImmediateCallerIs(info.CallersWithRole(ControlRoleMaintenance)...)
 
 		err := st.MaskSectorNumbers(store, params.MaskSectorNumbers)
 
@@ -1796,12 +2747,13 @@ func (a Actor) WithdrawBalance(rt Runtime, params *WithdrawBalanceParams) *abi.E
 	newlyVested := big.Zero()
 	feeToBurn := big.Zero()
 	availableBalance := big.Zero()
+	amountWithdrawn := big.Zero()
 	rt.StateTransaction(&st, func() {
 		var err error
 		info = getMinerInfo(rt, &st)
-		// Only the owner is allowed to withdraw the balance as it belongs to/is controlled by the owner
-		// and not the worker.
-		rt.ValidateImmediateCallerIs(info.Owner)
+		// Only the owner and the beneficiary are allowed to withdraw the balance as it belongs to/is controlled
+		// by the owner and not the worker.
+		rt.ValidateImmediateCallerIs(info.Owner, info.Beneficiary)
 
 		// Ensure we don't have any pending terminations.
 		if count, err := st.EarlyTerminations.Count(); err != nil {
@@ -1827,14 +2779,24 @@ func (a Actor) WithdrawBalance(rt Runtime, params *WithdrawBalanceParams) *abi.E
 		// Verify unlocked funds cover both InitialPledgeRequirement and FeeDebt
 		// and repay fee debt now.
 		feeToBurn = RepayDebtsOrAbort(rt, &st)
+
+		amountWithdrawn = big.Min(availableBalance, params.AmountRequested)
+		// A beneficiary other than the owner may withdraw no more than its remaining quota.
+		if info.Beneficiary != info.Owner {
+			amountWithdrawn = big.Min(amountWithdrawn, info.BeneficiaryTerm.Available(rt.CurrEpoch()))
+			if amountWithdrawn.GreaterThan(big.Zero()) {
+				info.BeneficiaryTerm.UsedQuota = big.Add(info.BeneficiaryTerm.UsedQuota, amountWithdrawn)
+				err = st.SaveInfo(adt.AsStore(rt), info)
+				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to save miner info")
+			}
+		}
 	})
 
-	amountWithdrawn := big.Min(availableBalance, params.AmountRequested)
 	builtin.RequireState(rt, amountWithdrawn.GreaterThanEqual(big.Zero()), "negative amount to withdraw: %v", amountWithdrawn)
 	builtin.RequireState(rt, amountWithdrawn.LessThanEqual(availableBalance), "amount to withdraw %v < available %v", amountWithdrawn, availableBalance)
 
 	if amountWithdrawn.GreaterThan(abi.NewTokenAmount(0)) {
-		code := rt.Send(info.Owner, builtin.MethodSend, nil, amountWithdrawn, &builtin.Discard{})
+		code := rt.Send(info.Beneficiary, builtin.MethodSend, nil, amountWithdrawn, &builtin.Discard{})
 		builtin.RequireSuccess(rt, code, "failed to withdraw balance")
 	}
 
@@ -1855,7 +2817,7 @@ func (a Actor) RepayDebt(rt Runtime, _ *abi.EmptyValue) *abi.EmptyValue {
 	rt.StateTransaction(&st, func() {
 		var err error
 		info := getMinerInfo(rt, &st)
-		rt.ValidateImmediateCallerIs(append(info.ControlAddresses, info.Owner, info.Worker)...)
+		rt.ValidateImmediateCallerIs(info.CallersWithRole(ControlRoleMaintenance)...)
 
 		// Repay as much fee debt as possible.
 		fromVesting, fromBalance, err = st.RepayPartialDebtInPriorityOrder(adt.AsStore(rt), rt.CurrEpoch(), rt.CurrentBalance())
@@ -2474,6 +3436,14 @@ func currentDeadlineIndex(currEpoch abi.ChainEpoch, periodStart abi.ChainEpoch)
 	return uint64((currEpoch - periodStart) / WPoStChallengeWindow)
 }
 
+func asSectorNumbers(nos []uint64) []abi.SectorNumber {
+	sectorNos := make([]abi.SectorNumber, len(nos))
+	for i, n := range nos {
+		sectorNos[i] = abi.SectorNumber(n)
+	}
+	return sectorNos
+}
+
 func asMapBySectorNumber(sectors []*SectorOnChainInfo) map[abi.SectorNumber]*SectorOnChainInfo {
 	m := make(map[abi.SectorNumber]*SectorOnChainInfo, len(sectors))
 	for _, s := range sectors {
@@ -2505,6 +3475,7 @@ func processPendingWorker(info *MinerInfo, rt Runtime, st *State) {
 	}
 
 	info.Worker = info.PendingWorkerKey.NewWorker
+	info.recordWorkerKey(info.Worker, rt.CurrEpoch())
 	info.PendingWorkerKey = nil
 
 	err := st.SaveInfo(adt.AsStore(rt), info)
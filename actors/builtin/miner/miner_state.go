@@ -92,6 +92,7 @@ type MinerInfo struct {
 	Worker addr.Address // Must be an ID-address.
 
 	// Additional addresses that are permitted to submit messages controlling this actor (optional).
+	// Each is permitted to call only the methods covered by its roles in ControlAddressRoles.
 	ControlAddresses []addr.Address // Must all be ID addresses.
 
 	PendingWorkerKey *WorkerKeyChange
@@ -122,6 +123,53 @@ type MinerInfo struct {
 	// A proposed new owner account for this miner.
 	// Must be confirmed by a message from the pending address itself.
 	PendingOwnerAddress *addr.Address
+
+	// Account receiving funds withdrawn from this miner.
+	// Equal to the owner unless the owner has delegated withdrawals to another account,
+	// in which case withdrawals by the beneficiary are limited by the BeneficiaryTerm.
+	Beneficiary addr.Address // Must be an ID-address.
+
+	// Quota and expiration of the beneficiary's right to withdraw funds.
+	BeneficiaryTerm BeneficiaryTerm
+
+	// A proposed change of beneficiary.
+	// Must be approved by both the current beneficiary (unless it is the owner) and the nominee.
+	PendingBeneficiaryTerm *PendingBeneficiaryChange
+
+	// Worker keys and the epochs from which they took effect, oldest first, ending with the current worker.
+	// Holds at most WorkerKeyHistoryMax entries. Workers from before the log was introduced are not included.
+	WorkerKeyHistory []WorkerKeyRecord
+
+	// Roles granted to each control address, in the same order as ControlAddresses.
+	ControlAddressRoles []ControlRoles
+}
+
+// A set of roles, each permitting a control address to call some of the miner's methods.
+// The owner and worker may call all methods that control addresses may.
+type ControlRoles uint64
+
+const (
+	// Permits submitting Window PoSt proofs.
+	ControlRolePoSt ControlRoles = 1 << iota
+	// Permits pre-committing, proving, upgrading and extending sectors.
+	ControlRoleSealing
+	// Permits declaring faults and recoveries.
+	ControlRoleFaults
+	// Permits terminating sectors.
+	ControlRoleTermination
+	// Permits changing peer info, compacting partitions and sector numbers, and repaying debt.
+	ControlRoleMaintenance
+
+	ControlRolesAll = ControlRolePoSt | ControlRoleSealing | ControlRoleFaults | ControlRoleTermination | ControlRoleMaintenance
+)
+
+// Returns roles granting all permissions to each of n control addresses.
+func AllControlRoles(n int) []ControlRoles {
+	roles := make([]ControlRoles, n)
+	for i := range roles {
+		roles[i] = ControlRolesAll
+	}
+	return roles
 }
 
 type WorkerKeyChange struct {
@@ -129,6 +177,67 @@ type WorkerKeyChange struct {
 	EffectiveAt abi.ChainEpoch
 }
 
+type WorkerKeyRecord struct {
+	Worker      addr.Address // Must be an ID address
+	EffectiveAt abi.ChainEpoch
+}
+
+type BeneficiaryTerm struct {
+	// Total amount the beneficiary may withdraw.
+	Quota abi.TokenAmount
+	// Amount withdrawn by the beneficiary so far.
+	UsedQuota abi.TokenAmount
+	// Epoch from which the beneficiary may no longer withdraw.
+	Expiration abi.ChainEpoch
+}
+
+type PendingBeneficiaryChange struct {
+	NewBeneficiary        addr.Address // Must be an ID address
+	NewQuota              abi.TokenAmount
+	NewExpiration         abi.ChainEpoch
+	ApprovedByBeneficiary bool
+	ApprovedByNominee     bool
+}
+
+func NewBeneficiaryTerm(quota abi.TokenAmount, expiration abi.ChainEpoch) BeneficiaryTerm {
+	return BeneficiaryTerm{
+		Quota:      quota,
+		UsedQuota:  big.Zero(),
+		Expiration: expiration,
+	}
+}
+
+// Returns the amount the beneficiary may still withdraw at an epoch.
+func (t *BeneficiaryTerm) Available(currEpoch abi.ChainEpoch) abi.TokenAmount {
+	if t.Expiration <= currEpoch {
+		return big.Zero()
+	}
+	return big.Max(big.Sub(t.Quota, t.UsedQuota), big.Zero())
+}
+
+// Returns the addresses permitted to call methods requiring a role: the control addresses granted that role,
+// followed by the owner and worker.
+func (mi *MinerInfo) CallersWithRole(role ControlRoles) []addr.Address {
+	callers := make([]addr.Address, 0, len(mi.ControlAddresses)+2)
+	for i, a := range mi.ControlAddresses {
+		if i < len(mi.ControlAddressRoles) && mi.ControlAddressRoles[i]&role != 0 {
+			callers = append(callers, a)
+		}
+	}
+	return append(callers, mi.Owner, mi.Worker)
+}
+
+// Appends a worker to the worker key history, dropping the oldest entries beyond WorkerKeyHistoryMax.
+func (mi *MinerInfo) recordWorkerKey(worker addr.Address, effectiveAt abi.ChainEpoch) {
+	mi.WorkerKeyHistory = append(mi.WorkerKeyHistory, WorkerKeyRecord{
+		Worker:      worker,
+		EffectiveAt: effectiveAt,
+	})
+	if excess := len(mi.WorkerKeyHistory) - WorkerKeyHistoryMax; excess > 0 {
+		mi.WorkerKeyHistory = append([]WorkerKeyRecord{}, mi.WorkerKeyHistory[excess:]...)
+	}
+}
+
 // Information provided by a miner when pre-committing a sector.
 type SectorPreCommitInfo struct {
 	SealProof       abi.RegisteredSealProof
@@ -253,6 +362,10 @@ func ConstructMinerInfo(owner, worker addr.Address, controlAddrs []addr.Address,
 		WindowPoStPartitionSectors: partitionSectors,
 		ConsensusFaultElapsed:      abi.ChainEpoch(-1),
 		PendingOwnerAddress:        nil,
+		Beneficiary:                owner,
+		BeneficiaryTerm:            NewBeneficiaryTerm(big.Zero(), 0),
+		PendingBeneficiaryTerm:     nil,
+		ControlAddressRoles:        AllControlRoles(len(controlAddrs)),
 	}, nil
 }
 
@@ -309,6 +422,41 @@ func (st *State) AllocateSectorNumber(store adt.Store, sectorNo abi.SectorNumber
 	return nil
 }
 
+// Marks a set of sector numbers as having been allocated, failing if any of them have already been allocated.
+func (st *State) AllocateSectorNumbers(store adt.Store, sectorNos bitfield.BitField) error {
+	// This will likely already have been checked, but this is a good place
+	// to catch any mistakes.
+	if lastSectorNo, err := sectorNos.Last(); err != nil {
+		return xc.ErrIllegalArgument.Wrapf("invalid sector numbers bitfield: %w", err)
+	} else if lastSectorNo > abi.MaxSectorNumber {
+		return xc.ErrIllegalArgument.Wrapf("sector number out of range: %d", lastSectorNo)
+	}
+
+	var allocatedSectors bitfield.BitField
+	if err := store.Get(store.Context(), st.AllocatedSectors, &allocatedSectors); err != nil {
+		return xc.ErrIllegalState.Wrapf("failed to load allocated sectors bitfield: %w", err)
+	}
+	if collisions, err := bitfield.IntersectBitField(allocatedSectors, sectorNos); err != nil {
+		return xc.ErrIllegalState.Wrapf("failed to intersect sector numbers with allocated sectors bitfield: %w", err)
+	} else if empty, err := collisions.IsEmpty(); err != nil {
+		return xc.ErrIllegalState.Wrapf("failed to check allocated sector number collisions: %w", err)
+	} else if !empty {
+		return xc.ErrIllegalArgument.Wrapf("sector numbers %v have already been allocated", collisions)
+	}
+
+	allocatedSectors, err := bitfield.MergeBitFields(allocatedSectors, sectorNos)
+	if err != nil {
+		return xc.ErrIllegalState.Wrapf("failed to merge allocated sectors bitfield: %w", err)
+	}
+
+	if root, err := store.Put(store.Context(), allocatedSectors); err != nil {
+		return xc.ErrIllegalArgument.Wrapf("failed to store allocated sectors bitfield: %w", err)
+	} else {
+		st.AllocatedSectors = root
+	}
+	return nil
+}
+
 func (st *State) MaskSectorNumbers(store adt.Store, sectorNos bitfield.BitField) error {
 	lastSectorNo, err := sectorNos.Last()
 	if err != nil {
@@ -339,15 +487,22 @@ func (st *State) MaskSectorNumbers(store adt.Store, sectorNos bitfield.BitField)
 
 // Stores a pre-committed sector info, failing if the sector number is already present.
 func (st *State) PutPrecommittedSector(store adt.Store, info *SectorPreCommitOnChainInfo) error {
+	return st.PutPrecommittedSectors(store, info)
+}
+
+// Stores pre-committed sector infos, failing if any sector number is already present.
+func (st *State) PutPrecommittedSectors(store adt.Store, infos ...*SectorPreCommitOnChainInfo) error {
 	precommitted, err := adt.AsMap(store, st.PreCommittedSectors, builtin.DefaultHamtBitwidth)
 	if err != nil {
 		return err
 	}
 
-	if modified, err := precommitted.PutIfAbsent(SectorKey(info.Info.SectorNumber), info); err != nil {
-		return errors.Wrapf(err, "failed to store pre-commitment for %v", info)
-	} else if !modified {
-		return xerrors.Errorf("sector %v already pre-committed", info.Info.SectorNumber)
+	for _, info := range infos {
+		if modified, err := precommitted.PutIfAbsent(SectorKey(info.Info.SectorNumber), info); err != nil {
+			return errors.Wrapf(err, "failed to store pre-commitment for %v", info)
+		} else if !modified {
+			return xerrors.Errorf("sector %v already pre-committed", info.Info.SectorNumber)
+		}
 	}
 	st.PreCommittedSectors, err = precommitted.Root()
 	return err
@@ -988,6 +1143,11 @@ func (st *State) QuantSpecEveryDeadline() QuantSpec {
 }
 
 func (st *State) AddPreCommitExpiry(store adt.Store, expireEpoch abi.ChainEpoch, sectorNum abi.SectorNumber) error {
+	return st.AddPreCommitExpirations(store, map[abi.ChainEpoch][]uint64{expireEpoch: {uint64(sectorNum)}})
+}
+
+// Adds sector numbers to the pre-commit expiry queue, keyed by the epoch at which they expire.
+func (st *State) AddPreCommitExpirations(store adt.Store, expirations map[abi.ChainEpoch][]uint64) error {
 	// Load BitField Queue for sector expiry
 	quant := st.QuantSpecEveryDeadline()
 	queue, err := LoadBitfieldQueue(store, st.PreCommittedSectorsExpiry, quant, PrecommitExpiryAmtBitwidth)
@@ -995,9 +1155,9 @@ func (st *State) AddPreCommitExpiry(store adt.Store, expireEpoch abi.ChainEpoch,
 		return xerrors.Errorf("failed to load pre-commit expiry queue: %w", err)
 	}
 
-	// add entry for this sector to the queue
-	if err := queue.AddToQueueValues(expireEpoch, uint64(sectorNum)); err != nil {
-		return xerrors.Errorf("failed to add pre-commit sector expiry to queue: %w", err)
+	// add entries for these sectors to the queue
+	if err := queue.AddManyToQueueValues(expirations); err != nil {
+		return xerrors.Errorf("failed to add pre-commit sector expiries to queue: %w", err)
 	}
 
 	st.PreCommittedSectorsExpiry, err = queue.Root()
@@ -1211,6 +1371,42 @@ func (st *State) AdvanceDeadline(store adt.Store, currEpoch abi.ChainEpoch) (*Ad
 	}, nil
 }
 
+// PreviewPoSt computes the result of a Window PoSt for partitions of the current deadline, without saving
+// any changes to state. It returns the result as it would be recorded by SubmitWindowedPoSt, and the
+// deadline's faulty power after the proof is recorded. Absent further declarations, this faulty power
+// is charged the continued fault fee when the deadline closes.
+func (st *State) PreviewPoSt(store adt.Store, currEpoch abi.ChainEpoch, sectorSize abi.SectorSize, dlIdx uint64,
+	postPartitions []PoStPartition) (*PoStResult, PowerPair, error) {
+	dlInfo := st.DeadlineInfo(currEpoch)
+	if !dlInfo.IsOpen() {
+		return nil, PowerPair{}, xc.ErrIllegalState.Wrapf("proving period %d not yet open at %d", dlInfo.PeriodStart, currEpoch)
+	}
+	if dlIdx != dlInfo.Index {
+		return nil, PowerPair{}, xc.ErrIllegalArgument.Wrapf("invalid deadline %d at epoch %d, expected %d", dlIdx, currEpoch, dlInfo.Index)
+	}
+
+	sectors, err := LoadSectors(store, st.Sectors)
+	if err != nil {
+		return nil, PowerPair{}, xerrors.Errorf("failed to load sectors: %w", err)
+	}
+	deadlines, err := st.LoadDeadlines(store)
+	if err != nil {
+		return nil, PowerPair{}, xerrors.Errorf("failed to load deadlines: %w", err)
+	}
+	deadline, err := deadlines.LoadDeadline(store, dlIdx)
+	if err != nil {
+		return nil, PowerPair{}, xerrors.Errorf("failed to load deadline %d: %w", dlIdx, err)
+	}
+
+	// The updated deadline is discarded, so the partitions written here are never referenced by state.
+	faultExpiration := dlInfo.Last() + FaultMaxAge
+	result, err := deadline.RecordProvenSectors(store, sectors, sectorSize, QuantSpecForDeadline(dlInfo), faultExpiration, postPartitions)
+	if err != nil {
+		return nil, PowerPair{}, xerrors.Errorf("failed to process post for deadline %d: %w", dlIdx, err)
+	}
+	return result, deadline.FaultyPower, nil
+}
+
 //
 // Misc helpers
 //
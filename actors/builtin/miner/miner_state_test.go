@@ -718,6 +718,16 @@ func TestSectorNumberAllocation(t *testing.T) {
 		assert.Error(t, harness.s.AllocateSectorNumber(harness.store, sectorNo))
 	})
 
+	t.Run("can allocate many sector numbers at once", func(t *testing.T) {
+		harness := constructStateHarness(t, abi.ChainEpoch(0))
+
+		assert.NoError(t, harness.s.AllocateSectorNumbers(harness.store, bf(1, 2, 5)))
+		assert.Error(t, harness.s.AllocateSectorNumbers(harness.store, bf(3, 5)))
+		assert.Error(t, harness.s.AllocateSectorNumber(harness.store, 2))
+		assert.NoError(t, harness.s.AllocateSectorNumbers(harness.store, bf(3, 4)))
+		assert.Error(t, harness.s.AllocateSectorNumbers(harness.store, bf(99, abi.MaxSectorNumber+1)))
+	})
+
 	t.Run("can mask sector numbers", func(t *testing.T) {
 		harness := constructStateHarness(t, abi.ChainEpoch(0))
 		sectorNo := abi.SectorNumber(1)
@@ -913,6 +923,8 @@ func constructStateHarness(t *testing.T, periodBoundary abi.ChainEpoch) *stateHa
 		WindowPoStProofType:        testWindowPoStProofType,
 		SectorSize:                 sectorSize,
 		WindowPoStPartitionSectors: partitionSectors,
+		Beneficiary:                owner,
+		BeneficiaryTerm:            miner.NewBeneficiaryTerm(big.Zero(), 0),
 	}
 	infoCid, err := store.Put(context.Background(), &info)
 	require.NoError(t, err)
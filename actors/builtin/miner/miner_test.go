@@ -1003,6 +1003,286 @@ func TestCommitments(t *testing.T) {
 }
 
 // Test sector lifecycle when a sector is upgraded
+func TestPreCommitBatch(t *testing.T) {
+	periodOffset := abi.ChainEpoch(100)
+
+	setup := func(t *testing.T, balance abi.TokenAmount) (*actorHarness, *mock.Runtime, abi.ChainEpoch, abi.ChainEpoch) {
+		actor := newHarness(t, periodOffset)
+		rt := builderForHarness(actor).
+			WithBalance(balance, big.Zero()).
+			Build(t)
+		precommitEpoch := periodOffset + 1
+		rt.SetEpoch(precommitEpoch)
+		actor.constructAndVerify(rt)
+		dlInfo := actor.deadline(rt)
+		expiration := dlInfo.PeriodEnd() + defaultSectorExpiration*miner.WPoStProvingPeriod
+		return actor, rt, precommitEpoch - 1, expiration
+	}
+
+	t.Run("pre-commits many sectors with one deposit snapshot", func(t *testing.T) {
+		actor, rt, challengeEpoch, expiration := setup(t, bigBalance)
+
+		sectors := []miner.PreCommitSectorParams{
+			*actor.makePreCommit(100, challengeEpoch, expiration, nil),
+			*actor.makePreCommit(101, challengeEpoch, expiration, []abi.DealID{1}),
+			*actor.makePreCommit(102, challengeEpoch, expiration, []abi.DealID{2, 3}),
+		}
+		weights := []market.SectorWeights{
+			{DealSpace: 0, DealWeight: big.Zero(), VerifiedDealWeight: big.Zero()},
+			{DealSpace: uint64(actor.sectorSize / 2), DealWeight: big.NewInt(1 << 40), VerifiedDealWeight: big.Zero()},
+			{DealSpace: uint64(actor.sectorSize), DealWeight: big.Zero(), VerifiedDealWeight: big.NewInt(1 << 41)},
+		}
+		precommits := actor.preCommitSectorBatch(rt, &miner.PreCommitSectorBatchParams{Sectors: sectors}, preCommitBatchConf{sectorWeights: weights})
+
+		require.Len(t, precommits, 3)
+		totalDeposit := big.Zero()
+		for i, precommit := range precommits {
+			assert.Equal(t, sectors[i].SectorNumber, precommit.Info.SectorNumber)
+			assert.Equal(t, rt.Epoch(), precommit.PreCommitEpoch)
+			assert.Equal(t, weights[i].DealWeight, precommit.DealWeight)
+			assert.Equal(t, weights[i].VerifiedDealWeight, precommit.VerifiedDealWeight)
+
+			qaPower := miner.QAPowerForWeight(actor.sectorSize, expiration-rt.Epoch(), weights[i].DealWeight, weights[i].VerifiedDealWeight)
+			expectedDeposit := miner.PreCommitDepositForPower(actor.epochRewardSmooth, actor.epochQAPowerSmooth, qaPower)
+			assert.Equal(t, expectedDeposit, precommit.PreCommitDeposit)
+			totalDeposit = big.Add(totalDeposit, expectedDeposit)
+		}
+
+		st := getState(rt)
+		assert.Equal(t, totalDeposit, st.PreCommitDeposits)
+
+		// All sectors share a single expiry queue entry.
+		expirations := actor.collectPrecommitExpirations(rt, st)
+		expectedExpiry := st.QuantSpecEveryDeadline().QuantizeUp(rt.Epoch() + miner.MaxProveCommitDuration[actor.sealProofType] + 1)
+		assert.Equal(t, map[abi.ChainEpoch][]uint64{expectedExpiry: {100, 101, 102}}, expirations)
+		actor.checkState(rt)
+	})
+
+	t.Run("fails with empty batch", func(t *testing.T) {
+		actor, rt, _, _ := setup(t, bigBalance)
+		rt.ExpectAbortContainsMessage(exitcode.ErrIllegalArgument, "batch empty", func() {
+			actor.preCommitSectorBatch(rt, &miner.PreCommitSectorBatchParams{}, preCommitBatchConf{})
+		})
+		actor.checkState(rt)
+	})
+
+	t.Run("fails with too many sectors", func(t *testing.T) {
+		actor, rt, challengeEpoch, expiration := setup(t, bigBalance)
+		sectors := make([]miner.PreCommitSectorParams, miner.PreCommitSectorBatchMaxSize+1)
+		for i := range sectors {
+			sectors[i] = *actor.makePreCommit(abi.SectorNumber(100+i), challengeEpoch, expiration, nil)
+		}
+		rt.ExpectAbortContainsMessage(exitcode.ErrIllegalArgument, "too large", func() {
+			actor.preCommitSectorBatch(rt, &miner.PreCommitSectorBatchParams{Sectors: sectors}, preCommitBatchConf{})
+		})
+		actor.checkState(rt)
+	})
+
+	t.Run("fails with duplicate sector numbers", func(t *testing.T) {
+		actor, rt, challengeEpoch, expiration := setup(t, bigBalance)
+		sectors := []miner.PreCommitSectorParams{
+			*actor.makePreCommit(100, challengeEpoch, expiration, nil),
+			*actor.makePreCommit(101, challengeEpoch, expiration, nil),
+			*actor.makePreCommit(100, challengeEpoch, expiration, nil),
+		}
+		rt.ExpectAbortContainsMessage(exitcode.ErrIllegalArgument, "duplicate sector number 100", func() {
+			actor.preCommitSectorBatch(rt, &miner.PreCommitSectorBatchParams{Sectors: sectors}, preCommitBatchConf{})
+		})
+		actor.checkState(rt)
+	})
+
+	t.Run("one invalid sector aborts the whole batch", func(t *testing.T) {
+		actor, rt, challengeEpoch, expiration := setup(t, bigBalance)
+		sectors := []miner.PreCommitSectorParams{
+			*actor.makePreCommit(100, challengeEpoch, expiration, nil),
+			*actor.makePreCommit(101, rt.Epoch(), expiration, nil),
+		}
+		rt.ExpectAbortContainsMessage(exitcode.ErrIllegalArgument, "must be before now", func() {
+			actor.preCommitSectorBatch(rt, &miner.PreCommitSectorBatchParams{Sectors: sectors}, preCommitBatchConf{})
+		})
+		st := getState(rt)
+		assert.Equal(t, big.Zero(), st.PreCommitDeposits)
+		actor.checkState(rt)
+	})
+
+	t.Run("fails if sector number already allocated", func(t *testing.T) {
+		actor, rt, challengeEpoch, expiration := setup(t, bigBalance)
+		actor.preCommitSector(rt, actor.makePreCommit(101, challengeEpoch, expiration, nil), preCommitConf{})
+
+		sectors := []miner.PreCommitSectorParams{
+			*actor.makePreCommit(100, challengeEpoch, expiration, nil),
+			*actor.makePreCommit(101, challengeEpoch, expiration, nil),
+		}
+		rt.ExpectAbortContainsMessage(exitcode.ErrIllegalArgument, "already been allocated", func() {
+			actor.preCommitSectorBatch(rt, &miner.PreCommitSectorBatchParams{Sectors: sectors}, preCommitBatchConf{})
+		})
+		actor.checkState(rt)
+	})
+
+	t.Run("insufficient funds for batch deposit", func(t *testing.T) {
+		actor, rt, challengeEpoch, expiration := setup(t, big.Zero())
+		sectors := []miner.PreCommitSectorParams{
+			*actor.makePreCommit(100, challengeEpoch, expiration, nil),
+			*actor.makePreCommit(101, challengeEpoch, expiration, nil),
+		}
+		// Enough balance for one sector's deposit but not both.
+		qaPower := miner.QAPowerForWeight(actor.sectorSize, expiration-rt.Epoch(), big.Zero(), big.Zero())
+		deposit := miner.PreCommitDepositForPower(actor.epochRewardSmooth, actor.epochQAPowerSmooth, qaPower)
+		rt.SetBalance(big.Add(deposit, big.NewInt(1)))
+
+		rt.ExpectAbortContainsMessage(exitcode.ErrInsufficientFunds, "insufficient funds", func() {
+			actor.preCommitSectorBatch(rt, &miner.PreCommitSectorBatchParams{Sectors: sectors}, preCommitBatchConf{})
+		})
+		actor.checkState(rt)
+	})
+}
+
+func TestProveCommitAggregate(t *testing.T) {
+	periodOffset := abi.ChainEpoch(100)
+
+	setup := func(t *testing.T) (*actorHarness, *mock.Runtime) {
+		actor := newHarness(t, periodOffset)
+		rt := builderForHarness(actor).
+			WithBalance(bigBalance, big.Zero()).
+			Build(t)
+		rt.SetEpoch(periodOffset + 1)
+		actor.constructAndVerify(rt)
+		return actor, rt
+	}
+
+	preCommitSectors := func(actor *actorHarness, rt *mock.Runtime, sectorNos ...abi.SectorNumber) []*miner.SectorPreCommitOnChainInfo {
+		precommitEpoch := rt.Epoch()
+		dlInfo := actor.deadline(rt)
+		expiration := dlInfo.PeriodEnd() + defaultSectorExpiration*miner.WPoStProvingPeriod
+		precommits := make([]*miner.SectorPreCommitOnChainInfo, len(sectorNos))
+		for i, sectorNo := range sectorNos {
+			params := actor.makePreCommit(sectorNo, precommitEpoch-1, expiration, nil)
+			precommits[i] = actor.preCommitSector(rt, params, preCommitConf{})
+		}
+		return precommits
+	}
+
+	t.Run("valid aggregate activates all sectors", func(t *testing.T) {
+		actor, rt := setup(t)
+		precommits := preCommitSectors(actor, rt, 100, 101, 102, 103)
+		rt.SetEpoch(rt.Epoch() + miner.PreCommitChallengeDelay + 1)
+
+		params := &miner.ProveCommitAggregateParams{
+			SectorNumbers:  bitfield.NewFromSet([]uint64{100, 101, 102, 103}),
+			AggregateProof: []byte{1, 2, 3},
+		}
+		actor.proveCommitAggregateSector(rt, proveCommitConf{}, precommits, params)
+
+		st := getState(rt)
+		assert.Equal(t, big.Zero(), st.PreCommitDeposits)
+		expectedPledge := big.Zero()
+		for _, precommit := range precommits {
+			sector := actor.getSector(rt, precommit.Info.SectorNumber)
+			assert.Equal(t, rt.Epoch(), sector.Activation)
+			assert.Equal(t, precommit.Info.SealedCID, sector.SealedCID)
+			expectedPledge = big.Add(expectedPledge, sector.InitialPledge)
+
+			_, found, err := st.GetPrecommittedSector(rt.AdtStore(), precommit.Info.SectorNumber)
+			require.NoError(t, err)
+			assert.False(t, found)
+		}
+		assert.Equal(t, expectedPledge, st.InitialPledge)
+		actor.checkState(rt)
+	})
+
+	t.Run("fails with too few sectors", func(t *testing.T) {
+		actor, rt := setup(t)
+		params := &miner.ProveCommitAggregateParams{SectorNumbers: bitfield.NewFromSet([]uint64{100, 101, 102})}
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		rt.ExpectAbortContainsMessage(exitcode.ErrIllegalArgument, "too few sectors", func() {
+			rt.Call(actor.a.ProveCommitAggregate, params)
+		})
+		rt.Reset()
+		actor.checkState(rt)
+	})
+
+	t.Run("fails with too many sectors", func(t *testing.T) {
+		actor, rt := setup(t)
+		sectorNos := make([]uint64, miner.MaxAggregatedSectors+1)
+		for i := range sectorNos {
+			sectorNos[i] = uint64(i)
+		}
+		params := &miner.ProveCommitAggregateParams{SectorNumbers: bitfield.NewFromSet(sectorNos)}
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		rt.ExpectAbortContainsMessage(exitcode.ErrIllegalArgument, "too many sectors", func() {
+			rt.Call(actor.a.ProveCommitAggregate, params)
+		})
+		rt.Reset()
+		actor.checkState(rt)
+	})
+
+	t.Run("fails with oversize proof", func(t *testing.T) {
+		actor, rt := setup(t)
+		params := &miner.ProveCommitAggregateParams{
+			SectorNumbers:  bitfield.NewFromSet([]uint64{100, 101, 102, 103}),
+			AggregateProof: make([]byte, miner.MaxAggregateProofSize+1),
+		}
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		rt.ExpectAbortContainsMessage(exitcode.ErrIllegalArgument, "exceeds max size", func() {
+			rt.Call(actor.a.ProveCommitAggregate, params)
+		})
+		rt.Reset()
+		actor.checkState(rt)
+	})
+
+	t.Run("fails if a sector is not pre-committed", func(t *testing.T) {
+		actor, rt := setup(t)
+		preCommitSectors(actor, rt, 100, 101, 102, 103)
+		rt.SetEpoch(rt.Epoch() + miner.PreCommitChallengeDelay + 1)
+
+		params := &miner.ProveCommitAggregateParams{SectorNumbers: bitfield.NewFromSet([]uint64{100, 101, 102, 104})}
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(append(actor.controlAddrs, actor.owner, actor.worker)...)
+		rt.ExpectAbortContainsMessage(exitcode.ErrNotFound, "found 3 of 4 pre-committed sectors", func() {
+			rt.Call(actor.a.ProveCommitAggregate, params)
+		})
+		rt.Reset()
+		actor.checkState(rt)
+	})
+
+	t.Run("fails if proven too early", func(t *testing.T) {
+		actor, rt := setup(t)
+		preCommitSectors(actor, rt, 100, 101, 102, 103)
+
+		params := &miner.ProveCommitAggregateParams{SectorNumbers: bitfield.NewFromSet([]uint64{100, 101, 102, 103})}
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(append(actor.controlAddrs, actor.owner, actor.worker)...)
+		rt.ExpectAbortContainsMessage(exitcode.ErrForbidden, "too early", func() {
+			rt.Call(actor.a.ProveCommitAggregate, params)
+		})
+		rt.Reset()
+		actor.checkState(rt)
+	})
+
+	t.Run("fails when aggregate proof does not verify", func(t *testing.T) {
+		actor, rt := setup(t)
+		precommits := preCommitSectors(actor, rt, 100, 101, 102, 103)
+		rt.SetEpoch(rt.Epoch() + miner.PreCommitChallengeDelay + 1)
+
+		params := &miner.ProveCommitAggregateParams{
+			SectorNumbers:  bitfield.NewFromSet([]uint64{100, 101, 102, 103}),
+			AggregateProof: []byte{1, 2, 3},
+		}
+		actor.expectProveCommitAggregate(rt, precommits, params, fmt.Errorf("invalid aggregate"))
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		rt.ExpectAbortContainsMessage(exitcode.ErrIllegalArgument, "aggregate seal verify failed", func() {
+			rt.Call(actor.a.ProveCommitAggregate, params)
+		})
+		rt.Verify()
+
+		// Pre-commits remain in place to be proven again.
+		for _, precommit := range precommits {
+			actor.getPreCommit(rt, precommit.Info.SectorNumber)
+		}
+		actor.checkState(rt)
+	})
+}
+
 func TestCCUpgrade(t *testing.T) {
 	periodOffset := abi.ChainEpoch(100)
 	t.Run("valid committed capacity upgrade", func(t *testing.T) {
@@ -2943,6 +3223,176 @@ func TestDeclareRecoveries(t *testing.T) {
 	})
 }
 
+func TestProveReplicaUpdates(t *testing.T) {
+	periodOffset := abi.ChainEpoch(100)
+	actor := newHarness(t, periodOffset)
+	builder := builderForHarness(actor).
+		WithEpoch(1).
+		WithBalance(bigBalance, big.Zero())
+
+	dealIDs := []abi.DealID{10, 11}
+	weights := market.SectorWeights{
+		DealSpace:          uint64(actor.sectorSize / 2),
+		DealWeight:         big.Mul(big.NewInt(int64(actor.sectorSize/4)), big.NewInt(int64(miner.WPoStProvingPeriod))),
+		VerifiedDealWeight: big.Mul(big.NewInt(int64(actor.sectorSize/4)), big.NewInt(int64(miner.WPoStProvingPeriod))),
+	}
+
+	// Commits and proves a committed-capacity sector, then advances until its deadline is mutable.
+	// Returns the sector and an update for it.
+	setup := func(t *testing.T, rt *mock.Runtime) (*miner.SectorOnChainInfo, miner.ReplicaUpdate) {
+		actor.constructAndVerify(rt)
+		sector := actor.commitAndProveSectors(rt, 1, defaultSectorExpiration, nil)[0]
+		advanceAndSubmitPoSts(rt, actor, sector)
+
+		st := getState(rt)
+		dlIdx, pIdx, err := st.FindSector(rt.AdtStore(), sector.SectorNumber)
+		require.NoError(t, err)
+		advanceToMutableDeadline(rt, actor, dlIdx)
+
+		return sector, miner.ReplicaUpdate{
+			SectorNumber:    sector.SectorNumber,
+			Deadline:        dlIdx,
+			Partition:       pIdx,
+			NewSealedCID:    tutil.MakeCID("updated", &miner.SealedCIDPrefix),
+			NewUnsealedCID:  tutil.MakeCID("deals", &market.PieceCIDPrefix),
+			Deals:           dealIDs,
+			UpdateProofType: proof.RegisteredUpdateProof_StackedDrg32GiBV1,
+			ReplicaProof:    []byte{1, 2, 3},
+		}
+	}
+
+	t.Run("updates sector with deals", func(t *testing.T) {
+		rt := builder.Build(t)
+		oldSector, update := setup(t, rt)
+
+		actor.proveReplicaUpdate(rt, update, weights, replicaUpdateConf{})
+
+		newSector := actor.getSector(rt, oldSector.SectorNumber)
+		assert.Equal(t, update.NewSealedCID, newSector.SealedCID)
+		assert.Equal(t, dealIDs, newSector.DealIDs)
+		assert.Equal(t, rt.Epoch(), newSector.Activation)
+		assert.Equal(t, oldSector.Expiration, newSector.Expiration)
+		assert.Equal(t, weights.DealWeight, newSector.DealWeight)
+		assert.Equal(t, weights.VerifiedDealWeight, newSector.VerifiedDealWeight)
+		assert.True(t, newSector.InitialPledge.GreaterThanEqual(oldSector.InitialPledge))
+		assert.Equal(t, oldSector.ExpectedDayReward, newSector.ReplacedDayReward)
+
+		// The partition's power reflects the new sector's power.
+		_, partition := actor.getDeadlineAndPartition(rt, update.Deadline, update.Partition)
+		assert.Equal(t, miner.PowerForSector(actor.sectorSize, newSector), partition.LivePower)
+
+		st := getState(rt)
+		assert.Equal(t, newSector.InitialPledge, st.InitialPledge)
+		actor.checkState(rt)
+	})
+
+	t.Run("rejects sector with deals", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+		sector := actor.commitAndProveSectors(rt, 1, defaultSectorExpiration, [][]abi.DealID{{1}})[0]
+		advanceAndSubmitPoSts(rt, actor, sector)
+		st := getState(rt)
+		dlIdx, pIdx, err := st.FindSector(rt.AdtStore(), sector.SectorNumber)
+		require.NoError(t, err)
+		advanceToMutableDeadline(rt, actor, dlIdx)
+
+		update := miner.ReplicaUpdate{
+			SectorNumber:    sector.SectorNumber,
+			Deadline:        dlIdx,
+			Partition:       pIdx,
+			NewSealedCID:    tutil.MakeCID("updated", &miner.SealedCIDPrefix),
+			NewUnsealedCID:  tutil.MakeCID("deals", &market.PieceCIDPrefix),
+			Deals:           dealIDs,
+			UpdateProofType: proof.RegisteredUpdateProof_StackedDrg32GiBV1,
+		}
+		rt.ExpectAbortContainsMessage(exitcode.ErrForbidden, "already has deals", func() {
+			actor.proveReplicaUpdate(rt, update, weights, replicaUpdateConf{})
+		})
+		rt.Reset()
+		actor.checkState(rt)
+	})
+
+	t.Run("rejects unproven sector", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+		sector := actor.commitAndProveSectors(rt, 1, defaultSectorExpiration, nil)[0]
+		st := getState(rt)
+		dlIdx, pIdx, err := st.FindSector(rt.AdtStore(), sector.SectorNumber)
+		require.NoError(t, err)
+		advanceToMutableDeadline(rt, actor, dlIdx)
+
+		update := miner.ReplicaUpdate{
+			SectorNumber:    sector.SectorNumber,
+			Deadline:        dlIdx,
+			Partition:       pIdx,
+			NewSealedCID:    tutil.MakeCID("updated", &miner.SealedCIDPrefix),
+			NewUnsealedCID:  tutil.MakeCID("deals", &market.PieceCIDPrefix),
+			Deals:           dealIDs,
+			UpdateProofType: proof.RegisteredUpdateProof_StackedDrg32GiBV1,
+		}
+		rt.ExpectAbortContainsMessage(exitcode.ErrForbidden, "is not active", func() {
+			actor.proveReplicaUpdate(rt, update, weights, replicaUpdateConf{})
+		})
+		rt.Reset()
+	})
+
+	t.Run("rejects invalid updates", func(t *testing.T) {
+		rt := builder.Build(t)
+		_, update := setup(t, rt)
+
+		noDeals := update
+		noDeals.Deals = nil
+		rt.ExpectAbortContainsMessage(exitcode.ErrIllegalArgument, "no deals", func() {
+			actor.proveReplicaUpdate(rt, noDeals, weights, replicaUpdateConf{})
+		})
+		rt.Reset()
+
+		badSealedCID := update
+		badSealedCID.NewSealedCID = tutil.MakeCID("updated", &market.PieceCIDPrefix)
+		rt.ExpectAbortContainsMessage(exitcode.ErrIllegalArgument, "wrong prefix", func() {
+			actor.proveReplicaUpdate(rt, badSealedCID, weights, replicaUpdateConf{})
+		})
+		rt.Reset()
+
+		badProofType := update
+		badProofType.UpdateProofType = proof.RegisteredUpdateProof_StackedDrg64GiBV1
+		rt.ExpectAbortContainsMessage(exitcode.ErrIllegalArgument, "does not match seal proof type", func() {
+			actor.proveReplicaUpdate(rt, badProofType, weights, replicaUpdateConf{})
+		})
+		rt.Reset()
+
+		rt.ExpectAbortContainsMessage(exitcode.ErrIllegalArgument, "does not match deals' data commitment", func() {
+			actor.proveReplicaUpdate(rt, update, weights, replicaUpdateConf{
+				unsealedCID: tutil.MakeCID("other deals", &market.PieceCIDPrefix),
+			})
+		})
+		rt.Reset()
+
+		rt.ExpectAbortContainsMessage(exitcode.ErrIllegalArgument, "failed to verify replica update", func() {
+			actor.proveReplicaUpdate(rt, update, weights, replicaUpdateConf{
+				verifyErr: fmt.Errorf("invalid proof"),
+			})
+		})
+		rt.Reset()
+		actor.checkState(rt)
+	})
+
+	t.Run("rejects update in immutable deadline", func(t *testing.T) {
+		rt := builder.Build(t)
+		_, update := setup(t, rt)
+
+		// Advance to the epoch at which the sector's deadline is next.
+		st := getState(rt)
+		dlInfo := miner.NewDeadlineInfo(st.ProvingPeriodStart, update.Deadline, rt.Epoch()).NextNotElapsed()
+		rt.SetEpoch(dlInfo.Open - miner.WPoStChallengeWindow)
+
+		rt.ExpectAbortContainsMessage(exitcode.ErrIllegalArgument, "immutable deadline", func() {
+			actor.proveReplicaUpdate(rt, update, weights, replicaUpdateConf{})
+		})
+		rt.Reset()
+	})
+}
+
 func TestExtendSectorExpiration(t *testing.T) {
 	periodOffset := abi.ChainEpoch(100)
 	actor := newHarness(t, periodOffset)
@@ -3286,43 +3736,570 @@ func TestExtendSectorExpiration(t *testing.T) {
 	})
 }
 
-func TestTerminateSectors(t *testing.T) {
+func TestExtendSectorExpiration2(t *testing.T) {
 	periodOffset := abi.ChainEpoch(100)
 	actor := newHarness(t, periodOffset)
+	precommitEpoch := abi.ChainEpoch(1)
 	builder := builderForHarness(actor).
-		WithBalance(big.Mul(big.NewInt(1e18), big.NewInt(200000)), big.Zero())
+		WithEpoch(precommitEpoch).
+		WithBalance(bigBalance, big.Zero())
 
-	t.Run("removes sector with correct accounting", func(t *testing.T) {
-		rt := builder.Build(t)
+	// Commits and proves a sector with deals filling the given space.
+	commitSectorWithDeals := func(t *testing.T, rt *mock.Runtime, dealSpace, verifiedSpace abi.SectorSize) *miner.SectorOnChainInfo {
 		actor.constructAndVerify(rt)
-		rt.SetEpoch(abi.ChainEpoch(1))
-		sectorInfo := actor.commitAndProveSectors(rt, 1, defaultSectorExpiration, nil)
-		sector := sectorInfo[0]
+		dlInfo := actor.deadline(rt)
+		sectorNo := abi.SectorNumber(100)
+		proveCommitEpoch := rt.Epoch() + miner.PreCommitChallengeDelay + 1
+		expiration := dlInfo.PeriodEnd() + defaultSectorExpiration*miner.WPoStProvingPeriod
+		duration := big.NewInt(int64(expiration - proveCommitEpoch))
+
+		precommitParams := actor.makePreCommit(sectorNo, rt.Epoch()-1, expiration, []abi.DealID{1})
+		precommit := actor.preCommitSector(rt, precommitParams, preCommitConf{
+			dealWeight:         big.Mul(big.NewIntUnsigned(uint64(dealSpace)), duration),
+			verifiedDealWeight: big.Mul(big.NewIntUnsigned(uint64(verifiedSpace)), duration),
+		})
+		rt.SetEpoch(proveCommitEpoch)
+		sector := actor.proveCommitSectorAndConfirm(rt, precommit, makeProveCommit(sectorNo), proveCommitConf{})
 		advanceAndSubmitPoSts(rt, actor, sector)
+		return sector
+	}
 
-		// A miner will pay the minimum of termination fee and locked funds. Add some locked funds to ensure
-		// correct fee calculation is used.
-		actor.applyRewards(rt, bigRewards, big.Zero())
-		st := getState(rt)
-		initialLockedFunds := st.LockedFunds
+	// Commits and proves a sector filled with verified deals.
+	commitVerifiedSector := func(t *testing.T, rt *mock.Runtime) *miner.SectorOnChainInfo {
+		return commitSectorWithDeals(t, rt, 0, actor.sectorSize)
+	}
 
-		sectorSize, err := sector.SealProof.SectorSize()
+	extensionFor := func(t *testing.T, rt *mock.Runtime, sector *miner.SectorOnChainInfo, newExpiration abi.ChainEpoch, dropVerified bool) miner.ExpirationExtension2 {
+		st := getState(rt)
+		dlIdx, pIdx, err := st.FindSector(rt.AdtStore(), sector.SectorNumber)
 		require.NoError(t, err)
-		sectorPower := miner.QAPowerForSector(sectorSize, sector)
-		dayReward := miner.ExpectedRewardForPower(actor.epochRewardSmooth, actor.epochQAPowerSmooth, sectorPower, builtin.EpochsInDay)
-		twentyDayReward := miner.ExpectedRewardForPower(actor.epochRewardSmooth, actor.epochQAPowerSmooth, sectorPower, miner.InitialPledgeProjectionPeriod)
-		sectorAge := rt.Epoch() - sector.Activation
-		expectedFee := miner.PledgePenaltyForTermination(dayReward, sectorAge, twentyDayReward, actor.epochQAPowerSmooth, sectorPower, actor.epochRewardSmooth, big.Zero(), 0)
+		drop := bf()
+		if dropVerified {
+			drop = bf(uint64(sector.SectorNumber))
+		}
+		return miner.ExpirationExtension2{
+			Deadline:      dlIdx,
+			Partition:     pIdx,
+			Sectors:       bf(uint64(sector.SectorNumber)),
+			NewExpiration: newExpiration,
+			DropVerified:  drop,
+		}
+	}
 
-		sectors := bf(uint64(sector.SectorNumber))
-		actor.terminateSectors(rt, sectors, expectedFee)
+	t.Run("retains verified power within verified term", func(t *testing.T) {
+		rt := builder.Build(t)
+		oldSector := commitVerifiedSector(t, rt)
+		oldPower := miner.QAPowerForSector(actor.sectorSize, oldSector)
 
-		{
-			st := getState(rt)
+		newExpiration := oldSector.Expiration + 42*miner.WPoStProvingPeriod
+		ret := actor.extendSectors2(rt, &miner.ExtendSectorExpiration2Params{
+			Extensions: []miner.ExpirationExtension2{extensionFor(t, rt, oldSector, newExpiration, false)},
+		})
 
-			// expect sector to be marked as terminated and the early termination queue to be empty (having been fully processed)
-			_, partition := actor.findSector(rt, sector.SectorNumber)
-			terminated, err := partition.Terminated.IsSet(uint64(sector.SectorNumber))
+		newSector := actor.getSector(rt, oldSector.SectorNumber)
+		assert.Equal(t, newExpiration, newSector.Expiration)
+		assert.Equal(t, oldPower, miner.QAPowerForSector(actor.sectorSize, newSector))
+		assert.True(t, newSector.VerifiedDealWeight.GreaterThan(oldSector.VerifiedDealWeight))
+
+		require.Len(t, ret.Sectors, 1)
+		assert.Equal(t, oldSector.SectorNumber, ret.Sectors[0].SectorNumber)
+		assert.False(t, ret.Sectors[0].VerifiedDropped)
+		assert.Equal(t, oldPower, ret.Sectors[0].OldQAPower)
+		assert.Equal(t, oldPower, ret.Sectors[0].NewQAPower)
+		assert.True(t, ret.Sectors[0].PledgeDelta.IsZero())
+		actor.checkState(rt)
+	})
+
+	t.Run("scales deal weight and verified weight together", func(t *testing.T) {
+		rt := builder.Build(t)
+		oldSector := commitSectorWithDeals(t, rt, actor.sectorSize/4, actor.sectorSize/2)
+		oldPower := miner.QAPowerForSector(actor.sectorSize, oldSector)
+
+		newExpiration := oldSector.Expiration + 42*miner.WPoStProvingPeriod
+		ret := actor.extendSectors2(rt, &miner.ExtendSectorExpiration2Params{
+			Extensions: []miner.ExpirationExtension2{extensionFor(t, rt, oldSector, newExpiration, false)},
+		})
+		require.Len(t, ret.Sectors, 1)
+		assert.True(t, ret.Sectors[0].PledgeDelta.IsZero())
+
+		// Both weights still describe the same deal space over the sector's new lifetime.
+		newSector := actor.getSector(rt, oldSector.SectorNumber)
+		newDuration := big.NewInt(int64(newSector.Expiration - newSector.Activation))
+		assert.Equal(t, big.Mul(big.NewIntUnsigned(uint64(actor.sectorSize/4)), newDuration), newSector.DealWeight)
+		assert.Equal(t, big.Mul(big.NewIntUnsigned(uint64(actor.sectorSize/2)), newDuration), newSector.VerifiedDealWeight)
+		assert.Equal(t, oldPower, miner.QAPowerForSector(actor.sectorSize, newSector))
+		assert.Equal(t, oldSector.InitialPledge, newSector.InitialPledge)
+		actor.checkState(rt)
+	})
+
+	t.Run("rejects extension beyond verified term without dropping verified weight", func(t *testing.T) {
+		rt := builder.Build(t)
+		sector := commitVerifiedSector(t, rt)
+
+		newExpiration := sector.Activation + miner.VerifiedDealMaxTerm + 1
+		params := &miner.ExtendSectorExpiration2Params{
+			Extensions: []miner.ExpirationExtension2{extensionFor(t, rt, sector, newExpiration, false)},
+		}
+		rt.ExpectAbortContainsMessage(exitcode.ErrForbidden, "without dropping verified weight", func() {
+			actor.extendSectors2(rt, params)
+		})
+		rt.Reset()
+		actor.checkState(rt)
+	})
+
+	t.Run("drops verified weight beyond verified term", func(t *testing.T) {
+		rt := builder.Build(t)
+		oldSector := commitVerifiedSector(t, rt)
+		oldPower := miner.QAPowerForSector(actor.sectorSize, oldSector)
+
+		newExpiration := oldSector.Activation + miner.VerifiedDealMaxTerm + 1
+		ret := actor.extendSectors2(rt, &miner.ExtendSectorExpiration2Params{
+			Extensions: []miner.ExpirationExtension2{extensionFor(t, rt, oldSector, newExpiration, true)},
+		})
+
+		newSector := actor.getSector(rt, oldSector.SectorNumber)
+		assert.Equal(t, newExpiration, newSector.Expiration)
+		assert.True(t, newSector.VerifiedDealWeight.IsZero())
+		newPower := miner.QAPowerForSector(actor.sectorSize, newSector)
+		assert.Equal(t, big.NewIntUnsigned(uint64(actor.sectorSize)), newPower)
+
+		require.Len(t, ret.Sectors, 1)
+		assert.True(t, ret.Sectors[0].VerifiedDropped)
+		assert.Equal(t, oldPower, ret.Sectors[0].OldQAPower)
+		assert.Equal(t, newPower, ret.Sectors[0].NewQAPower)
+		assert.True(t, ret.Sectors[0].PledgeDelta.IsZero())
+		actor.checkState(rt)
+	})
+
+	t.Run("rejects dropping verified weight from sectors not extended", func(t *testing.T) {
+		rt := builder.Build(t)
+		sector := commitVerifiedSector(t, rt)
+
+		ext := extensionFor(t, rt, sector, sector.Expiration+miner.WPoStProvingPeriod, false)
+		ext.DropVerified = bf(uint64(sector.SectorNumber) + 1)
+		params := &miner.ExtendSectorExpiration2Params{Extensions: []miner.ExpirationExtension2{ext}}
+		rt.ExpectAbortContainsMessage(exitcode.ErrIllegalArgument, "not all extended", func() {
+			actor.extendSectors2(rt, params)
+		})
+		rt.Reset()
+		actor.checkState(rt)
+	})
+}
+
+func TestRenewSectorDeal(t *testing.T) {
+	periodOffset := abi.ChainEpoch(100)
+	actor := newHarness(t, periodOffset)
+	precommitEpoch := abi.ChainEpoch(1)
+	builder := builderForHarness(actor).
+		WithEpoch(precommitEpoch).
+		WithBalance(bigBalance, big.Zero())
+	dealID := abi.DealID(1)
+
+	// Commits and proves a sector with a single deal over half its space.
+	commitSector := func(t *testing.T, rt *mock.Runtime) *miner.SectorOnChainInfo {
+		actor.constructAndVerify(rt)
+		dlInfo := actor.deadline(rt)
+		sectorNo := abi.SectorNumber(100)
+		proveCommitEpoch := rt.Epoch() + miner.PreCommitChallengeDelay + 1
+		expiration := dlInfo.PeriodEnd() + defaultSectorExpiration*miner.WPoStProvingPeriod
+		dealWeight := big.Mul(big.NewInt(int64(actor.sectorSize/2)), big.NewInt(int64(expiration-proveCommitEpoch)/2))
+
+		precommitParams := actor.makePreCommit(sectorNo, rt.Epoch()-1, expiration, []abi.DealID{dealID})
+		precommit := actor.preCommitSector(rt, precommitParams, preCommitConf{
+			dealWeight:         dealWeight,
+			verifiedDealWeight: big.Zero(),
+		})
+		rt.SetEpoch(proveCommitEpoch)
+		sector := actor.proveCommitSectorAndConfirm(rt, precommit, makeProveCommit(sectorNo), proveCommitConf{})
+		advanceAndSubmitPoSts(rt, actor, sector)
+		return sector
+	}
+
+	paramsFor := func(t *testing.T, rt *mock.Runtime, sector *miner.SectorOnChainInfo, dealID abi.DealID) *miner.RenewSectorDealParams {
+		st := getState(rt)
+		dlIdx, pIdx, err := st.FindSector(rt.AdtStore(), sector.SectorNumber)
+		require.NoError(t, err)
+		return &miner.RenewSectorDealParams{
+			Deadline:  dlIdx,
+			Partition: pIdx,
+			Sector:    sector.SectorNumber,
+			Renewal: market.ClientDealRenewal{
+				Renewal: market.DealRenewal{
+					DealID:                       dealID,
+					NewEndEpoch:                  sector.Expiration,
+					NewStoragePricePerEpoch:      big.Zero(),
+					AdditionalClientCollateral:   big.Zero(),
+					AdditionalProviderCollateral: big.Zero(),
+				},
+				ClientSignature: crypto.Signature{Type: crypto.SigTypeBLS, Data: []byte("renew")},
+			},
+		}
+	}
+
+	t.Run("adds renewed deal weight to sector", func(t *testing.T) {
+		rt := builder.Build(t)
+		oldSector := commitSector(t, rt)
+		params := paramsFor(t, rt, oldSector, dealID)
+
+		weightDelta := big.Mul(big.NewInt(int64(actor.sectorSize/2)), big.NewInt(int64(miner.WPoStProvingPeriod)))
+		actor.renewSectorDeal(rt, params, oldSector.Expiration, &market.RenewDealReturn{
+			DealWeightDelta:         weightDelta,
+			VerifiedDealWeightDelta: big.Zero(),
+		})
+
+		newSector := actor.getSector(rt, oldSector.SectorNumber)
+		assert.Equal(t, big.Add(oldSector.DealWeight, weightDelta), newSector.DealWeight)
+		assert.Equal(t, oldSector.Expiration, newSector.Expiration)
+		assert.Equal(t, oldSector.InitialPledge, newSector.InitialPledge)
+		actor.checkState(rt)
+	})
+
+	t.Run("verified renewal increases sector power", func(t *testing.T) {
+		rt := builder.Build(t)
+		oldSector := commitSector(t, rt)
+		params := paramsFor(t, rt, oldSector, dealID)
+
+		weightDelta := big.Mul(big.NewInt(int64(actor.sectorSize/2)), big.NewInt(int64(miner.WPoStProvingPeriod)))
+		actor.renewSectorDeal(rt, params, oldSector.Expiration, &market.RenewDealReturn{
+			DealWeightDelta:         big.Zero(),
+			VerifiedDealWeightDelta: weightDelta,
+		})
+
+		newSector := actor.getSector(rt, oldSector.SectorNumber)
+		assert.Equal(t, weightDelta, newSector.VerifiedDealWeight)
+		assert.True(t, miner.QAPowerForSector(actor.sectorSize, newSector).GreaterThan(miner.QAPowerForSector(actor.sectorSize, oldSector)))
+		actor.checkState(rt)
+	})
+
+	t.Run("verified renewal locks pledge for the increased power", func(t *testing.T) {
+		rt := builder.Build(t)
+		oldSector := commitSector(t, rt)
+		params := paramsFor(t, rt, oldSector, dealID)
+		oldPledge := getState(rt).InitialPledge
+
+		weightDelta := big.Mul(big.NewInt(int64(actor.sectorSize/2)), big.NewInt(int64(oldSector.Expiration-oldSector.Activation)/2))
+		actor.renewSectorDeal(rt, params, oldSector.Expiration, &market.RenewDealReturn{
+			DealWeightDelta:         big.Zero(),
+			VerifiedDealWeightDelta: weightDelta,
+		})
+
+		newSector := actor.getSector(rt, oldSector.SectorNumber)
+		newQAPower := miner.QAPowerForSector(actor.sectorSize, newSector)
+		assert.True(t, newQAPower.GreaterThan(miner.QAPowerForSector(actor.sectorSize, oldSector)))
+
+		expectedPledge := miner.InitialPledgeForPower(newQAPower, actor.baselinePower, actor.epochRewardSmooth,
+			actor.epochQAPowerSmooth, rt.TotalFilCircSupply())
+		assert.True(t, expectedPledge.GreaterThan(oldSector.InitialPledge))
+		assert.Equal(t, expectedPledge, newSector.InitialPledge)
+		assert.Equal(t, big.Add(oldPledge, big.Sub(expectedPledge, oldSector.InitialPledge)), getState(rt).InitialPledge)
+		assert.Equal(t, miner.ExpectedRewardForPower(actor.epochRewardSmooth, actor.epochQAPowerSmooth, newQAPower, builtin.EpochsInDay),
+			newSector.ExpectedDayReward)
+		assert.Equal(t, miner.ExpectedRewardForPower(actor.epochRewardSmooth, actor.epochQAPowerSmooth, newQAPower, miner.InitialPledgeProjectionPeriod),
+			newSector.ExpectedStoragePledge)
+
+		// The sector's power in its partition and its pledge stay consistent.
+		actor.checkState(rt)
+	})
+
+	t.Run("fails if miner cannot cover the increased pledge", func(t *testing.T) {
+		rt := builder.Build(t)
+		oldSector := commitSector(t, rt)
+		params := paramsFor(t, rt, oldSector, dealID)
+		st := getState(rt)
+		rt.SetBalance(big.Sum(st.PreCommitDeposits, st.InitialPledge, st.LockedFunds))
+
+		weightDelta := big.Mul(big.NewInt(int64(actor.sectorSize/2)), big.NewInt(int64(oldSector.Expiration-oldSector.Activation)/2))
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(append(actor.controlAddrs, actor.owner, actor.worker)...)
+		rt.ExpectSend(builtin.StorageMarketActorAddr, builtin.MethodsMarket.RenewDeal,
+			&market.RenewDealParams{Renewal: params.Renewal, SectorExpiry: oldSector.Expiration},
+			abi.NewTokenAmount(0), &market.RenewDealReturn{DealWeightDelta: big.Zero(), VerifiedDealWeightDelta: weightDelta}, exitcode.Ok)
+		expectQueryNetworkInfo(rt, actor)
+		rt.ExpectAbortContainsMessage(exitcode.ErrInsufficientFunds, "insufficient funds", func() {
+			rt.Call(actor.a.RenewSectorDeal, params)
+		})
+		rt.Reset()
+		assert.True(t, actor.getSector(rt, oldSector.SectorNumber).VerifiedDealWeight.IsZero())
+		actor.checkState(rt)
+	})
+
+	t.Run("fails for deal not in sector", func(t *testing.T) {
+		rt := builder.Build(t)
+		sector := commitSector(t, rt)
+		params := paramsFor(t, rt, sector, dealID+1)
+
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(append(actor.controlAddrs, actor.owner, actor.worker)...)
+		rt.ExpectAbortContainsMessage(exitcode.ErrIllegalArgument, "is not in sector", func() {
+			rt.Call(actor.a.RenewSectorDeal, params)
+		})
+		rt.Reset()
+		actor.checkState(rt)
+	})
+
+	t.Run("fails for unknown sector", func(t *testing.T) {
+		rt := builder.Build(t)
+		sector := commitSector(t, rt)
+		params := paramsFor(t, rt, sector, dealID)
+		params.Sector = sector.SectorNumber + 1
+
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(append(actor.controlAddrs, actor.owner, actor.worker)...)
+		rt.ExpectAbort(exitcode.ErrNotFound, func() {
+			rt.Call(actor.a.RenewSectorDeal, params)
+		})
+		rt.Reset()
+		actor.checkState(rt)
+	})
+
+	t.Run("fails if market rejects renewal", func(t *testing.T) {
+		rt := builder.Build(t)
+		sector := commitSector(t, rt)
+		params := paramsFor(t, rt, sector, dealID)
+
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(append(actor.controlAddrs, actor.owner, actor.worker)...)
+		rt.ExpectSend(builtin.StorageMarketActorAddr, builtin.MethodsMarket.RenewDeal,
+			&market.RenewDealParams{Renewal: params.Renewal, SectorExpiry: sector.Expiration},
+			abi.NewTokenAmount(0), &market.RenewDealReturn{DealWeightDelta: big.Zero(), VerifiedDealWeightDelta: big.Zero()}, exitcode.ErrIllegalArgument)
+		rt.ExpectAbort(exitcode.ErrIllegalArgument, func() {
+			rt.Call(actor.a.RenewSectorDeal, params)
+		})
+		rt.Reset()
+
+		assert.Equal(t, sector, actor.getSector(rt, sector.SectorNumber))
+		actor.checkState(rt)
+	})
+}
+
+func TestPreviewTerminateSectors(t *testing.T) {
+	periodOffset := abi.ChainEpoch(100)
+	actor := newHarness(t, periodOffset)
+	builder := builderForHarness(actor).
+		WithBalance(big.Mul(big.NewInt(1e18), big.NewInt(200000)), big.Zero())
+
+	t.Run("previews fee and pledge charged by termination", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+		rt.SetEpoch(abi.ChainEpoch(1))
+		sectors := actor.commitAndProveSectors(rt, 2, defaultSectorExpiration, nil)
+		advanceAndSubmitPoSts(rt, actor, sectors...)
+		actor.applyRewards(rt, bigRewards, big.Zero())
+
+		toTerminate := bf(uint64(sectors[0].SectorNumber), uint64(sectors[1].SectorNumber))
+		stBefore := getState(rt)
+		preview := actor.previewTerminateSectors(rt, toTerminate)
+		assert.Equal(t, stBefore, getState(rt))
+
+		require.Len(t, preview.Sectors, 2)
+		expectedFee := big.Zero()
+		expectedPledge := big.Zero()
+		for i, sector := range sectors {
+			sectorPower := miner.QAPowerForSector(actor.sectorSize, sector)
+			fee := miner.PledgePenaltyForTermination(sector.ExpectedDayReward, rt.Epoch()-sector.Activation,
+				sector.ExpectedStoragePledge, actor.epochQAPowerSmooth, sectorPower, actor.epochRewardSmooth, big.Zero(), 0)
+			assert.Equal(t, sector.SectorNumber, preview.Sectors[i].SectorNumber)
+			assert.Equal(t, fee, preview.Sectors[i].Fee)
+			assert.Equal(t, sector.InitialPledge, preview.Sectors[i].Pledge)
+			expectedFee = big.Add(expectedFee, fee)
+			expectedPledge = big.Add(expectedPledge, sector.InitialPledge)
+		}
+		assert.Equal(t, expectedFee, preview.TotalFee)
+		assert.Equal(t, expectedPledge, preview.TotalPledge)
+
+		// Termination charges exactly the previewed fee and releases the previewed pledge.
+		_, pledgeDelta := actor.terminateSectors(rt, toTerminate, preview.TotalFee)
+		assert.Equal(t, big.Add(preview.TotalFee, preview.TotalPledge).Neg(), pledgeDelta)
+		actor.checkState(rt)
+	})
+
+	t.Run("rejects sectors that are not live", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+		rt.SetEpoch(abi.ChainEpoch(1))
+		sectors := actor.commitAndProveSectors(rt, 1, defaultSectorExpiration, nil)
+		advanceAndSubmitPoSts(rt, actor, sectors...)
+		actor.applyRewards(rt, bigRewards, big.Zero())
+
+		sector := sectors[0]
+		sectorPower := miner.QAPowerForSector(actor.sectorSize, sector)
+		fee := miner.PledgePenaltyForTermination(sector.ExpectedDayReward, rt.Epoch()-sector.Activation,
+			sector.ExpectedStoragePledge, actor.epochQAPowerSmooth, sectorPower, actor.epochRewardSmooth, big.Zero(), 0)
+		actor.terminateSectors(rt, bf(uint64(sector.SectorNumber)), fee)
+
+		rt.ExpectAbortContainsMessage(exitcode.ErrIllegalArgument, "can only terminate live sectors", func() {
+			actor.previewTerminateSectors(rt, bf(uint64(sector.SectorNumber)))
+		})
+		actor.checkState(rt)
+	})
+
+	t.Run("rejects too many sectors", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+
+		sectorNos := make([]uint64, miner.AddressedSectorsMax+1)
+		for i := range sectorNos {
+			sectorNos[i] = uint64(i)
+		}
+		params := &miner.TerminateSectorsParams{Terminations: []miner.TerminationDeclaration{{
+			Deadline:  0,
+			Partition: 0,
+			Sectors:   bitfield.NewFromSet(sectorNos),
+		}}}
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAny()
+		rt.ExpectAbortContainsMessage(exitcode.ErrIllegalArgument, "too many sectors", func() {
+			rt.Call(actor.a.PreviewTerminateSectors, params)
+		})
+		actor.checkState(rt)
+	})
+}
+
+func TestPreviewWindowedPoSt(t *testing.T) {
+	periodOffset := abi.ChainEpoch(100)
+	actor := newHarness(t, periodOffset)
+	builder := builderForHarness(actor).
+		WithBalance(bigBalance, big.Zero())
+
+	t.Run("previews skipped faults and the fee charged at deadline close", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+		infos := actor.commitAndProveSectors(rt, 2, defaultSectorExpiration, nil)
+		actor.applyRewards(rt, bigRewards, big.Zero())
+
+		st := getState(rt)
+		dlIdx, pIdx, err := st.FindSector(rt.AdtStore(), infos[0].SectorNumber)
+		require.NoError(t, err)
+		dlinfo := actor.deadline(rt)
+		for dlinfo.Index != dlIdx {
+			dlinfo = advanceDeadline(rt, actor, &cronConfig{})
+		}
+
+		partitions := []miner.PoStPartition{
+			{Index: pIdx, Skipped: bf(uint64(infos[0].SectorNumber))},
+		}
+		stBefore := getState(rt)
+		preview := actor.previewWindowedPoSt(rt, dlIdx, partitions)
+		assert.Equal(t, stBefore, getState(rt))
+
+		faultyPower := actor.powerPairForSectors(infos[:1])
+		assert.True(t, faultyPower.Equals(preview.NewFaultyPower))
+		assert.True(t, preview.RetractedRecoveryPower.IsZero())
+		assert.True(t, preview.RecoveredPower.IsZero())
+		assert.True(t, faultyPower.Equals(preview.FaultyPower))
+		assert.Equal(t, actor.continuedFaultPenalty(infos[:1]), preview.ProjectedFaultFee)
+
+		// The submission and deadline close match the preview.
+		actor.submitWindowPoSt(rt, dlinfo, partitions, infos, &poStConfig{
+			expectedPowerDelta: miner.PowerForSectors(actor.sectorSize, infos[1:]),
+		})
+		advanceDeadline(rt, actor, &cronConfig{continuedFaultsPenalty: preview.ProjectedFaultFee})
+		actor.checkState(rt)
+	})
+
+	t.Run("previews recovered and retracted recovery power", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+		infos := actor.commitAndProveSectors(rt, 3, defaultSectorExpiration, nil)
+		actor.applyRewards(rt, bigRewards, big.Zero())
+		advanceAndSubmitPoSts(rt, actor, infos...)
+
+		advanceDeadline(rt, actor, &cronConfig{})
+		actor.declareFaults(rt, infos[0], infos[1])
+		advanceDeadline(rt, actor, &cronConfig{})
+
+		st := getState(rt)
+		dlIdx, pIdx, err := st.FindSector(rt.AdtStore(), infos[0].SectorNumber)
+		require.NoError(t, err)
+		actor.declareRecoveries(rt, dlIdx, pIdx, bf(uint64(infos[0].SectorNumber), uint64(infos[1].SectorNumber)), big.Zero())
+
+		dlinfo := actor.deadline(rt)
+		for dlinfo.Index != dlIdx {
+			dlinfo = advanceDeadline(rt, actor, &cronConfig{})
+		}
+
+		// Recover the first sector and skip the second.
+		preview := actor.previewWindowedPoSt(rt, dlIdx, []miner.PoStPartition{
+			{Index: pIdx, Skipped: bf(uint64(infos[1].SectorNumber))},
+		})
+		recoveredPower := actor.powerPairForSectors(infos[:1])
+		retractedPower := actor.powerPairForSectors(infos[1:2])
+		assert.True(t, preview.NewFaultyPower.IsZero())
+		assert.True(t, retractedPower.Equals(preview.RetractedRecoveryPower))
+		assert.True(t, recoveredPower.Equals(preview.RecoveredPower))
+		assert.True(t, retractedPower.Equals(preview.FaultyPower))
+		assert.Equal(t, actor.continuedFaultPenalty(infos[1:2]), preview.ProjectedFaultFee)
+
+		// Recovering both leaves nothing to be charged.
+		preview = actor.previewWindowedPoSt(rt, dlIdx, []miner.PoStPartition{
+			{Index: pIdx, Skipped: bitfield.New()},
+		})
+		recoveredPower = actor.powerPairForSectors(infos[:2])
+		assert.True(t, recoveredPower.Equals(preview.RecoveredPower))
+		assert.True(t, preview.FaultyPower.IsZero())
+		assert.True(t, preview.ProjectedFaultFee.IsZero())
+		actor.checkState(rt)
+	})
+
+	t.Run("rejects a deadline that is not current", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+		infos := actor.commitAndProveSectors(rt, 1, defaultSectorExpiration, nil)
+
+		st := getState(rt)
+		dlIdx, pIdx, err := st.FindSector(rt.AdtStore(), infos[0].SectorNumber)
+		require.NoError(t, err)
+		dlinfo := actor.deadline(rt)
+		for dlinfo.Index != dlIdx {
+			dlinfo = advanceDeadline(rt, actor, &cronConfig{})
+		}
+
+		rt.ExpectAbortContainsMessage(exitcode.ErrIllegalArgument, "invalid deadline", func() {
+			actor.previewWindowedPoSt(rt, (dlIdx+1)%miner.WPoStPeriodDeadlines, []miner.PoStPartition{
+				{Index: pIdx, Skipped: bitfield.New()},
+			})
+		})
+		actor.checkState(rt)
+	})
+}
+
+func TestTerminateSectors(t *testing.T) {
+	periodOffset := abi.ChainEpoch(100)
+	actor := newHarness(t, periodOffset)
+	builder := builderForHarness(actor).
+		WithBalance(big.Mul(big.NewInt(1e18), big.NewInt(200000)), big.Zero())
+
+	t.Run("removes sector with correct accounting", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+		rt.SetEpoch(abi.ChainEpoch(1))
+		sectorInfo := actor.commitAndProveSectors(rt, 1, defaultSectorExpiration, nil)
+		sector := sectorInfo[0]
+		advanceAndSubmitPoSts(rt, actor, sector)
+
+		// A miner will pay the minimum of termination fee and locked funds. Add some locked funds to ensure
+		// correct fee calculation is used.
+		actor.applyRewards(rt, bigRewards, big.Zero())
+		st := getState(rt)
+		initialLockedFunds := st.LockedFunds
+
+		sectorSize, err := sector.SealProof.SectorSize()
+		require.NoError(t, err)
+		sectorPower := miner.QAPowerForSector(sectorSize, sector)
+		dayReward := miner.ExpectedRewardForPower(actor.epochRewardSmooth, actor.epochQAPowerSmooth, sectorPower, builtin.EpochsInDay)
+		twentyDayReward := miner.ExpectedRewardForPower(actor.epochRewardSmooth, actor.epochQAPowerSmooth, sectorPower, miner.InitialPledgeProjectionPeriod)
+		sectorAge := rt.Epoch() - sector.Activation
+		expectedFee := miner.PledgePenaltyForTermination(dayReward, sectorAge, twentyDayReward, actor.epochQAPowerSmooth, sectorPower, actor.epochRewardSmooth, big.Zero(), 0)
+
+		sectors := bf(uint64(sector.SectorNumber))
+		actor.terminateSectors(rt, sectors, expectedFee)
+
+		{
+			st := getState(rt)
+
+			// expect sector to be marked as terminated and the early termination queue to be empty (having been fully processed)
+			_, partition := actor.findSector(rt, sector.SectorNumber)
+			terminated, err := partition.Terminated.IsSet(uint64(sector.SectorNumber))
 			require.NoError(t, err)
 			assert.True(t, terminated)
 			result, _, err := partition.PopEarlyTerminations(rt.AdtStore(), 1000)
@@ -3338,139 +4315,395 @@ func TestTerminateSectors(t *testing.T) {
 		actor.checkState(rt)
 	})
 
-	t.Run("charges correct fee for young termination of committed capacity upgrade", func(t *testing.T) {
-		actor := newHarness(t, periodOffset)
-		rt := builderForHarness(actor).
-			WithBalance(bigBalance, big.Zero()).
-			Build(t)
+	t.Run("charges correct fee for young termination of committed capacity upgrade", func(t *testing.T) {
+		actor := newHarness(t, periodOffset)
+		rt := builderForHarness(actor).
+			WithBalance(bigBalance, big.Zero()).
+			Build(t)
+		actor.constructAndVerify(rt)
+
+		// Move the current epoch forward so that the first deadline is a stable candidate for both sectors
+		rt.SetEpoch(periodOffset + miner.WPoStChallengeWindow)
+
+		// Commit a sector to upgrade
+		oldSector := actor.commitAndProveSector(rt, 1, defaultSectorExpiration, nil)
+		advanceAndSubmitPoSts(rt, actor, oldSector) // activate power
+		st := getState(rt)
+		dlIdx, partIdx, err := st.FindSector(rt.AdtStore(), oldSector.SectorNumber)
+		require.NoError(t, err)
+
+		// advance clock so upgrade happens later
+		rt.SetEpoch(rt.Epoch() + 10_000)
+
+		challengeEpoch := rt.Epoch() - 1
+		upgradeParams := actor.makePreCommit(200, challengeEpoch, oldSector.Expiration, []abi.DealID{1})
+		upgradeParams.ReplaceCapacity = true
+		upgradeParams.ReplaceSectorDeadline = dlIdx
+		upgradeParams.ReplaceSectorPartition = partIdx
+		upgradeParams.ReplaceSectorNumber = oldSector.SectorNumber
+		upgrade := actor.preCommitSector(rt, upgradeParams, preCommitConf{})
+
+		// Prove new sector
+		rt.SetEpoch(upgrade.PreCommitEpoch + miner.PreCommitChallengeDelay + 1)
+		newSector := actor.proveCommitSectorAndConfirm(rt, upgrade, makeProveCommit(upgrade.Info.SectorNumber), proveCommitConf{})
+
+		// Expect replace parameters have been set
+		assert.Equal(t, oldSector.ExpectedDayReward, newSector.ReplacedDayReward)
+		assert.Equal(t, rt.Epoch()-oldSector.Activation, newSector.ReplacedSectorAge)
+
+		// post new sector to activate power
+		advanceAndSubmitPoSts(rt, actor, oldSector, newSector)
+
+		// advance clock a little and terminate new sector
+		rt.SetEpoch(rt.Epoch() + 5_000)
+
+		// Add some locked funds to ensure full termination fee appears as pledge change.
+		actor.applyRewards(rt, bigRewards, big.Zero())
+
+		sectorPower := miner.QAPowerForSector(actor.sectorSize, newSector)
+		twentyDayReward := miner.ExpectedRewardForPower(actor.epochRewardSmooth, actor.epochQAPowerSmooth, sectorPower, miner.InitialPledgeProjectionPeriod)
+		newSectorAge := rt.Epoch() - newSector.Activation
+		oldSectorAge := newSector.Activation - oldSector.Activation
+		expectedFee := miner.PledgePenaltyForTermination(newSector.ExpectedDayReward, newSectorAge, twentyDayReward,
+			actor.epochQAPowerSmooth, sectorPower, actor.epochRewardSmooth, oldSector.ExpectedDayReward, oldSectorAge)
+
+		sectors := bf(uint64(newSector.SectorNumber))
+		actor.terminateSectors(rt, sectors, expectedFee)
+		actor.checkState(rt)
+	})
+
+	t.Run("cannot terminate a sector when the challenge window is open", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+		rt.SetEpoch(abi.ChainEpoch(1))
+		sectorInfo := actor.commitAndProveSectors(rt, 1, defaultSectorExpiration, nil)
+		sector := sectorInfo[0]
+
+		st := getState(rt)
+		dlIdx, pIdx, err := st.FindSector(rt.AdtStore(), sector.SectorNumber)
+		require.NoError(t, err)
+
+		// advance into the deadline, but not past it.
+		dlinfo := actor.deadline(rt)
+		for dlinfo.Index != dlIdx {
+			dlinfo = advanceDeadline(rt, actor, &cronConfig{})
+		}
+
+		params := &miner.TerminateSectorsParams{Terminations: []miner.TerminationDeclaration{{
+			Deadline:  dlIdx,
+			Partition: pIdx,
+			Sectors:   bf(uint64(sector.SectorNumber)),
+		}}}
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(append(actor.controlAddrs, actor.owner, actor.worker)...)
+		rt.ExpectAbortContainsMessage(exitcode.ErrIllegalArgument, "cannot terminate sectors in immutable deadline", func() {
+			rt.Call(actor.a.TerminateSectors, params)
+		})
+
+		actor.checkState(rt)
+	})
+
+}
+
+func TestWithdrawBalance(t *testing.T) {
+	periodOffset := abi.ChainEpoch(100)
+	actor := newHarness(t, periodOffset)
+	builder := builderForHarness(actor).
+		WithBalance(bigBalance, big.Zero())
+
+	onePercentBalance := big.Div(bigBalance, big.NewInt(100))
+
+	t.Run("happy path withdraws funds", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+
+		// withdraw 1% of balance
+		actor.withdrawFunds(rt, onePercentBalance, onePercentBalance, big.Zero())
+		actor.checkState(rt)
+	})
+
+	t.Run("fails if miner can't repay fee debt", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+
+		st := getState(rt)
+		st.FeeDebt = big.Add(rt.Balance(), abi.NewTokenAmount(1e18))
+		rt.ReplaceState(st)
+		rt.ExpectAbortContainsMessage(exitcode.ErrInsufficientFunds, "unlocked balance can not repay fee debt", func() {
+			actor.withdrawFunds(rt, onePercentBalance, onePercentBalance, big.Zero())
+		})
+		actor.checkState(rt)
+	})
+
+	t.Run("withdraw only what we can after fee debt", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+
+		st := getState(rt)
+		feeDebt := big.Sub(bigBalance, onePercentBalance)
+		st.FeeDebt = feeDebt
+		rt.ReplaceState(st)
+
+		requested := rt.Balance()
+		expectedWithdraw := big.Sub(requested, feeDebt)
+		actor.withdrawFunds(rt, requested, expectedWithdraw, feeDebt)
+		actor.checkState(rt)
+	})
+}
+
+func TestChangeBeneficiary(t *testing.T) {
+	actor := newHarness(t, 0)
+	beneficiary := tutil.NewIDAddr(t, 1001)
+	otherAddr := tutil.NewIDAddr(t, 1002)
+	builder := builderForHarness(actor).
+		WithBalance(bigBalance, big.Zero()).
+		WithActorType(beneficiary, builtin.AccountActorCodeID).
+		WithActorType(otherAddr, builtin.AccountActorCodeID)
+	quota := abi.NewTokenAmount(1e18)
+	expiration := abi.ChainEpoch(1000)
+
+	t.Run("owner is initial beneficiary", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+
+		ret := actor.getBeneficiary(rt)
+		assert.Equal(t, actor.owner, ret.Active.Beneficiary)
+		assert.Equal(t, miner.NewBeneficiaryTerm(big.Zero(), 0), ret.Active.Term)
+		assert.Nil(t, ret.Proposed)
+		actor.checkState(rt)
+	})
+
+	t.Run("successful change", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+
+		actor.changeBeneficiary(rt, actor.owner, beneficiary, quota, expiration)
+		ret := actor.getBeneficiary(rt)
+		assert.Equal(t, actor.owner, ret.Active.Beneficiary)
+		require.NotNil(t, ret.Proposed)
+		assert.Equal(t, miner.PendingBeneficiaryChange{
+			NewBeneficiary:        beneficiary,
+			NewQuota:              quota,
+			NewExpiration:         expiration,
+			ApprovedByBeneficiary: true,
+			ApprovedByNominee:     false,
+		}, *ret.Proposed)
+
+		actor.changeBeneficiary(rt, beneficiary, beneficiary, quota, expiration)
+		ret = actor.getBeneficiary(rt)
+		assert.Equal(t, beneficiary, ret.Active.Beneficiary)
+		assert.Equal(t, miner.NewBeneficiaryTerm(quota, expiration), ret.Active.Term)
+		assert.Nil(t, ret.Proposed)
+		actor.checkState(rt)
+	})
+
+	t.Run("proposal must be valid", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+		rt.SetEpoch(100)
+
+		rt.ExpectAbort(exitcode.ErrIllegalArgument, func() {
+			actor.changeBeneficiary(rt, actor.owner, beneficiary, quota.Neg(), expiration)
+		})
+		rt.ExpectAbort(exitcode.ErrIllegalArgument, func() {
+			actor.changeBeneficiary(rt, actor.owner, beneficiary, quota, 100)
+		})
+		rt.ExpectAbort(exitcode.ErrIllegalArgument, func() {
+			actor.changeBeneficiary(rt, actor.owner, actor.owner, quota, 0)
+		})
+		rt.ExpectAbort(exitcode.ErrIllegalArgument, func() {
+			actor.changeBeneficiary(rt, actor.owner, tutil.NewIDAddr(t, 1234), quota, expiration)
+		})
+	})
+
+	t.Run("only owner can propose", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+
+		rt.ExpectAbort(exitcode.SysErrForbidden, func() {
+			actor.changeBeneficiary(rt, beneficiary, beneficiary, quota, expiration)
+		})
+		rt.ExpectAbort(exitcode.SysErrForbidden, func() {
+			actor.changeBeneficiary(rt, actor.worker, beneficiary, quota, expiration)
+		})
+	})
+
+	t.Run("confirmation must match proposal", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+
+		actor.changeBeneficiary(rt, actor.owner, beneficiary, quota, expiration)
+		rt.ExpectAbort(exitcode.ErrIllegalArgument, func() {
+			actor.changeBeneficiary(rt, beneficiary, beneficiary, big.Mul(quota, big.NewInt(2)), expiration)
+		})
+		rt.ExpectAbort(exitcode.ErrIllegalArgument, func() {
+			actor.changeBeneficiary(rt, beneficiary, beneficiary, quota, expiration+1)
+		})
+		rt.ExpectAbort(exitcode.SysErrForbidden, func() {
+			actor.changeBeneficiary(rt, otherAddr, beneficiary, quota, expiration)
+		})
+		assert.Equal(t, actor.owner, actor.getInfo(rt).Beneficiary)
+	})
+
+	t.Run("replacing an active beneficiary requires its approval", func(t *testing.T) {
+		rt := builder.Build(t)
 		actor.constructAndVerify(rt)
 
-		// Move the current epoch forward so that the first deadline is a stable candidate for both sectors
-		rt.SetEpoch(periodOffset + miner.WPoStChallengeWindow)
-
-		// Commit a sector to upgrade
-		oldSector := actor.commitAndProveSector(rt, 1, defaultSectorExpiration, nil)
-		advanceAndSubmitPoSts(rt, actor, oldSector) // activate power
-		st := getState(rt)
-		dlIdx, partIdx, err := st.FindSector(rt.AdtStore(), oldSector.SectorNumber)
-		require.NoError(t, err)
+		actor.changeBeneficiary(rt, actor.owner, beneficiary, quota, expiration)
+		actor.changeBeneficiary(rt, beneficiary, beneficiary, quota, expiration)
 
-		// advance clock so upgrade happens later
-		rt.SetEpoch(rt.Epoch() + 10_000)
+		// The nominee's approval alone is not enough.
+		actor.changeBeneficiary(rt, actor.owner, otherAddr, quota, expiration)
+		actor.changeBeneficiary(rt, otherAddr, otherAddr, quota, expiration)
+		info := actor.getInfo(rt)
+		assert.Equal(t, beneficiary, info.Beneficiary)
+		assert.True(t, info.PendingBeneficiaryTerm.ApprovedByNominee)
+		assert.False(t, info.PendingBeneficiaryTerm.ApprovedByBeneficiary)
 
-		challengeEpoch := rt.Epoch() - 1
-		upgradeParams := actor.makePreCommit(200, challengeEpoch, oldSector.Expiration, []abi.DealID{1})
-		upgradeParams.ReplaceCapacity = true
-		upgradeParams.ReplaceSectorDeadline = dlIdx
-		upgradeParams.ReplaceSectorPartition = partIdx
-		upgradeParams.ReplaceSectorNumber = oldSector.SectorNumber
-		upgrade := actor.preCommitSector(rt, upgradeParams, preCommitConf{})
+		actor.changeBeneficiary(rt, beneficiary, otherAddr, quota, expiration)
+		info = actor.getInfo(rt)
+		assert.Equal(t, otherAddr, info.Beneficiary)
+		assert.Nil(t, info.PendingBeneficiaryTerm)
+		actor.checkState(rt)
+	})
 
-		// Prove new sector
-		rt.SetEpoch(upgrade.PreCommitEpoch + miner.PreCommitChallengeDelay + 1)
-		newSector := actor.proveCommitSectorAndConfirm(rt, upgrade, makeProveCommit(upgrade.Info.SectorNumber), proveCommitConf{})
+	t.Run("reverting to the owner requires approval of an active beneficiary", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
 
-		// Expect replace parameters have been set
-		assert.Equal(t, oldSector.ExpectedDayReward, newSector.ReplacedDayReward)
-		assert.Equal(t, rt.Epoch()-oldSector.Activation, newSector.ReplacedSectorAge)
+		actor.changeBeneficiary(rt, actor.owner, beneficiary, quota, expiration)
+		actor.changeBeneficiary(rt, beneficiary, beneficiary, quota, expiration)
 
-		// post new sector to activate power
-		advanceAndSubmitPoSts(rt, actor, oldSector, newSector)
+		actor.changeBeneficiary(rt, actor.owner, actor.owner, big.Zero(), 0)
+		assert.Equal(t, beneficiary, actor.getInfo(rt).Beneficiary)
 
-		// advance clock a little and terminate new sector
-		rt.SetEpoch(rt.Epoch() + 5_000)
+		actor.changeBeneficiary(rt, beneficiary, actor.owner, big.Zero(), 0)
+		info := actor.getInfo(rt)
+		assert.Equal(t, actor.owner, info.Beneficiary)
+		assert.Equal(t, miner.NewBeneficiaryTerm(big.Zero(), 0), info.BeneficiaryTerm)
+		actor.checkState(rt)
+	})
 
-		// Add some locked funds to ensure full termination fee appears as pledge change.
-		actor.applyRewards(rt, bigRewards, big.Zero())
+	t.Run("expired beneficiary is replaced without its approval", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
 
-		sectorPower := miner.QAPowerForSector(actor.sectorSize, newSector)
-		twentyDayReward := miner.ExpectedRewardForPower(actor.epochRewardSmooth, actor.epochQAPowerSmooth, sectorPower, miner.InitialPledgeProjectionPeriod)
-		newSectorAge := rt.Epoch() - newSector.Activation
-		oldSectorAge := newSector.Activation - oldSector.Activation
-		expectedFee := miner.PledgePenaltyForTermination(newSector.ExpectedDayReward, newSectorAge, twentyDayReward,
-			actor.epochQAPowerSmooth, sectorPower, actor.epochRewardSmooth, oldSector.ExpectedDayReward, oldSectorAge)
+		actor.changeBeneficiary(rt, actor.owner, beneficiary, quota, expiration)
+		actor.changeBeneficiary(rt, beneficiary, beneficiary, quota, expiration)
 
-		sectors := bf(uint64(newSector.SectorNumber))
-		actor.terminateSectors(rt, sectors, expectedFee)
+		rt.SetEpoch(expiration)
+		actor.changeBeneficiary(rt, actor.owner, actor.owner, big.Zero(), 0)
+		assert.Equal(t, actor.owner, actor.getInfo(rt).Beneficiary)
 		actor.checkState(rt)
 	})
 
-	t.Run("cannot terminate a sector when the challenge window is open", func(t *testing.T) {
+	t.Run("beneficiary follows owner change", func(t *testing.T) {
 		rt := builder.Build(t)
 		actor.constructAndVerify(rt)
-		rt.SetEpoch(abi.ChainEpoch(1))
-		sectorInfo := actor.commitAndProveSectors(rt, 1, defaultSectorExpiration, nil)
-		sector := sectorInfo[0]
 
-		st := getState(rt)
-		dlIdx, pIdx, err := st.FindSector(rt.AdtStore(), sector.SectorNumber)
-		require.NoError(t, err)
+		actor.changeBeneficiary(rt, actor.owner, beneficiary, quota, expiration)
 
-		// advance into the deadline, but not past it.
-		dlinfo := actor.deadline(rt)
-		for dlinfo.Index != dlIdx {
-			dlinfo = advanceDeadline(rt, actor, &cronConfig{})
-		}
+		rt.SetCaller(actor.owner, builtin.AccountActorCodeID)
+		actor.changeOwnerAddress(rt, otherAddr)
+		rt.SetCaller(otherAddr, builtin.AccountActorCodeID)
+		actor.changeOwnerAddress(rt, otherAddr)
 
-		params := &miner.TerminateSectorsParams{Terminations: []miner.TerminationDeclaration{{
-			Deadline:  dlIdx,
-			Partition: pIdx,
-			Sectors:   bf(uint64(sector.SectorNumber)),
-		}}}
-		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
-		rt.ExpectValidateCallerAddr(append(actor.controlAddrs, actor.owner, actor.worker)...)
-		rt.ExpectAbortContainsMessage(exitcode.ErrIllegalArgument, "cannot terminate sectors in immutable deadline", func() {
-			rt.Call(actor.a.TerminateSectors, params)
-		})
+		info := actor.getInfo(rt)
+		assert.Equal(t, otherAddr, info.Owner)
+		assert.Equal(t, otherAddr, info.Beneficiary)
+		assert.Nil(t, info.PendingBeneficiaryTerm)
+	})
+
+	t.Run("beneficiary becoming owner loses its term", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
 
+		actor.changeBeneficiary(rt, actor.owner, beneficiary, quota, expiration)
+		actor.changeBeneficiary(rt, beneficiary, beneficiary, quota, expiration)
+
+		rt.SetCaller(actor.owner, builtin.AccountActorCodeID)
+		actor.changeOwnerAddress(rt, beneficiary)
+		rt.SetCaller(beneficiary, builtin.AccountActorCodeID)
+		actor.changeOwnerAddress(rt, beneficiary)
+
+		ret := actor.getBeneficiary(rt)
+		assert.Equal(t, beneficiary, actor.getInfo(rt).Owner)
+		assert.Equal(t, beneficiary, ret.Active.Beneficiary)
+		assert.Equal(t, miner.NewBeneficiaryTerm(big.Zero(), 0), ret.Active.Term)
 		actor.checkState(rt)
 	})
-
 }
 
-func TestWithdrawBalance(t *testing.T) {
-	periodOffset := abi.ChainEpoch(100)
-	actor := newHarness(t, periodOffset)
+func TestWithdrawBalanceToBeneficiary(t *testing.T) {
+	actor := newHarness(t, abi.ChainEpoch(100))
+	beneficiary := tutil.NewIDAddr(t, 1001)
 	builder := builderForHarness(actor).
-		WithBalance(bigBalance, big.Zero())
-
+		WithBalance(bigBalance, big.Zero()).
+		WithActorType(beneficiary, builtin.AccountActorCodeID)
 	onePercentBalance := big.Div(bigBalance, big.NewInt(100))
+	quota := big.Mul(onePercentBalance, big.NewInt(2))
+	expiration := abi.ChainEpoch(1000)
 
-	t.Run("happy path withdraws funds", func(t *testing.T) {
+	t.Run("beneficiary withdraws up to quota", func(t *testing.T) {
 		rt := builder.Build(t)
 		actor.constructAndVerify(rt)
+		actor.changeBeneficiary(rt, actor.owner, beneficiary, quota, expiration)
+		actor.changeBeneficiary(rt, beneficiary, beneficiary, quota, expiration)
 
-		// withdraw 1% of balance
-		actor.withdrawFunds(rt, onePercentBalance, onePercentBalance, big.Zero())
+		actor.withdrawFundsAs(rt, beneficiary, onePercentBalance, onePercentBalance, big.Zero())
+		assert.Equal(t, onePercentBalance, actor.getInfo(rt).BeneficiaryTerm.UsedQuota)
+
+		// The owner's withdrawals also go to the beneficiary and are limited by the remaining quota.
+		actor.withdrawFundsAs(rt, actor.owner, quota, onePercentBalance, big.Zero())
+		assert.Equal(t, quota, actor.getInfo(rt).BeneficiaryTerm.UsedQuota)
+
+		actor.withdrawFundsAs(rt, beneficiary, onePercentBalance, big.Zero(), big.Zero())
 		actor.checkState(rt)
 	})
 
-	t.Run("fails if miner can't repay fee debt", func(t *testing.T) {
+	t.Run("expired beneficiary withdraws nothing", func(t *testing.T) {
 		rt := builder.Build(t)
 		actor.constructAndVerify(rt)
+		actor.changeBeneficiary(rt, actor.owner, beneficiary, quota, expiration)
+		actor.changeBeneficiary(rt, beneficiary, beneficiary, quota, expiration)
 
-		st := getState(rt)
-		st.FeeDebt = big.Add(rt.Balance(), abi.NewTokenAmount(1e18))
-		rt.ReplaceState(st)
-		rt.ExpectAbortContainsMessage(exitcode.ErrInsufficientFunds, "unlocked balance can not repay fee debt", func() {
-			actor.withdrawFunds(rt, onePercentBalance, onePercentBalance, big.Zero())
-		})
+		rt.SetEpoch(expiration)
+		actor.withdrawFundsAs(rt, beneficiary, onePercentBalance, big.Zero(), big.Zero())
+		assert.Equal(t, big.Zero(), actor.getInfo(rt).BeneficiaryTerm.UsedQuota)
 		actor.checkState(rt)
 	})
 
-	t.Run("withdraw only what we can after fee debt", func(t *testing.T) {
+	t.Run("renewing the term keeps the used quota", func(t *testing.T) {
 		rt := builder.Build(t)
 		actor.constructAndVerify(rt)
+		actor.changeBeneficiary(rt, actor.owner, beneficiary, quota, expiration)
+		actor.changeBeneficiary(rt, beneficiary, beneficiary, quota, expiration)
+		actor.withdrawFundsAs(rt, beneficiary, quota, quota, big.Zero())
+
+		// The beneficiary has no quota left, so need not approve.
+		newQuota := big.Mul(quota, big.NewInt(2))
+		actor.changeBeneficiary(rt, actor.owner, beneficiary, newQuota, expiration)
+		actor.changeBeneficiary(rt, beneficiary, beneficiary, newQuota, expiration)
+		term := actor.getInfo(rt).BeneficiaryTerm
+		assert.Equal(t, newQuota, term.Quota)
+		assert.Equal(t, quota, term.UsedQuota)
+
+		actor.withdrawFundsAs(rt, beneficiary, newQuota, quota, big.Zero())
+		actor.checkState(rt)
+	})
 
-		st := getState(rt)
-		feeDebt := big.Sub(bigBalance, onePercentBalance)
-		st.FeeDebt = feeDebt
-		rt.ReplaceState(st)
+	t.Run("others cannot withdraw", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+		actor.changeBeneficiary(rt, actor.owner, beneficiary, quota, expiration)
 
-		requested := rt.Balance()
-		expectedWithdraw := big.Sub(requested, feeDebt)
-		actor.withdrawFunds(rt, requested, expectedWithdraw, feeDebt)
-		actor.checkState(rt)
+		// Not yet confirmed.
+		rt.ExpectAbort(exitcode.SysErrForbidden, func() {
+			actor.withdrawFundsAs(rt, beneficiary, onePercentBalance, onePercentBalance, big.Zero())
+		})
+		rt.ExpectAbort(exitcode.SysErrForbidden, func() {
+			actor.withdrawFundsAs(rt, actor.worker, onePercentBalance, onePercentBalance, big.Zero())
+		})
 	})
 }
 
@@ -3902,7 +5135,7 @@ func TestChangeWorkerAddress(t *testing.T) {
 		actor.checkState(rt)
 	})
 
-	t.Run("change cannot be overridden", func(t *testing.T) {
+	t.Run("change to a different worker is rejected while one is pending", func(t *testing.T) {
 		rt, actor := setupFunc()
 		actor.constructAndVerify(rt)
 		originalControlAddrs := actor.controlAddrs
@@ -3921,7 +5154,13 @@ func TestChangeWorkerAddress(t *testing.T) {
 		actor.advancePastDeadlineEndWithCron(rt)
 
 		// attempt to change address again
-		actor.changeWorkerAddress(rt, newWorker2, rt.Epoch()+miner.WorkerKeyChangeDelay, originalControlAddrs)
+		rt.ExpectAbort(miner.ErrWorkerKeyChangePending, func() {
+			actor.changeWorkerAddress(rt, newWorker2, rt.Epoch()+miner.WorkerKeyChangeDelay, originalControlAddrs)
+		})
+		rt.Reset()
+
+		// repeating the pending change is permitted, and does not delay it
+		actor.changeWorkerAddress(rt, newWorker1, effectiveEpoch, originalControlAddrs)
 
 		// assert change has not been modified
 		info := actor.getInfo(rt)
@@ -3992,6 +5231,34 @@ func TestChangeWorkerAddress(t *testing.T) {
 		actor.checkState(rt)
 	})
 
+	t.Run("accepts params encoded without control address roles", func(t *testing.T) {
+		rt, actor := setupFunc()
+		actor.constructAndVerify(rt)
+
+		c1 := tutil.NewIDAddr(t, 5001)
+		rt.SetAddressActorType(c1, builtin.AccountActorCodeID)
+
+		// A two-field message, as sent before control address roles were introduced.
+		var buf bytes.Buffer
+		require.NoError(t, cbg.CborWriteHeader(&buf, cbg.MajArray, 2))
+		require.NoError(t, actor.worker.MarshalCBOR(&buf))
+		require.NoError(t, cbg.CborWriteHeader(&buf, cbg.MajArray, 1))
+		require.NoError(t, c1.MarshalCBOR(&buf))
+
+		var params miner.ChangeWorkerAddressParams
+		require.NoError(t, params.UnmarshalCBOR(&buf))
+		assert.Equal(t, actor.worker, params.NewWorker)
+		assert.Equal(t, []addr.Address{c1}, params.NewControlAddrs)
+
+		actor.expectChangeWorkerAddress(rt, actor.worker, params.NewControlAddrs, func() {
+			rt.Call(actor.a.ChangeWorkerAddress, &params)
+		})
+		info := actor.getInfo(rt)
+		assert.Equal(t, []addr.Address{c1}, info.ControlAddresses)
+		assert.Equal(t, miner.AllControlRoles(1), info.ControlAddressRoles)
+		actor.checkState(rt)
+	})
+
 	t.Run("successfully clear all control addresses", func(t *testing.T) {
 		rt, actor := setupFunc()
 		actor.constructAndVerify(rt)
@@ -4116,6 +5383,99 @@ func TestChangeWorkerAddress(t *testing.T) {
 	})
 }
 
+func TestControlAddressRoles(t *testing.T) {
+	periodOffset := abi.ChainEpoch(100)
+	actor := newHarness(t, periodOffset)
+	builder := builderForHarness(actor).
+		WithBalance(bigBalance, big.Zero())
+
+	postAddr := tutil.NewIDAddr(t, 5001)
+	sealAddr := tutil.NewIDAddr(t, 5002)
+
+	setup := func(t *testing.T) *mock.Runtime {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+		rt.SetAddressActorType(postAddr, builtin.AccountActorCodeID)
+		rt.SetAddressActorType(sealAddr, builtin.AccountActorCodeID)
+		return rt
+	}
+
+	t.Run("new control addresses are granted all roles by default", func(t *testing.T) {
+		rt := setup(t)
+		actor.changeWorkerAddress(rt, actor.worker, abi.ChainEpoch(-1), []addr.Address{postAddr, sealAddr})
+
+		info := actor.getInfo(rt)
+		assert.Equal(t, miner.AllControlRoles(2), info.ControlAddressRoles)
+		assert.Equal(t, []addr.Address{postAddr, sealAddr, actor.owner, actor.worker}, info.CallersWithRole(miner.ControlRoleTermination))
+		actor.checkState(rt)
+	})
+
+	t.Run("control addresses may only call methods permitted by their roles", func(t *testing.T) {
+		rt := setup(t)
+		actor.changeWorkerAddressWithRoles(rt, actor.worker, []addr.Address{postAddr, sealAddr},
+			[]miner.ControlRoles{miner.ControlRolePoSt, miner.ControlRoleSealing | miner.ControlRoleFaults})
+
+		info := actor.getInfo(rt)
+		assert.Equal(t, []addr.Address{postAddr, actor.owner, actor.worker}, info.CallersWithRole(miner.ControlRolePoSt))
+		assert.Equal(t, []addr.Address{sealAddr, actor.owner, actor.worker}, info.CallersWithRole(miner.ControlRoleSealing))
+		assert.Equal(t, []addr.Address{sealAddr, actor.owner, actor.worker}, info.CallersWithRole(miner.ControlRoleFaults))
+		assert.Equal(t, []addr.Address{actor.owner, actor.worker}, info.CallersWithRole(miner.ControlRoleTermination))
+
+		// The PoSt address cannot declare faults.
+		rt.SetCaller(postAddr, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(sealAddr, actor.owner, actor.worker)
+		rt.ExpectAbort(exitcode.SysErrForbidden, func() {
+			rt.Call(actor.a.DeclareFaults, &miner.DeclareFaultsParams{})
+		})
+
+		// Nor terminate sectors.
+		rt.SetCaller(postAddr, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(actor.owner, actor.worker)
+		rt.ExpectAbort(exitcode.SysErrForbidden, func() {
+			rt.Call(actor.a.TerminateSectors, &miner.TerminateSectorsParams{})
+		})
+		actor.checkState(rt)
+	})
+
+	t.Run("legacy change keeps the roles of retained control addresses", func(t *testing.T) {
+		rt := setup(t)
+		actor.changeWorkerAddressWithRoles(rt, actor.worker, []addr.Address{postAddr}, []miner.ControlRoles{miner.ControlRolePoSt})
+
+		// Adding a control address without roles leaves the PoSt address restricted, and grants the new one all roles.
+		actor.changeWorkerAddress(rt, actor.worker, abi.ChainEpoch(-1), []addr.Address{sealAddr, postAddr})
+		info := actor.getInfo(rt)
+		assert.Equal(t, []miner.ControlRoles{miner.ControlRolesAll, miner.ControlRolePoSt}, info.ControlAddressRoles)
+		assert.Equal(t, []addr.Address{sealAddr, actor.owner, actor.worker}, info.CallersWithRole(miner.ControlRoleTermination))
+
+		// Removing and re-adding the address forgets its restriction.
+		actor.changeWorkerAddress(rt, actor.worker, abi.ChainEpoch(-1), []addr.Address{sealAddr})
+		actor.changeWorkerAddress(rt, actor.worker, abi.ChainEpoch(-1), []addr.Address{sealAddr, postAddr})
+		assert.Equal(t, miner.AllControlRoles(2), actor.getInfo(rt).ControlAddressRoles)
+		actor.checkState(rt)
+	})
+
+	t.Run("rejects roles not matching control addresses", func(t *testing.T) {
+		rt := setup(t)
+		rt.ExpectAbortContainsMessage(exitcode.ErrIllegalArgument, "1 control address roles for 2 control addresses", func() {
+			actor.changeWorkerAddressWithRoles(rt, actor.worker, []addr.Address{postAddr, sealAddr},
+				[]miner.ControlRoles{miner.ControlRolePoSt})
+		})
+		rt.Reset()
+		actor.checkState(rt)
+	})
+
+	t.Run("rejects empty or unknown roles", func(t *testing.T) {
+		rt := setup(t)
+		for _, roles := range []miner.ControlRoles{0, miner.ControlRolesAll + 1} {
+			rt.ExpectAbortContainsMessage(exitcode.ErrIllegalArgument, "invalid roles", func() {
+				actor.changeWorkerAddressWithRoles(rt, actor.worker, []addr.Address{postAddr}, []miner.ControlRoles{roles})
+			})
+			rt.Reset()
+		}
+		actor.checkState(rt)
+	})
+}
+
 func TestConfirmUpdateWorkerKey(t *testing.T) {
 	periodOffset := abi.ChainEpoch(100)
 	newWorker := tutil.NewIDAddr(t, 999)
@@ -4144,38 +5504,136 @@ func TestConfirmUpdateWorkerKey(t *testing.T) {
 		actor.checkState(rt)
 	})
 
-	t.Run("does nothing before the effective date", func(t *testing.T) {
+	t.Run("does nothing before the effective date", func(t *testing.T) {
+		rt := builder.Build(t)
+		rt.SetEpoch(currentEpoch)
+		actor.constructAndVerify(rt)
+
+		effectiveEpoch := currentEpoch + miner.WorkerKeyChangeDelay
+		actor.changeWorkerAddress(rt, newWorker, effectiveEpoch, actor.controlAddrs)
+
+		// confirm right before the effective epoch
+		rt.SetEpoch(effectiveEpoch - 1)
+		actor.confirmUpdateWorkerKey(rt)
+
+		st := getState(rt)
+		info, err := st.GetInfo(adt.AsStore(rt))
+		require.NoError(t, err)
+		require.Equal(t, actor.worker, info.Worker)
+		require.NotNil(t, info.PendingWorkerKey)
+		actor.checkState(rt)
+	})
+
+	t.Run("records worker key history", func(t *testing.T) {
+		rt := builder.Build(t)
+		rt.SetEpoch(currentEpoch)
+		actor.constructAndVerify(rt)
+
+		info := actor.getInfo(rt)
+		assert.Equal(t, []miner.WorkerKeyRecord{{Worker: actor.worker, EffectiveAt: currentEpoch}}, info.WorkerKeyHistory)
+
+		effectiveEpoch := currentEpoch + miner.WorkerKeyChangeDelay
+		actor.changeWorkerAddress(rt, newWorker, effectiveEpoch, actor.controlAddrs)
+		rt.SetEpoch(effectiveEpoch + 10)
+		actor.confirmUpdateWorkerKey(rt)
+
+		info = actor.getInfo(rt)
+		assert.Equal(t, []miner.WorkerKeyRecord{
+			{Worker: actor.worker, EffectiveAt: currentEpoch},
+			{Worker: newWorker, EffectiveAt: effectiveEpoch + 10},
+		}, info.WorkerKeyHistory)
+		actor.checkState(rt)
+	})
+
+	t.Run("bounds worker key history", func(t *testing.T) {
+		rt := builder.Build(t)
+		rt.SetEpoch(currentEpoch)
+		actor.constructAndVerify(rt)
+
+		var workers []addr.Address
+		for i := 0; i < miner.WorkerKeyHistoryMax+2; i++ {
+			worker := tutil.NewIDAddr(t, uint64(2000+i))
+			workers = append(workers, worker)
+			actor.changeWorkerAddress(rt, worker, rt.Epoch()+miner.WorkerKeyChangeDelay, actor.controlAddrs)
+			rt.SetEpoch(rt.Epoch() + miner.WorkerKeyChangeDelay)
+			actor.confirmUpdateWorkerKey(rt)
+		}
+
+		info := actor.getInfo(rt)
+		require.Len(t, info.WorkerKeyHistory, miner.WorkerKeyHistoryMax)
+		assert.Equal(t, workers[2], info.WorkerKeyHistory[0].Worker)
+		assert.Equal(t, workers[len(workers)-1], info.WorkerKeyHistory[miner.WorkerKeyHistoryMax-1].Worker)
+		assert.Equal(t, rt.Epoch(), info.WorkerKeyHistory[miner.WorkerKeyHistoryMax-1].EffectiveAt)
+		actor.checkState(rt)
+	})
+
+	t.Run("does nothing when no update is set", func(t *testing.T) {
+		rt := builder.Build(t)
+		rt.SetEpoch(currentEpoch)
+		actor.constructAndVerify(rt)
+
+		actor.confirmUpdateWorkerKey(rt)
+
+		st := getState(rt)
+		info, err := st.GetInfo(adt.AsStore(rt))
+		require.NoError(t, err)
+		require.Equal(t, actor.worker, info.Worker)
+		require.Nil(t, info.PendingWorkerKey)
+		actor.checkState(rt)
+	})
+}
+
+func TestCancelChangeWorkerAddress(t *testing.T) {
+	periodOffset := abi.ChainEpoch(100)
+	newWorker := tutil.NewIDAddr(t, 999)
+	otherWorker := tutil.NewIDAddr(t, 1000)
+	currentEpoch := abi.ChainEpoch(5)
+	actor := newHarness(t, periodOffset)
+	builder := builderForHarness(actor).
+		WithBalance(bigBalance, big.Zero())
+
+	t.Run("cancels pending change", func(t *testing.T) {
 		rt := builder.Build(t)
 		rt.SetEpoch(currentEpoch)
 		actor.constructAndVerify(rt)
 
 		effectiveEpoch := currentEpoch + miner.WorkerKeyChangeDelay
 		actor.changeWorkerAddress(rt, newWorker, effectiveEpoch, actor.controlAddrs)
+		actor.cancelChangeWorkerAddress(rt)
+		assert.Nil(t, actor.getInfo(rt).PendingWorkerKey)
 
-		// confirm right before the effective epoch
-		rt.SetEpoch(effectiveEpoch - 1)
+		// The cancelled change is not enacted.
+		rt.SetEpoch(effectiveEpoch)
 		actor.confirmUpdateWorkerKey(rt)
+		assert.Equal(t, actor.worker, actor.getInfo(rt).Worker)
 
-		st := getState(rt)
-		info, err := st.GetInfo(adt.AsStore(rt))
-		require.NoError(t, err)
-		require.Equal(t, actor.worker, info.Worker)
-		require.NotNil(t, info.PendingWorkerKey)
+		// A different change may now be requested.
+		actor.changeWorkerAddress(rt, otherWorker, rt.Epoch()+miner.WorkerKeyChangeDelay, actor.controlAddrs)
+		assert.Equal(t, otherWorker, actor.getInfo(rt).PendingWorkerKey.NewWorker)
 		actor.checkState(rt)
 	})
 
-	t.Run("does nothing when no update is set", func(t *testing.T) {
+	t.Run("fails with no pending change", func(t *testing.T) {
 		rt := builder.Build(t)
-		rt.SetEpoch(currentEpoch)
 		actor.constructAndVerify(rt)
 
-		actor.confirmUpdateWorkerKey(rt)
+		rt.ExpectAbort(exitcode.ErrNotFound, func() {
+			actor.cancelChangeWorkerAddress(rt)
+		})
+		actor.checkState(rt)
+	})
 
-		st := getState(rt)
-		info, err := st.GetInfo(adt.AsStore(rt))
-		require.NoError(t, err)
-		require.Equal(t, actor.worker, info.Worker)
-		require.Nil(t, info.PendingWorkerKey)
+	t.Run("only owner can cancel", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+		actor.changeWorkerAddress(rt, newWorker, rt.Epoch()+miner.WorkerKeyChangeDelay, actor.controlAddrs)
+
+		rt.ExpectValidateCallerAddr(actor.owner)
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		rt.ExpectAbort(exitcode.SysErrForbidden, func() {
+			rt.Call(actor.a.CancelChangeWorkerAddress, nil)
+		})
+		assert.NotNil(t, actor.getInfo(rt).PendingWorkerKey)
 		actor.checkState(rt)
 	})
 }
@@ -4972,6 +6430,19 @@ func (h *actorHarness) collectPartitionExpirations(rt *mock.Runtime, partition *
 	return expirations
 }
 
+func (h *actorHarness) collectPrecommitExpirations(rt *mock.Runtime, st *miner.State) map[abi.ChainEpoch][]uint64 {
+	queue, err := miner.LoadBitfieldQueue(rt.AdtStore(), st.PreCommittedSectorsExpiry, miner.NoQuantization, miner.PrecommitExpiryAmtBitwidth)
+	require.NoError(h.t, err)
+	expirations := map[abi.ChainEpoch][]uint64{}
+	_ = queue.ForEach(func(epoch abi.ChainEpoch, bf bitfield.BitField) error {
+		expanded, err := bf.All(miner.AddressedSectorsMax)
+		require.NoError(h.t, err)
+		expirations[epoch] = expanded
+		return nil
+	})
+	return expirations
+}
+
 func (h *actorHarness) getLockedFunds(rt *mock.Runtime) abi.TokenAmount {
 	st := getState(rt)
 	return st.LockedFunds
@@ -4988,16 +6459,31 @@ func (h *actorHarness) checkState(rt *mock.Runtime) {
 //
 
 func (h *actorHarness) changeWorkerAddress(rt *mock.Runtime, newWorker addr.Address, effectiveEpoch abi.ChainEpoch, newControlAddrs []addr.Address) {
-	rt.SetAddressActorType(newWorker, builtin.AccountActorCodeID)
+	h.expectChangeWorkerAddress(rt, newWorker, newControlAddrs, func() {
+		rt.Call(h.a.ChangeWorkerAddress, &miner.ChangeWorkerAddressParams{
+			NewWorker:       newWorker,
+			NewControlAddrs: newControlAddrs,
+		})
+	})
+}
+
+func (h *actorHarness) changeWorkerAddressWithRoles(rt *mock.Runtime, newWorker addr.Address, newControlAddrs []addr.Address, roles []miner.ControlRoles) {
+	h.expectChangeWorkerAddress(rt, newWorker, newControlAddrs, func() {
+		rt.Call(h.a.ChangeWorkerAddressWithRoles, &miner.ChangeWorkerAddressWithRolesParams{
+			NewWorker:           newWorker,
+			NewControlAddrs:     newControlAddrs,
+			NewControlAddrRoles: roles,
+		})
+	})
+}
 
-	param := &miner.ChangeWorkerAddressParams{}
-	param.NewControlAddrs = newControlAddrs
-	param.NewWorker = newWorker
+func (h *actorHarness) expectChangeWorkerAddress(rt *mock.Runtime, newWorker addr.Address, newControlAddrs []addr.Address, call func()) {
+	rt.SetAddressActorType(newWorker, builtin.AccountActorCodeID)
 	rt.ExpectSend(newWorker, builtin.MethodsAccount.PubkeyAddress, nil, big.Zero(), &h.key, exitcode.Ok)
 
 	rt.ExpectValidateCallerAddr(h.owner)
 	rt.SetCaller(h.owner, builtin.AccountActorCodeID)
-	rt.Call(h.a.ChangeWorkerAddress, param)
+	call()
 	rt.Verify()
 
 	st := getState(rt)
@@ -5021,6 +6507,13 @@ func (h *actorHarness) confirmUpdateWorkerKey(rt *mock.Runtime) {
 	rt.Verify()
 }
 
+func (h *actorHarness) cancelChangeWorkerAddress(rt *mock.Runtime) {
+	rt.ExpectValidateCallerAddr(h.owner)
+	rt.SetCaller(h.owner, builtin.AccountActorCodeID)
+	rt.Call(h.a.CancelChangeWorkerAddress, nil)
+	rt.Verify()
+}
+
 func (h *actorHarness) changeOwnerAddress(rt *mock.Runtime, newAddr addr.Address) {
 	if rt.Caller() == h.owner {
 		rt.ExpectValidateCallerAddr(h.owner)
@@ -5036,6 +6529,29 @@ func (h *actorHarness) changeOwnerAddress(rt *mock.Runtime, newAddr addr.Address
 	rt.Verify()
 }
 
+func (h *actorHarness) changeBeneficiary(rt *mock.Runtime, caller, beneficiary addr.Address, quota abi.TokenAmount, expiration abi.ChainEpoch) {
+	info := h.getInfo(rt)
+	rt.SetCaller(caller, builtin.AccountActorCodeID)
+	if caller == info.Owner || info.PendingBeneficiaryTerm == nil {
+		rt.ExpectValidateCallerAddr(info.Owner)
+	} else {
+		rt.ExpectValidateCallerAddr(info.Beneficiary, info.PendingBeneficiaryTerm.NewBeneficiary)
+	}
+	rt.Call(h.a.ChangeBeneficiary, &miner.ChangeBeneficiaryParams{
+		NewBeneficiary: beneficiary,
+		NewQuota:       quota,
+		NewExpiration:  expiration,
+	})
+	rt.Verify()
+}
+
+func (h *actorHarness) getBeneficiary(rt *mock.Runtime) *miner.GetBeneficiaryReturn {
+	rt.ExpectValidateCallerAny()
+	ret := rt.Call(h.a.GetBeneficiary, nil).(*miner.GetBeneficiaryReturn)
+	rt.Verify()
+	return ret
+}
+
 func (h *actorHarness) checkSectorProven(rt *mock.Runtime, sectorNum abi.SectorNumber) {
 	param := &miner.CheckSectorProvenParams{SectorNumber: sectorNum}
 
@@ -5143,6 +6659,46 @@ func (h *actorHarness) preCommitSector(rt *mock.Runtime, params *miner.PreCommit
 	return h.getPreCommit(rt, params.SectorNumber)
 }
 
+type preCommitBatchConf struct {
+	sectorWeights []market.SectorWeights
+}
+
+func (h *actorHarness) preCommitSectorBatch(rt *mock.Runtime, params *miner.PreCommitSectorBatchParams, conf preCommitBatchConf) []*miner.SectorPreCommitOnChainInfo {
+	rt.SetCaller(h.worker, builtin.AccountActorCodeID)
+	rt.ExpectValidateCallerAddr(append(h.controlAddrs, h.owner, h.worker)...)
+
+	expectQueryNetworkInfo(rt, h)
+
+	sectorDeals := make([]market.SectorDeals, len(params.Sectors))
+	anyDeals := false
+	for i, sector := range params.Sectors {
+		sectorDeals[i] = market.SectorDeals{
+			SectorExpiry: sector.Expiration,
+			DealIDs:      sector.DealIDs,
+		}
+		anyDeals = anyDeals || len(sector.DealIDs) > 0
+	}
+	if anyDeals {
+		vdReturn := market.VerifyDealsForActivationReturn{Sectors: conf.sectorWeights}
+		rt.ExpectSend(builtin.StorageMarketActorAddr, builtin.MethodsMarket.VerifyDealsForActivation,
+			&market.VerifyDealsForActivationParams{Sectors: sectorDeals}, big.Zero(), &vdReturn, exitcode.Ok)
+	}
+
+	st := getState(rt)
+	if st.FeeDebt.GreaterThan(big.Zero()) {
+		rt.ExpectSend(builtin.BurntFundsActorAddr, builtin.MethodSend, nil, st.FeeDebt, nil, exitcode.Ok)
+	}
+
+	rt.Call(h.a.PreCommitSectorBatch, params)
+	rt.Verify()
+
+	precommits := make([]*miner.SectorPreCommitOnChainInfo, len(params.Sectors))
+	for i, sector := range params.Sectors {
+		precommits[i] = h.getPreCommit(rt, sector.SectorNumber)
+	}
+	return precommits
+}
+
 // Options for proveCommitSector behaviour.
 // Default zero values should let everything be ok.
 type proveCommitConf struct {
@@ -5196,15 +6752,81 @@ func (h *actorHarness) proveCommitSector(rt *mock.Runtime, precommit *miner.Sect
 	rt.Verify()
 }
 
+func (h *actorHarness) proveCommitAggregateSector(rt *mock.Runtime, conf proveCommitConf, precommits []*miner.SectorPreCommitOnChainInfo, params *miner.ProveCommitAggregateParams) {
+	h.expectProveCommitAggregate(rt, precommits, params, nil)
+	h.expectConfirmSectorProofsValid(rt, conf, precommits...)
+
+	rt.SetCaller(h.worker, builtin.AccountActorCodeID)
+	rt.Call(h.a.ProveCommitAggregate, params)
+	rt.Verify()
+}
+
+// Sets up the expected caller validation, sends, randomness and aggregate verification for a prove-commit aggregate.
+func (h *actorHarness) expectProveCommitAggregate(rt *mock.Runtime, precommits []*miner.SectorPreCommitOnChainInfo, params *miner.ProveCommitAggregateParams, verifyErr error) {
+	commd := cbg.CborCid(tutil.MakeCID("commd", &market.PieceCIDPrefix))
+	sealRand := abi.SealRandomness([]byte{1, 2, 3, 4})
+	sealIntRand := abi.InteractiveSealRandomness([]byte{5, 6, 7, 8})
+
+	var buf bytes.Buffer
+	receiver := rt.Receiver()
+	err := receiver.MarshalCBOR(&buf)
+	require.NoError(h.t, err)
+
+	rt.ExpectValidateCallerAddr(append(h.controlAddrs, h.owner, h.worker)...)
+	infos := make([]proof.AggregateSealVerifyInfo, len(precommits))
+	for i, precommit := range precommits {
+		cdcParams := market.ComputeDataCommitmentParams{
+			DealIDs:    precommit.Info.DealIDs,
+			SectorType: precommit.Info.SealProof,
+		}
+		rt.ExpectSend(builtin.StorageMarketActorAddr, builtin.MethodsMarket.ComputeDataCommitment, &cdcParams, big.Zero(), &commd, exitcode.Ok)
+
+		interactiveEpoch := precommit.PreCommitEpoch + miner.PreCommitChallengeDelay
+		rt.ExpectGetRandomnessTickets(crypto.DomainSeparationTag_SealRandomness, precommit.Info.SealRandEpoch, buf.Bytes(), abi.Randomness(sealRand))
+		rt.ExpectGetRandomnessBeacon(crypto.DomainSeparationTag_InteractiveSealChallengeSeed, interactiveEpoch, buf.Bytes(), abi.Randomness(sealIntRand))
+
+		infos[i] = proof.AggregateSealVerifyInfo{
+			Number:                precommit.Info.SectorNumber,
+			Randomness:            sealRand,
+			InteractiveRandomness: sealIntRand,
+			SealedCID:             precommit.Info.SealedCID,
+			UnsealedCID:           cid.Cid(commd),
+		}
+	}
+
+	actorId, err := addr.IDFromAddress(h.receiver)
+	require.NoError(h.t, err)
+	rt.ExpectAggregateVerifySeals(proof.AggregateSealVerifyProofAndInfos{
+		Miner:          abi.ActorID(actorId),
+		SealProof:      h.sealProofType,
+		AggregateProof: proof.RegisteredAggregationProof_SnarkPackV1,
+		Proof:          params.AggregateProof,
+		Infos:          infos,
+	}, verifyErr)
+}
+
 func (h *actorHarness) confirmSectorProofsValid(rt *mock.Runtime, conf proveCommitConf, precommits ...*miner.SectorPreCommitOnChainInfo) {
+	h.expectConfirmSectorProofsValid(rt, conf, precommits...)
+
+	var allSectorNumbers []abi.SectorNumber
+	for _, precommit := range precommits {
+		allSectorNumbers = append(allSectorNumbers, precommit.Info.SectorNumber)
+	}
+
+	rt.SetCaller(builtin.StoragePowerActorAddr, builtin.StoragePowerActorCodeID)
+	rt.ExpectValidateCallerAddr(builtin.StoragePowerActorAddr)
+	rt.Call(h.a.ConfirmSectorProofsValid, &builtin.ConfirmSectorProofsParams{Sectors: allSectorNumbers})
+	rt.Verify()
+}
+
+// Sets up the expected sends for activation of the given proven pre-committed sectors.
+func (h *actorHarness) expectConfirmSectorProofsValid(rt *mock.Runtime, conf proveCommitConf, precommits ...*miner.SectorPreCommitOnChainInfo) {
 	// expect calls to get network stats
 	expectQueryNetworkInfo(rt, h)
 
 	// Prepare for and receive call to ConfirmSectorProofsValid.
 	var validPrecommits []*miner.SectorPreCommitOnChainInfo
-	var allSectorNumbers []abi.SectorNumber
 	for _, precommit := range precommits {
-		allSectorNumbers = append(allSectorNumbers, precommit.Info.SectorNumber)
 		validPrecommits = append(validPrecommits, precommit)
 		if len(precommit.Info.DealIDs) > 0 {
 			vdParams := market.ActivateDealsParams{
@@ -5256,11 +6878,6 @@ func (h *actorHarness) confirmSectorProofsValid(rt *mock.Runtime, conf proveComm
 			rt.ExpectSend(builtin.StoragePowerActorAddr, builtin.MethodsPower.UpdatePledgeTotal, &expectPledge, big.Zero(), nil, exitcode.Ok)
 		}
 	}
-
-	rt.SetCaller(builtin.StoragePowerActorAddr, builtin.StoragePowerActorCodeID)
-	rt.ExpectValidateCallerAddr(builtin.StoragePowerActorAddr)
-	rt.Call(h.a.ConfirmSectorProofsValid, &builtin.ConfirmSectorProofsParams{Sectors: allSectorNumbers})
-	rt.Verify()
 }
 
 func (h *actorHarness) proveCommitSectorAndConfirm(rt *mock.Runtime, precommit *miner.SectorPreCommitOnChainInfo,
@@ -5679,6 +7296,67 @@ func (h *actorHarness) declareRecoveries(rt *mock.Runtime, deadlineIdx uint64, p
 	rt.Verify()
 }
 
+type replicaUpdateConf struct {
+	unsealedCID cid.Cid // Data commitment returned by the market, defaults to the update's unsealed CID.
+	verifyErr   error   // Result of replica update proof verification.
+}
+
+func (h *actorHarness) proveReplicaUpdate(rt *mock.Runtime, update miner.ReplicaUpdate, weights market.SectorWeights, conf replicaUpdateConf) {
+	rt.SetCaller(h.worker, builtin.AccountActorCodeID)
+	rt.ExpectValidateCallerAddr(append(h.controlAddrs, h.owner, h.worker)...)
+
+	oldSector := h.getSector(rt, update.SectorNumber)
+	rt.ExpectSend(builtin.StorageMarketActorAddr, builtin.MethodsMarket.VerifyDealsForActivation,
+		&market.VerifyDealsForActivationParams{Sectors: []market.SectorDeals{{
+			SectorExpiry: oldSector.Expiration,
+			DealIDs:      update.Deals,
+		}}},
+		big.Zero(), &market.VerifyDealsForActivationReturn{Sectors: []market.SectorWeights{weights}}, exitcode.Ok)
+
+	commD := conf.unsealedCID
+	if !commD.Defined() {
+		commD = update.NewUnsealedCID
+	}
+	cbgCommD := cbg.CborCid(commD)
+	rt.ExpectSend(builtin.StorageMarketActorAddr, builtin.MethodsMarket.ComputeDataCommitment,
+		&market.ComputeDataCommitmentParams{DealIDs: update.Deals, SectorType: oldSector.SealProof},
+		big.Zero(), &cbgCommD, exitcode.Ok)
+
+	if commD.Equals(update.NewUnsealedCID) {
+		rt.ExpectReplicaUpdate(proof.ReplicaUpdateInfo{
+			UpdateProofType:      update.UpdateProofType,
+			NewSealedSectorCID:   update.NewSealedCID,
+			OldSealedSectorCID:   oldSector.SealedCID,
+			NewUnsealedSectorCID: update.NewUnsealedCID,
+			Proof:                update.ReplicaProof,
+		}, conf.verifyErr)
+	}
+
+	if conf.verifyErr == nil && commD.Equals(update.NewUnsealedCID) {
+		rt.ExpectSend(builtin.StorageMarketActorAddr, builtin.MethodsMarket.ActivateDeals,
+			&market.ActivateDealsParams{DealIDs: update.Deals, SectorExpiry: oldSector.Expiration},
+			big.Zero(), nil, exitcode.Ok)
+		expectQueryNetworkInfo(rt, h)
+
+		newQAPower := miner.QAPowerForWeight(h.sectorSize, oldSector.Expiration-rt.Epoch(), weights.DealWeight, weights.VerifiedDealWeight)
+		qaDelta := big.Sub(newQAPower, miner.QAPowerForSector(h.sectorSize, oldSector))
+		if !qaDelta.IsZero() {
+			rt.ExpectSend(builtin.StoragePowerActorAddr, builtin.MethodsPower.UpdateClaimedPower,
+				&power.UpdateClaimedPowerParams{RawByteDelta: big.Zero(), QualityAdjustedDelta: qaDelta},
+				big.Zero(), nil, exitcode.Ok)
+		}
+		newPledge := big.Max(oldSector.InitialPledge, miner.InitialPledgeForPower(newQAPower, h.baselinePower,
+			h.epochRewardSmooth, h.epochQAPowerSmooth, rt.TotalFilCircSupply()))
+		pledgeDelta := big.Sub(newPledge, oldSector.InitialPledge)
+		if !pledgeDelta.IsZero() {
+			rt.ExpectSend(builtin.StoragePowerActorAddr, builtin.MethodsPower.UpdatePledgeTotal, &pledgeDelta, big.Zero(), nil, exitcode.Ok)
+		}
+	}
+
+	rt.Call(h.a.ProveReplicaUpdates, &miner.ProveReplicaUpdatesParams{Updates: []miner.ReplicaUpdate{update}})
+	rt.Verify()
+}
+
 func (h *actorHarness) extendSectors(rt *mock.Runtime, params *miner.ExtendSectorExpirationParams) {
 	rt.SetCaller(h.worker, builtin.AccountActorCodeID)
 	rt.ExpectValidateCallerAddr(append(h.controlAddrs, h.owner, h.worker)...)
@@ -5713,6 +7391,131 @@ func (h *actorHarness) extendSectors(rt *mock.Runtime, params *miner.ExtendSecto
 	rt.Verify()
 }
 
+func (h *actorHarness) extendSectors2(rt *mock.Runtime, params *miner.ExtendSectorExpiration2Params) *miner.ExtendSectorExpiration2Return {
+	rt.SetCaller(h.worker, builtin.AccountActorCodeID)
+	rt.ExpectValidateCallerAddr(append(h.controlAddrs, h.owner, h.worker)...)
+
+	qaDelta := big.Zero()
+	for _, extension := range params.Extensions {
+		err := extension.Sectors.ForEach(func(sno uint64) error {
+			sector := h.getSector(rt, abi.SectorNumber(sno))
+			newSector := *sector
+			newSector.Expiration = extension.NewExpiration
+			drop, err := extension.DropVerified.IsSet(sno)
+			require.NoError(h.t, err)
+			scale := func(weight abi.DealWeight) abi.DealWeight {
+				return big.Div(
+					big.Mul(weight, big.NewInt(int64(newSector.Expiration-newSector.Activation))),
+					big.NewInt(int64(sector.Expiration-sector.Activation)),
+				)
+			}
+			newSector.DealWeight = scale(sector.DealWeight)
+			if drop {
+				newSector.VerifiedDealWeight = big.Zero()
+			} else {
+				newSector.VerifiedDealWeight = scale(sector.VerifiedDealWeight)
+			}
+			qaDelta = big.Sum(qaDelta,
+				miner.QAPowerForSector(h.sectorSize, &newSector),
+				miner.QAPowerForSector(h.sectorSize, sector).Neg(),
+			)
+			return nil
+		})
+		require.NoError(h.t, err)
+	}
+	if !qaDelta.IsZero() {
+		rt.ExpectSend(builtin.StoragePowerActorAddr,
+			builtin.MethodsPower.UpdateClaimedPower,
+			&power.UpdateClaimedPowerParams{
+				RawByteDelta:         big.Zero(),
+				QualityAdjustedDelta: qaDelta,
+			},
+			abi.NewTokenAmount(0),
+			nil,
+			exitcode.Ok,
+		)
+	}
+	ret := rt.Call(h.a.ExtendSectorExpiration2, params).(*miner.ExtendSectorExpiration2Return)
+	rt.Verify()
+	return ret
+}
+
+func (h *actorHarness) renewSectorDeal(rt *mock.Runtime, params *miner.RenewSectorDealParams, sectorExpiry abi.ChainEpoch, renewed *market.RenewDealReturn) {
+	rt.SetCaller(h.worker, builtin.AccountActorCodeID)
+	rt.ExpectValidateCallerAddr(append(h.controlAddrs, h.owner, h.worker)...)
+
+	sector := h.getSector(rt, params.Sector)
+	rt.ExpectSend(builtin.StorageMarketActorAddr, builtin.MethodsMarket.RenewDeal,
+		&market.RenewDealParams{Renewal: params.Renewal, SectorExpiry: sectorExpiry},
+		abi.NewTokenAmount(0), renewed, exitcode.Ok)
+	expectQueryNetworkInfo(rt, h)
+
+	newSector := *sector
+	newSector.DealWeight = big.Add(sector.DealWeight, renewed.DealWeightDelta)
+	newSector.VerifiedDealWeight = big.Add(sector.VerifiedDealWeight, renewed.VerifiedDealWeightDelta)
+	newQAPower := miner.QAPowerForSector(h.sectorSize, &newSector)
+	qaDelta := big.Sub(newQAPower, miner.QAPowerForSector(h.sectorSize, sector))
+	if !qaDelta.IsZero() {
+		rt.ExpectSend(builtin.StoragePowerActorAddr,
+			builtin.MethodsPower.UpdateClaimedPower,
+			&power.UpdateClaimedPowerParams{
+				RawByteDelta:         big.Zero(),
+				QualityAdjustedDelta: qaDelta,
+			},
+			abi.NewTokenAmount(0),
+			nil,
+			exitcode.Ok,
+		)
+	}
+	newPledge := big.Max(sector.InitialPledge, miner.InitialPledgeForPower(newQAPower, h.baselinePower,
+		h.epochRewardSmooth, h.epochQAPowerSmooth, rt.TotalFilCircSupply()))
+	pledgeDelta := big.Sub(newPledge, sector.InitialPledge)
+	if !pledgeDelta.IsZero() {
+		rt.ExpectSend(builtin.StoragePowerActorAddr, builtin.MethodsPower.UpdatePledgeTotal, &pledgeDelta, big.Zero(), nil, exitcode.Ok)
+	}
+	ret := rt.Call(h.a.RenewSectorDeal, params)
+	require.Nil(h.t, ret)
+	rt.Verify()
+}
+
+func (h *actorHarness) previewTerminateSectors(rt *mock.Runtime, sectors bitfield.BitField) *miner.PreviewTerminateSectorsReturn {
+	st := getState(rt)
+	deadlines, err := st.LoadDeadlines(rt.AdtStore())
+	require.NoError(h.t, err)
+
+	declarations := []miner.TerminationDeclaration{}
+	err = sectors.ForEach(func(id uint64) error {
+		dlIdx, pIdx, err := miner.FindSector(rt.AdtStore(), deadlines, abi.SectorNumber(id))
+		require.NoError(h.t, err)
+		declarations = append(declarations, miner.TerminationDeclaration{
+			Deadline:  dlIdx,
+			Partition: pIdx,
+			Sectors:   bf(id),
+		})
+		return nil
+	})
+	require.NoError(h.t, err)
+
+	rt.SetCaller(h.worker, builtin.AccountActorCodeID)
+	rt.ExpectValidateCallerAny()
+	expectQueryNetworkInfo(rt, h)
+	ret := rt.Call(h.a.PreviewTerminateSectors, &miner.TerminateSectorsParams{Terminations: declarations}).(*miner.PreviewTerminateSectorsReturn)
+	rt.Verify()
+	return ret
+}
+
+func (h *actorHarness) previewWindowedPoSt(rt *mock.Runtime, dlIdx uint64, partitions []miner.PoStPartition) *miner.PreviewWindowedPoStReturn {
+	rt.SetCaller(h.worker, builtin.AccountActorCodeID)
+	rt.ExpectValidateCallerAny()
+	expectQueryNetworkInfo(rt, h)
+	ret := rt.Call(h.a.PreviewWindowedPoSt, &miner.PreviewWindowedPoStParams{
+		Deadline:   dlIdx,
+		Partitions: partitions,
+	}).(*miner.PreviewWindowedPoStReturn)
+	rt.Verify()
+	return ret
+}
+
 func (h *actorHarness) terminateSectors(rt *mock.Runtime, sectors bitfield.BitField, expectedFee abi.TokenAmount) (miner.PowerPair, abi.TokenAmount) {
 	rt.SetCaller(h.worker, builtin.AccountActorCodeID)
 	rt.ExpectValidateCallerAddr(append(h.controlAddrs, h.owner, h.worker)...)
@@ -5946,10 +7749,17 @@ func (h *actorHarness) onDeadlineCron(rt *mock.Runtime, config *cronConfig) {
 }
 
 func (h *actorHarness) withdrawFunds(rt *mock.Runtime, amountRequested, amountWithdrawn, expectedDebtRepaid abi.TokenAmount) {
-	rt.SetCaller(h.owner, builtin.AccountActorCodeID)
-	rt.ExpectValidateCallerAddr(h.owner)
+	h.withdrawFundsAs(rt, h.owner, amountRequested, amountWithdrawn, expectedDebtRepaid)
+}
 
-	rt.ExpectSend(h.owner, builtin.MethodSend, nil, amountWithdrawn, nil, exitcode.Ok)
+func (h *actorHarness) withdrawFundsAs(rt *mock.Runtime, caller addr.Address, amountRequested, amountWithdrawn, expectedDebtRepaid abi.TokenAmount) {
+	info := h.getInfo(rt)
+	rt.SetCaller(caller, builtin.AccountActorCodeID)
+	rt.ExpectValidateCallerAddr(info.Owner, info.Beneficiary)
+
+	if amountWithdrawn.GreaterThan(big.Zero()) {
+		rt.ExpectSend(info.Beneficiary, builtin.MethodSend, nil, amountWithdrawn, nil, exitcode.Ok)
+	}
 	if expectedDebtRepaid.GreaterThan(big.Zero()) {
 		rt.ExpectSend(builtin.BurntFundsActorAddr, builtin.MethodSend, nil, expectedDebtRepaid, nil, exitcode.Ok)
 	}
@@ -6071,6 +7881,18 @@ func advanceToEpochWithCron(rt *mock.Runtime, h *actorHarness, e abi.ChainEpoch)
 	rt.SetEpoch(e)
 }
 
+// Advances deadlines until the deadline at an index may be modified.
+func advanceToMutableDeadline(rt *mock.Runtime, h *actorHarness, dlIdx uint64) {
+	for {
+		st := getState(rt)
+		dlInfo := miner.NewDeadlineInfo(st.ProvingPeriodStart, dlIdx, rt.Epoch()).NextNotElapsed()
+		if rt.Epoch() < dlInfo.Open-miner.WPoStChallengeWindow {
+			return
+		}
+		advanceDeadline(rt, h, &cronConfig{})
+	}
+}
+
 func advanceAndSubmitPoSts(rt *mock.Runtime, h *actorHarness, sectors ...*miner.SectorOnChainInfo) {
 	st := getState(rt)
 
@@ -10,6 +10,7 @@ import (
 	mh "github.com/multiformats/go-multihash"
 
 	"github.com/filecoin-project/specs-actors/v3/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v3/actors/runtime/proof"
 )
 
 // The period over which a miner's active sectors are expected to be proven via WindowPoSt.
@@ -100,6 +101,24 @@ const MaxProveCommitSize = 10240
 // Maximum size of a single prove-commit proof, in bytes (the expected size is 192).
 const MaxPoStProofSize = 1024
 
+// Maximum number of sectors that may be pre-committed in a single PreCommitSectorBatch message.
+const PreCommitSectorBatchMaxSize = 256
+
+// Maximum number of sectors whose seal proofs may be aggregated in a single ProveCommitAggregate message.
+const MaxAggregatedSectors = 819
+
+// Minimum number of sectors whose seal proofs may be aggregated; fewer should be proven individually.
+const MinAggregatedSectors = 4
+
+// Maximum size of an aggregated seal proof, in bytes.
+const MaxAggregateProofSize = 81960
+
+// Maximum number of sectors that may be updated in a single ProveReplicaUpdates message.
+const ProveReplicaUpdatesMaxSize = PreCommitSectorBatchMaxSize
+
+// Maximum size of a replica update proof, in bytes.
+const MaxReplicaUpdateProofSize = 4096
+
 // Maximum number of control addresses a miner may register.
 const MaxControlAddresses = 10
 
@@ -165,6 +184,15 @@ func CanExtendSealProofType(s abi.RegisteredSealProof) bool {
 	return ok
 }
 
+// The replica update proof type for each seal proof type whose sectors may be updated with data in place.
+var ReplicaUpdateProofTypes = map[abi.RegisteredSealProof]proof.RegisteredUpdateProof{
+	abi.RegisteredSealProof_StackedDrg2KiBV1_1:   proof.RegisteredUpdateProof_StackedDrg2KiBV1,
+	abi.RegisteredSealProof_StackedDrg8MiBV1_1:   proof.RegisteredUpdateProof_StackedDrg8MiBV1,
+	abi.RegisteredSealProof_StackedDrg512MiBV1_1: proof.RegisteredUpdateProof_StackedDrg512MiBV1,
+	abi.RegisteredSealProof_StackedDrg32GiBV1_1:  proof.RegisteredUpdateProof_StackedDrg32GiBV1,
+	abi.RegisteredSealProof_StackedDrg64GiBV1_1:  proof.RegisteredUpdateProof_StackedDrg64GiBV1,
+}
+
 // Maximum delay to allow between sector pre-commit and subsequent proof.
 // The allowable delay depends on seal proof algorithm.
 var MaxProveCommitDuration = map[abi.RegisteredSealProof]abi.ChainEpoch{
@@ -211,6 +239,9 @@ var FaultMaxAge = WPoStProvingPeriod * 14 // PARAM_SPEC
 // This delay prevents a miner choosing a more favorable worker key that wins leader elections.
 const WorkerKeyChangeDelay = ChainFinality // PARAM_SPEC
 
+// Maximum number of entries in a miner's worker key history.
+const WorkerKeyHistoryMax = 16
+
 // Minimum number of epochs past the current epoch a sector may be set to expire.
 const MinSectorExpiration = 180 * builtin.EpochsInDay // PARAM_SPEC
 
@@ -219,6 +250,11 @@ const MinSectorExpiration = 180 * builtin.EpochsInDay // PARAM_SPEC
 // the associated seal proof's maximum lifetime.
 const MaxSectorExpirationExtension = 540 * builtin.EpochsInDay // PARAM_SPEC
 
+// The maximum number of epochs after a sector's activation for which its verified deal weight may be retained
+// when its expiration is extended with ExtendSectorExpiration2. This bounds the term for which a verified client's
+// data counts as verified, matching the maximum duration of a deal.
+const VerifiedDealMaxTerm = 540 * builtin.EpochsInDay
+
 // Ratio of sector size to maximum number of deals per sector.
 // The maximum number of deals is the sector size divided by this number (2^27)
 // which limits 32GiB sectors to 256 deals and 64GiB sectors to 512
@@ -0,0 +1,384 @@
+// Package query provides read-only views over miner actor state for off-chain tooling.
+// Nothing in this package mutates state or is invoked by the actor itself.
+package query
+
+import (
+	"sort"
+
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/v3/actors/util/adt"
+)
+
+// The status of a sector within its partition.
+type SectorStatus int
+
+const (
+	SectorActive SectorStatus = iota
+	SectorFaulty
+	SectorRecovering
+	SectorTerminated
+	SectorUnproven
+)
+
+func (s SectorStatus) String() string {
+	switch s {
+	case SectorActive:
+		return "active"
+	case SectorFaulty:
+		return "faulty"
+	case SectorRecovering:
+		return "recovering"
+	case SectorTerminated:
+		return "terminated"
+	case SectorUnproven:
+		return "unproven"
+	default:
+		return "unknown"
+	}
+}
+
+// The deadline and partition to which a sector is assigned.
+type SectorLocation struct {
+	Deadline  uint64
+	Partition uint64
+}
+
+// Returns the location of a sector, or an error if it is not assigned to any partition.
+func LocateSector(store adt.Store, st *miner.State, sno abi.SectorNumber) (SectorLocation, error) {
+	dlIdx, pIdx, err := st.FindSector(store, sno)
+	if err != nil {
+		return SectorLocation{}, err
+	}
+	return SectorLocation{Deadline: dlIdx, Partition: pIdx}, nil
+}
+
+// Returns the location and status of a sector.
+// A terminated sector is reported only until it is removed from its partition.
+func GetSectorStatus(store adt.Store, st *miner.State, sno abi.SectorNumber) (SectorLocation, SectorStatus, error) {
+	loc, err := LocateSector(store, st, sno)
+	if err != nil {
+		return SectorLocation{}, 0, err
+	}
+	partition, err := loadPartition(store, st, loc.Deadline, loc.Partition)
+	if err != nil {
+		return SectorLocation{}, 0, err
+	}
+
+	// Recoveries are a subset of faults, so must be checked first.
+	for _, check := range []struct {
+		bf     bitfield.BitField
+		status SectorStatus
+	}{
+		{partition.Terminated, SectorTerminated},
+		{partition.Unproven, SectorUnproven},
+		{partition.Recoveries, SectorRecovering},
+		{partition.Faults, SectorFaulty},
+	} {
+		if set, err := check.bf.IsSet(uint64(sno)); err != nil {
+			return SectorLocation{}, 0, xerrors.Errorf("failed to check sector %d: %w", sno, err)
+		} else if set {
+			return loc, check.status, nil
+		}
+	}
+	return loc, SectorActive, nil
+}
+
+// The epochs at which a sector is scheduled to expire.
+// Early is zero unless the sector is faulty and scheduled to terminate early.
+// Both epochs are quantized to the end of the sector's deadline.
+type SectorExpiration struct {
+	OnTime abi.ChainEpoch
+	Early  abi.ChainEpoch
+}
+
+// Returns the scheduled expiration of a live sector, as recorded in its partition's expiration queue.
+func GetSectorExpiration(store adt.Store, st *miner.State, sno abi.SectorNumber) (*SectorExpiration, error) {
+	loc, err := LocateSector(store, st, sno)
+	if err != nil {
+		return nil, err
+	}
+	partition, err := loadPartition(store, st, loc.Deadline, loc.Partition)
+	if err != nil {
+		return nil, err
+	}
+	queue, err := miner.LoadExpirationQueue(store, partition.ExpirationsEpochs, st.QuantSpecForDeadline(loc.Deadline), miner.PartitionExpirationAmtBitwidth)
+	if err != nil {
+		return nil, err
+	}
+
+	var result SectorExpiration
+	var es miner.ExpirationSet
+	if err := queue.ForEach(&es, func(epoch int64) error {
+		if result.OnTime == 0 {
+			if onTime, err := es.OnTimeSectors.IsSet(uint64(sno)); err != nil {
+				return err
+			} else if onTime {
+				result.OnTime = abi.ChainEpoch(epoch)
+			}
+		}
+		if result.Early == 0 {
+			if early, err := es.EarlySectors.IsSet(uint64(sno)); err != nil {
+				return err
+			} else if early {
+				result.Early = abi.ChainEpoch(epoch)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, xerrors.Errorf("failed to iterate expiration queue: %w", err)
+	}
+	if result.OnTime == 0 && result.Early == 0 {
+		return nil, xerrors.Errorf("sector %d not found in expiration queue of deadline %d partition %d", sno, loc.Deadline, loc.Partition)
+	}
+	return &result, nil
+}
+
+// A summary of the sectors and power in a single partition.
+type PartitionSummary struct {
+	Deadline  uint64
+	Partition uint64
+
+	AllSectors        bitfield.BitField // All sectors, including terminated ones not yet removed.
+	LiveSectors       bitfield.BitField
+	ActiveSectors     bitfield.BitField
+	FaultySectors     bitfield.BitField
+	RecoveringSectors bitfield.BitField
+	TerminatedSectors bitfield.BitField
+	UnprovenSectors   bitfield.BitField
+
+	LivePower       miner.PowerPair
+	ActivePower     miner.PowerPair
+	FaultyPower     miner.PowerPair
+	RecoveringPower miner.PowerPair
+	UnprovenPower   miner.PowerPair
+}
+
+// Returns summaries of every partition in a deadline, ordered by partition index.
+func DeadlinePartitions(store adt.Store, st *miner.State, dlIdx uint64) ([]PartitionSummary, error) {
+	deadlines, err := st.LoadDeadlines(store)
+	if err != nil {
+		return nil, err
+	}
+	deadline, err := deadlines.LoadDeadline(store, dlIdx)
+	if err != nil {
+		return nil, err
+	}
+	return summarizePartitions(store, dlIdx, deadline)
+}
+
+// Returns summaries of every partition in every deadline, ordered by deadline then partition index.
+func AllPartitions(store adt.Store, st *miner.State) ([]PartitionSummary, error) {
+	deadlines, err := st.LoadDeadlines(store)
+	if err != nil {
+		return nil, err
+	}
+	var summaries []PartitionSummary
+	if err := deadlines.ForEach(store, func(dlIdx uint64, deadline *miner.Deadline) error {
+		dlSummaries, err := summarizePartitions(store, dlIdx, deadline)
+		if err != nil {
+			return err
+		}
+		summaries = append(summaries, dlSummaries...)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
+
+// An aggregate of the expiration queue entries of all partitions at a single epoch.
+type ExpirationEntry struct {
+	Epoch         abi.ChainEpoch
+	OnTimeSectors bitfield.BitField
+	EarlySectors  bitfield.BitField
+	OnTimePledge  abi.TokenAmount
+	ActivePower   miner.PowerPair
+	FaultyPower   miner.PowerPair
+}
+
+// Projects the expiration schedule of all partitions up to and including an epoch, ordered by epoch.
+// The schedule includes entries that are overdue but not yet processed by the deadline cron.
+func ExpirationSchedule(store adt.Store, st *miner.State, until abi.ChainEpoch) ([]ExpirationEntry, error) {
+	deadlines, err := st.LoadDeadlines(store)
+	if err != nil {
+		return nil, err
+	}
+
+	byEpoch := map[abi.ChainEpoch]*miner.ExpirationSet{}
+	if err := deadlines.ForEach(store, func(dlIdx uint64, deadline *miner.Deadline) error {
+		quant := st.QuantSpecForDeadline(dlIdx)
+		return forEachPartition(store, deadline, func(_ uint64, partition *miner.Partition) error {
+			queue, err := miner.LoadExpirationQueue(store, partition.ExpirationsEpochs, quant, miner.PartitionExpirationAmtBitwidth)
+			if err != nil {
+				return err
+			}
+			var es miner.ExpirationSet
+			return queue.ForEach(&es, func(e int64) error {
+				epoch := abi.ChainEpoch(e)
+				if epoch > until {
+					return nil
+				}
+				acc, ok := byEpoch[epoch]
+				if !ok {
+					acc = miner.NewExpirationSetEmpty()
+					byEpoch[epoch] = acc
+				}
+				return acc.Add(es.OnTimeSectors, es.EarlySectors, es.OnTimePledge, es.ActivePower, es.FaultyPower)
+			})
+		})
+	}); err != nil {
+		return nil, xerrors.Errorf("failed to iterate expiration queues: %w", err)
+	}
+
+	epochs := make([]abi.ChainEpoch, 0, len(byEpoch))
+	for epoch := range byEpoch { //nolint:nomaprange
+		epochs = append(epochs, epoch)
+	}
+	sort.Slice(epochs, func(i, j int) bool {
+		return epochs[i] < epochs[j]
+	})
+	entries := make([]ExpirationEntry, 0, len(epochs))
+	for _, epoch := range epochs {
+		es := byEpoch[epoch]
+		entries = append(entries, ExpirationEntry{
+			Epoch:         epoch,
+			OnTimeSectors: es.OnTimeSectors,
+			EarlySectors:  es.EarlySectors,
+			OnTimePledge:  es.OnTimePledge,
+			ActivePower:   es.ActivePower,
+			FaultyPower:   es.FaultyPower,
+		})
+	}
+	return entries, nil
+}
+
+// Returns the vesting table entries with vesting epochs up to and including an epoch, ordered by epoch.
+// Funds in an entry are unlocked at the first epoch after the entry's epoch.
+func VestingSchedule(store adt.Store, st *miner.State, until abi.ChainEpoch) ([]miner.VestingFund, error) {
+	funds, err := st.LoadVestingFunds(store)
+	if err != nil {
+		return nil, err
+	}
+	var schedule []miner.VestingFund
+	for _, vf := range funds.Funds {
+		if vf.Epoch > until {
+			break
+		}
+		schedule = append(schedule, vf)
+	}
+	return schedule, nil
+}
+
+// Returns the total amount of locked funds with vesting epochs in the range [from, until).
+func VestingBetween(store adt.Store, st *miner.State, from, until abi.ChainEpoch) (abi.TokenAmount, error) {
+	funds, err := st.LoadVestingFunds(store)
+	if err != nil {
+		return big.Zero(), err
+	}
+	total := big.Zero()
+	for _, vf := range funds.Funds {
+		if vf.Epoch >= from && vf.Epoch < until {
+			total = big.Add(total, vf.Amount)
+		}
+	}
+	return total, nil
+}
+
+// A breakdown of a miner's balance by the purposes for which it is held.
+type BalanceBreakdown struct {
+	Balance           abi.TokenAmount // The actor's total balance.
+	VestingFunds      abi.TokenAmount // Locked funds not yet vested as of the query epoch.
+	VestedFunds       abi.TokenAmount // Locked funds vested as of the query epoch, but not yet unlocked.
+	PreCommitDeposits abi.TokenAmount
+	InitialPledge     abi.TokenAmount
+	FeeDebt           abi.TokenAmount
+	// Balance less locked funds, pre-commit deposits and initial pledge.
+	Unlocked abi.TokenAmount
+	// Unlocked balance plus vested funds, less fee debt: the amount that may be withdrawn at the query epoch.
+	// May be negative if the miner is in debt.
+	Available abi.TokenAmount
+}
+
+// Breaks down a miner's balance as of an epoch.
+func Balances(store adt.Store, st *miner.State, balance abi.TokenAmount, currEpoch abi.ChainEpoch) (*BalanceBreakdown, error) {
+	vested, err := st.CheckVestedFunds(store, currEpoch)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to check vested funds: %w", err)
+	}
+	unlocked, err := st.GetUnlockedBalance(balance)
+	if err != nil {
+		return nil, err
+	}
+	return &BalanceBreakdown{
+		Balance:           balance,
+		VestingFunds:      big.Sub(st.LockedFunds, vested),
+		VestedFunds:       vested,
+		PreCommitDeposits: st.PreCommitDeposits,
+		InitialPledge:     st.InitialPledge,
+		FeeDebt:           st.FeeDebt,
+		Unlocked:          unlocked,
+		Available:         big.Sub(big.Add(unlocked, vested), st.FeeDebt),
+	}, nil
+}
+
+func loadPartition(store adt.Store, st *miner.State, dlIdx, pIdx uint64) (*miner.Partition, error) {
+	deadlines, err := st.LoadDeadlines(store)
+	if err != nil {
+		return nil, err
+	}
+	deadline, err := deadlines.LoadDeadline(store, dlIdx)
+	if err != nil {
+		return nil, err
+	}
+	return deadline.LoadPartition(store, pIdx)
+}
+
+func forEachPartition(store adt.Store, deadline *miner.Deadline, f func(pIdx uint64, partition *miner.Partition) error) error {
+	partitions, err := deadline.PartitionsArray(store)
+	if err != nil {
+		return err
+	}
+	var partition miner.Partition
+	return partitions.ForEach(&partition, func(i int64) error {
+		return f(uint64(i), &partition)
+	})
+}
+
+func summarizePartitions(store adt.Store, dlIdx uint64, deadline *miner.Deadline) ([]PartitionSummary, error) {
+	var summaries []PartitionSummary
+	if err := forEachPartition(store, deadline, func(pIdx uint64, partition *miner.Partition) error {
+		live, err := partition.LiveSectors()
+		if err != nil {
+			return err
+		}
+		active, err := partition.ActiveSectors()
+		if err != nil {
+			return err
+		}
+		summaries = append(summaries, PartitionSummary{
+			Deadline:          dlIdx,
+			Partition:         pIdx,
+			AllSectors:        partition.Sectors,
+			LiveSectors:       live,
+			ActiveSectors:     active,
+			FaultySectors:     partition.Faults,
+			RecoveringSectors: partition.Recoveries,
+			TerminatedSectors: partition.Terminated,
+			UnprovenSectors:   partition.Unproven,
+			LivePower:         partition.LivePower,
+			ActivePower:       partition.ActivePower(),
+			FaultyPower:       partition.FaultyPower,
+			RecoveringPower:   partition.RecoveringPower,
+			UnprovenPower:     partition.UnprovenPower,
+		})
+		return nil
+	}); err != nil {
+		return nil, xerrors.Errorf("failed to summarize partitions of deadline %d: %w", dlIdx, err)
+	}
+	return summaries, nil
+}
@@ -0,0 +1,309 @@
+package query_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/miner/query"
+	"github.com/filecoin-project/specs-actors/v3/actors/util/adt"
+	"github.com/filecoin-project/specs-actors/v3/support/ipld"
+	tutil "github.com/filecoin-project/specs-actors/v3/support/testing"
+)
+
+const sealProof = abi.RegisteredSealProof_StackedDrg32GiBV1_1
+
+func TestSectorStatus(t *testing.T) {
+	f := newFixture(t)
+	f.addSectors(1, 2, 3, 4)
+	f.prove(1, 2, 3, 4)
+	f.addSectors(5)
+	f.fault(2, 3)
+	f.recover(3)
+	f.terminate(4)
+	f.checkState()
+
+	for sno, expected := range map[abi.SectorNumber]query.SectorStatus{
+		1: query.SectorActive,
+		2: query.SectorFaulty,
+		3: query.SectorRecovering,
+		4: query.SectorTerminated,
+		5: query.SectorUnproven,
+	} {
+		loc, status, err := query.GetSectorStatus(f.store, f.st, sno)
+		require.NoError(t, err)
+		assert.Equal(t, expected, status, "sector %d is %s", sno, status)
+		assert.Equal(t, f.location(sno), loc)
+	}
+
+	_, _, err := query.GetSectorStatus(f.store, f.st, 99)
+	assert.Error(t, err)
+}
+
+func TestPartitionSummaries(t *testing.T) {
+	f := newFixture(t)
+	f.addSectors(1, 2, 3)
+	f.prove(1, 2, 3)
+	f.fault(2)
+	f.checkState()
+
+	all, err := query.AllPartitions(f.store, f.st)
+	require.NoError(t, err)
+
+	faulty := bitfield.New()
+	total := 0
+	activePower := miner.NewPowerPairZero()
+	faultyPower := miner.NewPowerPairZero()
+	for _, summary := range all {
+		dlSummaries, err := query.DeadlinePartitions(f.store, f.st, summary.Deadline)
+		require.NoError(t, err)
+		require.Len(t, dlSummaries, 1)
+		assert.Equal(t, summary.ActivePower, dlSummaries[0].ActivePower)
+
+		count, err := summary.AllSectors.Count()
+		require.NoError(t, err)
+		total += int(count)
+		faulty, err = bitfield.MergeBitFields(faulty, summary.FaultySectors)
+		require.NoError(t, err)
+		activePower = activePower.Add(summary.ActivePower)
+		faultyPower = faultyPower.Add(summary.FaultyPower)
+	}
+	assert.Equal(t, 3, total)
+	faults, err := faulty.All(10)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{2}, faults)
+
+	sectorPower := miner.PowerForSector(f.sectorSize, f.sectors[1])
+	assert.Equal(t, sectorPower.Add(sectorPower), activePower)
+	assert.Equal(t, sectorPower, faultyPower)
+}
+
+func TestExpirationSchedule(t *testing.T) {
+	f := newFixture(t)
+	f.addSectors(1, 2, 3)
+	f.prove(1, 2, 3)
+	f.checkState()
+
+	entries, err := query.ExpirationSchedule(f.store, f.st, abi.ChainEpoch(1<<40))
+	require.NoError(t, err)
+	expiring := 0
+	pledge := big.Zero()
+	for i, entry := range entries {
+		if i > 0 {
+			assert.Greater(t, int64(entry.Epoch), int64(entries[i-1].Epoch))
+		}
+		count, err := entry.OnTimeSectors.Count()
+		require.NoError(t, err)
+		expiring += int(count)
+		pledge = big.Add(pledge, entry.OnTimePledge)
+	}
+	assert.Equal(t, 3, expiring)
+	assert.Equal(t, f.st.InitialPledge, pledge)
+
+	// Only the first sector expires by its expiration epoch, quantized to the end of its deadline.
+	exp, err := query.GetSectorExpiration(f.store, f.st, 1)
+	require.NoError(t, err)
+	assert.Equal(t, f.st.QuantSpecForDeadline(f.location(1).Deadline).QuantizeUp(f.sectors[1].Expiration), exp.OnTime)
+	assert.Equal(t, abi.ChainEpoch(0), exp.Early)
+
+	entries, err = query.ExpirationSchedule(f.store, f.st, exp.OnTime)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	onTime, err := entries[0].OnTimeSectors.All(10)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{1}, onTime)
+}
+
+func TestVestingAndBalances(t *testing.T) {
+	f := newFixture(t)
+	f.addSectors(1)
+	f.prove(1)
+
+	locked := abi.NewTokenAmount(1_000_000)
+	_, err := f.st.AddLockedFunds(f.store, 0, locked, &miner.RewardVestingSpec)
+	require.NoError(t, err)
+	f.balance = big.Sum(f.balance, locked, abi.NewTokenAmount(500))
+	f.checkState()
+
+	horizon := miner.RewardVestingSpec.InitialDelay + miner.RewardVestingSpec.VestPeriod + miner.RewardVestingSpec.Quantization
+	schedule, err := query.VestingSchedule(f.store, f.st, horizon)
+	require.NoError(t, err)
+	require.NotEmpty(t, schedule)
+	total := big.Zero()
+	for _, vf := range schedule {
+		total = big.Add(total, vf.Amount)
+	}
+	assert.Equal(t, locked, total)
+
+	// Nothing vests during the initial delay.
+	early, err := query.VestingBetween(f.store, f.st, 0, miner.RewardVestingSpec.InitialDelay)
+	require.NoError(t, err)
+	assert.True(t, early.IsZero())
+	all, err := query.VestingBetween(f.store, f.st, 0, horizon+1)
+	require.NoError(t, err)
+	assert.Equal(t, locked, all)
+
+	// Halfway through, some funds have vested and are available to withdraw.
+	midEpoch := schedule[len(schedule)/2].Epoch + 1
+	balances, err := query.Balances(f.store, f.st, f.balance, midEpoch)
+	require.NoError(t, err)
+	vested, err := query.VestingBetween(f.store, f.st, 0, midEpoch)
+	require.NoError(t, err)
+	assert.Equal(t, vested, balances.VestedFunds)
+	assert.Equal(t, big.Sub(locked, vested), balances.VestingFunds)
+	assert.Equal(t, f.st.InitialPledge, balances.InitialPledge)
+	assert.Equal(t, abi.NewTokenAmount(500), balances.Unlocked)
+	assert.Equal(t, big.Add(abi.NewTokenAmount(500), vested), balances.Available)
+
+	// Querying does not unlock anything.
+	assert.Equal(t, locked, f.st.LockedFunds)
+}
+
+type fixture struct {
+	t             *testing.T
+	store         adt.Store
+	st            *miner.State
+	balance       abi.TokenAmount
+	sectorSize    abi.SectorSize
+	partitionSize uint64
+	sectors       map[abi.SectorNumber]*miner.SectorOnChainInfo
+}
+
+func newFixture(t *testing.T) *fixture {
+	store := ipld.NewADTStore(context.Background())
+	postProof, err := sealProof.RegisteredWindowPoStProof()
+	require.NoError(t, err)
+	sectorSize, err := sealProof.SectorSize()
+	require.NoError(t, err)
+	partitionSize, err := builtin.SealProofWindowPoStPartitionSectors(sealProof)
+	require.NoError(t, err)
+
+	owner := tutil.NewIDAddr(t, 100)
+	info := miner.MinerInfo{
+		Owner:                      owner,
+		Worker:                     tutil.NewIDAddr(t, 101),
+		PeerId:                     abi.PeerID("peer"),
+		WindowPoStProofType:        postProof,
+		SectorSize:                 sectorSize,
+		WindowPoStPartitionSectors: partitionSize,
+		Beneficiary:                owner,
+		BeneficiaryTerm:            miner.NewBeneficiaryTerm(big.Zero(), 0),
+	}
+	infoCid, err := store.Put(context.Background(), &info)
+	require.NoError(t, err)
+	st, err := miner.ConstructState(store, infoCid, 0, 0)
+	require.NoError(t, err)
+
+	return &fixture{
+		t:             t,
+		store:         store,
+		st:            st,
+		balance:       big.Zero(),
+		sectorSize:    sectorSize,
+		partitionSize: partitionSize,
+		sectors:       map[abi.SectorNumber]*miner.SectorOnChainInfo{},
+	}
+}
+
+// Adds unproven sectors, each expiring one proving period later than the last.
+func (f *fixture) addSectors(snos ...abi.SectorNumber) {
+	var infos []*miner.SectorOnChainInfo
+	for _, sno := range snos {
+		require.NoError(f.t, f.st.AllocateSectorNumber(f.store, sno))
+		info := &miner.SectorOnChainInfo{
+			SectorNumber:          sno,
+			SealProof:             sealProof,
+			SealedCID:             tutil.MakeCID(sno.String(), &miner.SealedCIDPrefix),
+			Activation:            0,
+			Expiration:            miner.MinSectorExpiration + abi.ChainEpoch(sno)*miner.WPoStProvingPeriod,
+			DealWeight:            big.Zero(),
+			VerifiedDealWeight:    big.Zero(),
+			InitialPledge:         abi.NewTokenAmount(1000 * int64(sno)),
+			ExpectedDayReward:     big.Zero(),
+			ExpectedStoragePledge: big.Zero(),
+			ReplacedDayReward:     big.Zero(),
+		}
+		f.sectors[sno] = info
+		infos = append(infos, info)
+		require.NoError(f.t, f.st.AddInitialPledge(info.InitialPledge))
+		f.balance = big.Add(f.balance, info.InitialPledge)
+	}
+	require.NoError(f.t, f.st.PutSectors(f.store, infos...))
+	require.NoError(f.t, f.st.AssignSectorsToDeadlines(f.store, 0, infos, f.partitionSize, f.sectorSize))
+}
+
+func (f *fixture) prove(snos ...abi.SectorNumber) {
+	f.mutate(snos, func(dl *miner.Deadline, sectors miner.Sectors, quant miner.QuantSpec, pm miner.PartitionSectorMap) error {
+		var postPartitions []miner.PoStPartition
+		for _, pIdx := range pm.Partitions() {
+			postPartitions = append(postPartitions, miner.PoStPartition{Index: pIdx, Skipped: bitfield.New()})
+		}
+		_, err := dl.RecordProvenSectors(f.store, sectors, f.sectorSize, quant, 0, postPartitions)
+		return err
+	})
+}
+
+func (f *fixture) fault(snos ...abi.SectorNumber) {
+	f.mutate(snos, func(dl *miner.Deadline, sectors miner.Sectors, quant miner.QuantSpec, pm miner.PartitionSectorMap) error {
+		_, err := dl.RecordFaults(f.store, sectors, f.sectorSize, quant, quant.QuantizeUp(miner.FaultMaxAge), pm)
+		return err
+	})
+}
+
+func (f *fixture) recover(snos ...abi.SectorNumber) {
+	f.mutate(snos, func(dl *miner.Deadline, sectors miner.Sectors, quant miner.QuantSpec, pm miner.PartitionSectorMap) error {
+		return dl.DeclareFaultsRecovered(f.store, sectors, f.sectorSize, pm)
+	})
+}
+
+func (f *fixture) terminate(snos ...abi.SectorNumber) {
+	f.mutate(snos, func(dl *miner.Deadline, sectors miner.Sectors, quant miner.QuantSpec, pm miner.PartitionSectorMap) error {
+		_, err := dl.TerminateSectors(f.store, sectors, 1, pm, f.sectorSize, quant)
+		return err
+	})
+	for _, sno := range snos {
+		f.st.EarlyTerminations.Set(f.location(sno).Deadline)
+	}
+}
+
+// Applies a deadline mutation to the given sectors, grouped by deadline.
+func (f *fixture) mutate(snos []abi.SectorNumber, op func(dl *miner.Deadline, sectors miner.Sectors, quant miner.QuantSpec, pm miner.PartitionSectorMap) error) {
+	dm := make(miner.DeadlineSectorMap)
+	for _, sno := range snos {
+		loc := f.location(sno)
+		require.NoError(f.t, dm.AddValues(loc.Deadline, loc.Partition, uint64(sno)))
+	}
+	sectors, err := miner.LoadSectors(f.store, f.st.Sectors)
+	require.NoError(f.t, err)
+	deadlines, err := f.st.LoadDeadlines(f.store)
+	require.NoError(f.t, err)
+	require.NoError(f.t, dm.ForEach(func(dlIdx uint64, pm miner.PartitionSectorMap) error {
+		dl, err := deadlines.LoadDeadline(f.store, dlIdx)
+		if err != nil {
+			return err
+		}
+		if err := op(dl, sectors, f.st.QuantSpecForDeadline(dlIdx), pm); err != nil {
+			return err
+		}
+		return deadlines.UpdateDeadline(f.store, dlIdx, dl)
+	}))
+	require.NoError(f.t, f.st.SaveDeadlines(f.store, deadlines))
+}
+
+func (f *fixture) location(sno abi.SectorNumber) query.SectorLocation {
+	loc, err := query.LocateSector(f.store, f.st, sno)
+	require.NoError(f.t, err)
+	return loc
+}
+
+func (f *fixture) checkState() {
+	_, msgs := miner.CheckStateInvariants(f.st, f.store, f.balance)
+	assert.Empty(f.t, msgs.Messages(), msgs.Messages())
+}
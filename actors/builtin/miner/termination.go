@@ -5,6 +5,13 @@ import (
 
 	"github.com/filecoin-project/go-bitfield"
 	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	xc "github.com/filecoin-project/go-state-types/exitcode"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/specs-actors/v3/actors/util"
+	"github.com/filecoin-project/specs-actors/v3/actors/util/adt"
+	"github.com/filecoin-project/specs-actors/v3/actors/util/smoothing"
 )
 
 type TerminationResult struct {
@@ -62,3 +69,82 @@ func (t *TerminationResult) ForEach(cb func(epoch abi.ChainEpoch, sectors bitfie
 	}
 	return nil
 }
+
+// Computes the fees that would be charged and the pledge that would be released by terminating sectors at an epoch,
+// given network reward and power estimates, without mutating state.
+// The fees are computed as when processing early terminations, but are not limited by the miner's available funds.
+// Only live sectors may be included, but the deadlines need not be mutable.
+func PreviewTerminationFees(store adt.Store, st *State, currEpoch abi.ChainEpoch,
+	rewardEstimate, networkQAPowerEstimate smoothing.FilterEstimate, params *TerminateSectorsParams) (*PreviewTerminateSectorsReturn, error) {
+	toProcess := make(DeadlineSectorMap)
+	for _, term := range params.Terminations {
+		if err := toProcess.Add(term.Deadline, term.Partition, term.Sectors); err != nil {
+			return nil, xc.ErrIllegalArgument.Wrapf("failed to process deadline %d, partition %d: %w", term.Deadline, term.Partition, err)
+		}
+	}
+
+	info, err := st.GetInfo(store)
+	if err != nil {
+		return nil, err
+	}
+	deadlines, err := st.LoadDeadlines(store)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to load deadlines: %w", err)
+	}
+	sectors, err := LoadSectors(store, st.Sectors)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to load sectors: %w", err)
+	}
+
+	ret := &PreviewTerminateSectorsReturn{
+		TotalFee:    big.Zero(),
+		TotalPledge: big.Zero(),
+	}
+	if err := toProcess.ForEach(func(dlIdx uint64, partitionSectors PartitionSectorMap) error {
+		if dlIdx >= WPoStPeriodDeadlines {
+			return xc.ErrIllegalArgument.Wrapf("invalid deadline %d", dlIdx)
+		}
+		deadline, err := deadlines.LoadDeadline(store, dlIdx)
+		if err != nil {
+			return xerrors.Errorf("failed to load deadline %d: %w", dlIdx, err)
+		}
+		return partitionSectors.ForEach(func(partIdx uint64, sectorNos bitfield.BitField) error {
+			partition, err := deadline.LoadPartition(store, partIdx)
+			if err != nil {
+				return xc.ErrIllegalArgument.Wrapf("failed to load partition %d in deadline %d: %w", partIdx, dlIdx, err)
+			}
+			live, err := partition.LiveSectors()
+			if err != nil {
+				return err
+			}
+			if contains, err := util.BitFieldContainsAll(live, sectorNos); err != nil {
+				return xc.ErrIllegalArgument.Wrapf("failed to intersect live sectors with terminating sectors: %w", err)
+			} else if !contains {
+				return xc.ErrIllegalArgument.Wrapf("can only terminate live sectors in deadline %d partition %d", dlIdx, partIdx)
+			}
+
+			infos, err := sectors.Load(sectorNos)
+			if err != nil {
+				return err
+			}
+			for _, sector := range infos {
+				fee := terminationPenalty(info.SectorSize, currEpoch, rewardEstimate, networkQAPowerEstimate, []*SectorOnChainInfo{sector})
+				ret.Sectors = append(ret.Sectors, SectorTerminationFee{
+					SectorNumber: sector.SectorNumber,
+					Fee:          fee,
+					Pledge:       sector.InitialPledge,
+				})
+				ret.TotalFee = big.Add(ret.TotalFee, fee)
+				ret.TotalPledge = big.Add(ret.TotalPledge, sector.InitialPledge)
+			}
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(ret.Sectors, func(i, j int) bool {
+		return ret.Sectors[i].SectorNumber < ret.Sectors[j].SectorNumber
+	})
+	return ret, nil
+}
@@ -686,6 +686,11 @@ func CheckMinerInfo(info *MinerInfo, acc *builtin.MessageAccumulator) {
 	for _, a := range info.ControlAddresses {
 		acc.Require(a.Protocol() == addr.ID, "control address %v is not an ID address", a)
 	}
+	acc.Require(len(info.ControlAddressRoles) == len(info.ControlAddresses),
+		"%d control address roles for %d control addresses", len(info.ControlAddressRoles), len(info.ControlAddresses))
+	for i, roles := range info.ControlAddressRoles {
+		acc.Require(roles != 0 && roles&^ControlRolesAll == 0, "invalid roles %b for control address %d", roles, i)
+	}
 
 	if info.PendingWorkerKey != nil {
 		acc.Require(info.PendingWorkerKey.NewWorker.Protocol() == addr.ID,
@@ -694,6 +699,20 @@ func CheckMinerInfo(info *MinerInfo, acc *builtin.MessageAccumulator) {
 			"pending worker key %v is same as existing worker %v", info.PendingWorkerKey.NewWorker, info.Worker)
 	}
 
+	acc.Require(len(info.WorkerKeyHistory) <= WorkerKeyHistoryMax,
+		"worker key history length %d exceeds maximum %d", len(info.WorkerKeyHistory), WorkerKeyHistoryMax)
+	for i, record := range info.WorkerKeyHistory {
+		acc.Require(record.Worker.Protocol() == addr.ID, "worker key history address %v is not an ID address", record.Worker)
+		if i > 0 {
+			acc.Require(record.EffectiveAt >= info.WorkerKeyHistory[i-1].EffectiveAt,
+				"worker key history epoch %d precedes previous entry %d", record.EffectiveAt, info.WorkerKeyHistory[i-1].EffectiveAt)
+		}
+	}
+	if len(info.WorkerKeyHistory) > 0 {
+		last := info.WorkerKeyHistory[len(info.WorkerKeyHistory)-1]
+		acc.Require(last.Worker == info.Worker, "latest worker key history entry %v is not current worker %v", last.Worker, info.Worker)
+	}
+
 	if info.PendingOwnerAddress != nil {
 		acc.Require(info.PendingOwnerAddress.Protocol() == addr.ID,
 			"pending owner address %v is not an ID address", info.PendingOwnerAddress)
@@ -701,6 +720,18 @@ func CheckMinerInfo(info *MinerInfo, acc *builtin.MessageAccumulator) {
 			"pending owner address %v is same as existing owner %v", info.PendingOwnerAddress, info.Owner)
 	}
 
+	acc.Require(info.Beneficiary.Protocol() == addr.ID, "beneficiary address %v is not an ID address", info.Beneficiary)
+	acc.Require(info.BeneficiaryTerm.UsedQuota.GreaterThanEqual(big.Zero()),
+		"beneficiary used quota %v is negative", info.BeneficiaryTerm.UsedQuota)
+	if info.Beneficiary == info.Owner {
+		acc.Require(info.BeneficiaryTerm.Quota.IsZero() && info.BeneficiaryTerm.Expiration == 0,
+			"owner as beneficiary has non-zero term %v", info.BeneficiaryTerm)
+	}
+	if info.PendingBeneficiaryTerm != nil {
+		acc.Require(info.PendingBeneficiaryTerm.NewBeneficiary.Protocol() == addr.ID,
+			"pending beneficiary address %v is not an ID address", info.PendingBeneficiaryTerm.NewBeneficiary)
+	}
+
 	windowPoStProofInfo, found := abi.PoStProofInfos[info.WindowPoStProofType]
 	acc.Require(found, "miner has unrecognized Window PoSt proof type %d", info.WindowPoStProofType)
 	if found {
@@ -1,10 +1,16 @@
 package nv10
 
 import (
+	"bytes"
 	"context"
+	"unicode/utf8"
 
+	amt2 "github.com/filecoin-project/go-amt-ipld/v2"
+	amt3 "github.com/filecoin-project/go-amt-ipld/v3"
+	"github.com/filecoin-project/go-state-types/abi"
 	cid "github.com/ipfs/go-cid"
 	cbor "github.com/ipfs/go-ipld-cbor"
+	cbg "github.com/whyrusleeping/cbor-gen"
 
 	market2 "github.com/filecoin-project/specs-actors/v2/actors/builtin/market"
 	adt2 "github.com/filecoin-project/specs-actors/v2/actors/util/adt"
@@ -14,7 +20,10 @@ import (
 	adt3 "github.com/filecoin-project/specs-actors/v3/actors/util/adt"
 )
 
-type marketMigrator struct{}
+type marketMigrator struct {
+	// IDs of the deals included in sectors precommitted, but not yet proven, before the upgrade.
+	precommittedDeals []abi.DealID
+}
 
 func (m marketMigrator) migrateState(ctx context.Context, store cbor.IpldStore, in actorMigrationInput) (*actorMigrationResult, error) {
 	var inState market2.State
@@ -22,11 +31,11 @@ func (m marketMigrator) migrateState(ctx context.Context, store cbor.IpldStore,
 		return nil, err
 	}
 
-	pendingProposalsCidOut, err := m.MapPendingProposals(ctx, store, inState.PendingProposals)
+	proposalsCidOut, relabelled, err := m.migrateProposals(ctx, store, inState.Proposals)
 	if err != nil {
 		return nil, err
 	}
-	proposalsCidOut, err := migrateAMTRaw(ctx, store, inState.Proposals, market3.ProposalsAmtBitwidth)
+	pendingProposalsCidOut, err := m.MapPendingProposals(ctx, store, inState.PendingProposals, relabelled)
 	if err != nil {
 		return nil, err
 	}
@@ -47,6 +56,11 @@ func (m marketMigrator) migrateState(ctx context.Context, store cbor.IpldStore,
 		return nil, err
 	}
 
+	precommittedDealsCidOut, err := m.migratePrecommittedDeals(ctx, store, proposalsCidOut, statesCidOut)
+	if err != nil {
+		return nil, err
+	}
+
 	outState := market3.State{
 		Proposals:                     proposalsCidOut,
 		States:                        statesCidOut,
@@ -59,6 +73,7 @@ func (m marketMigrator) migrateState(ctx context.Context, store cbor.IpldStore,
 		TotalClientLockedCollateral:   inState.TotalClientLockedCollateral,
 		TotalProviderLockedCollateral: inState.TotalProviderLockedCollateral,
 		TotalClientStorageFee:         inState.TotalClientStorageFee,
+		PrecommittedDeals:             precommittedDealsCidOut,
 	}
 
 	newHead, err := store.Put(ctx, &outState)
@@ -72,7 +87,100 @@ func (m marketMigrator) migratedCodeCID() cid.Cid {
 	return builtin3.StorageMarketActorCodeID
 }
 
-func (a marketMigrator) MapPendingProposals(ctx context.Context, store cbor.IpldStore, pendingProposalsRoot cid.Cid) (cid.Cid, error) {
+// Records the deals in sectors precommitted before the upgrade, so that their clients may no longer cancel them
+// alone. Deals that have since been activated, or whose proposals have been removed, are not recorded.
+func (m marketMigrator) migratePrecommittedDeals(ctx context.Context, store cbor.IpldStore, proposalsRoot, statesRoot cid.Cid) (cid.Cid, error) {
+	adtStore := adt3.WrapStore(ctx, store)
+	proposals, err := market3.AsDealProposalArray(adtStore, proposalsRoot)
+	if err != nil {
+		return cid.Undef, err
+	}
+	states, err := market3.AsDealStateArray(adtStore, statesRoot)
+	if err != nil {
+		return cid.Undef, err
+	}
+	precommitted, err := adt3.MakeEmptySet(adtStore, builtin3.DefaultHamtBitwidth)
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	for _, dealID := range m.precommittedDeals {
+		if _, found, err := proposals.Get(dealID); err != nil {
+			return cid.Undef, err
+		} else if !found {
+			continue
+		}
+		if _, found, err := states.Get(dealID); err != nil {
+			return cid.Undef, err
+		} else if found {
+			continue
+		}
+		if err := precommitted.Put(abi.UIntKey(uint64(dealID))); err != nil {
+			return cid.Undef, err
+		}
+	}
+	return precommitted.Root()
+}
+
+// Migrates deal proposals, converting any label that is not valid UTF-8 into a bytes label.
+// Such labels could be encoded as strings by v2 but cannot be decoded by v3. Converting a label changes the
+// proposal's CID, so the CIDs of converted proposals are returned keyed by their previous CID.
+// Other proposals are copied without re-encoding.
+func (m marketMigrator) migrateProposals(ctx context.Context, store cbor.IpldStore, root cid.Cid) (cid.Cid, map[cid.Cid]cid.Cid, error) {
+	inRootNode, err := amt2.LoadAMT(ctx, store, root)
+	if err != nil {
+		return cid.Undef, nil, err
+	}
+
+	newOpts := append(adt3.DefaultAmtOptions, amt3.UseTreeBitWidth(uint(market3.ProposalsAmtBitwidth)))
+	outRootNode, err := amt3.NewAMT(store, newOpts...)
+	if err != nil {
+		return cid.Undef, nil, err
+	}
+
+	relabelled := make(map[cid.Cid]cid.Cid)
+	if err = inRootNode.ForEach(ctx, func(k uint64, d *cbg.Deferred) error {
+		var inProposal market2.DealProposal
+		if err := inProposal.UnmarshalCBOR(bytes.NewReader(d.Raw)); err != nil {
+			return err
+		}
+		if utf8.ValidString(inProposal.Label) {
+			return outRootNode.Set(ctx, k, d)
+		}
+
+		outProposal := market3.DealProposal{
+			PieceCID:             inProposal.PieceCID,
+			PieceSize:            inProposal.PieceSize,
+			VerifiedDeal:         inProposal.VerifiedDeal,
+			Client:               inProposal.Client,
+			Provider:             inProposal.Provider,
+			Label:                market3.LabelFromLegacyString(inProposal.Label),
+			StartEpoch:           inProposal.StartEpoch,
+			EndEpoch:             inProposal.EndEpoch,
+			StoragePricePerEpoch: inProposal.StoragePricePerEpoch,
+			ProviderCollateral:   inProposal.ProviderCollateral,
+			ClientCollateral:     inProposal.ClientCollateral,
+		}
+		inCid, err := inProposal.Cid()
+		if err != nil {
+			return err
+		}
+		outCid, err := outProposal.Cid()
+		if err != nil {
+			return err
+		}
+		relabelled[inCid] = outCid
+		return outRootNode.Set(ctx, k, &outProposal)
+	}); err != nil {
+		return cid.Undef, nil, err
+	}
+
+	outRoot, err := outRootNode.Flush(ctx)
+	return outRoot, relabelled, err
+}
+
+// Migrates the set of pending proposal CIDs, replacing the CIDs of proposals whose labels were converted.
+func (a marketMigrator) MapPendingProposals(ctx context.Context, store cbor.IpldStore, pendingProposalsRoot cid.Cid, relabelled map[cid.Cid]cid.Cid) (cid.Cid, error) {
 	oldPendingProposals, err := adt2.AsMap(adt2.WrapStore(ctx, store), pendingProposalsRoot)
 	if err != nil {
 		return cid.Undef, err
@@ -84,6 +192,15 @@ func (a marketMigrator) MapPendingProposals(ctx context.Context, store cbor.Ipld
 	}
 
 	err = oldPendingProposals.ForEach(nil, func(key string) error {
+		if len(relabelled) > 0 {
+			proposalCid, err := cid.Cast([]byte(key))
+			if err != nil {
+				return err
+			}
+			if outCid, ok := relabelled[proposalCid]; ok {
+				return newPendingProposals.Put(abi.CidKey(outCid))
+			}
+		}
 		return newPendingProposals.Put(StringKey(key))
 	})
 	if err != nil {
@@ -3,12 +3,17 @@ package nv10
 import (
 	"context"
 
+	address "github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
 	cid "github.com/ipfs/go-cid"
 	cbor "github.com/ipfs/go-ipld-cbor"
 	"golang.org/x/xerrors"
 
+	builtin2 "github.com/filecoin-project/specs-actors/v2/actors/builtin"
 	miner2 "github.com/filecoin-project/specs-actors/v2/actors/builtin/miner"
+	states2 "github.com/filecoin-project/specs-actors/v2/actors/states"
 	adt2 "github.com/filecoin-project/specs-actors/v2/actors/util/adt"
 
 	builtin3 "github.com/filecoin-project/specs-actors/v3/actors/builtin"
@@ -108,6 +113,10 @@ func (m *minerMigrator) migrateInfo(ctx context.Context, store cbor.IpldStore, c
 		WindowPoStPartitionSectors: oldInfo.WindowPoStPartitionSectors,
 		ConsensusFaultElapsed:      oldInfo.ConsensusFaultElapsed,
 		PendingOwnerAddress:        oldInfo.PendingOwnerAddress,
+		Beneficiary:                oldInfo.Owner,
+		BeneficiaryTerm:            miner3.NewBeneficiaryTerm(big.Zero(), 0),
+		PendingBeneficiaryTerm:     nil,
+		ControlAddressRoles:        miner3.AllControlRoles(len(oldInfo.ControlAddresses)),
 	}
 	return store.Put(ctx, &newInfo)
 }
@@ -226,3 +235,29 @@ func (m *minerMigrator) migratePartitions(ctx context.Context, store cbor.IpldSt
 
 	return outArray.Root()
 }
+
+// Collects the IDs of the deals in every miner's precommitted sectors, in order of miner address
+// and then sector number.
+func collectPrecommittedDeals(ctx context.Context, store cbor.IpldStore, actorsIn *states2.Tree) ([]abi.DealID, error) {
+	adtStore := adt2.WrapStore(ctx, store)
+	var dealIDs []abi.DealID
+	err := actorsIn.ForEach(func(addr address.Address, actor *states2.Actor) error {
+		if !actor.Code.Equals(builtin2.StorageMinerActorCodeID) {
+			return nil
+		}
+		var st miner2.State
+		if err := store.Get(ctx, actor.Head, &st); err != nil {
+			return err
+		}
+		precommits, err := adt2.AsMap(adtStore, st.PreCommittedSectors)
+		if err != nil {
+			return xerrors.Errorf("failed to load precommitted sectors of miner %v: %w", addr, err)
+		}
+		var precommit miner2.SectorPreCommitOnChainInfo
+		return precommits.ForEach(&precommit, func(_ string) error {
+			dealIDs = append(dealIDs, precommit.Info.DealIDs...)
+			return nil
+		})
+	})
+	return dealIDs, err
+}
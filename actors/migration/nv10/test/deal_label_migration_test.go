@@ -0,0 +1,119 @@
+package test_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/rt"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	builtin2 "github.com/filecoin-project/specs-actors/v2/actors/builtin"
+	market2 "github.com/filecoin-project/specs-actors/v2/actors/builtin/market"
+	power2 "github.com/filecoin-project/specs-actors/v2/actors/builtin/power"
+	ipld2 "github.com/filecoin-project/specs-actors/v2/support/ipld"
+	vm2 "github.com/filecoin-project/specs-actors/v2/support/vm"
+
+	builtin3 "github.com/filecoin-project/specs-actors/v3/actors/builtin"
+	exported3 "github.com/filecoin-project/specs-actors/v3/actors/builtin/exported"
+	market3 "github.com/filecoin-project/specs-actors/v3/actors/builtin/market"
+	"github.com/filecoin-project/specs-actors/v3/actors/migration/nv10"
+	states3 "github.com/filecoin-project/specs-actors/v3/actors/states"
+	adt3 "github.com/filecoin-project/specs-actors/v3/actors/util/adt"
+	vm3 "github.com/filecoin-project/specs-actors/v3/support/vm"
+)
+
+func TestDealLabelMigration(t *testing.T) {
+	ctx := context.Background()
+	log := TestLogger{t}
+	v := vm2.NewVMWithSingletons(ctx, t, ipld2.NewSyncBlockStoreInMemory())
+	addrs := vm2.CreateAccounts(ctx, t, v, 2, big.Mul(big.NewInt(100_000), vm2.FIL), 93837778)
+	worker, client := addrs[0], addrs[1]
+
+	ret := vm2.ApplyOk(t, v, worker, builtin2.StoragePowerActorAddr, big.Mul(big.NewInt(10_000), vm2.FIL), builtin2.MethodsPower.CreateMiner, &power2.CreateMinerParams{
+		Owner:         worker,
+		Worker:        worker,
+		SealProofType: abi.RegisteredSealProof_StackedDrg32GiBV1_1,
+		Peer:          abi.PeerID("not really a peer id"),
+	})
+	minerAddrs, ok := ret.(*power2.CreateMinerReturn)
+	require.True(t, ok)
+
+	vm2.ApplyOk(t, v, client, builtin2.StorageMarketActorAddr, big.Mul(big.NewInt(30), vm2.FIL), builtin2.MethodsMarket.AddBalance, &client)
+	vm2.ApplyOk(t, v, worker, builtin2.StorageMarketActorAddr, big.Mul(big.NewInt(64), vm2.FIL), builtin2.MethodsMarket.AddBalance, &minerAddrs.IDAddress)
+
+	// v2 accepts labels that are not valid UTF-8.
+	dealStart := abi.ChainEpoch(252)
+	validLabel := "valid"
+	invalidLabel := "invalid\xff\xfe"
+	validID := publishDeal(t, v, worker, client, minerAddrs.IDAddress, validLabel, 1<<26, false, dealStart, 210*builtin2.EpochsInDay).IDs[0]
+	invalidID := publishDeal(t, v, worker, client, minerAddrs.IDAddress, invalidLabel, 1<<26, false, dealStart, 210*builtin2.EpochsInDay).IDs[0]
+
+	var marketSt2 market2.State
+	require.NoError(t, v.GetState(builtin2.StorageMarketActorAddr, &marketSt2))
+	proposals2, err := market2.AsDealProposalArray(v.Store(), marketSt2.Proposals)
+	require.NoError(t, err)
+	validProposal2, found, err := proposals2.Get(validID)
+	require.NoError(t, err)
+	require.True(t, found)
+	validCid2, err := validProposal2.Cid()
+	require.NoError(t, err)
+
+	// Advancing the epoch flushes the state root.
+	v, err = v.WithEpoch(v.GetEpoch() + 1)
+	require.NoError(t, err)
+	nextRoot, err := nv10.MigrateStateTree(ctx, v.Store(), v.StateRoot(), v.GetEpoch(), nv10.Config{MaxWorkers: 1}, log, nv10.NewMemMigrationCache())
+	require.NoError(t, err)
+
+	lookup := map[cid.Cid]rt.VMActor{}
+	for _, ba := range exported3.BuiltinActors() {
+		lookup[ba.Code()] = ba
+	}
+	v3, err := vm3.NewVMAtEpoch(ctx, lookup, v.Store(), nextRoot, v.GetEpoch()+1)
+	require.NoError(t, err)
+
+	var marketSt3 market3.State
+	require.NoError(t, v3.GetState(builtin3.StorageMarketActorAddr, &marketSt3))
+	proposals3, err := market3.AsDealProposalArray(v3.Store(), marketSt3.Proposals)
+	require.NoError(t, err)
+	pending, err := adt3.AsSet(v3.Store(), marketSt3.PendingProposals, builtin3.DefaultHamtBitwidth)
+	require.NoError(t, err)
+
+	// The valid label is unchanged, as is the proposal's CID.
+	validProposal3, found, err := proposals3.Get(validID)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.True(t, validProposal3.Label.IsString())
+	assert.Equal(t, validLabel, validProposal3.Label.String())
+	validCid3, err := validProposal3.Cid()
+	require.NoError(t, err)
+	assert.Equal(t, validCid2, validCid3)
+
+	// The invalid label becomes bytes, and the pending proposal is keyed by the new CID.
+	invalidProposal3, found, err := proposals3.Get(invalidID)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.True(t, invalidProposal3.Label.IsBytes())
+	assert.Equal(t, []byte(invalidLabel), invalidProposal3.Label.ToBytes())
+	for _, proposal := range []*market3.DealProposal{validProposal3, invalidProposal3} {
+		pcid, err := proposal.Cid()
+		require.NoError(t, err)
+		has, err := pending.Has(abi.CidKey(pcid))
+		require.NoError(t, err)
+		assert.True(t, has)
+	}
+
+	// The market invariants check that each pending proposal is keyed by its CID.
+	vm3.ApplyOk(t, v3, builtin3.SystemActorAddr, builtin3.CronActorAddr, big.Zero(), builtin3.MethodsCron.EpochTick, nil)
+	stateTree, err := v3.GetStateTree()
+	require.NoError(t, err)
+	totalBalance, err := v3.GetTotalActorBalance()
+	require.NoError(t, err)
+	msgs, err := states3.CheckStateInvariants(stateTree, totalBalance, v3.GetEpoch())
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(msgs.Messages()), strings.Join(msgs.Messages(), "\n"))
+}
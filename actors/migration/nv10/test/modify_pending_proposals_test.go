@@ -64,13 +64,13 @@ func TestUpdatePendingDealsMigration(t *testing.T) {
 
 	// create 100 deals over 100 epochs to fill pending proposals structure
 	dealStart := abi.ChainEpoch(252)
-	var deals []*market2.PublishStorageDealsReturn
+	var deals []abi.DealID
 	for i := 0; i < 100; i++ {
 		var err error
 		v, err = v.WithEpoch(v.GetEpoch() + 1)
 		require.NoError(t, err)
 		deals = append(deals, publishDeal(t, v, worker, addrs[1+i%9], minerAddrs.IDAddress, fmt.Sprintf("deal%d", i),
-			1<<26, false, dealStart, 210*builtin2.EpochsInDay))
+			1<<26, false, dealStart, 210*builtin2.EpochsInDay).IDs...)
 	}
 
 	// run migration
@@ -92,13 +92,13 @@ func TestUpdatePendingDealsMigration(t *testing.T) {
 		require.NoError(t, err)
 
 		deals = append(deals, publishV3Deal(t, v3, worker, addrs[1+i%9], minerAddrs.IDAddress, fmt.Sprintf("deal1%d", i),
-			1<<26, false, dealStart, 210*builtin3.EpochsInDay))
+			1<<26, false, dealStart, 210*builtin3.EpochsInDay).IDs...)
 	}
 
 	// add even deals to a sector we will commit (to let the odd deals expire)
 	var dealIDs []abi.DealID
 	for i := 0; i < len(deals); i += 2 {
-		dealIDs = append(dealIDs, deals[i].IDs[0])
+		dealIDs = append(dealIDs, deals[i])
 	}
 
 	// precommit capacity upgrade sector with deals
@@ -192,14 +192,16 @@ func publishDeal(t *testing.T, v *vm2.VM, provider, dealClient, minerID addr.Add
 
 func publishV3Deal(t *testing.T, v *vm3.VM, provider, dealClient, minerID addr.Address, dealLabel string,
 	pieceSize abi.PaddedPieceSize, verifiedDeal bool, dealStart abi.ChainEpoch, dealLifetime abi.ChainEpoch,
-) *market2.PublishStorageDealsReturn {
+) *market3.PublishStorageDealsReturn {
+	label, err := market3.NewLabelFromString(dealLabel)
+	require.NoError(t, err)
 	deal := market3.DealProposal{
 		PieceCID:             tutil.MakeCID(dealLabel, &market2.PieceCIDPrefix),
 		PieceSize:            pieceSize,
 		VerifiedDeal:         verifiedDeal,
 		Client:               dealClient,
 		Provider:             minerID,
-		Label:                dealLabel,
+		Label:                label,
 		StartEpoch:           dealStart,
 		EndEpoch:             dealStart + dealLifetime,
 		StoragePricePerEpoch: abi.NewTokenAmount(1 << 20),
@@ -228,5 +230,5 @@ func publishV3Deal(t *testing.T, v *vm3.VM, provider, dealClient, minerID addr.A
 		SubInvocations: expectedPublishSubinvocations,
 	}.Matches(t, v.LastInvocation())
 
-	return ret.(*market2.PublishStorageDealsReturn)
+	return ret.(*market3.PublishStorageDealsReturn)
 }
@@ -0,0 +1,111 @@
+package test_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/filecoin-project/go-state-types/rt"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	builtin2 "github.com/filecoin-project/specs-actors/v2/actors/builtin"
+	miner2 "github.com/filecoin-project/specs-actors/v2/actors/builtin/miner"
+	power2 "github.com/filecoin-project/specs-actors/v2/actors/builtin/power"
+	ipld2 "github.com/filecoin-project/specs-actors/v2/support/ipld"
+	vm2 "github.com/filecoin-project/specs-actors/v2/support/vm"
+
+	builtin3 "github.com/filecoin-project/specs-actors/v3/actors/builtin"
+	exported3 "github.com/filecoin-project/specs-actors/v3/actors/builtin/exported"
+	market3 "github.com/filecoin-project/specs-actors/v3/actors/builtin/market"
+	"github.com/filecoin-project/specs-actors/v3/actors/migration/nv10"
+	states3 "github.com/filecoin-project/specs-actors/v3/actors/states"
+	adt3 "github.com/filecoin-project/specs-actors/v3/actors/util/adt"
+	tutil "github.com/filecoin-project/specs-actors/v3/support/testing"
+	vm3 "github.com/filecoin-project/specs-actors/v3/support/vm"
+)
+
+func TestPrecommittedDealsMigration(t *testing.T) {
+	ctx := context.Background()
+	log := TestLogger{t}
+	v := vm2.NewVMWithSingletons(ctx, t, ipld2.NewSyncBlockStoreInMemory())
+	addrs := vm2.CreateAccounts(ctx, t, v, 2, big.Mul(big.NewInt(100_000), vm2.FIL), 93837778)
+	worker, client := addrs[0], addrs[1]
+
+	ret := vm2.ApplyOk(t, v, worker, builtin2.StoragePowerActorAddr, big.Mul(big.NewInt(10_000), vm2.FIL), builtin2.MethodsPower.CreateMiner, &power2.CreateMinerParams{
+		Owner:         worker,
+		Worker:        worker,
+		SealProofType: abi.RegisteredSealProof_StackedDrg32GiBV1_1,
+		Peer:          abi.PeerID("not really a peer id"),
+	})
+	minerAddrs, ok := ret.(*power2.CreateMinerReturn)
+	require.True(t, ok)
+
+	vm2.ApplyOk(t, v, client, builtin2.StorageMarketActorAddr, big.Mul(big.NewInt(30), vm2.FIL), builtin2.MethodsMarket.AddBalance, &client)
+	vm2.ApplyOk(t, v, worker, builtin2.StorageMarketActorAddr, big.Mul(big.NewInt(64), vm2.FIL), builtin2.MethodsMarket.AddBalance, &minerAddrs.IDAddress)
+
+	v, err := v.WithEpoch(v.GetEpoch() + 1)
+	require.NoError(t, err)
+	dealStart := abi.ChainEpoch(252)
+	precommittedID := publishDeal(t, v, worker, client, minerAddrs.IDAddress, "precommitted", 1<<26, false, dealStart, 210*builtin2.EpochsInDay).IDs[0]
+	pendingID := publishDeal(t, v, worker, client, minerAddrs.IDAddress, "pending", 1<<26, false, dealStart, 210*builtin2.EpochsInDay).IDs[0]
+
+	// Precommit a sector including one of the deals before the upgrade.
+	vm2.ApplyOk(t, v, worker, minerAddrs.RobustAddress, big.Zero(), builtin2.MethodsMiner.PreCommitSector, &miner2.PreCommitSectorParams{
+		SealProof:     abi.RegisteredSealProof_StackedDrg32GiBV1_1,
+		SectorNumber:  100,
+		SealedCID:     tutil.MakeCID("100", &miner2.SealedCIDPrefix),
+		SealRandEpoch: v.GetEpoch() - 1,
+		DealIDs:       []abi.DealID{precommittedID},
+		Expiration:    v.GetEpoch() + 220*builtin2.EpochsInDay,
+	})
+
+	v, err = v.WithEpoch(v.GetEpoch() + 1)
+	require.NoError(t, err)
+	nextRoot, err := nv10.MigrateStateTree(ctx, v.Store(), v.StateRoot(), v.GetEpoch(), nv10.Config{MaxWorkers: 1}, log, nv10.NewMemMigrationCache())
+	require.NoError(t, err)
+
+	lookup := map[cid.Cid]rt.VMActor{}
+	for _, ba := range exported3.BuiltinActors() {
+		lookup[ba.Code()] = ba
+	}
+	v3, err := vm3.NewVMAtEpoch(ctx, lookup, v.Store(), nextRoot, v.GetEpoch()+1)
+	require.NoError(t, err)
+
+	// Only the deal in the precommitted sector is recorded.
+	var marketSt market3.State
+	require.NoError(t, v3.GetState(builtin3.StorageMarketActorAddr, &marketSt))
+	precommitted, err := adt3.AsSet(v3.Store(), marketSt.PrecommittedDeals, builtin3.DefaultHamtBitwidth)
+	require.NoError(t, err)
+	has, err := precommitted.Has(abi.UIntKey(uint64(precommittedID)))
+	require.NoError(t, err)
+	assert.True(t, has)
+	has, err = precommitted.Has(abi.UIntKey(uint64(pendingID)))
+	require.NoError(t, err)
+	assert.False(t, has)
+
+	cancelParams := func(dealID abi.DealID) *market3.CancelDealParams {
+		processEpoch, found, err := marketSt.FindPendingDealOpEpoch(v3.Store(), dealID)
+		require.NoError(t, err)
+		require.True(t, found)
+		return &market3.CancelDealParams{DealID: dealID, ProcessEpoch: processEpoch}
+	}
+
+	// The client can't cancel the precommitted deal alone, but can cancel the other.
+	_, code := v3.ApplyMessage(client, builtin3.StorageMarketActorAddr, big.Zero(), builtin3.MethodsMarket.CancelDeal, cancelParams(precommittedID))
+	assert.Equal(t, exitcode.ErrForbidden, code)
+	vm3.ApplyOk(t, v3, client, builtin3.StorageMarketActorAddr, big.Zero(), builtin3.MethodsMarket.CancelDeal, cancelParams(pendingID))
+
+	vm3.ApplyOk(t, v3, builtin3.SystemActorAddr, builtin3.CronActorAddr, big.Zero(), builtin3.MethodsCron.EpochTick, nil)
+	stateTree, err := v3.GetStateTree()
+	require.NoError(t, err)
+	totalBalance, err := v3.GetTotalActorBalance()
+	require.NoError(t, err)
+	msgs, err := states3.CheckStateInvariants(stateTree, totalBalance, v3.GetEpoch())
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(msgs.Messages()), strings.Join(msgs.Messages(), "\n"))
+}
@@ -78,7 +78,6 @@ func MigrateStateTree(ctx context.Context, store cbor.IpldStore, actorsRootIn ci
 		builtin2.MultisigActorCodeID:         cachedMigration(cache, multisigMigrator{}),
 		builtin2.PaymentChannelActorCodeID:   cachedMigration(cache, paychMigrator{}),
 		builtin2.RewardActorCodeID:           nilMigrator{builtin3.RewardActorCodeID},
-		builtin2.StorageMarketActorCodeID:    cachedMigration(cache, marketMigrator{}),
 		builtin2.StorageMinerActorCodeID:     cachedMigration(cache, minerMigrator{}),
 		builtin2.StoragePowerActorCodeID:     cachedMigration(cache, powerMigrator{}),
 		builtin2.SystemActorCodeID:           nilMigrator{builtin3.SystemActorCodeID},
@@ -86,7 +85,7 @@ func MigrateStateTree(ctx context.Context, store cbor.IpldStore, actorsRootIn ci
 	}
 	// Set of prior version code CIDs for actors to defer during iteration, for explicit migration afterwards.
 	var deferredCodeIDs = map[cid.Cid]struct{}{
-		// None
+		builtin2.StorageMarketActorCodeID: {},
 	}
 	if len(migrations)+len(deferredCodeIDs) != 11 {
 		panic(fmt.Sprintf("incomplete migration specification with %d code CIDs", len(migrations)))
@@ -221,6 +220,37 @@ func MigrateStateTree(ctx context.Context, store cbor.IpldStore, actorsRootIn ci
 	// Perform any deferred migrations explicitly here.
 	// Deferred migrations might depend on values accumulated through migration of other actors.
 
+	// The group's context is done once it has been waited on, so use the store's context from here.
+	ctx = adtStore.Context()
+
+	// The market records the deals in sectors precommitted before the upgrade, which are found in miner state.
+	// The result depends on state other than the market's own, so is not cached.
+	log.Log(rt.INFO, "Collecting precommitted deals")
+	precommittedDeals, err := collectPrecommittedDeals(ctx, store, actorsIn)
+	if err != nil {
+		return cid.Undef, xerrors.Errorf("failed to collect precommitted deals: %w", err)
+	}
+	marketActorIn, found, err := actorsIn.GetActor(builtin2.StorageMarketActorAddr)
+	if err != nil {
+		return cid.Undef, err
+	}
+	if !found {
+		return cid.Undef, xerrors.Errorf("could not find market actor in state")
+	}
+	marketJob := migrationJob{
+		Address:        builtin2.StorageMarketActorAddr,
+		Actor:          *marketActorIn,
+		actorMigration: marketMigrator{precommittedDeals: precommittedDeals},
+		cache:          cache,
+	}
+	marketResult, err := marketJob.run(ctx, store, priorEpoch)
+	if err != nil {
+		return cid.Undef, err
+	}
+	if err := actorsOut.SetActor(marketResult.Address, &marketResult.Actor); err != nil {
+		return cid.Undef, err
+	}
+
 	elapsed := time.Since(startTime)
 	rate := float64(doneCount) / elapsed.Seconds()
 	log.Log(rt.INFO, "All %d done after %v (%.0f/s). Flushing state tree root.", doneCount, elapsed, rate)
@@ -1,7 +1,9 @@
 package proof
 
 import (
+	"github.com/filecoin-project/go-state-types/abi"
 	proof0 "github.com/filecoin-project/specs-actors/actors/runtime/proof"
+	"github.com/ipfs/go-cid"
 )
 
 ///
@@ -23,6 +25,62 @@ import (
 //}
 type SealVerifyInfo = proof0.SealVerifyInfo
 
+///
+/// Aggregate sealing
+///
+
+// Identifies the scheme by which many seal proofs are aggregated into one.
+type RegisteredAggregationProof int64
+
+const (
+	RegisteredAggregationProof_SnarkPackV1 = RegisteredAggregationProof(0)
+)
+
+// Information needed to verify one seal proof within an aggregate.
+// The proof type and miner are shared by all sectors in the aggregate.
+type AggregateSealVerifyInfo struct {
+	Number                abi.SectorNumber
+	Randomness            abi.SealRandomness
+	InteractiveRandomness abi.InteractiveSealRandomness
+
+	// Safe because we get those from the miner actor
+	SealedCID   cid.Cid `checked:"true"` // CommR
+	UnsealedCID cid.Cid `checked:"true"` // CommD
+}
+
+// Information needed to verify an aggregated proof of many sector seals by a single miner.
+type AggregateSealVerifyProofAndInfos struct {
+	Miner          abi.ActorID
+	SealProof      abi.RegisteredSealProof
+	AggregateProof RegisteredAggregationProof
+	Proof          []byte
+	Infos          []AggregateSealVerifyInfo
+}
+
+///
+/// Replica update
+///
+
+// Identifies the scheme by which the replica of a sector sealed without data is updated to encode data.
+type RegisteredUpdateProof int64
+
+const (
+	RegisteredUpdateProof_StackedDrg2KiBV1   = RegisteredUpdateProof(0)
+	RegisteredUpdateProof_StackedDrg8MiBV1   = RegisteredUpdateProof(1)
+	RegisteredUpdateProof_StackedDrg512MiBV1 = RegisteredUpdateProof(2)
+	RegisteredUpdateProof_StackedDrg32GiBV1  = RegisteredUpdateProof(3)
+	RegisteredUpdateProof_StackedDrg64GiBV1  = RegisteredUpdateProof(4)
+)
+
+// Information needed to verify a proof that a sector's replica has been updated to encode new data.
+type ReplicaUpdateInfo struct {
+	UpdateProofType      RegisteredUpdateProof
+	NewSealedSectorCID   cid.Cid // CommR of the updated replica
+	OldSealedSectorCID   cid.Cid // CommR of the replica before the update
+	NewUnsealedSectorCID cid.Cid // CommD of the new data
+	Proof                []byte
+}
+
 ///
 /// PoSting
 ///
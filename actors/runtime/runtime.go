@@ -182,6 +182,10 @@ type Syscalls interface {
 	VerifySeal(vi proof.SealVerifyInfo) error
 
 	BatchVerifySeals(vis map[addr.Address][]proof.SealVerifyInfo) (map[addr.Address][]bool, error)
+	// Verifies an aggregated proof of many sector seals by a single miner.
+	VerifyAggregateSeals(aggregate proof.AggregateSealVerifyProofAndInfos) error
+	// Verifies a proof that a sector's replica has been updated to encode new data.
+	VerifyReplicaUpdate(update proof.ReplicaUpdateInfo) error
 
 	// Verifies a proof of spacetime.
 	VerifyPoSt(vi proof.WindowPoStVerifyInfo) error
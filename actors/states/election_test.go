@@ -157,6 +157,8 @@ func constructMinerState(ctx context.Context, t *testing.T, store adt.Store, own
 		SectorSize:                 ssize,
 		WindowPoStPartitionSectors: psize,
 		ConsensusFaultElapsed:      0,
+		Beneficiary:                owner,
+		BeneficiaryTerm:            miner.NewBeneficiaryTerm(big.Zero(), 0),
 	}
 	infoCid, err := store.Put(ctx, &info)
 	require.NoError(t, err)
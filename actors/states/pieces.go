@@ -0,0 +1,151 @@
+package states
+
+import (
+	"bytes"
+	"sort"
+
+	addr "github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/market"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/v3/actors/util/adt"
+)
+
+// The location of an active deal's data within its provider's sectors.
+type PieceLocation struct {
+	DealID       abi.DealID
+	PieceCID     cid.Cid
+	Provider     addr.Address
+	SectorNumber abi.SectorNumber
+	Deadline     uint64
+	Partition    uint64
+	DealEndEpoch abi.ChainEpoch
+}
+
+// An index from piece CID to the active deals for that piece, and from deal ID to the sector holding it.
+// The index is a snapshot of a single state tree and is not updated as the chain advances.
+type PieceIndex struct {
+	byPiece map[cid.Cid][]abi.DealID
+	byDeal  map[abi.DealID]PieceLocation
+}
+
+// Builds an index over all active deals in a state tree.
+// A deal is active once it has been activated in a sector and until it is slashed or expires from market state.
+// Deals that have been published but not yet activated are not indexed.
+func BuildPieceIndex(tree *Tree) (*PieceIndex, error) {
+	var marketSt market.State
+	if err := loadActorState(tree, builtin.StorageMarketActorAddr, &marketSt); err != nil {
+		return nil, err
+	}
+	proposals, err := adt.AsArray(tree.Store, marketSt.Proposals, market.ProposalsAmtBitwidth)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to load deal proposals: %w", err)
+	}
+	dealStates, err := market.AsDealStateArray(tree.Store, marketSt.States)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to load deal states: %w", err)
+	}
+
+	// Collect active deals by provider, to be matched with the provider's sectors.
+	active := map[abi.DealID]*market.DealProposal{}
+	providers := map[addr.Address]struct{}{}
+	var proposal market.DealProposal
+	if err := proposals.ForEach(&proposal, func(i int64) error {
+		dealID := abi.DealID(i)
+		dealState, found, err := dealStates.Get(dealID)
+		if err != nil {
+			return xerrors.Errorf("failed to load state for deal %d: %w", dealID, err)
+		}
+		if !found || dealState.SectorStartEpoch == epochUndefined || dealState.SlashEpoch != epochUndefined {
+			return nil
+		}
+		p := proposal
+		active[dealID] = &p
+		providers[p.Provider] = struct{}{}
+		return nil
+	}); err != nil {
+		return nil, xerrors.Errorf("failed to iterate deal proposals: %w", err)
+	}
+
+	idx := &PieceIndex{
+		byPiece: map[cid.Cid][]abi.DealID{},
+		byDeal:  map[abi.DealID]PieceLocation{},
+	}
+	// Visit providers in a fixed order, so that any error reported is deterministic.
+	sortedProviders := make([]addr.Address, 0, len(providers))
+	for provider := range providers { //nolint:nomaprange
+		sortedProviders = append(sortedProviders, provider)
+	}
+	sort.Slice(sortedProviders, func(i, j int) bool {
+		return bytes.Compare(sortedProviders[i].Bytes(), sortedProviders[j].Bytes()) < 0
+	})
+	for _, provider := range sortedProviders {
+		var minerSt miner.State
+		if err := loadActorState(tree, provider, &minerSt); err != nil {
+			return nil, err
+		}
+		var sectors []*miner.SectorOnChainInfo
+		if err := minerSt.ForEachSector(tree.Store, func(sector *miner.SectorOnChainInfo) {
+			sectors = append(sectors, sector)
+		}); err != nil {
+			return nil, xerrors.Errorf("failed to iterate sectors of miner %v: %w", provider, err)
+		}
+		for _, sector := range sectors {
+			var located bool
+			var dlIdx, pIdx uint64
+			for _, dealID := range sector.DealIDs {
+				proposal, ok := active[dealID]
+				if !ok || proposal.Provider != provider {
+					continue
+				}
+				if !located {
+					if dlIdx, pIdx, err = minerSt.FindSector(tree.Store, sector.SectorNumber); err != nil {
+						return nil, xerrors.Errorf("failed to locate sector %d of miner %v: %w", sector.SectorNumber, provider, err)
+					}
+					located = true
+				}
+				idx.byDeal[dealID] = PieceLocation{
+					DealID:       dealID,
+					PieceCID:     proposal.PieceCID,
+					Provider:     provider,
+					SectorNumber: sector.SectorNumber,
+					Deadline:     dlIdx,
+					Partition:    pIdx,
+					DealEndEpoch: proposal.EndEpoch,
+				}
+				idx.byPiece[proposal.PieceCID] = append(idx.byPiece[proposal.PieceCID], dealID)
+			}
+		}
+	}
+	for _, dealIDs := range idx.byPiece { //nolint:nomaprange // sorts each value independently
+		sort.Slice(dealIDs, func(i, j int) bool { return dealIDs[i] < dealIDs[j] })
+	}
+	return idx, nil
+}
+
+// Returns the IDs of active deals for a piece, in ascending order.
+func (idx *PieceIndex) DealsForPiece(pieceCID cid.Cid) []abi.DealID {
+	return append([]abi.DealID(nil), idx.byPiece[pieceCID]...)
+}
+
+// Returns the location of each active deal for a piece, in ascending order of deal ID.
+func (idx *PieceIndex) LocatePiece(pieceCID cid.Cid) []PieceLocation {
+	dealIDs := idx.byPiece[pieceCID]
+	locations := make([]PieceLocation, len(dealIDs))
+	for i, dealID := range dealIDs {
+		locations[i] = idx.byDeal[dealID]
+	}
+	return locations
+}
+
+// Returns the location of an active deal, and whether the deal was found.
+func (idx *PieceIndex) LocateDeal(dealID abi.DealID) (PieceLocation, bool) {
+	loc, found := idx.byDeal[dealID]
+	return loc, found
+}
+
+const epochUndefined = abi.ChainEpoch(-1)
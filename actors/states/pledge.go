@@ -0,0 +1,94 @@
+package states
+
+import (
+	addr "github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/power"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/reward"
+)
+
+// Describes a hypothetical sector to be committed.
+type SectorPledgeParams struct {
+	SectorSize         abi.SectorSize
+	Duration           abi.ChainEpoch // Epochs from commitment to expiration.
+	DealWeight         abi.DealWeight
+	VerifiedDealWeight abi.DealWeight
+}
+
+// Projected collateral requirements and penalties for a hypothetical sector, computed with the
+// network reward and power estimates held constant at their values in the state tree.
+type SectorPledgeProjection struct {
+	QAPower               abi.StoragePower
+	PreCommitDeposit      abi.TokenAmount
+	InitialPledge         abi.TokenAmount
+	ExpectedDayReward     abi.TokenAmount
+	ExpectedStoragePledge abi.TokenAmount
+	// The fee charged for each proving period (one day) the sector is faulty.
+	FaultFeePerDay abi.TokenAmount
+	// The fee for terminating the sector after each whole day of its lifetime, indexed by age in days.
+	// The final entry is for the sector's full lifetime.
+	TerminationFees []abi.TokenAmount
+}
+
+// Projects the collateral requirements and penalties for a sector committed at the state tree's current
+// network conditions.
+// The circulating supply is not recorded in the state tree, so must be provided.
+func ProjectSectorPledge(tree *Tree, circulatingSupply abi.TokenAmount, params *SectorPledgeParams) (*SectorPledgeProjection, error) {
+	if params.Duration <= 0 {
+		return nil, xerrors.Errorf("non-positive sector duration %d", params.Duration)
+	}
+	var rewardSt reward.State
+	if err := loadActorState(tree, builtin.RewardActorAddr, &rewardSt); err != nil {
+		return nil, err
+	}
+	var powerSt power.State
+	if err := loadActorState(tree, builtin.StoragePowerActorAddr, &powerSt); err != nil {
+		return nil, err
+	}
+	rewardEstimate := rewardSt.ThisEpochRewardSmoothed
+	powerEstimate := powerSt.ThisEpochQAPowerSmoothed
+
+	qaPower := miner.QAPowerForWeight(params.SectorSize, params.Duration, params.DealWeight, params.VerifiedDealWeight)
+	dayReward := miner.ExpectedRewardForPower(rewardEstimate, powerEstimate, qaPower, builtin.EpochsInDay)
+	storagePledge := miner.ExpectedRewardForPower(rewardEstimate, powerEstimate, qaPower, miner.InitialPledgeProjectionPeriod)
+
+	days := int64((params.Duration + builtin.EpochsInDay - 1) / builtin.EpochsInDay)
+	terminationFees := make([]abi.TokenAmount, 0, days+1)
+	for day := int64(0); day <= days; day++ {
+		age := abi.ChainEpoch(day) * builtin.EpochsInDay
+		if age > params.Duration {
+			age = params.Duration
+		}
+		terminationFees = append(terminationFees, miner.PledgePenaltyForTermination(dayReward, age, storagePledge,
+			powerEstimate, qaPower, rewardEstimate, big.Zero(), 0))
+	}
+
+	return &SectorPledgeProjection{
+		QAPower:               qaPower,
+		PreCommitDeposit:      miner.PreCommitDepositForPower(rewardEstimate, powerEstimate, qaPower),
+		InitialPledge:         miner.InitialPledgeForPower(qaPower, rewardSt.ThisEpochBaselinePower, rewardEstimate, powerEstimate, circulatingSupply),
+		ExpectedDayReward:     dayReward,
+		ExpectedStoragePledge: storagePledge,
+		FaultFeePerDay:        miner.PledgePenaltyForContinuedFault(rewardEstimate, powerEstimate, qaPower),
+		TerminationFees:       terminationFees,
+	}, nil
+}
+
+func loadActorState(tree *Tree, a addr.Address, st cbg.CBORUnmarshaler) error {
+	actor, found, err := tree.GetActor(a)
+	if err != nil {
+		return err
+	} else if !found {
+		return xerrors.Errorf("actor %v not found in state tree", a)
+	}
+	if err := tree.Store.Get(tree.Store.Context(), actor.Head, st); err != nil {
+		return xerrors.Errorf("failed to load state of actor %v: %w", a, err)
+	}
+	return nil
+}
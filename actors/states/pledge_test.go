@@ -0,0 +1,97 @@
+package states_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/power"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/reward"
+	"github.com/filecoin-project/specs-actors/v3/actors/states"
+	"github.com/filecoin-project/specs-actors/v3/support/ipld"
+)
+
+func TestProjectSectorPledge(t *testing.T) {
+	store := ipld.NewADTStore(context.Background())
+	tree, err := states.NewTree(store)
+	require.NoError(t, err)
+
+	rewardSt := reward.ConstructState(abi.NewStoragePower(1 << 50))
+	powerSt, err := power.ConstructState(store)
+	require.NoError(t, err)
+	rewardHead, err := store.Put(context.Background(), rewardSt)
+	require.NoError(t, err)
+	powerHead, err := store.Put(context.Background(), powerSt)
+	require.NoError(t, err)
+	require.NoError(t, tree.SetActor(builtin.RewardActorAddr, &states.Actor{
+		Code: builtin.RewardActorCodeID, Head: rewardHead, Balance: big.Zero(),
+	}))
+	require.NoError(t, tree.SetActor(builtin.StoragePowerActorAddr, &states.Actor{
+		Code: builtin.StoragePowerActorCodeID, Head: powerHead, Balance: big.Zero(),
+	}))
+
+	circSupply := big.Mul(big.NewInt(1e9), builtin.TokenPrecision)
+	sectorSize := abi.SectorSize(32 << 30)
+	duration := abi.ChainEpoch(180*builtin.EpochsInDay + 1)
+	params := &states.SectorPledgeParams{
+		SectorSize:         sectorSize,
+		Duration:           duration,
+		DealWeight:         big.Zero(),
+		VerifiedDealWeight: big.Zero(),
+	}
+
+	t.Run("matches miner actor computations", func(t *testing.T) {
+		proj, err := states.ProjectSectorPledge(tree, circSupply, params)
+		require.NoError(t, err)
+
+		rewardEstimate := rewardSt.ThisEpochRewardSmoothed
+		powerEstimate := powerSt.ThisEpochQAPowerSmoothed
+		qaPower := big.NewIntUnsigned(uint64(sectorSize))
+		assert.Equal(t, qaPower, proj.QAPower)
+		assert.Equal(t, miner.PreCommitDepositForPower(rewardEstimate, powerEstimate, qaPower), proj.PreCommitDeposit)
+		assert.Equal(t, miner.InitialPledgeForPower(qaPower, rewardSt.ThisEpochBaselinePower, rewardEstimate, powerEstimate, circSupply), proj.InitialPledge)
+		assert.Equal(t, miner.ExpectedRewardForPower(rewardEstimate, powerEstimate, qaPower, builtin.EpochsInDay), proj.ExpectedDayReward)
+		assert.Equal(t, miner.PledgePenaltyForContinuedFault(rewardEstimate, powerEstimate, qaPower), proj.FaultFeePerDay)
+		assert.True(t, proj.InitialPledge.GreaterThan(big.Zero()))
+
+		// One fee for each day of age from zero, with the final partial day rounded up.
+		require.Len(t, proj.TerminationFees, 182)
+		assert.Equal(t, miner.PledgePenaltyForTermination(proj.ExpectedDayReward, duration, proj.ExpectedStoragePledge,
+			powerEstimate, qaPower, rewardEstimate, big.Zero(), 0), proj.TerminationFees[181])
+		for i := 1; i < len(proj.TerminationFees); i++ {
+			assert.True(t, proj.TerminationFees[i].GreaterThanEqual(proj.TerminationFees[i-1]))
+		}
+	})
+
+	t.Run("verified deals increase requirements", func(t *testing.T) {
+		base, err := states.ProjectSectorPledge(tree, circSupply, params)
+		require.NoError(t, err)
+
+		verified := *params
+		verified.VerifiedDealWeight = big.Mul(big.NewIntUnsigned(uint64(sectorSize)), big.NewInt(int64(duration)))
+		proj, err := states.ProjectSectorPledge(tree, circSupply, &verified)
+		require.NoError(t, err)
+
+		assert.Equal(t, big.Mul(base.QAPower, big.NewInt(10)), proj.QAPower)
+		assert.True(t, proj.InitialPledge.GreaterThan(base.InitialPledge))
+		assert.True(t, proj.FaultFeePerDay.GreaterThan(base.FaultFeePerDay))
+	})
+
+	t.Run("rejects missing actors and invalid duration", func(t *testing.T) {
+		empty, err := states.NewTree(store)
+		require.NoError(t, err)
+		_, err = states.ProjectSectorPledge(empty, circSupply, params)
+		assert.Error(t, err)
+
+		invalid := *params
+		invalid.Duration = 0
+		_, err = states.ProjectSectorPledge(tree, circSupply, &invalid)
+		assert.Error(t, err)
+	})
+}
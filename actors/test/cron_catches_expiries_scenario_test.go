@@ -7,6 +7,7 @@ import (
 	"github.com/filecoin-project/go-bitfield"
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/crypto"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -19,8 +20,6 @@ import (
 	vm "github.com/filecoin-project/specs-actors/v3/support/vm"
 )
 
-var fakeChainRandomness = []byte("not really random")
-
 func TestCronCatchedCCExpirationsAtDeadlineBoundary(t *testing.T) {
 	ctx := context.Background()
 	v := vm.NewVMWithSingletons(ctx, t, ipld.NewBlockStoreInMemory())
@@ -83,7 +82,7 @@ func TestCronCatchedCCExpirationsAtDeadlineBoundary(t *testing.T) {
 			PoStProof: abi.RegisteredPoStProof_StackedDrgWindow32GiBV1,
 		}},
 		ChainCommitEpoch: dlInfo.Challenge,
-		ChainCommitRand:  fakeChainRandomness,
+		ChainCommitRand:  v.GetRandomnessFromTickets(crypto.DomainSeparationTag_PoStChainCommit, dlInfo.Challenge, nil),
 	}
 
 	vm.ApplyOk(t, v, addrs[0], minerAddrs.RobustAddress, big.Zero(), builtin.MethodsMiner.SubmitWindowedPoSt, &submitParams)
@@ -125,6 +124,7 @@ func TestCronCatchedCCExpirationsAtDeadlineBoundary(t *testing.T) {
 
 	// prove original sector so it won't be faulted
 	submitParams.ChainCommitEpoch = dlInfo.Challenge
+	submitParams.ChainCommitRand = v.GetRandomnessFromTickets(crypto.DomainSeparationTag_PoStChainCommit, dlInfo.Challenge, nil)
 	vm.ApplyOk(t, v, addrs[0], minerAddrs.RobustAddress, big.Zero(), builtin.MethodsMiner.SubmitWindowedPoSt, &submitParams)
 
 	// one epoch before deadline close (i.e. Last) is where we might see a problem with cron scheduling of expirations
@@ -0,0 +1,123 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin"
+	init_ "github.com/filecoin-project/specs-actors/v3/actors/builtin/init"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/multisig"
+	"github.com/filecoin-project/specs-actors/v3/support/ipld"
+	vm "github.com/filecoin-project/specs-actors/v3/support/vm"
+)
+
+func TestGasAccounting(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (*vm.VM, *init_.ExecParams, address.Address) {
+		v := vm.NewVMWithSingletons(ctx, t, ipld.NewBlockStoreInMemory())
+		addrs := vm.CreateAccounts(ctx, t, v, 2, big.Mul(big.NewInt(10_000), big.NewInt(1e18)), 93837778)
+
+		paramBuf := new(bytes.Buffer)
+		err := (&multisig.ConstructorParams{Signers: addrs, NumApprovalsThreshold: 2}).MarshalCBOR(paramBuf)
+		require.NoError(t, err)
+		return v, &init_.ExecParams{CodeCID: builtin.MultisigActorCodeID, ConstructorParams: paramBuf.Bytes()}, addrs[0]
+	}
+
+	t.Run("charges gas for top-level and nested invocations", func(t *testing.T) {
+		v, params, sender := setup(t)
+
+		_, code, gasUsed := v.ApplyMessageWithGasLimit(sender, builtin.InitActorAddr, big.Zero(), builtin.MethodsInit.Exec, params, 1_000_000_000)
+		require.Equal(t, exitcode.Ok, code)
+
+		// The top-level invocation is charged for everything except message inclusion.
+		inv := v.LastInvocation()
+		assert.True(t, inv.GasUsed > 0)
+		assert.True(t, inv.GasUsed < gasUsed)
+
+		// The multisig constructor is a nested invocation whose gas is included in its parent's.
+		require.Len(t, inv.SubInvocations, 1)
+		sub := inv.SubInvocations[0]
+		assert.True(t, sub.GasUsed > 0)
+		assert.True(t, sub.GasUsed < inv.GasUsed)
+	})
+
+	t.Run("out of gas aborts and rolls back state", func(t *testing.T) {
+		v, params, sender := setup(t)
+
+		var initSt init_.State
+		require.NoError(t, v.GetState(builtin.InitActorAddr, &initSt))
+		nextID := initSt.NextID
+
+		// Measure the message's gas on a copy of the VM.
+		measure, err := v.WithEpoch(v.GetEpoch())
+		require.NoError(t, err)
+		_, code, gasUsed := measure.ApplyMessageWithGasLimit(sender, builtin.InitActorAddr, big.Zero(), builtin.MethodsInit.Exec, params, vm.UnlimitedGas)
+		require.Equal(t, exitcode.Ok, code)
+
+		_, code, outOfGasUsed := v.ApplyMessageWithGasLimit(sender, builtin.InitActorAddr, big.Zero(), builtin.MethodsInit.Exec, params, gasUsed-1)
+		assert.Equal(t, exitcode.SysErrOutOfGas, code)
+		assert.Equal(t, gasUsed-1, outOfGasUsed)
+
+		require.NoError(t, v.GetState(builtin.InitActorAddr, &initSt))
+		assert.Equal(t, nextID, initSt.NextID)
+
+		// Exactly enough gas succeeds.
+		_, code, _ = v.ApplyMessageWithGasLimit(sender, builtin.InitActorAddr, big.Zero(), builtin.MethodsInit.Exec, params, gasUsed)
+		assert.Equal(t, exitcode.Ok, code)
+	})
+
+	t.Run("message inclusion alone may exceed limit", func(t *testing.T) {
+		v, params, sender := setup(t)
+
+		_, code, gasUsed := v.ApplyMessageWithGasLimit(sender, builtin.InitActorAddr, big.Zero(), builtin.MethodsInit.Exec, params, 1)
+		assert.Equal(t, exitcode.SysErrOutOfGas, code)
+		assert.Equal(t, int64(1), gasUsed)
+	})
+
+	t.Run("out of gas in nested send fails the message though the caller handles it", func(t *testing.T) {
+		v := vm.NewVMWithSingletons(ctx, t, ipld.NewBlockStoreInMemory())
+		addrs := vm.CreateAccounts(ctx, t, v, 2, big.Mul(big.NewInt(10_000), big.NewInt(1e18)), 93837778)
+		signer, recipient := addrs[0], addrs[1]
+
+		// A multisig with a single signer executes proposals immediately, recording a failed send's exit code
+		// rather than aborting.
+		paramBuf := new(bytes.Buffer)
+		err := (&multisig.ConstructorParams{Signers: []address.Address{signer}, NumApprovalsThreshold: 1}).MarshalCBOR(paramBuf)
+		require.NoError(t, err)
+		ret := vm.ApplyOk(t, v, signer, builtin.InitActorAddr, big.Zero(), builtin.MethodsInit.Exec,
+			&init_.ExecParams{CodeCID: builtin.MultisigActorCodeID, ConstructorParams: paramBuf.Bytes()})
+		msigAddr := ret.(*init_.ExecReturn).IDAddress
+
+		// The proposal and its nested send are each charged once.
+		v.SetPriceList(&vm.ScalarPriceList{SendBase: 10})
+		propose := &multisig.ProposeParams{To: recipient, Value: big.Zero(), Method: builtin.MethodSend}
+		_, code, gasUsed := v.ApplyMessageWithGasLimit(signer, msigAddr, big.Zero(), builtin.MethodsMultisig.Propose, propose, 15)
+		assert.Equal(t, exitcode.SysErrOutOfGas, code)
+		assert.Equal(t, int64(15), gasUsed)
+
+		var msigSt multisig.State
+		require.NoError(t, v.GetState(msigAddr, &msigSt))
+		assert.Equal(t, multisig.TxnID(0), msigSt.NextTxnID)
+
+		_, code, _ = v.ApplyMessageWithGasLimit(signer, msigAddr, big.Zero(), builtin.MethodsMultisig.Propose, propose, 20)
+		assert.Equal(t, exitcode.Ok, code)
+	})
+
+	t.Run("custom price list", func(t *testing.T) {
+		v, params, sender := setup(t)
+
+		v.SetPriceList(&vm.ScalarPriceList{SendBase: 10})
+		_, code, gasUsed := v.ApplyMessageWithGasLimit(sender, builtin.InitActorAddr, big.Zero(), builtin.MethodsInit.Exec, params, vm.UnlimitedGas)
+		require.Equal(t, exitcode.Ok, code)
+		// One charge for the exec and one for the nested constructor.
+		assert.Equal(t, int64(20), gasUsed)
+	})
+}
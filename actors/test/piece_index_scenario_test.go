@@ -0,0 +1,106 @@
+package test_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/market"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/power"
+	"github.com/filecoin-project/specs-actors/v3/actors/states"
+	"github.com/filecoin-project/specs-actors/v3/support/ipld"
+	tutil "github.com/filecoin-project/specs-actors/v3/support/testing"
+	vm "github.com/filecoin-project/specs-actors/v3/support/vm"
+)
+
+func TestPieceIndex(t *testing.T) {
+	ctx := context.Background()
+	v := vm.NewVMWithSingletons(ctx, t, ipld.NewBlockStoreInMemory())
+	addrs := vm.CreateAccounts(ctx, t, v, 2, big.Mul(big.NewInt(10_000), vm.FIL), 93837778)
+	worker, client := addrs[0], addrs[1]
+
+	sectorNumber := abi.SectorNumber(100)
+	sealedCid := tutil.MakeCID("100", &miner.SealedCIDPrefix)
+	sealProof := abi.RegisteredSealProof_StackedDrg32GiBV1_1
+
+	ret := vm.ApplyOk(t, v, worker, builtin.StoragePowerActorAddr, big.Mul(big.NewInt(1_000), vm.FIL), builtin.MethodsPower.CreateMiner, &power.CreateMinerParams{
+		Owner:               worker,
+		Worker:              worker,
+		WindowPoStProofType: abi.RegisteredPoStProof_StackedDrgWindow32GiBV1,
+		Peer:                abi.PeerID("not really a peer id"),
+	})
+	minerAddrs, ok := ret.(*power.CreateMinerReturn)
+	require.True(t, ok)
+
+	vm.ApplyOk(t, v, client, builtin.StorageMarketActorAddr, big.Mul(big.NewInt(10), vm.FIL), builtin.MethodsMarket.AddBalance, &client)
+	vm.ApplyOk(t, v, worker, builtin.StorageMarketActorAddr, big.Mul(big.NewInt(64), vm.FIL), builtin.MethodsMarket.AddBalance, &minerAddrs.IDAddress)
+
+	// Two deals for the same piece, one for another, and one that is published but never activated.
+	dealStart := v.GetEpoch() + miner.PreCommitChallengeDelay + 1
+	var dealIDs []abi.DealID
+	dealIDs = append(dealIDs, publishDeal(t, v, worker, client, minerAddrs.IDAddress, "piece1", 1<<30, false, dealStart, 181*builtin.EpochsInDay).IDs...)
+	dealIDs = append(dealIDs, publishDeal(t, v, worker, client, minerAddrs.IDAddress, "piece1", 1<<30, false, dealStart, 200*builtin.EpochsInDay).IDs...)
+	dealIDs = append(dealIDs, publishDeal(t, v, worker, client, minerAddrs.IDAddress, "piece2", 1<<31, false, dealStart, 210*builtin.EpochsInDay).IDs...)
+	pending := publishDeal(t, v, worker, client, minerAddrs.IDAddress, "piece3", 1<<30, false, dealStart, 190*builtin.EpochsInDay).IDs[0]
+
+	vm.ApplyOk(t, v, worker, minerAddrs.RobustAddress, big.Zero(), builtin.MethodsMiner.PreCommitSector, &miner.PreCommitSectorParams{
+		SealProof:     sealProof,
+		SectorNumber:  sectorNumber,
+		SealedCID:     sealedCid,
+		SealRandEpoch: v.GetEpoch() - 1,
+		DealIDs:       dealIDs,
+		Expiration:    v.GetEpoch() + 220*builtin.EpochsInDay,
+	})
+
+	proveTime := v.GetEpoch() + miner.PreCommitChallengeDelay + 1
+	v, _ = vm.AdvanceByDeadlineTillEpoch(t, v, minerAddrs.IDAddress, proveTime)
+	v, err := v.WithEpoch(proveTime)
+	require.NoError(t, err)
+	vm.ApplyOk(t, v, worker, minerAddrs.RobustAddress, big.Zero(), builtin.MethodsMiner.ProveCommitSector, &miner.ProveCommitSectorParams{
+		SectorNumber: sectorNumber,
+	})
+	vm.ApplyOk(t, v, builtin.SystemActorAddr, builtin.CronActorAddr, big.Zero(), builtin.MethodsCron.EpochTick, nil)
+
+	tree, err := states.LoadTree(v.Store(), v.StateRoot())
+	require.NoError(t, err)
+	idx, err := states.BuildPieceIndex(tree)
+	require.NoError(t, err)
+
+	var minerSt miner.State
+	require.NoError(t, v.GetState(minerAddrs.IDAddress, &minerSt))
+	dlIdx, pIdx, err := minerSt.FindSector(v.Store(), sectorNumber)
+	require.NoError(t, err)
+
+	piece1 := tutil.MakeCID("piece1", &market.PieceCIDPrefix)
+	assert.Equal(t, dealIDs[:2], idx.DealsForPiece(piece1))
+	locations := idx.LocatePiece(piece1)
+	require.Len(t, locations, 2)
+	dealEnds := []abi.ChainEpoch{dealStart + 181*builtin.EpochsInDay, dealStart + 200*builtin.EpochsInDay}
+	for i, loc := range locations {
+		assert.Equal(t, states.PieceLocation{
+			DealID:       dealIDs[i],
+			PieceCID:     piece1,
+			Provider:     minerAddrs.IDAddress,
+			SectorNumber: sectorNumber,
+			Deadline:     dlIdx,
+			Partition:    pIdx,
+			DealEndEpoch: dealEnds[i],
+		}, loc)
+	}
+
+	loc, found := idx.LocateDeal(dealIDs[2])
+	require.True(t, found)
+	assert.Equal(t, tutil.MakeCID("piece2", &market.PieceCIDPrefix), loc.PieceCID)
+	assert.Equal(t, sectorNumber, loc.SectorNumber)
+
+	// Unactivated deals are not indexed.
+	_, found = idx.LocateDeal(pending)
+	assert.False(t, found)
+	assert.Empty(t, idx.LocatePiece(tutil.MakeCID("piece3", &market.PieceCIDPrefix)))
+}
@@ -0,0 +1,131 @@
+package test_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/power"
+	"github.com/filecoin-project/specs-actors/v3/actors/runtime/proof"
+	"github.com/filecoin-project/specs-actors/v3/support/ipld"
+	tutil "github.com/filecoin-project/specs-actors/v3/support/testing"
+	vm "github.com/filecoin-project/specs-actors/v3/support/vm"
+)
+
+func TestRandomnessSource(t *testing.T) {
+	req := vm.RandomnessRequest{
+		Kind:    vm.RandomnessTickets,
+		Tag:     crypto.DomainSeparationTag_SealRandomness,
+		Epoch:   100,
+		Entropy: []byte{1, 2, 3},
+	}
+
+	t.Run("same seed gives same values", func(t *testing.T) {
+		assert.Equal(t, vm.NewRandomnessSource(7).Get(req), vm.NewRandomnessSource(7).Get(req))
+		assert.Len(t, vm.NewRandomnessSource(7).Get(req), abi.RandomnessLength)
+	})
+
+	t.Run("every input distinguishes values", func(t *testing.T) {
+		r := vm.NewRandomnessSource(7)
+		base := r.Get(req)
+
+		otherSeed := vm.NewRandomnessSource(8).Get(req)
+		assert.NotEqual(t, base, otherSeed)
+
+		variants := []vm.RandomnessRequest{req, req, req, req}
+		variants[0].Kind = vm.RandomnessBeacon
+		variants[1].Tag = crypto.DomainSeparationTag_InteractiveSealChallengeSeed
+		variants[2].Epoch = 101
+		variants[3].Entropy = []byte{1, 2, 4}
+		for _, v := range variants {
+			assert.NotEqual(t, base, r.Get(v))
+		}
+	})
+
+	t.Run("registered values override derived values", func(t *testing.T) {
+		r := vm.NewRandomnessSource(7)
+		r.Register(vm.RandomnessTickets, 100, abi.Randomness("registered"))
+		assert.Equal(t, abi.Randomness("registered"), r.Get(req))
+
+		// Only the registered kind and epoch are affected.
+		beaconReq := req
+		beaconReq.Kind = vm.RandomnessBeacon
+		assert.Equal(t, vm.NewRandomnessSource(7).Get(beaconReq), r.Get(beaconReq))
+		otherEpochReq := req
+		otherEpochReq.Epoch = 99
+		assert.Equal(t, vm.NewRandomnessSource(7).Get(otherEpochReq), r.Get(otherEpochReq))
+	})
+}
+
+func TestProveCommitRandomnessRequests(t *testing.T) {
+	ctx := context.Background()
+	v := vm.NewVMWithSingletons(ctx, t, ipld.NewBlockStoreInMemory())
+	v.SetRandomnessSource(vm.NewRandomnessSource(1234))
+	addrs := vm.CreateAccounts(ctx, t, v, 1, big.Mul(big.NewInt(10_000), big.NewInt(1e18)), 93837778)
+
+	minerBalance := big.Mul(big.NewInt(10_000), vm.FIL)
+	sealProof := abi.RegisteredSealProof_StackedDrg32GiBV1_1
+
+	params := power.CreateMinerParams{
+		Owner:               addrs[0],
+		Worker:              addrs[0],
+		WindowPoStProofType: abi.RegisteredPoStProof_StackedDrgWindow32GiBV1,
+		Peer:                abi.PeerID("not really a peer id"),
+	}
+	ret := vm.ApplyOk(t, v, addrs[0], builtin.StoragePowerActorAddr, minerBalance, builtin.MethodsPower.CreateMiner, &params)
+	minerAddrs, ok := ret.(*power.CreateMinerReturn)
+	require.True(t, ok)
+
+	v, err := v.WithEpoch(200)
+	require.NoError(t, err)
+
+	sectorNumber := abi.SectorNumber(100)
+	preCommitParams := miner.PreCommitSectorParams{
+		SealProof:     sealProof,
+		SectorNumber:  sectorNumber,
+		SealedCID:     tutil.MakeCID("100", &miner.SealedCIDPrefix),
+		SealRandEpoch: v.GetEpoch() - 1,
+		Expiration:    v.GetEpoch() + miner.MinSectorExpiration + miner.MaxProveCommitDuration[sealProof] + 100,
+	}
+	vm.ApplyOk(t, v, addrs[0], minerAddrs.RobustAddress, big.Zero(), builtin.MethodsMiner.PreCommitSector, &preCommitParams)
+	interactiveEpoch := v.GetEpoch() + miner.PreCommitChallengeDelay
+
+	// Register the seal randomness so we can check it reaches the proof verification.
+	sealRand := abi.Randomness("registered seal randomness")
+	v.GetRandomnessSource().Register(vm.RandomnessTickets, preCommitParams.SealRandEpoch, sealRand)
+
+	v, err = v.WithEpoch(interactiveEpoch + 1)
+	require.NoError(t, err)
+	vm.ApplyOk(t, v, addrs[0], minerAddrs.RobustAddress, big.Zero(), builtin.MethodsMiner.ProveCommitSector,
+		&miner.ProveCommitSectorParams{SectorNumber: sectorNumber})
+
+	var minerEntropy bytes.Buffer
+	require.NoError(t, minerAddrs.IDAddress.MarshalCBOR(&minerEntropy))
+
+	vm.ExpectInvocation{
+		To:     minerAddrs.IDAddress,
+		Method: builtin.MethodsMiner.ProveCommitSector,
+		RandomnessRequests: []vm.RandomnessRequest{
+			{Kind: vm.RandomnessTickets, Tag: crypto.DomainSeparationTag_SealRandomness, Epoch: preCommitParams.SealRandEpoch, Entropy: minerEntropy.Bytes()},
+			{Kind: vm.RandomnessBeacon, Tag: crypto.DomainSeparationTag_InteractiveSealChallengeSeed, Epoch: interactiveEpoch, Entropy: minerEntropy.Bytes()},
+		},
+		SubInvocations: []vm.ExpectInvocation{
+			{To: builtin.StorageMarketActorAddr, Method: builtin.MethodsMarket.ComputeDataCommitment},
+			{To: builtin.StoragePowerActorAddr, Method: builtin.MethodsPower.SubmitPoRepForBulkVerify},
+		},
+	}.Matches(t, v.Invocations()[0])
+
+	svi, ok := vm.ParamsForInvocation(t, v, 0, 1).(*proof.SealVerifyInfo)
+	require.True(t, ok)
+	assert.Equal(t, abi.SealRandomness(sealRand), svi.Randomness)
+	interactiveRand := v.GetRandomnessFromBeacon(crypto.DomainSeparationTag_InteractiveSealChallengeSeed, interactiveEpoch, minerEntropy.Bytes())
+	assert.Equal(t, abi.InteractiveSealRandomness(interactiveRand), svi.InteractiveRandomness)
+}
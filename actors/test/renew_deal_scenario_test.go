@@ -0,0 +1,145 @@
+package test_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/market"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/power"
+	"github.com/filecoin-project/specs-actors/v3/actors/runtime/proof"
+	"github.com/filecoin-project/specs-actors/v3/actors/states"
+	"github.com/filecoin-project/specs-actors/v3/support/ipld"
+	tutil "github.com/filecoin-project/specs-actors/v3/support/testing"
+	vm "github.com/filecoin-project/specs-actors/v3/support/vm"
+)
+
+func TestRenewDeal(t *testing.T) {
+	ctx := context.Background()
+	v := vm.NewVMWithSingletons(ctx, t, ipld.NewBlockStoreInMemory())
+	addrs := vm.CreateAccounts(ctx, t, v, 2, big.Mul(big.NewInt(10_000), vm.FIL), 93837778)
+	worker, client := addrs[0], addrs[1]
+
+	sectorNumber := abi.SectorNumber(100)
+	sealedCid := tutil.MakeCID("100", &miner.SealedCIDPrefix)
+	sealProof := abi.RegisteredSealProof_StackedDrg32GiBV1_1
+
+	ret := vm.ApplyOk(t, v, worker, builtin.StoragePowerActorAddr, big.Mul(big.NewInt(1_000), vm.FIL), builtin.MethodsPower.CreateMiner, &power.CreateMinerParams{
+		Owner:               worker,
+		Worker:              worker,
+		WindowPoStProofType: abi.RegisteredPoStProof_StackedDrgWindow32GiBV1,
+		Peer:                abi.PeerID("not really a peer id"),
+	})
+	minerAddrs, ok := ret.(*power.CreateMinerReturn)
+	require.True(t, ok)
+
+	vm.ApplyOk(t, v, client, builtin.StorageMarketActorAddr, big.Mul(big.NewInt(10), vm.FIL), builtin.MethodsMarket.AddBalance, &client)
+	vm.ApplyOk(t, v, worker, builtin.StorageMarketActorAddr, big.Mul(big.NewInt(64), vm.FIL), builtin.MethodsMarket.AddBalance, &minerAddrs.IDAddress)
+
+	dealStart := v.GetEpoch() + miner.PreCommitChallengeDelay + 1
+	dealID := publishDeal(t, v, worker, client, minerAddrs.IDAddress, "piece", 1<<30, false, dealStart, 181*builtin.EpochsInDay).IDs[0]
+
+	vm.ApplyOk(t, v, worker, minerAddrs.RobustAddress, big.Zero(), builtin.MethodsMiner.PreCommitSector, &miner.PreCommitSectorParams{
+		SealProof:     sealProof,
+		SectorNumber:  sectorNumber,
+		SealedCID:     sealedCid,
+		SealRandEpoch: v.GetEpoch() - 1,
+		DealIDs:       []abi.DealID{dealID},
+		Expiration:    v.GetEpoch() + 220*builtin.EpochsInDay,
+	})
+
+	proveTime := v.GetEpoch() + miner.PreCommitChallengeDelay + 1
+	v, _ = vm.AdvanceByDeadlineTillEpoch(t, v, minerAddrs.IDAddress, proveTime)
+	v, err := v.WithEpoch(proveTime)
+	require.NoError(t, err)
+	vm.ApplyOk(t, v, worker, minerAddrs.RobustAddress, big.Zero(), builtin.MethodsMiner.ProveCommitSector, &miner.ProveCommitSectorParams{
+		SectorNumber: sectorNumber,
+	})
+	vm.ApplyOk(t, v, builtin.SystemActorAddr, builtin.CronActorAddr, big.Zero(), builtin.MethodsCron.EpochTick, nil)
+
+	// The sector becomes active once proven.
+	dlInfo, pIdx, v := vm.AdvanceTillProvingDeadline(t, v, minerAddrs.IDAddress, sectorNumber)
+	vm.ApplyOk(t, v, worker, minerAddrs.RobustAddress, big.Zero(), builtin.MethodsMiner.SubmitWindowedPoSt, &miner.SubmitWindowedPoStParams{
+		Deadline: dlInfo.Index,
+		Partitions: []miner.PoStPartition{{
+			Index:   pIdx,
+			Skipped: bitfield.New(),
+		}},
+		Proofs: []proof.PoStProof{{
+			PoStProof: abi.RegisteredPoStProof_StackedDrgWindow32GiBV1,
+		}},
+		ChainCommitEpoch: dlInfo.Challenge,
+		ChainCommitRand:  v.GetRandomnessFromTickets(crypto.DomainSeparationTag_PoStChainCommit, dlInfo.Challenge, nil),
+	})
+
+	// Advance until the deal has been processed by cron.
+	v, _ = vm.AdvanceByDeadlineTillEpoch(t, v, minerAddrs.IDAddress, dealStart+market.DealUpdatesInterval)
+	dealState, found := vm.GetDealState(t, v, dealID)
+	require.True(t, found)
+	require.NotEqual(t, abi.ChainEpoch(-1), dealState.LastUpdatedEpoch)
+
+	var minerSt miner.State
+	require.NoError(t, v.GetState(minerAddrs.IDAddress, &minerSt))
+	oldSector, found, err := minerSt.GetSector(v.Store(), sectorNumber)
+	require.NoError(t, err)
+	require.True(t, found)
+	oldMinerPledge := minerSt.InitialPledge
+
+	newEnd := dealStart + 200*builtin.EpochsInDay
+	vm.ApplyOk(t, v, worker, minerAddrs.RobustAddress, big.Zero(), builtin.MethodsMiner.RenewSectorDeal, &miner.RenewSectorDealParams{
+		Deadline:  dlInfo.Index,
+		Partition: pIdx,
+		Sector:    sectorNumber,
+		Renewal: market.ClientDealRenewal{
+			Renewal: market.DealRenewal{
+				DealID:                       dealID,
+				NewEndEpoch:                  newEnd,
+				NewStoragePricePerEpoch:      abi.NewTokenAmount(1 << 21),
+				AdditionalClientCollateral:   big.Zero(),
+				AdditionalProviderCollateral: vm.FIL,
+			},
+			ClientSignature: crypto.Signature{},
+		},
+	})
+
+	var marketSt market.State
+	require.NoError(t, v.GetState(builtin.StorageMarketActorAddr, &marketSt))
+	proposals, err := market.AsDealProposalArray(v.Store(), marketSt.Proposals)
+	require.NoError(t, err)
+	proposal, found, err := proposals.Get(dealID)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, newEnd, proposal.EndEpoch)
+	assert.Equal(t, abi.NewTokenAmount(1<<21), proposal.StoragePricePerEpoch)
+	assert.Equal(t, big.Mul(big.NewInt(3), vm.FIL), proposal.ProviderCollateral)
+
+	require.NoError(t, v.GetState(minerAddrs.IDAddress, &minerSt))
+	newSector, found, err := minerSt.GetSector(v.Store(), sectorNumber)
+	require.NoError(t, err)
+	require.True(t, found)
+	weightDelta := big.Mul(big.NewIntUnsigned(uint64(proposal.PieceSize)), big.NewInt(int64(19*builtin.EpochsInDay)))
+	assert.Equal(t, big.Add(oldSector.DealWeight, weightDelta), newSector.DealWeight)
+	// The pledge recomputed for the sector's new power is locked by the miner.
+	assert.True(t, newSector.InitialPledge.GreaterThanEqual(oldSector.InitialPledge))
+	assert.Equal(t, big.Sub(newSector.InitialPledge, oldSector.InitialPledge), big.Sub(minerSt.InitialPledge, oldMinerPledge))
+
+	v, err = v.WithEpoch(v.GetEpoch() + 1)
+	require.NoError(t, err)
+	vm.ApplyOk(t, v, builtin.SystemActorAddr, builtin.CronActorAddr, big.Zero(), builtin.MethodsCron.EpochTick, nil)
+	stateTree, err := v.GetStateTree()
+	require.NoError(t, err)
+	totalBalance, err := v.GetTotalActorBalance()
+	require.NoError(t, err)
+	msgs, err := states.CheckStateInvariants(stateTree, totalBalance, v.GetEpoch())
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(msgs.Messages()), strings.Join(msgs.Messages(), "\n"))
+}
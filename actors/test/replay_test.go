@@ -0,0 +1,134 @@
+package test_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/filecoin-project/go-state-types/network"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin"
+	init_ "github.com/filecoin-project/specs-actors/v3/actors/builtin/init"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/multisig"
+	"github.com/filecoin-project/specs-actors/v3/support/ipld"
+	vm "github.com/filecoin-project/specs-actors/v3/support/vm"
+)
+
+func TestReplay(t *testing.T) {
+	ctx := context.Background()
+	bs := ipld.NewBlockStoreInMemory()
+	v := vm.NewVMWithSingletons(ctx, t, bs)
+	addrs := vm.CreateAccounts(ctx, t, v, 2, big.Mul(big.NewInt(10_000), big.NewInt(1e18)), 93837778)
+	v, err := v.WithEpoch(10) // Commits the state root.
+	require.NoError(t, err)
+	startRoot := v.StateRoot()
+	startEpoch := v.GetEpoch()
+
+	msigParams := new(bytes.Buffer)
+	require.NoError(t, (&multisig.ConstructorParams{Signers: addrs, NumApprovalsThreshold: 2}).MarshalCBOR(msigParams))
+	execParams := new(bytes.Buffer)
+	require.NoError(t, (&init_.ExecParams{CodeCID: builtin.MultisigActorCodeID, ConstructorParams: msigParams.Bytes()}).MarshalCBOR(execParams))
+
+	supply := big.Mul(big.NewInt(1e8), big.NewInt(1e18))
+	msgs := []vm.ReplayMessage{{
+		From:              addrs[0],
+		To:                addrs[1],
+		Value:             big.NewInt(1000),
+		Method:            builtin.MethodSend,
+		Epoch:             startEpoch,
+		CirculatingSupply: supply,
+	}, {
+		From:              addrs[1],
+		To:                builtin.InitActorAddr,
+		Value:             big.NewInt(100),
+		Method:            builtin.MethodsInit.Exec,
+		Params:            execParams.Bytes(),
+		Epoch:             startEpoch + 10,
+		CirculatingSupply: supply,
+	}, {
+		// Fails, since only the system actor may construct the init actor.
+		From:              addrs[1],
+		To:                builtin.InitActorAddr,
+		Value:             big.Zero(),
+		Method:            builtin.MethodsInit.Constructor,
+		Epoch:             startEpoch + 20,
+		CirculatingSupply: supply,
+		ExpectedExitCode:  exitcode.ErrForbidden,
+	}}
+
+	// Record the expected outcomes by replaying once onto the original VM.
+	recorded, err := vm.ReplayOnVM(v, msgs)
+	require.NoError(t, err)
+	require.Nil(t, recorded.Divergence)
+	for i := range msgs {
+		msgs[i].ExpectedRoot = recorded.Receipts[i].StateRoot
+	}
+	assert.Equal(t, exitcode.Ok, recorded.Receipts[0].ExitCode)
+	assert.Equal(t, exitcode.Ok, recorded.Receipts[1].ExitCode)
+	assert.Equal(t, exitcode.ErrForbidden, recorded.Receipts[2].ExitCode)
+
+	var execRet init_.ExecReturn
+	require.NoError(t, execRet.UnmarshalCBOR(bytes.NewReader(recorded.Receipts[1].Return)))
+	assert.Equal(t, address.ID, execRet.IDAddress.Protocol())
+
+	replay := func(t *testing.T, msgs []vm.ReplayMessage) *vm.ReplayResult {
+		result, err := vm.Replay(ctx, v.ActorImpls, bs.Blocks(), startRoot, startEpoch, network.VersionMax, msgs)
+		require.NoError(t, err)
+		require.Len(t, result.Receipts, len(msgs))
+		return result
+	}
+
+	t.Run("replay reproduces recorded outcomes", func(t *testing.T) {
+		result := replay(t, msgs)
+		assert.Nil(t, result.Divergence)
+		assert.Equal(t, recorded.FinalRoot, result.FinalRoot)
+		assert.Equal(t, recorded.Receipts, result.Receipts)
+	})
+
+	t.Run("reports divergent exit code", func(t *testing.T) {
+		altered := append([]vm.ReplayMessage{}, msgs...)
+		altered[2].ExpectedExitCode = exitcode.Ok
+
+		result := replay(t, altered)
+		require.NotNil(t, result.Divergence)
+		assert.Equal(t, 2, result.Divergence.Index)
+		assert.Equal(t, exitcode.Ok, result.Divergence.ExpectedExitCode)
+		assert.Equal(t, exitcode.ErrForbidden, result.Divergence.ActualExitCode)
+		assert.Equal(t, recorded.FinalRoot, result.FinalRoot)
+	})
+
+	t.Run("reports first divergent state root", func(t *testing.T) {
+		altered := append([]vm.ReplayMessage{}, msgs...)
+		altered[0].Value = big.NewInt(1001)
+
+		result := replay(t, altered)
+		require.NotNil(t, result.Divergence)
+		assert.Equal(t, 0, result.Divergence.Index)
+		assert.Equal(t, exitcode.Ok, result.Divergence.ActualExitCode)
+		assert.Equal(t, msgs[0].ExpectedRoot, result.Divergence.ExpectedRoot)
+		assert.Equal(t, result.Receipts[0].StateRoot, result.Divergence.ActualRoot)
+		assert.NotEqual(t, result.Divergence.ExpectedRoot, result.Divergence.ActualRoot)
+
+		// Later messages are still applied.
+		assert.Equal(t, exitcode.Ok, result.Receipts[1].ExitCode)
+		assert.Equal(t, exitcode.ErrForbidden, result.Receipts[2].ExitCode)
+	})
+
+	t.Run("rejects messages out of epoch order", func(t *testing.T) {
+		altered := append([]vm.ReplayMessage{}, msgs...)
+		altered[2].Epoch = startEpoch
+
+		_, err := vm.Replay(ctx, v.ActorImpls, bs.Blocks(), startRoot, startEpoch, network.VersionMax, altered)
+		assert.Error(t, err)
+	})
+
+	t.Run("fails without the starting state", func(t *testing.T) {
+		_, err := vm.Replay(ctx, v.ActorImpls, nil, startRoot, startEpoch, network.VersionMax, msgs)
+		assert.Error(t, err)
+	})
+}
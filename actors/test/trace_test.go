@@ -0,0 +1,120 @@
+package test_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin"
+	init_ "github.com/filecoin-project/specs-actors/v3/actors/builtin/init"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/multisig"
+	"github.com/filecoin-project/specs-actors/v3/support/ipld"
+	vm "github.com/filecoin-project/specs-actors/v3/support/vm"
+)
+
+func TestTraceExport(t *testing.T) {
+	ctx := context.Background()
+
+	// Creates a multisig and proposes a transfer of value from it.
+	runScenario := func(t *testing.T, value int64) *vm.VM {
+		bs := ipld.NewMetricsBlockStore(ipld.NewBlockStoreInMemory())
+		v := vm.NewVMWithSingletons(ctx, t, bs)
+		v.SetStatsSource(bs)
+		addrs := vm.CreateAccounts(ctx, t, v, 2, big.Mul(big.NewInt(10_000), big.NewInt(1e18)), 93837778)
+
+		paramBuf := new(bytes.Buffer)
+		require.NoError(t, (&multisig.ConstructorParams{Signers: addrs, NumApprovalsThreshold: 1}).MarshalCBOR(paramBuf))
+		ret := vm.ApplyOk(t, v, addrs[0], builtin.InitActorAddr, big.NewInt(100), builtin.MethodsInit.Exec,
+			&init_.ExecParams{CodeCID: builtin.MultisigActorCodeID, ConstructorParams: paramBuf.Bytes()})
+		msigAddr := ret.(*init_.ExecReturn).IDAddress
+
+		vm.ApplyOk(t, v, addrs[0], msigAddr, big.Zero(), builtin.MethodsMultisig.Propose, &multisig.ProposeParams{
+			To:     addrs[1],
+			Value:  big.NewInt(value),
+			Method: builtin.MethodSend,
+		})
+
+		// A failing message is traced too.
+		_, code := v.ApplyMessage(addrs[1], msigAddr, big.Zero(), builtin.MethodsMultisig.Approve, &multisig.TxnIDParams{ID: 5})
+		require.Equal(t, exitcode.ErrNotFound, code)
+		return v
+	}
+
+	t.Run("trace records the call tree", func(t *testing.T) {
+		v := runScenario(t, 10)
+		trace := v.Trace()
+		require.Len(t, trace.Invocations, 3)
+
+		exec := trace.Invocations[0]
+		assert.Equal(t, "fil/3/init", exec.Actor)
+		assert.Equal(t, "Exec", exec.MethodName)
+		assert.Equal(t, big.NewInt(100), exec.Value)
+		assert.Equal(t, int64(exitcode.Ok), exec.ExitCode)
+		assert.True(t, exec.Reads > 0)
+		assert.True(t, exec.Writes > 0)
+		assert.True(t, exec.GasUsed > 0)
+		require.Len(t, exec.SubInvocations, 1)
+		assert.Equal(t, "fil/3/multisig", exec.SubInvocations[0].Actor)
+		assert.Equal(t, "Constructor", exec.SubInvocations[0].MethodName)
+
+		propose := trace.Invocations[1]
+		assert.Equal(t, "Propose", propose.MethodName)
+		require.Len(t, propose.SubInvocations, 1)
+		assert.Equal(t, "Send", propose.SubInvocations[0].MethodName)
+		assert.Equal(t, big.NewInt(10), propose.SubInvocations[0].Value)
+
+		approve := trace.Invocations[2]
+		assert.Equal(t, "Approve", approve.MethodName)
+		assert.Equal(t, int64(exitcode.ErrNotFound), approve.ExitCode)
+	})
+
+	t.Run("round trips through JSON", func(t *testing.T) {
+		v := runScenario(t, 10)
+		trace := v.Trace()
+
+		var buf bytes.Buffer
+		require.NoError(t, trace.WriteJSON(&buf, v.ActorImpls))
+		assert.Contains(t, buf.String(), `"DecodedParams"`)
+		assert.Contains(t, buf.String(), `"MethodName": "Propose"`)
+
+		loaded, err := vm.LoadTraceJSON(&buf)
+		require.NoError(t, err)
+		assert.Empty(t, vm.DiffTraces(trace, loaded))
+	})
+
+	t.Run("round trips through CBOR", func(t *testing.T) {
+		v := runScenario(t, 10)
+		trace := v.Trace()
+
+		var buf bytes.Buffer
+		require.NoError(t, trace.WriteCBOR(&buf))
+		loaded, err := vm.LoadTraceCBOR(&buf)
+		require.NoError(t, err)
+		assert.Empty(t, vm.DiffTraces(trace, loaded))
+	})
+
+	t.Run("diffs runs of the same scenario", func(t *testing.T) {
+		same := vm.DiffTraces(runScenario(t, 10).Trace(), runScenario(t, 10).Trace())
+		assert.Empty(t, same)
+
+		diffs := vm.DiffTraces(runScenario(t, 10).Trace(), runScenario(t, 11).Trace())
+		require.NotEmpty(t, diffs)
+		assert.Equal(t, "/1", diffs[0].Path)
+		assert.Equal(t, "Params", diffs[0].Field)
+
+		var sendDiff *vm.TraceDifference
+		for i := range diffs {
+			if diffs[i].Path == "/1/0" && diffs[i].Field == "Value" {
+				sendDiff = &diffs[i]
+			}
+		}
+		require.NotNil(t, sendDiff)
+		assert.Equal(t, "10", sendDiff.A)
+		assert.Equal(t, "11", sendDiff.B)
+	})
+}
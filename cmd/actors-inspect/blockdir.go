@@ -0,0 +1,42 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	block "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	ipldcbor "github.com/ipfs/go-ipld-cbor"
+	"golang.org/x/xerrors"
+)
+
+// A blockstore backed by a directory holding one file per block, named by the block's CID.
+type dirBlockstore struct {
+	dir string
+}
+
+var _ ipldcbor.IpldBlockstore = (*dirBlockstore)(nil)
+
+func newDirBlockstore(dir string) *dirBlockstore {
+	return &dirBlockstore{dir: dir}
+}
+
+func (ds *dirBlockstore) Get(c cid.Cid) (block.Block, error) {
+	data, err := ioutil.ReadFile(filepath.Join(ds.dir, c.String()))
+	if err != nil {
+		return nil, err
+	}
+	return block.NewBlockWithCid(data, c)
+}
+
+func (ds *dirBlockstore) Put(b block.Block) error {
+	path := filepath.Join(ds.dir, b.Cid().String())
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := ioutil.WriteFile(path, b.RawData(), 0644); err != nil {
+		return xerrors.Errorf("failed to write block %s: %w", b.Cid(), err)
+	}
+	return nil
+}
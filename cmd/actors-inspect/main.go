@@ -0,0 +1,256 @@
+// Command actors-inspect prints the state of actors in a state tree read from a CAR file or block directory,
+// and checks state invariants, without a running node.
+//
+// Usage:
+//
+//	actors-inspect (-car FILE | -blockdir DIR) [-root CID] COMMAND [ARGS]
+//
+// Commands:
+//
+//	actors                  list all actors in the tree
+//	actor ADDR              print an actor and its state
+//	miner-info ADDR         print a miner's info
+//	miner-deadlines ADDR    print a miner's deadlines and partitions
+//	miner-vesting ADDR      print a miner's vesting table
+//	deal ID                 print a market deal proposal and state
+//	claim ADDR              print a miner's power claim
+//	datacap ADDR            print the data cap of a verifier or verified client
+//	check                   run state invariant checks
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/ipfs/go-cid"
+	ipldcbor "github.com/ipfs/go-ipld-cbor"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin"
+	init_ "github.com/filecoin-project/specs-actors/v3/actors/builtin/init"
+	"github.com/filecoin-project/specs-actors/v3/actors/states"
+	"github.com/filecoin-project/specs-actors/v3/actors/util/adt"
+	"github.com/filecoin-project/specs-actors/v3/support/agent"
+	"github.com/filecoin-project/specs-actors/v3/support/ipld"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("actors-inspect", flag.ContinueOnError)
+	carPath := flags.String("car", "", "CAR file holding the state tree")
+	blockDir := flags.String("blockdir", "", "directory holding one file of block data per CID, named by the CID")
+	rootStr := flags.String("root", "", "state tree root CID, defaults to the first root of the CAR file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 1 {
+		flags.Usage()
+		return xerrors.Errorf("no command")
+	}
+
+	ctx := context.Background()
+	bs, roots, err := openBlockstore(*carPath, *blockDir)
+	if err != nil {
+		return err
+	}
+	var root cid.Cid
+	if *rootStr != "" {
+		if root, err = cid.Decode(*rootStr); err != nil {
+			return xerrors.Errorf("invalid root %s: %w", *rootStr, err)
+		}
+	} else if len(roots) > 0 {
+		root = roots[0]
+	} else {
+		return xerrors.Errorf("no state root given")
+	}
+
+	store := adt.WrapBlockStore(ctx, bs)
+	tree, err := states.LoadTree(store, root)
+	if err != nil {
+		return xerrors.Errorf("failed to load state tree %s: %w", root, err)
+	}
+	in := inspector{out: out, store: store, tree: tree}
+
+	cmd, cmdArgs := flags.Arg(0), flags.Args()[1:]
+	switch cmd {
+	case "actors":
+		return in.printActors()
+	case "actor", "miner-info", "miner-deadlines", "miner-vesting", "claim", "datacap":
+		if len(cmdArgs) != 1 {
+			return xerrors.Errorf("%s requires an address", cmd)
+		}
+		a, err := in.resolveAddress(cmdArgs[0])
+		if err != nil {
+			return err
+		}
+		switch cmd {
+		case "actor":
+			return in.printActor(a)
+		case "miner-info":
+			return in.printMinerInfo(a)
+		case "miner-deadlines":
+			return in.printMinerDeadlines(a)
+		case "miner-vesting":
+			return in.printMinerVesting(a)
+		case "claim":
+			return in.printClaim(a)
+		default:
+			return in.printDataCap(a)
+		}
+	case "deal":
+		if len(cmdArgs) != 1 {
+			return xerrors.Errorf("deal requires a deal ID")
+		}
+		id, err := strconv.ParseUint(cmdArgs[0], 10, 64)
+		if err != nil {
+			return xerrors.Errorf("invalid deal ID %s: %w", cmdArgs[0], err)
+		}
+		return in.printDeal(abi.DealID(id))
+	case "check":
+		return in.check(cmdArgs)
+	default:
+		return xerrors.Errorf("unknown command %s", cmd)
+	}
+}
+
+// Opens exactly one of a CAR file or block directory, returning the roots listed in a CAR file.
+func openBlockstore(carPath, blockDir string) (ipldcbor.IpldBlockstore, []cid.Cid, error) {
+	switch {
+	case carPath != "" && blockDir != "":
+		return nil, nil, xerrors.Errorf("only one of -car and -blockdir may be given")
+	case carPath != "":
+		f, err := os.Open(carPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer func() { _ = f.Close() }()
+		bs := ipld.NewBlockStoreInMemory()
+		roots, err := agent.ImportCar(bs, f)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("failed to read %s: %w", carPath, err)
+		}
+		return bs, roots, nil
+	case blockDir != "":
+		return newDirBlockstore(blockDir), nil, nil
+	default:
+		return nil, nil, xerrors.Errorf("one of -car or -blockdir is required")
+	}
+}
+
+type inspector struct {
+	out   io.Writer
+	store adt.Store
+	tree  *states.Tree
+}
+
+func (in *inspector) printf(format string, args ...interface{}) {
+	_, _ = fmt.Fprintf(in.out, format, args...)
+}
+
+// Resolves an address to an ID address through the init actor.
+func (in *inspector) resolveAddress(s string) (address.Address, error) {
+	a, err := address.NewFromString(s)
+	if err != nil {
+		return address.Undef, xerrors.Errorf("invalid address %s: %w", s, err)
+	}
+	if a.Protocol() == address.ID {
+		return a, nil
+	}
+	var st init_.State
+	if err := in.loadActorState(builtin.InitActorAddr, builtin.InitActorCodeID, &st); err != nil {
+		return address.Undef, err
+	}
+	idAddr, found, err := st.ResolveAddress(in.store, a)
+	if err != nil {
+		return address.Undef, err
+	}
+	if !found {
+		return address.Undef, xerrors.Errorf("address %s not found", a)
+	}
+	return idAddr, nil
+}
+
+// Loads an actor, checking its code if code is defined.
+func (in *inspector) loadActor(a address.Address, code cid.Cid) (*states.Actor, error) {
+	act, found, err := in.tree.GetActor(a)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, xerrors.Errorf("actor %s not found", a)
+	}
+	if code.Defined() && !act.Code.Equals(code) {
+		return nil, xerrors.Errorf("actor %s is a %s, not a %s", a, builtin.ActorNameByCode(act.Code), builtin.ActorNameByCode(code))
+	}
+	return act, nil
+}
+
+func (in *inspector) loadActorState(a address.Address, code cid.Cid, out interface{}) error {
+	act, err := in.loadActor(a, code)
+	if err != nil {
+		return err
+	}
+	if err := in.store.Get(in.store.Context(), act.Head, out); err != nil {
+		return xerrors.Errorf("failed to load state of %s: %w", a, err)
+	}
+	return nil
+}
+
+func (in *inspector) printActors() error {
+	in.printf("%-12s %-20s %30s %8s %s\n", "Address", "Code", "Balance", "Nonce", "Head")
+	return in.tree.ForEach(func(a address.Address, act *states.Actor) error {
+		in.printf("%-12s %-20s %30s %8d %s\n", a, builtin.ActorNameByCode(act.Code), act.Balance, act.CallSeqNum, act.Head)
+		return nil
+	})
+}
+
+func (in *inspector) check(args []string) error {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	balanceStr := flags.String("balance", "", "expected total FIL balance in attoFIL, defaults to the sum of actor balances")
+	epoch := flags.Int64("epoch", 0, "epoch prior to the state")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var expected abi.TokenAmount
+	if *balanceStr != "" {
+		var err error
+		if expected, err = big.FromString(*balanceStr); err != nil {
+			return xerrors.Errorf("invalid balance %s: %w", *balanceStr, err)
+		}
+	} else {
+		expected = big.Zero()
+		if err := in.tree.ForEach(func(_ address.Address, act *states.Actor) error {
+			expected = big.Add(expected, act.Balance)
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	acc, err := states.CheckStateInvariants(in.tree, expected, abi.ChainEpoch(*epoch))
+	if err != nil {
+		return err
+	}
+	if acc.IsEmpty() {
+		in.printf("no invariant violations\n")
+		return nil
+	}
+	for _, msg := range acc.Messages() {
+		in.printf("%s\n", msg)
+	}
+	return xerrors.Errorf("%d invariant violations", len(acc.Messages()))
+}
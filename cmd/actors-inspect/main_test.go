@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/power"
+	"github.com/filecoin-project/specs-actors/v3/support/agent"
+	"github.com/filecoin-project/specs-actors/v3/support/ipld"
+	vm "github.com/filecoin-project/specs-actors/v3/support/vm"
+)
+
+func TestInspect(t *testing.T) {
+	ctx := context.Background()
+	bs := ipld.NewBlockStoreInMemory()
+	v := vm.NewVMWithSingletons(ctx, t, bs)
+	addrs := vm.CreateAccounts(ctx, t, v, 1, big.Mul(big.NewInt(10_000), big.NewInt(1e18)), 93837778)
+	ret := vm.ApplyOk(t, v, addrs[0], builtin.StoragePowerActorAddr, big.NewInt(1e10), builtin.MethodsPower.CreateMiner, &power.CreateMinerParams{
+		Owner:               addrs[0],
+		Worker:              addrs[0],
+		WindowPoStProofType: abi.RegisteredPoStProof_StackedDrgWindow32GiBV1,
+	})
+	minerAddr := ret.(*power.CreateMinerReturn).IDAddress
+	v, err := v.WithEpoch(1)
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "actors-inspect")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	carPath := filepath.Join(dir, "state.car")
+	var car bytes.Buffer
+	require.NoError(t, agent.ExportStateTree(ctx, bs, &car, v.StateRoot()))
+	require.NoError(t, ioutil.WriteFile(carPath, car.Bytes(), 0644))
+
+	inspect := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		err := run(append([]string{"-car", carPath}, args...), &out)
+		return out.String(), err
+	}
+
+	t.Run("actors", func(t *testing.T) {
+		out, err := inspect("actors")
+		require.NoError(t, err)
+		assert.Contains(t, out, "fil/3/storageminer")
+		assert.Contains(t, out, minerAddr.String())
+	})
+
+	t.Run("actor by robust address", func(t *testing.T) {
+		out, err := inspect("actor", addrs[0].String())
+		require.NoError(t, err)
+		assert.Contains(t, out, "fil/3/account")
+		assert.Contains(t, out, addrs[0].String())
+	})
+
+	t.Run("miner", func(t *testing.T) {
+		out, err := inspect("miner-info", minerAddr.String())
+		require.NoError(t, err)
+		assert.Contains(t, out, "Sector size:           32GiB")
+
+		out, err = inspect("miner-deadlines", minerAddr.String())
+		require.NoError(t, err)
+		assert.Contains(t, out, "Deadline 47:")
+
+		_, err = inspect("miner-vesting", minerAddr.String())
+		require.NoError(t, err)
+
+		_, err = inspect("miner-info", builtin.StoragePowerActorAddr.String())
+		assert.Error(t, err)
+	})
+
+	t.Run("claim", func(t *testing.T) {
+		out, err := inspect("claim", minerAddr.String())
+		require.NoError(t, err)
+		assert.Contains(t, out, "Raw byte power:     0")
+	})
+
+	t.Run("deal not found", func(t *testing.T) {
+		_, err := inspect("deal", "1")
+		assert.Error(t, err)
+	})
+
+	t.Run("check", func(t *testing.T) {
+		// The test VM's genesis reward state is not consistent with the epoch.
+		out, err := inspect("check", "-epoch", "-1")
+		assert.Error(t, err)
+		assert.NotContains(t, out, "does not match priorEpoch")
+		assert.Contains(t, out, "reward: effective baseline power > baseline power")
+
+		out, err = inspect("check", "-balance", "1")
+		assert.Error(t, err)
+		assert.Contains(t, out, ", expected 1\n")
+	})
+
+	t.Run("blockdir", func(t *testing.T) {
+		blockDir := filepath.Join(dir, "blocks")
+		require.NoError(t, os.Mkdir(blockDir, 0755))
+		_, err := agent.ImportCar(newDirBlockstore(blockDir), bytes.NewReader(car.Bytes()))
+		require.NoError(t, err)
+
+		var out bytes.Buffer
+		require.NoError(t, run([]string{"-blockdir", blockDir, "-root", v.StateRoot().String(), "claim", minerAddr.String()}, &out))
+		assert.Contains(t, out.String(), "Quality adj power:  0")
+	})
+}
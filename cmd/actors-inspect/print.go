@@ -0,0 +1,295 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/account"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/cron"
+	init_ "github.com/filecoin-project/specs-actors/v3/actors/builtin/init"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/market"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/multisig"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/paych"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/power"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/reward"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/system"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/verifreg"
+	"github.com/filecoin-project/specs-actors/v3/actors/util/adt"
+)
+
+// Constructors for the state object of each builtin actor.
+var stateTypes = map[cid.Cid]func() interface{}{
+	builtin.SystemActorCodeID:           func() interface{} { return new(system.State) },
+	builtin.InitActorCodeID:             func() interface{} { return new(init_.State) },
+	builtin.CronActorCodeID:             func() interface{} { return new(cron.State) },
+	builtin.AccountActorCodeID:          func() interface{} { return new(account.State) },
+	builtin.StoragePowerActorCodeID:     func() interface{} { return new(power.State) },
+	builtin.StorageMinerActorCodeID:     func() interface{} { return new(miner.State) },
+	builtin.StorageMarketActorCodeID:    func() interface{} { return new(market.State) },
+	builtin.PaymentChannelActorCodeID:   func() interface{} { return new(paych.State) },
+	builtin.MultisigActorCodeID:         func() interface{} { return new(multisig.State) },
+	builtin.RewardActorCodeID:           func() interface{} { return new(reward.State) },
+	builtin.VerifiedRegistryActorCodeID: func() interface{} { return new(verifreg.State) },
+}
+
+func (in *inspector) printActor(a address.Address) error {
+	act, err := in.loadActor(a, cid.Undef)
+	if err != nil {
+		return err
+	}
+	in.printf("Address: %s\nCode:    %s (%s)\nHead:    %s\nBalance: %s\nNonce:   %d\n",
+		a, builtin.ActorNameByCode(act.Code), act.Code, act.Head, act.Balance, act.CallSeqNum)
+
+	newState, ok := stateTypes[act.Code]
+	if !ok {
+		return nil
+	}
+	st := newState()
+	if err := in.store.Get(in.store.Context(), act.Head, st); err != nil {
+		return xerrors.Errorf("failed to load state of %s: %w", a, err)
+	}
+	js, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	in.printf("State:   %s\n", js)
+	return nil
+}
+
+func (in *inspector) loadMinerState(a address.Address) (*miner.State, error) {
+	var st miner.State
+	if err := in.loadActorState(a, builtin.StorageMinerActorCodeID, &st); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+func (in *inspector) printMinerInfo(a address.Address) error {
+	st, err := in.loadMinerState(a)
+	if err != nil {
+		return err
+	}
+	info, err := st.GetInfo(in.store)
+	if err != nil {
+		return err
+	}
+	in.printf("Owner:                 %s\n", info.Owner)
+	in.printf("Worker:                %s\n", info.Worker)
+	in.printf("Control addresses:     %v\n", info.ControlAddresses)
+	if info.PendingWorkerKey != nil {
+		in.printf("Pending worker:        %s at epoch %d\n", info.PendingWorkerKey.NewWorker, info.PendingWorkerKey.EffectiveAt)
+	}
+	for _, record := range info.WorkerKeyHistory {
+		in.printf("Past worker:           %s from epoch %d\n", record.Worker, record.EffectiveAt)
+	}
+	if info.PendingOwnerAddress != nil {
+		in.printf("Pending owner:         %s\n", *info.PendingOwnerAddress)
+	}
+	in.printf("Beneficiary:           %s\n", info.Beneficiary)
+	if info.Beneficiary != info.Owner {
+		in.printf("Beneficiary quota:     %s used of %s until epoch %d\n",
+			info.BeneficiaryTerm.UsedQuota, info.BeneficiaryTerm.Quota, info.BeneficiaryTerm.Expiration)
+	}
+	if pending := info.PendingBeneficiaryTerm; pending != nil {
+		in.printf("Pending beneficiary:   %s with quota %s until epoch %d\n", pending.NewBeneficiary, pending.NewQuota, pending.NewExpiration)
+	}
+	in.printf("Peer ID:               %x\n", info.PeerId)
+	in.printf("Multiaddrs:            %d\n", len(info.Multiaddrs))
+	in.printf("Window PoSt proof:     %d\n", info.WindowPoStProofType)
+	in.printf("Sector size:           %s\n", info.SectorSize.ShortString())
+	in.printf("Partition sectors:     %d\n", info.WindowPoStPartitionSectors)
+	in.printf("Consensus fault until: %d\n", info.ConsensusFaultElapsed)
+	in.printf("Proving period start:  %d\n", st.ProvingPeriodStart)
+	in.printf("Current deadline:      %d\n", st.CurrentDeadline)
+	in.printf("Initial pledge:        %s\n", st.InitialPledge)
+	in.printf("Pre-commit deposits:   %s\n", st.PreCommitDeposits)
+	in.printf("Locked funds:          %s\n", st.LockedFunds)
+	in.printf("Fee debt:              %s\n", st.FeeDebt)
+	return nil
+}
+
+func (in *inspector) printMinerDeadlines(a address.Address) error {
+	st, err := in.loadMinerState(a)
+	if err != nil {
+		return err
+	}
+	deadlines, err := st.LoadDeadlines(in.store)
+	if err != nil {
+		return err
+	}
+	for dlIdx := range deadlines.Due {
+		dl, err := deadlines.LoadDeadline(in.store, uint64(dlIdx))
+		if err != nil {
+			return err
+		}
+		in.printf("Deadline %d: live sectors %d, total sectors %d, faulty power %s, posted partitions %s\n",
+			dlIdx, dl.LiveSectors, dl.TotalSectors, formatPower(dl.FaultyPower), formatBitfield(dl.PartitionsPoSted))
+
+		partitions, err := dl.PartitionsArray(in.store)
+		if err != nil {
+			return err
+		}
+		var part miner.Partition
+		if err := partitions.ForEach(&part, func(partIdx int64) error {
+			in.printf("  Partition %d: live power %s, faulty power %s, recovering power %s, unproven power %s\n",
+				partIdx, formatPower(part.LivePower), formatPower(part.FaultyPower), formatPower(part.RecoveringPower), formatPower(part.UnprovenPower))
+			in.printf("    Sectors:    %s\n", formatBitfield(part.Sectors))
+			in.printf("    Unproven:   %s\n", formatBitfield(part.Unproven))
+			in.printf("    Faults:     %s\n", formatBitfield(part.Faults))
+			in.printf("    Recoveries: %s\n", formatBitfield(part.Recoveries))
+			in.printf("    Terminated: %s\n", formatBitfield(part.Terminated))
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (in *inspector) printMinerVesting(a address.Address) error {
+	st, err := in.loadMinerState(a)
+	if err != nil {
+		return err
+	}
+	vesting, err := st.LoadVestingFunds(in.store)
+	if err != nil {
+		return err
+	}
+	in.printf("%-10s %s\n", "Epoch", "Amount")
+	for _, fund := range vesting.Funds {
+		in.printf("%-10d %s\n", fund.Epoch, fund.Amount)
+	}
+	in.printf("Total locked: %s\n", st.LockedFunds)
+	return nil
+}
+
+func (in *inspector) printDeal(id abi.DealID) error {
+	var st market.State
+	if err := in.loadActorState(builtin.StorageMarketActorAddr, builtin.StorageMarketActorCodeID, &st); err != nil {
+		return err
+	}
+	proposals, err := market.AsDealProposalArray(in.store, st.Proposals)
+	if err != nil {
+		return err
+	}
+	proposal, found, err := proposals.Get(id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return xerrors.Errorf("deal %d not found", id)
+	}
+	in.printf("Piece:                %s (%d bytes)\n", proposal.PieceCID, proposal.PieceSize)
+	in.printf("Verified:             %t\n", proposal.VerifiedDeal)
+	in.printf("Client:               %s\n", proposal.Client)
+	in.printf("Provider:             %s\n", proposal.Provider)
+	in.printf("Label:                %s\n", proposal.Label)
+	in.printf("Start epoch:          %d\n", proposal.StartEpoch)
+	in.printf("End epoch:            %d\n", proposal.EndEpoch)
+	in.printf("Price per epoch:      %s\n", proposal.StoragePricePerEpoch)
+	in.printf("Provider collateral:  %s\n", proposal.ProviderCollateral)
+	in.printf("Client collateral:    %s\n", proposal.ClientCollateral)
+
+	dealStates, err := market.AsDealStateArray(in.store, st.States)
+	if err != nil {
+		return err
+	}
+	state, found, err := dealStates.Get(id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		in.printf("State:                not activated\n")
+		return nil
+	}
+	in.printf("Sector start epoch:   %d\n", state.SectorStartEpoch)
+	in.printf("Last updated epoch:   %d\n", state.LastUpdatedEpoch)
+	in.printf("Slash epoch:          %d\n", state.SlashEpoch)
+	return nil
+}
+
+func (in *inspector) printClaim(a address.Address) error {
+	var st power.State
+	if err := in.loadActorState(builtin.StoragePowerActorAddr, builtin.StoragePowerActorCodeID, &st); err != nil {
+		return err
+	}
+	claim, found, err := st.GetClaim(in.store, a)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return xerrors.Errorf("no claim for %s", a)
+	}
+	in.printf("Window PoSt proof:  %d\n", claim.WindowPoStProofType)
+	in.printf("Raw byte power:     %s\n", claim.RawBytePower)
+	in.printf("Quality adj power:  %s\n", claim.QualityAdjPower)
+	return nil
+}
+
+func (in *inspector) printDataCap(a address.Address) error {
+	var st verifreg.State
+	if err := in.loadActorState(builtin.VerifiedRegistryActorAddr, builtin.VerifiedRegistryActorCodeID, &st); err != nil {
+		return err
+	}
+	found := false
+	for _, table := range []struct {
+		name string
+		root cid.Cid
+	}{{"verifier", st.Verifiers}, {"verified client", st.VerifiedClients}} {
+		m, err := adt.AsMap(in.store, table.root, builtin.DefaultHamtBitwidth)
+		if err != nil {
+			return err
+		}
+		var dcap verifreg.DataCap
+		ok, err := m.Get(abi.AddrKey(a), &dcap)
+		if err != nil {
+			return err
+		}
+		if ok {
+			in.printf("Data cap as %s: %s\n", table.name, dcap)
+			found = true
+		}
+	}
+	if !found {
+		return xerrors.Errorf("%s is neither a verifier nor a verified client", a)
+	}
+	return nil
+}
+
+func formatPower(p miner.PowerPair) string {
+	return fmt.Sprintf("%s raw/%s QA", p.Raw, p.QA)
+}
+
+// Formats a bitfield as a list of set bits and runs, e.g. "[1 3-5]".
+func formatBitfield(bf bitfield.BitField) string {
+	it, err := bf.RunIterator()
+	if err != nil {
+		return fmt.Sprintf("<invalid: %s>", err)
+	}
+	var runs []string
+	var pos uint64
+	for it.HasNext() {
+		run, err := it.NextRun()
+		if err != nil {
+			return fmt.Sprintf("<invalid: %s>", err)
+		}
+		if run.Val {
+			if run.Len == 1 {
+				runs = append(runs, fmt.Sprintf("%d", pos))
+			} else {
+				runs = append(runs, fmt.Sprintf("%d-%d", pos, pos+run.Len-1))
+			}
+		}
+		pos += run.Len
+	}
+	return "[" + strings.Join(runs, " ") + "]"
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/filecoin-project/specs-actors/v3/actors/builtin/system"
 	"github.com/filecoin-project/specs-actors/v3/actors/builtin/verifreg"
 	"github.com/filecoin-project/specs-actors/v3/actors/util/smoothing"
+	vm "github.com/filecoin-project/specs-actors/v3/support/vm"
 )
 
 func main() {
@@ -147,19 +148,27 @@ func main() {
 		market.State{},
 		// method params and returns
 		//market.WithdrawBalanceParams{}, // Aliased from v0
-		//market.PublishStorageDealsParams{}, // Aliased from v0
-		//market.PublishStorageDealsReturn{}, // Aliased from v0
+		market.PublishStorageDealsParams{},
+		market.PublishStorageDealsReturn{},
 		//market.ActivateDealsParams{}, // Aliased from v0
 		market.VerifyDealsForActivationParams{},
 		market.VerifyDealsForActivationReturn{},
+		market.CancelDealParams{},
+		market.RenewDealParams{},
+		market.RenewDealReturn{},
+		market.GetBalanceScheduleReturn{},
 		//market.ComputeDataCommitmentParams{}, // Aliased from v0
 		//market.OnMinerSectorsTerminateParams{}, // Aliased from v0
 		// other types
-		//market.DealProposal{}, // Aliased from v0
-		//market.ClientDealProposal{}, // Aliased from v0
+		market.DealProposal{},
+		market.ClientDealProposal{},
 		market.SectorDeals{},
 		market.SectorWeights{},
 		market.DealState{},
+		market.DealCancellation{},
+		market.DealRenewal{},
+		market.ClientDealRenewal{},
+		market.BalanceUnlock{},
 	); err != nil {
 		panic(err)
 	}
@@ -177,6 +186,9 @@ func main() {
 		miner.SectorPreCommitInfo{},
 		miner.SectorOnChainInfo{},
 		miner.WorkerKeyChange{},
+		miner.BeneficiaryTerm{},
+		miner.PendingBeneficiaryChange{},
+		miner.WorkerKeyRecord{},
 		miner.VestingFunds{},
 		miner.VestingFund{},
 		miner.WindowedPoSt{},
@@ -188,7 +200,7 @@ func main() {
 		//miner.ChangePeerIDParams{}, // Aliased from v0
 		//miner.ChangeMultiaddrsParams{}, // Aliased from v0
 		//miner.ProveCommitSectorParams{}, // Aliased from v0
-		//miner.ChangeWorkerAddressParams{},  // Aliased from v0
+		miner.ChangeWorkerAddressWithRolesParams{},
 		//miner.ExtendSectorExpirationParams{}, // Aliased from v0
 		//miner.DeclareFaultsParams{}, // Aliased from v0
 		//miner.DeclareFaultsRecoveredParams{}, // Aliased from v0
@@ -200,6 +212,22 @@ func main() {
 		//miner.CompactSectorNumbersParams{}, // Aliased from v0
 		//miner.CronEventPayload{}, // Aliased from v0
 		miner.DisputeWindowedPoStParams{},
+		miner.PreCommitSectorBatchParams{},
+		miner.ProveCommitAggregateParams{},
+		miner.ChangeBeneficiaryParams{},
+		miner.ActiveBeneficiary{},
+		miner.GetBeneficiaryReturn{},
+		miner.ReplicaUpdate{},
+		miner.ProveReplicaUpdatesParams{},
+		miner.ExpirationExtension2{},
+		miner.ExtendSectorExpiration2Params{},
+		miner.SectorExtension{},
+		miner.ExtendSectorExpiration2Return{},
+		miner.SectorTerminationFee{},
+		miner.PreviewTerminateSectorsReturn{},
+		miner.PreviewWindowedPoStParams{},
+		miner.PreviewWindowedPoStReturn{},
+		miner.RenewSectorDealParams{},
 		// other types
 		//miner.FaultDeclaration{}, // Aliased from v0
 		//miner.RecoveryDeclaration{}, // Aliased from v0
@@ -229,4 +257,11 @@ func main() {
 		panic(err)
 	}
 
+	if err := gen.WriteTupleEncodersToFile("./support/vm/cbor_gen.go", "vm_test",
+		vm.Trace{},
+		vm.TraceInvocation{},
+	); err != nil {
+		panic(err)
+	}
+
 }
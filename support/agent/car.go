@@ -0,0 +1,321 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+
+	"github.com/filecoin-project/go-address"
+	block "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	mh "github.com/multiformats/go-multihash"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/specs-actors/v3/actors/states"
+	"github.com/filecoin-project/specs-actors/v3/actors/util/adt"
+)
+
+// Reading and writing of state as CARv1 files.
+// A CARv1 file is a varint length-prefixed DAG-CBOR header listing the root CIDs, followed by a sequence of
+// varint length-prefixed sections, each holding a CID followed by the block data it identifies.
+// See https://ipld.io/specs/transport/car/carv1/
+
+const carVersion = 1
+
+// Largest section accepted when reading, a generous bound on the size of a single block.
+const maxCarSectionSize = 32 << 20
+
+// CarWriter streams blocks to a CARv1 file, writing each distinct block at most once.
+type CarWriter struct {
+	w    io.Writer
+	seen *cid.Set
+
+	Blocks uint64 // Number of blocks written.
+	Bytes  uint64 // Size of the block data written, excluding CIDs and framing.
+}
+
+// NewCarWriter writes a CARv1 header listing the roots and returns a writer for the blocks that follow.
+func NewCarWriter(w io.Writer, roots []cid.Cid) (*CarWriter, error) {
+	var hdr bytes.Buffer
+	if err := cbg.CborWriteHeader(&hdr, cbg.MajMap, 2); err != nil {
+		return nil, err
+	}
+	// Keys are in canonical DAG-CBOR order, shortest first.
+	if err := writeCborString(&hdr, "roots"); err != nil {
+		return nil, err
+	}
+	if err := cbg.CborWriteHeader(&hdr, cbg.MajArray, uint64(len(roots))); err != nil {
+		return nil, err
+	}
+	for _, root := range roots {
+		if err := cbg.WriteCid(&hdr, root); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeCborString(&hdr, "version"); err != nil {
+		return nil, err
+	}
+	if err := cbg.CborWriteHeader(&hdr, cbg.MajUnsignedInt, carVersion); err != nil {
+		return nil, err
+	}
+
+	if err := writeCarSection(w, hdr.Bytes()); err != nil {
+		return nil, err
+	}
+	return &CarWriter{w: w, seen: cid.NewSet()}, nil
+}
+
+// WriteBlock writes a block, unless a block with the same CID has already been written.
+func (cw *CarWriter) WriteBlock(blk block.Block) error {
+	if !cw.seen.Visit(blk.Cid()) {
+		return nil
+	}
+	if err := writeCarSection(cw.w, blk.Cid().Bytes(), blk.RawData()); err != nil {
+		return err
+	}
+	cw.Blocks++
+	cw.Bytes += uint64(len(blk.RawData()))
+	return nil
+}
+
+// WriteDAG writes the block at root and all blocks reachable from it, skipping any links for which skip
+// returns true. Skip may be nil.
+func (cw *CarWriter) WriteDAG(from cbor.IpldBlockstore, root cid.Cid, skip func(cid.Cid) bool) error {
+	if root.Prefix().MhType == mh.IDENTITY || cw.seen.Has(root) {
+		return nil
+	}
+
+	blk, err := from.Get(root)
+	if err != nil {
+		return xerrors.Errorf("get %s failed: %w", root, err)
+	}
+	if err := cw.WriteBlock(blk); err != nil {
+		return xerrors.Errorf("write %s failed: %w", root, err)
+	}
+
+	var lerr error
+	err = linksForObj(blk, func(link cid.Cid) {
+		if lerr != nil {
+			return
+		}
+		prefix := link.Prefix()
+		if prefix.Codec == cid.FilCommitmentSealed || prefix.Codec == cid.FilCommitmentUnsealed {
+			return
+		}
+		if skip != nil && skip(link) {
+			return
+		}
+		lerr = cw.WriteDAG(from, link, skip)
+	})
+	if err != nil {
+		return xerrors.Errorf("linksForObj (%x): %w", blk.RawData(), err)
+	}
+	return lerr
+}
+
+// ExportCar writes a CARv1 file holding the DAGs under each of the roots, e.g. actor heads.
+func ExportCar(from cbor.IpldBlockstore, w io.Writer, roots ...cid.Cid) error {
+	cw, err := NewCarWriter(w, roots)
+	if err != nil {
+		return err
+	}
+	for _, root := range roots {
+		if err := cw.WriteDAG(from, root, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportStateTree writes a CARv1 file with the state tree at root as its single root.
+// If codes are given, only the state of actors with one of those code CIDs is written.
+// The file then still holds the complete tree of actors, but not the heads of other actors, so it
+// can be loaded as a states.Tree but the state of only the selected actors can be read.
+// Blocks that another actor's state shares with a selected actor's state are written.
+func ExportStateTree(ctx context.Context, from cbor.IpldBlockstore, w io.Writer, root cid.Cid, codes ...cid.Cid) error {
+	cw, err := NewCarWriter(w, []cid.Cid{root})
+	if err != nil {
+		return err
+	}
+	if len(codes) == 0 {
+		return cw.WriteDAG(from, root, nil)
+	}
+
+	include := cid.NewSet()
+	for _, code := range codes {
+		include.Add(code)
+	}
+	tree, err := states.LoadTree(adt.WrapBlockStore(ctx, from), root)
+	if err != nil {
+		return xerrors.Errorf("failed to load state tree %s: %w", root, err)
+	}
+	heads := cid.NewSet()
+	var selected []cid.Cid
+	if err := tree.ForEach(func(_ address.Address, act *states.Actor) error {
+		heads.Add(act.Head)
+		if include.Has(act.Code) {
+			selected = append(selected, act.Head)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	// Write the tree of actors without descending into any actor's state, then the complete state of
+	// each selected actor.
+	if err := cw.WriteDAG(from, root, heads.Has); err != nil {
+		return err
+	}
+	for _, head := range selected {
+		if err := cw.WriteDAG(from, head, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportCar reads all blocks from a CARv1 file into a blockstore, verifying that each block matches
+// its CID. Returns the roots listed in the file's header.
+func ImportCar(to cbor.IpldBlockstore, r io.Reader) ([]cid.Cid, error) {
+	br := bufio.NewReader(r)
+	hdr, err := readCarSection(br)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read car header: %w", err)
+	}
+	if hdr == nil {
+		return nil, xerrors.Errorf("empty car file")
+	}
+	roots, err := readCarHeader(hdr)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		section, err := readCarSection(br)
+		if err != nil {
+			return nil, err
+		}
+		if section == nil {
+			return roots, nil
+		}
+		n, c, err := cid.CidFromBytes(section)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to read block cid: %w", err)
+		}
+		data := section[n:]
+		sum, err := c.Prefix().Sum(data)
+		if err != nil {
+			return nil, xerrors.Errorf("block %s: %w", c, err)
+		}
+		if !sum.Equals(c) {
+			return nil, xerrors.Errorf("block %s does not match its data, hashes to %s", c, sum)
+		}
+		blk, err := block.NewBlockWithCid(data, c)
+		if err != nil {
+			return nil, err
+		}
+		if err := to.Put(blk); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func readCarHeader(hdr []byte) ([]cid.Cid, error) {
+	r := bytes.NewReader(hdr)
+	maj, n, err := cbg.CborReadHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	if maj != cbg.MajMap {
+		return nil, xerrors.Errorf("car header is not a map")
+	}
+
+	var roots []cid.Cid
+	var version uint64
+	for i := uint64(0); i < n; i++ {
+		key, err := cbg.ReadString(r)
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case "roots":
+			maj, count, err := cbg.CborReadHeader(r)
+			if err != nil {
+				return nil, err
+			}
+			if maj != cbg.MajArray {
+				return nil, xerrors.Errorf("car header roots is not an array")
+			}
+			for j := uint64(0); j < count; j++ {
+				root, err := cbg.ReadCid(r)
+				if err != nil {
+					return nil, xerrors.Errorf("failed to read car root: %w", err)
+				}
+				roots = append(roots, root)
+			}
+		case "version":
+			maj, version, err = cbg.CborReadHeader(r)
+			if err != nil {
+				return nil, err
+			}
+			if maj != cbg.MajUnsignedInt {
+				return nil, xerrors.Errorf("car header version is not an integer")
+			}
+		default:
+			return nil, xerrors.Errorf("unexpected car header field %s", key)
+		}
+	}
+	if version != carVersion {
+		return nil, xerrors.Errorf("unsupported car version %d", version)
+	}
+	return roots, nil
+}
+
+func writeCarSection(w io.Writer, data ...[]byte) error {
+	var size uint64
+	for _, d := range data {
+		size += uint64(len(d))
+	}
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, size)
+	if _, err := w.Write(buf[:n]); err != nil {
+		return err
+	}
+	for _, d := range data {
+		if _, err := w.Write(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reads a length-prefixed section, returning nil at the end of the input.
+func readCarSection(br *bufio.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(br)
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if size > maxCarSectionSize {
+		return nil, xerrors.Errorf("car section size %d exceeds maximum %d", size, maxCarSectionSize)
+	}
+	section := make([]byte, size)
+	if _, err := io.ReadFull(br, section); err != nil {
+		return nil, xerrors.Errorf("truncated car section: %w", err)
+	}
+	return section, nil
+}
+
+func writeCborString(w io.Writer, s string) error {
+	if err := cbg.CborWriteHeader(w, cbg.MajTextString, uint64(len(s))); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, s)
+	return err
+}
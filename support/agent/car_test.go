@@ -0,0 +1,182 @@
+package agent_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	block "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	cbg "github.com/whyrusleeping/cbor-gen"
+
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/power"
+	"github.com/filecoin-project/specs-actors/v3/actors/states"
+	"github.com/filecoin-project/specs-actors/v3/actors/util/adt"
+	"github.com/filecoin-project/specs-actors/v3/support/agent"
+	"github.com/filecoin-project/specs-actors/v3/support/ipld"
+	vm_test "github.com/filecoin-project/specs-actors/v3/support/vm"
+)
+
+func TestCarExport(t *testing.T) {
+	ctx := context.Background()
+	bs := ipld.NewBlockStoreInMemory()
+	v := vm_test.NewVMWithSingletons(ctx, t, bs)
+	addrs := vm_test.CreateAccounts(ctx, t, v, 1, big.Mul(big.NewInt(10_000), big.NewInt(1e18)), 93837778)
+	ret := vm_test.ApplyOk(t, v, addrs[0], builtin.StoragePowerActorAddr, big.NewInt(1e10), builtin.MethodsPower.CreateMiner, &power.CreateMinerParams{
+		Owner:               addrs[0],
+		Worker:              addrs[0],
+		WindowPoStProofType: abi.RegisteredPoStProof_StackedDrgWindow32GiBV1,
+	})
+	minerAddr := ret.(*power.CreateMinerReturn).IDAddress
+	accountAddr, found := v.NormalizeAddress(addrs[0])
+	require.True(t, found)
+	tree, err := v.GetStateTree()
+	require.NoError(t, err)
+	root, err := tree.Flush()
+	require.NoError(t, err)
+
+	hasHead := func(store *ipld.BlockStoreInMemory, addr address.Address) bool {
+		act, found, err := tree.GetActor(addr)
+		require.NoError(t, err)
+		require.True(t, found)
+		_, err = store.Get(act.Head)
+		return err == nil
+	}
+
+	t.Run("round trip state tree", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, agent.ExportStateTree(ctx, bs, &buf, root))
+
+		imported := ipld.NewBlockStoreInMemory()
+		roots, err := agent.ImportCar(imported, &buf)
+		require.NoError(t, err)
+		assert.Equal(t, []cid.Cid{root}, roots)
+
+		// Every actor's state can be read from the imported blocks.
+		loaded, err := states.LoadTree(adt.WrapBlockStore(ctx, imported), root)
+		require.NoError(t, err)
+		count := 0
+		require.NoError(t, loaded.ForEach(func(addr address.Address, act *states.Actor) error {
+			count++
+			assert.True(t, hasHead(imported, addr), "missing head of %v", addr)
+			return nil
+		}))
+		assert.True(t, count > 0)
+
+		var st miner.State
+		require.NoError(t, adt.WrapBlockStore(ctx, imported).Get(ctx, mustActor(t, loaded, minerAddr).Head, &st))
+		info, err := st.GetInfo(adt.WrapBlockStore(ctx, imported))
+		require.NoError(t, err)
+		assert.Equal(t, accountAddr, info.Owner)
+	})
+
+	t.Run("filter by actor code", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, agent.ExportStateTree(ctx, bs, &buf, root, builtin.StorageMinerActorCodeID))
+
+		imported := ipld.NewBlockStoreInMemory()
+		_, err := agent.ImportCar(imported, &buf)
+		require.NoError(t, err)
+
+		// The whole tree of actors is present, but only the miner's state.
+		loaded, err := states.LoadTree(adt.WrapBlockStore(ctx, imported), root)
+		require.NoError(t, err)
+		mustActor(t, loaded, accountAddr)
+		assert.True(t, hasHead(imported, minerAddr))
+		assert.False(t, hasHead(imported, accountAddr))
+		assert.False(t, hasHead(imported, builtin.StoragePowerActorAddr))
+
+		var st miner.State
+		require.NoError(t, adt.WrapBlockStore(ctx, imported).Get(ctx, mustActor(t, loaded, minerAddr).Head, &st))
+		_, err = st.GetInfo(adt.WrapBlockStore(ctx, imported))
+		require.NoError(t, err)
+	})
+
+	t.Run("filter keeps blocks shared with excluded actors", func(t *testing.T) {
+		store := adt.WrapBlockStore(ctx, bs)
+		// The excluded actor's head is also linked from the selected actor's state.
+		shared, err := adt.StoreEmptyMap(store, builtin.DefaultHamtBitwidth)
+		require.NoError(t, err)
+		sharedLink := cbg.CborCid(shared)
+		selectedHead, err := store.Put(ctx, &sharedLink)
+		require.NoError(t, err)
+
+		sharedTree, err := states.NewTree(store)
+		require.NoError(t, err)
+		require.NoError(t, sharedTree.SetActor(minerAddr, &states.Actor{Code: builtin.StorageMinerActorCodeID, Head: selectedHead, Balance: big.Zero()}))
+		require.NoError(t, sharedTree.SetActor(accountAddr, &states.Actor{Code: builtin.AccountActorCodeID, Head: shared, Balance: big.Zero()}))
+		sharedRoot, err := sharedTree.Flush()
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, agent.ExportStateTree(ctx, bs, &buf, sharedRoot, builtin.StorageMinerActorCodeID))
+		imported := ipld.NewBlockStoreInMemory()
+		_, err = agent.ImportCar(imported, &buf)
+		require.NoError(t, err)
+		_, err = imported.Get(selectedHead)
+		assert.NoError(t, err)
+		_, err = imported.Get(shared)
+		assert.NoError(t, err)
+	})
+
+	t.Run("export actor heads", func(t *testing.T) {
+		minerHead := mustActor(t, tree, minerAddr).Head
+		powerHead := mustActor(t, tree, builtin.StoragePowerActorAddr).Head
+		var buf bytes.Buffer
+		require.NoError(t, agent.ExportCar(bs, &buf, minerHead, powerHead))
+
+		imported := ipld.NewBlockStoreInMemory()
+		roots, err := agent.ImportCar(imported, &buf)
+		require.NoError(t, err)
+		assert.Equal(t, []cid.Cid{minerHead, powerHead}, roots)
+		assert.True(t, hasHead(imported, minerAddr))
+		assert.True(t, hasHead(imported, builtin.StoragePowerActorAddr))
+		assert.False(t, hasHead(imported, builtin.RewardActorAddr))
+		_, err = imported.Get(root)
+		assert.Error(t, err)
+	})
+
+	t.Run("writes blocks once", func(t *testing.T) {
+		var buf bytes.Buffer
+		cw, err := agent.NewCarWriter(&buf, nil)
+		require.NoError(t, err)
+		blk := block.NewBlock([]byte("data"))
+		require.NoError(t, cw.WriteBlock(blk))
+		require.NoError(t, cw.WriteBlock(blk))
+		assert.Equal(t, uint64(1), cw.Blocks)
+		assert.Equal(t, uint64(4), cw.Bytes)
+
+		// Exporting a subtree already written adds nothing.
+		minerHead := mustActor(t, tree, minerAddr).Head
+		require.NoError(t, cw.WriteDAG(bs, minerHead, nil))
+		written := cw.Blocks
+		require.NoError(t, cw.WriteDAG(bs, minerHead, nil))
+		assert.Equal(t, written, cw.Blocks)
+	})
+
+	t.Run("rejects corrupt block", func(t *testing.T) {
+		var buf bytes.Buffer
+		cw, err := agent.NewCarWriter(&buf, nil)
+		require.NoError(t, err)
+		blk, err := block.NewBlockWithCid([]byte("not the data"), block.NewBlock([]byte("data")).Cid())
+		require.NoError(t, err)
+		require.NoError(t, cw.WriteBlock(blk))
+
+		_, err = agent.ImportCar(ipld.NewBlockStoreInMemory(), &buf)
+		assert.Error(t, err)
+	})
+}
+
+func mustActor(t *testing.T, tree *states.Tree, addr address.Address) *states.Actor {
+	act, found, err := tree.GetActor(addr)
+	require.NoError(t, err)
+	require.True(t, found)
+	return act
+}
@@ -432,6 +432,87 @@ func TestCCUpgrades(t *testing.T) {
 	}
 }
 
+func TestCheckInvariantsEveryEpochs(t *testing.T) {
+	ctx := context.Background()
+	initialBalance := big.Mul(big.NewInt(1e9), big.NewInt(1e18))
+
+	newSim := func(halt bool) *agent.Sim {
+		rnd := rand.New(rand.NewSource(42))
+		sim := agent.NewSim(ctx, t, newBlockStore, agent.SimConfig{
+			Seed:                     rnd.Int63(),
+			CheckInvariantsEpochs:    3,
+			HaltOnInvariantViolation: halt,
+		})
+		accounts := vm_test.CreateAccounts(ctx, t, sim.GetVM(), 2, initialBalance, rnd.Int63())
+		sim.AddAgent(agent.NewMinerGenerator(
+			accounts,
+			agent.MinerAgentConfig{
+				PrecommitRate:    2.5,
+				ProofType:        abi.RegisteredSealProof_StackedDrg32GiBV1_1,
+				StartingBalance:  initialBalance,
+				MinMarketBalance: big.Zero(),
+				MaxMarketBalance: big.Zero(),
+			},
+			1.0,
+			rnd.Int63(),
+		))
+		return sim
+	}
+
+	// Corrupts power state in a way that is not repaired by cron.
+	corruptPower := func(sim *agent.Sim) {
+		var st power.State
+		require.NoError(t, sim.GetState(builtin.StoragePowerActorAddr, &st))
+		st.TotalBytesCommitted = big.NewInt(-1)
+		require.NoError(t, sim.GetVM().SetActorState(ctx, builtin.StoragePowerActorAddr, &st))
+	}
+
+	t.Run("no violations", func(t *testing.T) {
+		sim := newSim(false)
+		for i := 0; i < 10; i++ {
+			require.NoError(t, sim.Tick())
+		}
+		assert.Empty(t, sim.InvariantViolations)
+	})
+
+	t.Run("records violations", func(t *testing.T) {
+		sim := newSim(false)
+		require.NoError(t, sim.Tick())
+		checkedCount := sim.MessageCount
+		corruptPower(sim)
+		for i := 0; i < 3; i++ {
+			require.NoError(t, sim.Tick())
+		}
+		uncheckedCount := sim.MessageCount - checkedCount
+		for i := 0; i < 3; i++ {
+			require.NoError(t, sim.Tick())
+		}
+
+		// Checked at epochs 0 (before corruption), 3 and 6.
+		require.Len(t, sim.InvariantViolations, 2)
+		violation := sim.InvariantViolations[0]
+		assert.Equal(t, abi.ChainEpoch(3), violation.Epoch)
+		assert.Contains(t, strings.Join(violation.Violations, "\n"), "total raw power committed is negative")
+		// The violation holds the messages of every epoch since the previous check.
+		assert.NotZero(t, uncheckedCount)
+		assert.Len(t, violation.Messages, int(uncheckedCount))
+		assert.Equal(t, abi.ChainEpoch(6), sim.InvariantViolations[1].Epoch)
+		assert.Len(t, sim.InvariantViolations[1].Messages, int(sim.MessageCount-checkedCount-uncheckedCount))
+	})
+
+	t.Run("halts at first violation", func(t *testing.T) {
+		sim := newSim(true)
+		require.NoError(t, sim.Tick())
+		corruptPower(sim)
+		require.NoError(t, sim.Tick())
+		require.NoError(t, sim.Tick())
+		err := sim.Tick()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "state invariants violated at epoch 3")
+		require.Len(t, sim.InvariantViolations, 1)
+	})
+}
+
 func newBlockStore() cbor.IpldBlockstore {
 	return ipld.NewBlockStoreInMemory()
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/go-state-types/big"
 	"github.com/filecoin-project/go-state-types/cbor"
+	"github.com/filecoin-project/go-state-types/crypto"
 	"github.com/filecoin-project/go-state-types/exitcode"
 	ipldcbor "github.com/ipfs/go-ipld-cbor"
 	"github.com/pkg/errors"
@@ -19,6 +20,7 @@ import (
 	"github.com/filecoin-project/specs-actors/v3/actors/builtin/market"
 	"github.com/filecoin-project/specs-actors/v3/actors/builtin/power"
 	"github.com/filecoin-project/specs-actors/v3/actors/builtin/reward"
+	"github.com/filecoin-project/specs-actors/v3/actors/states"
 	"github.com/filecoin-project/specs-actors/v3/actors/util/adt"
 	"github.com/filecoin-project/specs-actors/v3/support/ipld"
 	vm "github.com/filecoin-project/specs-actors/v3/support/vm"
@@ -40,6 +42,12 @@ type Sim struct {
 	WinCount      uint64
 	MessageCount  uint64
 
+	// State invariant violations found by checks configured with SimConfig.CheckInvariantsEpochs.
+	InvariantViolations []InvariantViolation
+
+	// Messages applied since state invariants were last checked.
+	uncheckedMessages []SimMessage
+
 	v               *vm.VM
 	rnd             *rand.Rand
 	statsByMethod   map[vm.MethodKey]*vm.CallStats
@@ -103,8 +111,10 @@ func (s *Sim) Tick() error {
 	})
 
 	// run messages
+	var applied []SimMessage
 	for _, msg := range blockMessages {
 		ret, code := s.v.ApplyMessage(msg.From, msg.To, msg.Value, msg.Method, msg.Params)
+		applied = append(applied, SimMessage{msg.From, msg.To, msg.Value, msg.Method, msg.Params, code})
 
 		// for now, assume everything should work
 		if code != exitcode.Ok {
@@ -148,6 +158,16 @@ func (s *Sim) Tick() error {
 		fmt.Printf("%s\n", strings.Join(s.v.GetLogs(), "\n"))
 	}
 
+	if s.Config.CheckInvariantsEpochs > 0 {
+		s.uncheckedMessages = append(s.uncheckedMessages, applied...)
+		if uint64(s.v.GetEpoch())%s.Config.CheckInvariantsEpochs == 0 {
+			applied, s.uncheckedMessages = s.uncheckedMessages, nil
+			if err := s.checkInvariants(applied); err != nil {
+				return err
+			}
+		}
+	}
+
 	// create next vm
 	nextEpoch := s.v.GetEpoch() + 1
 	if s.Config.CheckpointEpochs > 0 && uint64(nextEpoch)%s.Config.CheckpointEpochs == 0 {
@@ -160,11 +180,13 @@ func (s *Sim) Tick() error {
 
 		s.blkStore = nextStore
 		metrics := ipld.NewMetricsBlockStore(nextStore)
+		randomness := s.v.GetRandomnessSource()
 		s.v, err = vm.NewVMAtEpoch(s.ctx, s.v.ActorImpls, adt.WrapBlockStore(s.ctx, metrics), s.v.StateRoot(), nextEpoch)
 		if err != nil {
 			return err
 		}
 		s.v.SetStatsSource(metrics)
+		s.v.SetRandomnessSource(randomness)
 
 	} else {
 		s.v, err = s.v.WithEpoch(nextEpoch)
@@ -199,6 +221,10 @@ func (s *Sim) AddDealProvider(d DealProvider) {
 	s.DealProviders = append(s.DealProviders, d)
 }
 
+func (s *Sim) GetRandomnessFromTickets(tag crypto.DomainSeparationTag, epoch abi.ChainEpoch, entropy []byte) abi.Randomness {
+	return s.v.GetRandomnessFromTickets(tag, epoch, entropy)
+}
+
 func (s *Sim) GetVM() *vm.VM {
 	return s.v
 }
@@ -224,6 +250,38 @@ func (s *Sim) NetworkCirculatingSupply() abi.TokenAmount {
 //
 //////////////////////////////////////////////////
 
+// Checks state invariants after the messages for the current epoch have been applied, recording any violations
+// along with the messages applied since the previous check.
+// Returns an error if the invariants are violated and the sim is configured to halt.
+func (s *Sim) checkInvariants(applied []SimMessage) error {
+	stateTree, err := s.v.GetStateTree()
+	if err != nil {
+		return err
+	}
+	totalBalance, err := s.v.GetTotalActorBalance()
+	if err != nil {
+		return err
+	}
+	acc, err := states.CheckStateInvariants(stateTree, totalBalance, s.v.GetEpoch())
+	if err != nil {
+		return err
+	}
+	if acc.IsEmpty() {
+		return nil
+	}
+
+	violation := InvariantViolation{
+		Epoch:      s.v.GetEpoch(),
+		Messages:   applied,
+		Violations: acc.Messages(),
+	}
+	s.InvariantViolations = append(s.InvariantViolations, violation)
+	if s.Config.HaltOnInvariantViolation {
+		return errors.Errorf("state invariants violated at epoch %d:\n%s", violation.Epoch, strings.Join(violation.Violations, "\n"))
+	}
+	return nil
+}
+
 func (s *Sim) rewardMiner(addr address.Address, wins uint64) error {
 	if wins < 1 {
 		return nil
@@ -310,6 +368,7 @@ type SimState interface {
 	AddAgent(a Agent)
 	AddDealProvider(d DealProvider)
 	NetworkCirculatingSupply() abi.TokenAmount
+	GetRandomnessFromTickets(tag crypto.DomainSeparationTag, epoch abi.ChainEpoch, entropy []byte) abi.Randomness
 
 	// randomly select an agent capable of making deals.
 	// Returns nil if no providers exist.
@@ -333,6 +392,28 @@ type SimConfig struct {
 	Seed                   int64
 	CreateMinerProbability float32
 	CheckpointEpochs       uint64
+	// If non-zero, state invariants are checked at the end of every epoch that is a multiple of this value.
+	CheckInvariantsEpochs uint64
+	// If set, Tick returns an error at the first invariant violation found.
+	HaltOnInvariantViolation bool
+}
+
+// A message applied by the sim, and its exit code.
+type SimMessage struct {
+	From     address.Address
+	To       address.Address
+	Value    abi.TokenAmount
+	Method   abi.MethodNum
+	Params   interface{}
+	ExitCode exitcode.ExitCode
+}
+
+// InvariantViolation records the state invariants found to be violated at the end of an epoch,
+// and the messages applied by agents since the previous check.
+type InvariantViolation struct {
+	Epoch      abi.ChainEpoch
+	Messages   []SimMessage
+	Violations []string
 }
 
 type returnHandler func(v SimState, msg message, ret cbor.Marshaler) error
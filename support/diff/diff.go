@@ -0,0 +1,755 @@
+// Package diff compares state trees, reporting the changes to each actor and to the typed state of
+// builtin actors.
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/big"
+	"math/bits"
+	"reflect"
+	"sort"
+
+	addr "github.com/filecoin-project/go-address"
+	hamt "github.com/filecoin-project/go-hamt-ipld/v3"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/cbor"
+	"github.com/ipfs/go-cid"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/market"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/multisig"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/power"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/verifreg"
+	"github.com/filecoin-project/specs-actors/v3/actors/states"
+	"github.com/filecoin-project/specs-actors/v3/actors/util/adt"
+)
+
+type ChangeKind int
+
+const (
+	Added ChangeKind = iota
+	Removed
+	Modified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Modified:
+		return "modified"
+	default:
+		return "unknown"
+	}
+}
+
+// Change describes a single difference between two state trees.
+type Change struct {
+	// Slash-separated location of the value, beginning with the actor's ID address,
+	// e.g. "f01000/State/Deadlines/3/Partitions/0/Faults".
+	Path   string
+	Kind   ChangeKind
+	Before interface{} // The value before, nil if added.
+	After  interface{} // The value after, nil if removed.
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("%s %s: %v", c.Path, c.Kind, c.After)
+	case Removed:
+		return fmt.Sprintf("%s %s: %v", c.Path, c.Kind, c.Before)
+	default:
+		return fmt.Sprintf("%s %s: %v -> %v", c.Path, c.Kind, c.Before, c.After)
+	}
+}
+
+// DiffTrees compares two state trees, both of which must be readable from the store.
+// Actors present in only one tree are reported as added or removed. For each actor present in both,
+// changes to its balance, nonce, code and head are reported. If the head of a builtin actor changes but its
+// code does not, the actor's state is compared field by field, descending into the collections it holds.
+// Any value or collection subtree with the same CID in both trees is assumed unchanged, and not traversed.
+func DiffTrees(store adt.Store, before, after cid.Cid) ([]Change, error) {
+	d := differ{store: store}
+	if err := d.diffMapRoots("", before, after, addressKey, func(path string, before, after []byte) error {
+		var actBefore, actAfter states.Actor
+		if err := actBefore.UnmarshalCBOR(bytes.NewReader(before)); err != nil {
+			return err
+		}
+		if err := actAfter.UnmarshalCBOR(bytes.NewReader(after)); err != nil {
+			return err
+		}
+		return d.diffActor(path, &actBefore, &actAfter)
+	}, func() cbor.Unmarshaler { return new(states.Actor) }); err != nil {
+		return nil, err
+	}
+	return d.changes, nil
+}
+
+type differ struct {
+	store   adt.Store
+	changes []Change
+}
+
+func (d *differ) record(path string, kind ChangeKind, before, after interface{}) {
+	d.changes = append(d.changes, Change{Path: path, Kind: kind, Before: before, After: after})
+}
+
+func (d *differ) diffActor(path string, before, after *states.Actor) error {
+	d.diffFields(path, before, after)
+	if before.Head.Equals(after.Head) || !before.Code.Equals(after.Code) {
+		return nil
+	}
+
+	path += "/State"
+	switch before.Code {
+	case builtin.StorageMinerActorCodeID:
+		var stBefore, stAfter miner.State
+		if err := d.loadStates(before.Head, after.Head, &stBefore, &stAfter); err != nil {
+			return err
+		}
+		return d.diffMinerState(path, &stBefore, &stAfter)
+	case builtin.StorageMarketActorCodeID:
+		var stBefore, stAfter market.State
+		if err := d.loadStates(before.Head, after.Head, &stBefore, &stAfter); err != nil {
+			return err
+		}
+		return d.diffMarketState(path, &stBefore, &stAfter)
+	case builtin.StoragePowerActorCodeID:
+		var stBefore, stAfter power.State
+		if err := d.loadStates(before.Head, after.Head, &stBefore, &stAfter); err != nil {
+			return err
+		}
+		return d.diffPowerState(path, &stBefore, &stAfter)
+	case builtin.VerifiedRegistryActorCodeID:
+		var stBefore, stAfter verifreg.State
+		if err := d.loadStates(before.Head, after.Head, &stBefore, &stAfter); err != nil {
+			return err
+		}
+		return d.diffVerifregState(path, &stBefore, &stAfter)
+	case builtin.MultisigActorCodeID:
+		var stBefore, stAfter multisig.State
+		if err := d.loadStates(before.Head, after.Head, &stBefore, &stAfter); err != nil {
+			return err
+		}
+		return d.diffMultisigState(path, &stBefore, &stAfter)
+	default:
+		// The state of other actors is reported only as a change of head.
+		return nil
+	}
+}
+
+func (d *differ) loadStates(before, after cid.Cid, outBefore, outAfter cbor.Unmarshaler) error {
+	if err := d.store.Get(d.store.Context(), before, outBefore); err != nil {
+		return xerrors.Errorf("failed to load state %s: %w", before, err)
+	}
+	if err := d.store.Get(d.store.Context(), after, outAfter); err != nil {
+		return xerrors.Errorf("failed to load state %s: %w", after, err)
+	}
+	return nil
+}
+
+//
+// Builtin actor states
+//
+
+func (d *differ) diffMinerState(path string, before, after *miner.State) error {
+	d.diffFields(path, before, after, "Info", "PreCommittedSectors", "Sectors", "Deadlines")
+
+	if !before.Info.Equals(after.Info) {
+		var infoBefore, infoAfter miner.MinerInfo
+		if err := d.loadStates(before.Info, after.Info, &infoBefore, &infoAfter); err != nil {
+			return err
+		}
+		d.diffFields(path+"/Info", &infoBefore, &infoAfter)
+	}
+	if err := d.diffMapRoots(path+"/PreCommittedSectors", before.PreCommittedSectors, after.PreCommittedSectors,
+		uintKey, nil, func() cbor.Unmarshaler { return new(miner.SectorPreCommitOnChainInfo) }); err != nil {
+		return err
+	}
+	if err := d.diffArrayRoots(path+"/Sectors", before.Sectors, after.Sectors,
+		func() cbor.Unmarshaler { return new(miner.SectorOnChainInfo) }); err != nil {
+		return err
+	}
+
+	if before.Deadlines.Equals(after.Deadlines) {
+		return nil
+	}
+	var dlsBefore, dlsAfter miner.Deadlines
+	if err := d.loadStates(before.Deadlines, after.Deadlines, &dlsBefore, &dlsAfter); err != nil {
+		return err
+	}
+	for dlIdx := range dlsBefore.Due {
+		if dlsBefore.Due[dlIdx].Equals(dlsAfter.Due[dlIdx]) {
+			continue
+		}
+		var dlBefore, dlAfter miner.Deadline
+		if err := d.loadStates(dlsBefore.Due[dlIdx], dlsAfter.Due[dlIdx], &dlBefore, &dlAfter); err != nil {
+			return err
+		}
+		dlPath := fmt.Sprintf("%s/Deadlines/%d", path, dlIdx)
+		d.diffFields(dlPath, &dlBefore, &dlAfter, "Partitions")
+		if err := d.diffArrayRootsWith(dlPath+"/Partitions", dlBefore.Partitions, dlAfter.Partitions,
+			func(path string, before, after []byte) error {
+				var partBefore, partAfter miner.Partition
+				if err := partBefore.UnmarshalCBOR(bytes.NewReader(before)); err != nil {
+					return err
+				}
+				if err := partAfter.UnmarshalCBOR(bytes.NewReader(after)); err != nil {
+					return err
+				}
+				d.diffFields(path, &partBefore, &partAfter)
+				return nil
+			}, func() cbor.Unmarshaler { return new(miner.Partition) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *differ) diffMarketState(path string, before, after *market.State) error {
+	d.diffFields(path, before, after, "Proposals", "States", "PendingProposals", "EscrowTable", "LockedTable")
+
+	if err := d.diffArrayRoots(path+"/Proposals", before.Proposals, after.Proposals,
+		func() cbor.Unmarshaler { return new(market.DealProposal) }); err != nil {
+		return err
+	}
+	if err := d.diffArrayRoots(path+"/States", before.States, after.States,
+		func() cbor.Unmarshaler { return new(market.DealState) }); err != nil {
+		return err
+	}
+	if err := d.diffMapRoots(path+"/PendingProposals", before.PendingProposals, after.PendingProposals,
+		cidKey, nil, func() cbor.Unmarshaler { return new(abi.EmptyValue) }); err != nil {
+		return err
+	}
+	if err := d.diffMapRoots(path+"/EscrowTable", before.EscrowTable, after.EscrowTable,
+		addressKey, nil, func() cbor.Unmarshaler { return new(abi.TokenAmount) }); err != nil {
+		return err
+	}
+	return d.diffMapRoots(path+"/LockedTable", before.LockedTable, after.LockedTable,
+		addressKey, nil, func() cbor.Unmarshaler { return new(abi.TokenAmount) })
+}
+
+func (d *differ) diffPowerState(path string, before, after *power.State) error {
+	d.diffFields(path, before, after, "Claims")
+	return d.diffMapRoots(path+"/Claims", before.Claims, after.Claims,
+		addressKey, nil, func() cbor.Unmarshaler { return new(power.Claim) })
+}
+
+func (d *differ) diffVerifregState(path string, before, after *verifreg.State) error {
+	d.diffFields(path, before, after, "Verifiers", "VerifiedClients")
+	if err := d.diffMapRoots(path+"/Verifiers", before.Verifiers, after.Verifiers,
+		addressKey, nil, func() cbor.Unmarshaler { return new(verifreg.DataCap) }); err != nil {
+		return err
+	}
+	return d.diffMapRoots(path+"/VerifiedClients", before.VerifiedClients, after.VerifiedClients,
+		addressKey, nil, func() cbor.Unmarshaler { return new(verifreg.DataCap) })
+}
+
+func (d *differ) diffMultisigState(path string, before, after *multisig.State) error {
+	d.diffFields(path, before, after, "PendingTxns")
+	return d.diffMapRoots(path+"/PendingTxns", before.PendingTxns, after.PendingTxns,
+		intKey, nil, func() cbor.Unmarshaler { return new(multisig.Transaction) })
+}
+
+//
+// Generic comparison
+//
+
+// Records a modification for each exported field of two structs that differs, except for skipped fields.
+// Fields are compared by their CBOR encoding, if any, so that equal values with different in-memory
+// representations (such as bitfields) compare equal.
+func (d *differ) diffFields(path string, before, after interface{}, skip ...string) {
+	vBefore := reflect.ValueOf(before).Elem()
+	vAfter := reflect.ValueOf(after).Elem()
+	t := vBefore.Type()
+fields:
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		for _, s := range skip {
+			if s == field.Name {
+				continue fields
+			}
+		}
+		fBefore, fAfter := vBefore.Field(i), vAfter.Field(i)
+		if !valuesEqual(fBefore, fAfter) {
+			d.record(path+"/"+field.Name, Modified, fBefore.Interface(), fAfter.Interface())
+		}
+	}
+}
+
+func valuesEqual(a, b reflect.Value) bool {
+	encA, okA := encode(a)
+	encB, okB := encode(b)
+	if okA && okB {
+		return bytes.Equal(encA, encB)
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+// Encodes an addressable value as CBOR, if its type supports it.
+func encode(v reflect.Value) ([]byte, bool) {
+	m, ok := v.Addr().Interface().(cbor.Marshaler)
+	if !ok {
+		return nil, false
+	}
+	var buf bytes.Buffer
+	if err := m.MarshalCBOR(&buf); err != nil {
+		return nil, false
+	}
+	return buf.Bytes(), true
+}
+
+// Compares the entries of two HAMTs, if their roots differ.
+// Values present in both but with different encodings are passed to modified, or if nil, decoded with
+// newValue and recorded as a modification.
+func (d *differ) diffMapRoots(path string, before, after cid.Cid, key func(string) string,
+	modified func(path string, before, after []byte) error, newValue func() cbor.Unmarshaler) error {
+	entriesBefore := make(map[string][]byte)
+	entriesAfter := make(map[string][]byte)
+	if err := d.mapEntries(before, after, entriesBefore, entriesAfter); err != nil {
+		return xerrors.Errorf("failed to load %s: %w", path, err)
+	}
+
+	keys := make([]string, 0, len(entriesBefore)+len(entriesAfter))
+	for k := range entriesBefore { // nolint:nomaprange
+		keys = append(keys, k)
+	}
+	for k := range entriesAfter { // nolint:nomaprange
+		if _, ok := entriesBefore[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		entryPath := key(k)
+		if path != "" {
+			entryPath = path + "/" + entryPath
+		}
+		if err := d.diffEntry(entryPath, entriesBefore[k], entriesAfter[k], modified, newValue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Compares the entries of two AMTs, if their roots differ, recording modified values.
+func (d *differ) diffArrayRoots(path string, before, after cid.Cid, newValue func() cbor.Unmarshaler) error {
+	return d.diffArrayRootsWith(path, before, after, nil, newValue)
+}
+
+func (d *differ) diffArrayRootsWith(path string, before, after cid.Cid,
+	modified func(path string, before, after []byte) error, newValue func() cbor.Unmarshaler) error {
+	entriesBefore := make(map[int64][]byte)
+	entriesAfter := make(map[int64][]byte)
+	if err := d.arrayEntries(before, after, entriesBefore, entriesAfter); err != nil {
+		return xerrors.Errorf("failed to load %s: %w", path, err)
+	}
+
+	indexes := make([]int64, 0, len(entriesBefore)+len(entriesAfter))
+	for i := range entriesBefore { // nolint:nomaprange
+		indexes = append(indexes, i)
+	}
+	for i := range entriesAfter { // nolint:nomaprange
+		if _, ok := entriesBefore[i]; !ok {
+			indexes = append(indexes, i)
+		}
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+
+	for _, i := range indexes {
+		if err := d.diffEntry(fmt.Sprintf("%s/%d", path, i), entriesBefore[i], entriesAfter[i], modified, newValue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Compares the encoded values of a collection entry, either of which may be absent (nil).
+func (d *differ) diffEntry(path string, before, after []byte, modified func(path string, before, after []byte) error,
+	newValue func() cbor.Unmarshaler) error {
+	if bytes.Equal(before, after) {
+		return nil
+	}
+	if before != nil && after != nil && modified != nil {
+		return modified(path, before, after)
+	}
+
+	var valBefore, valAfter interface{}
+	var err error
+	if before != nil {
+		if valBefore, err = decode(newValue, before); err != nil {
+			return xerrors.Errorf("failed to decode %s: %w", path, err)
+		}
+	}
+	if after != nil {
+		if valAfter, err = decode(newValue, after); err != nil {
+			return xerrors.Errorf("failed to decode %s: %w", path, err)
+		}
+	}
+	switch {
+	case before == nil:
+		d.record(path, Added, nil, valAfter)
+	case after == nil:
+		d.record(path, Removed, valBefore, nil)
+	default:
+		d.record(path, Modified, valBefore, valAfter)
+	}
+	return nil
+}
+
+func decode(newValue func() cbor.Unmarshaler, raw []byte) (interface{}, error) {
+	v := newValue()
+	if err := v.UnmarshalCBOR(bytes.NewReader(raw)); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+//
+// Collection traversal
+//
+// The HAMT and AMT nodes of two collections are walked side by side, skipping any pair of subtrees with the
+// same CID. The entries of every other subtree are collected in full, so some entries collected may be
+// unchanged.
+//
+
+// Collects the encoded values by key of the HAMT nodes at before and after, either of which may be undefined
+// for an empty node.
+func (d *differ) mapEntries(before, after cid.Cid, entriesBefore, entriesAfter map[string][]byte) error {
+	if before.Equals(after) {
+		return nil
+	}
+	ndBefore, err := d.loadHamtNode(before)
+	if err != nil {
+		return err
+	}
+	ndAfter, err := d.loadHamtNode(after)
+	if err != nil {
+		return err
+	}
+
+	// Pointers are compacted, present only for each set bit of a node's bitfield.
+	var iBefore, iAfter int
+	width := ndBefore.Bitfield.BitLen()
+	if ndAfter.Bitfield.BitLen() > width {
+		width = ndAfter.Bitfield.BitLen()
+	}
+	for bit := 0; bit < width; bit++ {
+		var linkBefore, linkAfter cid.Cid
+		if ndBefore.Bitfield.Bit(bit) == 1 {
+			linkBefore = collectBucket(ndBefore.Pointers[iBefore], entriesBefore)
+			iBefore++
+		}
+		if ndAfter.Bitfield.Bit(bit) == 1 {
+			linkAfter = collectBucket(ndAfter.Pointers[iAfter], entriesAfter)
+			iAfter++
+		}
+		if err := d.mapEntries(linkBefore, linkAfter, entriesBefore, entriesAfter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *differ) loadHamtNode(c cid.Cid) (*hamt.Node, error) {
+	nd := hamt.Node{Bitfield: new(big.Int)}
+	if !c.Defined() {
+		return &nd, nil
+	}
+	if err := d.store.Get(d.store.Context(), c, &nd); err != nil {
+		return nil, err
+	}
+	if len(nd.Pointers) != popCount(nd.Bitfield) {
+		return nil, xerrors.Errorf("hamt node %s has %d pointers for %d bits set", c, len(nd.Pointers), popCount(nd.Bitfield))
+	}
+	return &nd, nil
+}
+
+// Collects the values of a pointer's bucket, returning the pointer's link if it is not a bucket.
+func collectBucket(p *hamt.Pointer, entries map[string][]byte) cid.Cid {
+	for _, kv := range p.KVs {
+		entries[string(kv.Key)] = kv.Value.Raw
+	}
+	return p.Link
+}
+
+func popCount(n *big.Int) int {
+	count := 0
+	for _, w := range n.Bits() {
+		count += bits.OnesCount(uint(w))
+	}
+	return count
+}
+
+// Collects the encoded values by index of the AMTs at before and after.
+func (d *differ) arrayEntries(before, after cid.Cid, entriesBefore, entriesAfter map[int64][]byte) error {
+	if before.Equals(after) {
+		return nil
+	}
+	var rootBefore, rootAfter amtRoot
+	if err := d.loadStates(before, after, &rootBefore, &rootAfter); err != nil {
+		return err
+	}
+	if rootBefore.bitWidth != rootAfter.bitWidth {
+		return xerrors.Errorf("amts %s and %s have different bit widths %d, %d", before, after, rootBefore.bitWidth, rootAfter.bitWidth)
+	}
+	a := amtWalk{d: d, bitWidth: rootBefore.bitWidth}
+
+	// The shorter AMT's root corresponds to the first child, at its height, of the taller AMT's root.
+	ndBefore, ndAfter := &rootBefore.node, &rootAfter.node
+	heightBefore, heightAfter := rootBefore.height, rootAfter.height
+	var err error
+	for ; heightBefore > heightAfter; heightBefore-- {
+		if ndBefore, err = a.firstChild(ndBefore, heightBefore, entriesBefore); err != nil {
+			return err
+		}
+	}
+	for ; heightAfter > heightBefore; heightAfter-- {
+		if ndAfter, err = a.firstChild(ndAfter, heightAfter, entriesAfter); err != nil {
+			return err
+		}
+	}
+	return a.nodeEntries(ndBefore, ndAfter, heightBefore, 0, entriesBefore, entriesAfter)
+}
+
+type amtWalk struct {
+	d        *differ
+	bitWidth uint64
+}
+
+// Collects the values of the nodes before and after, at height and holding indexes from offset.
+func (a *amtWalk) nodeEntries(before, after *amtNode, height, offset uint64, entriesBefore, entriesAfter map[int64][]byte) error {
+	width := uint64(1) << a.bitWidth
+	var iBefore, iAfter int
+	if height == 0 {
+		for i := uint64(0); i < width; i++ {
+			if before.has(i) {
+				entriesBefore[int64(offset+i)] = before.values[iBefore].Raw
+				iBefore++
+			}
+			if after.has(i) {
+				entriesAfter[int64(offset+i)] = after.values[iAfter].Raw
+				iAfter++
+			}
+		}
+		return nil
+	}
+
+	childSpan := uint64(1) << (a.bitWidth * height)
+	for i := uint64(0); i < width; i++ {
+		var linkBefore, linkAfter cid.Cid
+		if before.has(i) {
+			linkBefore = before.links[iBefore]
+			iBefore++
+		}
+		if after.has(i) {
+			linkAfter = after.links[iAfter]
+			iAfter++
+		}
+		if linkBefore.Equals(linkAfter) {
+			continue
+		}
+		childBefore, err := a.load(linkBefore)
+		if err != nil {
+			return err
+		}
+		childAfter, err := a.load(linkAfter)
+		if err != nil {
+			return err
+		}
+		if err := a.nodeEntries(childBefore, childAfter, height-1, offset+i*childSpan, entriesBefore, entriesAfter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Collects the values of all but the first child of a node at height, returning the first child.
+func (a *amtWalk) firstChild(nd *amtNode, height uint64, entries map[int64][]byte) (*amtNode, error) {
+	first, err := a.load(cid.Undef)
+	if err != nil {
+		return nil, err
+	}
+	rest := *nd
+	if nd.has(0) {
+		if first, err = a.load(nd.links[0]); err != nil {
+			return nil, err
+		}
+		rest.bmap = append([]byte{}, nd.bmap...)
+		rest.bmap[0] &^= 1
+		rest.links = nd.links[1:]
+	}
+	return first, a.nodeEntries(&rest, &amtNode{}, height, 0, entries, map[int64][]byte{})
+}
+
+// Loads the node at c, or an empty node if c is undefined.
+func (a *amtWalk) load(c cid.Cid) (*amtNode, error) {
+	var nd amtNode
+	if !c.Defined() {
+		return &nd, nil
+	}
+	if err := a.d.store.Get(a.d.store.Context(), c, &nd); err != nil {
+		return nil, err
+	}
+	return &nd, nil
+}
+
+// The encoding of an AMT root, [bitWidth, height, count, node], which go-amt-ipld does not export.
+type amtRoot struct {
+	bitWidth uint64
+	height   uint64
+	node     amtNode
+}
+
+// The encoding of an AMT node, [bmap, links, values].
+type amtNode struct {
+	bmap   []byte
+	links  []cid.Cid
+	values []cbg.Deferred
+}
+
+func (n *amtNode) has(i uint64) bool {
+	return i/8 < uint64(len(n.bmap)) && n.bmap[i/8]&(1<<(i%8)) != 0
+}
+
+func (r *amtRoot) UnmarshalCBOR(rd io.Reader) error {
+	br := cbg.GetPeeker(rd)
+	if err := readArrayHeader(br, 4); err != nil {
+		return err
+	}
+	var err error
+	if r.bitWidth, err = readUint(br); err != nil {
+		return err
+	}
+	if r.bitWidth == 0 || r.bitWidth > 16 {
+		return xerrors.Errorf("invalid amt bit width %d", r.bitWidth)
+	}
+	if r.height, err = readUint(br); err != nil {
+		return err
+	}
+	if r.height*r.bitWidth >= 64 {
+		return xerrors.Errorf("invalid amt height %d", r.height)
+	}
+	if _, err = readUint(br); err != nil {
+		return err
+	}
+	return r.node.UnmarshalCBOR(br)
+}
+
+func (n *amtNode) UnmarshalCBOR(rd io.Reader) error {
+	br := cbg.GetPeeker(rd)
+	if err := readArrayHeader(br, 3); err != nil {
+		return err
+	}
+	var err error
+	if n.bmap, err = cbg.ReadByteArray(br, 1<<13); err != nil {
+		return err
+	}
+
+	maj, count, err := cbg.CborReadHeader(br)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajArray || count > 1<<16 {
+		return xerrors.Errorf("invalid amt node links")
+	}
+	n.links = make([]cid.Cid, count)
+	for i := range n.links {
+		if n.links[i], err = cbg.ReadCid(br); err != nil {
+			return err
+		}
+	}
+
+	maj, count, err = cbg.CborReadHeader(br)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajArray || count > 1<<16 {
+		return xerrors.Errorf("invalid amt node values")
+	}
+	n.values = make([]cbg.Deferred, count)
+	for i := range n.values {
+		if err := n.values[i].UnmarshalCBOR(br); err != nil {
+			return err
+		}
+	}
+
+	set := 0
+	for _, b := range n.bmap {
+		set += bits.OnesCount8(b)
+	}
+	if set != len(n.links) && set != len(n.values) {
+		return xerrors.Errorf("amt node bitmap has %d bits set for %d links and %d values", set, len(n.links), len(n.values))
+	}
+	return nil
+}
+
+func readArrayHeader(br io.Reader, length uint64) error {
+	maj, extra, err := cbg.CborReadHeader(br)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajArray || extra != length {
+		return xerrors.Errorf("expected array of %d elements", length)
+	}
+	return nil
+}
+
+func readUint(br io.Reader) (uint64, error) {
+	maj, extra, err := cbg.CborReadHeader(br)
+	if err != nil {
+		return 0, err
+	}
+	if maj != cbg.MajUnsignedInt {
+		return 0, xerrors.Errorf("expected unsigned integer")
+	}
+	return extra, nil
+}
+
+//
+// Key formatting
+//
+
+func addressKey(k string) string {
+	a, err := addr.NewFromBytes([]byte(k))
+	if err != nil {
+		return fmt.Sprintf("%x", k)
+	}
+	return a.String()
+}
+
+func uintKey(k string) string {
+	n, err := abi.ParseUIntKey(k)
+	if err != nil {
+		return fmt.Sprintf("%x", k)
+	}
+	return fmt.Sprintf("%d", n)
+}
+
+func intKey(k string) string {
+	n, err := abi.ParseIntKey(k)
+	if err != nil {
+		return fmt.Sprintf("%x", k)
+	}
+	return fmt.Sprintf("%d", n)
+}
+
+func cidKey(k string) string {
+	c, err := cid.Cast([]byte(k))
+	if err != nil {
+		return fmt.Sprintf("%x", k)
+	}
+	return c.String()
+}
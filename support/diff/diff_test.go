@@ -0,0 +1,266 @@
+package diff_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin"
+	init_ "github.com/filecoin-project/specs-actors/v3/actors/builtin/init"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/market"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/multisig"
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin/power"
+	"github.com/filecoin-project/specs-actors/v3/actors/states"
+	"github.com/filecoin-project/specs-actors/v3/actors/util/adt"
+	"github.com/filecoin-project/specs-actors/v3/support/diff"
+	"github.com/filecoin-project/specs-actors/v3/support/ipld"
+	tutil "github.com/filecoin-project/specs-actors/v3/support/testing"
+	vm "github.com/filecoin-project/specs-actors/v3/support/vm"
+)
+
+func TestDiffTrees(t *testing.T) {
+	ctx := context.Background()
+	v := vm.NewVMWithSingletons(ctx, t, ipld.NewBlockStoreInMemory())
+	addrs := vm.CreateAccounts(ctx, t, v, 2, big.Mul(big.NewInt(10_000), big.NewInt(1e18)), 93837778)
+	owner, found := v.NormalizeAddress(addrs[0])
+	require.True(t, found)
+	recipient, found := v.NormalizeAddress(addrs[1])
+	require.True(t, found)
+
+	ret := vm.ApplyOk(t, v, addrs[0], builtin.StoragePowerActorAddr, big.NewInt(1e10), builtin.MethodsPower.CreateMiner, &power.CreateMinerParams{
+		Owner:               addrs[0],
+		Worker:              addrs[0],
+		WindowPoStProofType: abi.RegisteredPoStProof_StackedDrgWindow32GiBV1,
+		Peer:                abi.PeerID("before"),
+	})
+	minerAddr := ret.(*power.CreateMinerReturn).IDAddress
+	msigAddr := createMultisig(t, v, addrs)
+
+	v, err := v.WithEpoch(1)
+	require.NoError(t, err)
+	before := v.StateRoot()
+
+	// Change account balances and nonces.
+	vm.ApplyOk(t, v, addrs[0], addrs[1], big.NewInt(1000), builtin.MethodSend, nil)
+	// Add a market escrow balance.
+	vm.ApplyOk(t, v, addrs[0], builtin.StorageMarketActorAddr, big.NewInt(2000), builtin.MethodsMarket.AddBalance, &owner)
+	// Add a pending multisig transaction.
+	vm.ApplyOk(t, v, addrs[0], msigAddr, big.Zero(), builtin.MethodsMultisig.Propose, &multisig.ProposeParams{
+		To:     addrs[1],
+		Value:  big.NewInt(10),
+		Method: builtin.MethodSend,
+	})
+	// Change miner info.
+	vm.ApplyOk(t, v, addrs[0], minerAddr, big.Zero(), builtin.MethodsMiner.ChangePeerID, &miner.ChangePeerIDParams{NewID: abi.PeerID("after")})
+	// Add a sector directly to the miner's state.
+	var minerSt miner.State
+	require.NoError(t, v.GetState(minerAddr, &minerSt))
+	sector := &miner.SectorOnChainInfo{
+		SectorNumber:          100,
+		SealProof:             abi.RegisteredSealProof_StackedDrg32GiBV1_1,
+		SealedCID:             tutil.MakeCID("100", &miner.SealedCIDPrefix),
+		Activation:            1,
+		Expiration:            1000,
+		DealWeight:            big.Zero(),
+		VerifiedDealWeight:    big.Zero(),
+		InitialPledge:         big.NewInt(100),
+		ExpectedDayReward:     big.Zero(),
+		ExpectedStoragePledge: big.Zero(),
+		ReplacedDayReward:     big.Zero(),
+	}
+	require.NoError(t, minerSt.PutSectors(v.Store(), sector))
+	require.NoError(t, v.SetActorState(ctx, minerAddr, &minerSt))
+	// Create a new actor.
+	newMsigAddr := createMultisig(t, v, addrs)
+
+	v, err = v.WithEpoch(2)
+	require.NoError(t, err)
+	after := v.StateRoot()
+
+	t.Run("identical trees", func(t *testing.T) {
+		changes, err := diff.DiffTrees(v.Store(), before, before)
+		require.NoError(t, err)
+		assert.Empty(t, changes)
+	})
+
+	t.Run("reports changes", func(t *testing.T) {
+		changes, err := diff.DiffTrees(v.Store(), before, after)
+		require.NoError(t, err)
+		byPath := map[string]diff.Change{}
+		for _, c := range changes {
+			byPath[c.Path] = c
+		}
+
+		// Actor fields.
+		c := requireChange(t, byPath, recipient, "/Balance", diff.Modified)
+		assert.Equal(t, big.NewInt(1000), big.Sub(c.After.(abi.TokenAmount), c.Before.(abi.TokenAmount)))
+		requireChange(t, byPath, owner, "/Balance", diff.Modified)
+		requireChange(t, byPath, minerAddr, "/Head", diff.Modified)
+		assert.NotContains(t, byPath, minerAddr.String()+"/Code")
+
+		// Builtin actor state.
+		c = requireChange(t, byPath, builtin.StorageMarketActorAddr, "/State/EscrowTable/"+owner.String(), diff.Added)
+		assert.Equal(t, big.NewInt(2000), *c.After.(*abi.TokenAmount))
+		c = requireChange(t, byPath, msigAddr, "/State/PendingTxns/0", diff.Added)
+		assert.Equal(t, big.NewInt(10), c.After.(*multisig.Transaction).Value)
+		requireChange(t, byPath, msigAddr, "/State/NextTxnID", diff.Modified)
+		c = requireChange(t, byPath, minerAddr, "/State/Info/PeerId", diff.Modified)
+		assert.Equal(t, abi.PeerID("before"), abi.PeerID(c.Before.([]byte)))
+		assert.Equal(t, abi.PeerID("after"), abi.PeerID(c.After.([]byte)))
+		c = requireChange(t, byPath, minerAddr, "/State/Sectors/100", diff.Added)
+		assert.Equal(t, sector, c.After)
+
+		// New actors.
+		c = requireChange(t, byPath, newMsigAddr, "", diff.Added)
+		assert.Equal(t, builtin.MultisigActorCodeID, c.After.(*states.Actor).Code)
+		requireChange(t, byPath, builtin.InitActorAddr, "/Head", diff.Modified)
+
+		// Unchanged actors are not reported.
+		for _, c := range changes {
+			assert.NotContains(t, c.Path, builtin.RewardActorAddr.String()+"/")
+			assert.NotContains(t, c.Path, builtin.VerifiedRegistryActorAddr.String()+"/")
+		}
+	})
+
+	t.Run("reverse reports removals", func(t *testing.T) {
+		changes, err := diff.DiffTrees(v.Store(), after, before)
+		require.NoError(t, err)
+		byPath := map[string]diff.Change{}
+		for _, c := range changes {
+			byPath[c.Path] = c
+		}
+		requireChange(t, byPath, minerAddr, "/State/Sectors/100", diff.Removed)
+		requireChange(t, byPath, msigAddr, "/State/PendingTxns/0", diff.Removed)
+		requireChange(t, byPath, newMsigAddr, "", diff.Removed)
+	})
+}
+
+func TestDiffLargeCollections(t *testing.T) {
+	ctx := context.Background()
+	bs := ipld.NewMetricsBlockStore(ipld.NewBlockStoreInMemory())
+	v := vm.NewVMWithSingletons(ctx, t, bs)
+	addrs := vm.CreateAccounts(ctx, t, v, 1, big.Mul(big.NewInt(10_000), big.NewInt(1e18)), 93837778)
+	ret := vm.ApplyOk(t, v, addrs[0], builtin.StoragePowerActorAddr, big.NewInt(1e10), builtin.MethodsPower.CreateMiner, &power.CreateMinerParams{
+		Owner:               addrs[0],
+		Worker:              addrs[0],
+		WindowPoStProofType: abi.RegisteredPoStProof_StackedDrgWindow32GiBV1,
+	})
+	minerAddr := ret.(*power.CreateMinerReturn).IDAddress
+
+	newSector := func(number abi.SectorNumber, pledge int64) *miner.SectorOnChainInfo {
+		return &miner.SectorOnChainInfo{
+			SectorNumber:          number,
+			SealProof:             abi.RegisteredSealProof_StackedDrg32GiBV1_1,
+			SealedCID:             tutil.MakeCID(fmt.Sprint(number), &miner.SealedCIDPrefix),
+			Expiration:            1000,
+			DealWeight:            big.Zero(),
+			VerifiedDealWeight:    big.Zero(),
+			InitialPledge:         big.NewInt(pledge),
+			ExpectedDayReward:     big.Zero(),
+			ExpectedStoragePledge: big.Zero(),
+			ReplacedDayReward:     big.Zero(),
+		}
+	}
+	escrowAddr := func(i int) address.Address {
+		a, err := address.NewIDAddress(uint64(5000 + i))
+		require.NoError(t, err)
+		return a
+	}
+
+	// Fill the miner's sectors and the market's escrow table.
+	var minerSt miner.State
+	require.NoError(t, v.GetState(minerAddr, &minerSt))
+	var sectors []*miner.SectorOnChainInfo
+	for i := 0; i < 2000; i++ {
+		sectors = append(sectors, newSector(abi.SectorNumber(i), 1))
+	}
+	require.NoError(t, minerSt.PutSectors(v.Store(), sectors...))
+	require.NoError(t, v.SetActorState(ctx, minerAddr, &minerSt))
+
+	var marketSt market.State
+	require.NoError(t, v.GetState(builtin.StorageMarketActorAddr, &marketSt))
+	escrow, err := adt.AsMap(v.Store(), marketSt.EscrowTable, adt.BalanceTableBitwidth)
+	require.NoError(t, err)
+	for i := 0; i < 2000; i++ {
+		amount := big.NewInt(1)
+		require.NoError(t, escrow.Put(abi.AddrKey(escrowAddr(i)), &amount))
+	}
+	marketSt.EscrowTable, err = escrow.Root()
+	require.NoError(t, err)
+	require.NoError(t, v.SetActorState(ctx, builtin.StorageMarketActorAddr, &marketSt))
+	v, err = v.WithEpoch(1)
+	require.NoError(t, err)
+	before := v.StateRoot()
+
+	// Modify, remove and add a few entries, growing the sectors AMT by a level.
+	require.NoError(t, minerSt.PutSectors(v.Store(), newSector(7, 2), newSector(1<<16, 1)))
+	deleted := bitfield.NewFromSet([]uint64{1500})
+	require.NoError(t, minerSt.DeleteSectors(v.Store(), deleted))
+	require.NoError(t, v.SetActorState(ctx, minerAddr, &minerSt))
+
+	escrow, err = adt.AsMap(v.Store(), marketSt.EscrowTable, adt.BalanceTableBitwidth)
+	require.NoError(t, err)
+	amount := big.NewInt(2)
+	require.NoError(t, escrow.Put(abi.AddrKey(escrowAddr(42)), &amount))
+	require.NoError(t, escrow.Delete(abi.AddrKey(escrowAddr(1042))))
+	require.NoError(t, escrow.Put(abi.AddrKey(escrowAddr(3000)), &amount))
+	marketSt.EscrowTable, err = escrow.Root()
+	require.NoError(t, err)
+	require.NoError(t, v.SetActorState(ctx, builtin.StorageMarketActorAddr, &marketSt))
+	v, err = v.WithEpoch(2)
+	require.NoError(t, err)
+	after := v.StateRoot()
+
+	reads := bs.ReadCount()
+	changes, err := diff.DiffTrees(v.Store(), before, after)
+	require.NoError(t, err)
+	// Unchanged subtrees of the collections are not read.
+	assert.Less(t, bs.ReadCount()-reads, uint64(100))
+
+	var paths []string
+	for _, c := range changes {
+		paths = append(paths, c.Path)
+	}
+	minerPath := minerAddr.String() + "/State/Sectors/"
+	marketPath := builtin.StorageMarketActorAddr.String() + "/State/EscrowTable/"
+	assert.Subset(t, paths, []string{
+		minerPath + "7",
+		minerPath + "1500",
+		minerPath + "65536",
+		marketPath + escrowAddr(42).String(),
+		marketPath + escrowAddr(1042).String(),
+		marketPath + escrowAddr(3000).String(),
+	})
+	count := 0
+	for _, p := range paths {
+		if strings.HasPrefix(p, minerPath) || strings.HasPrefix(p, marketPath) {
+			count++
+		}
+	}
+	assert.Equal(t, 6, count, strings.Join(paths, "\n"))
+}
+
+func createMultisig(t *testing.T, v *vm.VM, signers []address.Address) address.Address {
+	params := new(bytes.Buffer)
+	require.NoError(t, (&multisig.ConstructorParams{Signers: signers, NumApprovalsThreshold: 2}).MarshalCBOR(params))
+	ret := vm.ApplyOk(t, v, signers[0], builtin.InitActorAddr, big.Zero(), builtin.MethodsInit.Exec,
+		&init_.ExecParams{CodeCID: builtin.MultisigActorCodeID, ConstructorParams: params.Bytes()})
+	return ret.(*init_.ExecReturn).IDAddress
+}
+
+func requireChange(t *testing.T, changes map[string]diff.Change, a address.Address, path string, kind diff.ChangeKind) diff.Change {
+	c, ok := changes[a.String()+path]
+	require.True(t, ok, "no change at %s%s", a, path)
+	require.Equal(t, kind, c.Kind, c.String())
+	return c
+}
@@ -3,6 +3,7 @@ package ipld
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 
 	block "github.com/ipfs/go-block-format"
@@ -43,6 +44,18 @@ func (mb *BlockStoreInMemory) Put(b block.Block) error {
 	return nil
 }
 
+// Blocks returns all blocks in the store, ordered by CID.
+func (mb *BlockStoreInMemory) Blocks() []block.Block {
+	blocks := make([]block.Block, 0, len(mb.data))
+	for _, b := range mb.data { // nolint:nomaprange
+		blocks = append(blocks, b)
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].Cid().KeyString() < blocks[j].Cid().KeyString()
+	})
+	return blocks
+}
+
 //
 // Synchronized block store wrapper.
 //
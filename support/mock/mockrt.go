@@ -77,6 +77,8 @@ type Runtime struct {
 	expectVerifyConsensusFault     *expectVerifyConsensusFault
 	expectDeleteActor              *addr.Address
 	expectBatchVerifySeals         *expectBatchVerifySeals
+	expectAggregateVerifySeals     *expectAggregateVerifySeals
+	expectReplicaUpdate            *expectReplicaUpdate
 
 	logs []string
 	// Gas charged explicitly through rt.ChargeGas. Note: most charges are implicit
@@ -89,6 +91,16 @@ type expectBatchVerifySeals struct {
 	err error
 }
 
+type expectAggregateVerifySeals struct {
+	in  proof.AggregateSealVerifyProofAndInfos
+	err error
+}
+
+type expectReplicaUpdate struct {
+	in  proof.ReplicaUpdateInfo
+	err error
+}
+
 type expectRandomness struct {
 	// Expected parameters.
 	tag     crypto.DomainSeparationTag
@@ -660,6 +672,54 @@ func (rt *Runtime) BatchVerifySeals(vis map[addr.Address][]proof.SealVerifyInfo)
 	return nil, nil
 }
 
+func (rt *Runtime) ExpectAggregateVerifySeals(in proof.AggregateSealVerifyProofAndInfos, err error) {
+	rt.expectAggregateVerifySeals = &expectAggregateVerifySeals{
+		in, err,
+	}
+}
+
+func (rt *Runtime) VerifyAggregateSeals(agg proof.AggregateSealVerifyProofAndInfos) error {
+	exp := rt.expectAggregateVerifySeals
+	if exp != nil {
+		if !reflect.DeepEqual(exp.in, agg) {
+			rt.failTest("unexpected aggregate seal verification\n"+
+				"        : %v\n"+
+				"expected: %v",
+				agg, exp.in)
+		}
+		defer func() {
+			rt.expectAggregateVerifySeals = nil
+		}()
+		return exp.err
+	}
+	rt.failTestNow("unexpected syscall to verify aggregate seals %v", agg)
+	return nil
+}
+
+func (rt *Runtime) ExpectReplicaUpdate(in proof.ReplicaUpdateInfo, err error) {
+	rt.expectReplicaUpdate = &expectReplicaUpdate{
+		in, err,
+	}
+}
+
+func (rt *Runtime) VerifyReplicaUpdate(update proof.ReplicaUpdateInfo) error {
+	exp := rt.expectReplicaUpdate
+	if exp != nil {
+		if !reflect.DeepEqual(exp.in, update) {
+			rt.failTest("unexpected replica update verification\n"+
+				"        : %v\n"+
+				"expected: %v",
+				update, exp.in)
+		}
+		defer func() {
+			rt.expectReplicaUpdate = nil
+		}()
+		return exp.err
+	}
+	rt.failTestNow("unexpected syscall to verify replica update %v", update)
+	return nil
+}
+
 func (rt *Runtime) VerifyPoSt(vi proof.WindowPoStVerifyInfo) error {
 	exp := rt.expectVerifyPoSt
 	if exp != nil {
@@ -938,6 +998,14 @@ func (rt *Runtime) Verify() {
 		rt.failTest("missing expected batch verify seals with %v", rt.expectBatchVerifySeals)
 	}
 
+	if rt.expectAggregateVerifySeals != nil {
+		rt.failTest("missing expected aggregate verify seals with %v", rt.expectAggregateVerifySeals)
+	}
+
+	if rt.expectReplicaUpdate != nil {
+		rt.failTest("missing expected replica update verification with %v", rt.expectReplicaUpdate)
+	}
+
 	if rt.expectComputeUnsealedSectorCID != nil {
 		rt.failTest("missing expected ComputeUnsealedSectorCID with %v", rt.expectComputeUnsealedSectorCID)
 	}
@@ -968,6 +1036,8 @@ func (rt *Runtime) Reset() {
 	rt.expectVerifySigs = nil
 	rt.expectVerifySeal = nil
 	rt.expectBatchVerifySeals = nil
+	rt.expectAggregateVerifySeals = nil
+	rt.expectReplicaUpdate = nil
 	rt.expectComputeUnsealedSectorCID = nil
 }
 
@@ -0,0 +1,167 @@
+package vm_test
+
+import (
+	"math"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/crypto"
+
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v3/actors/runtime/proof"
+)
+
+// Gas limit applied to messages that don't specify one. Large enough to never be exhausted,
+// so gas is accounted but not enforced.
+const UnlimitedGas = int64(math.MaxInt64)
+
+// PriceList provides the gas prices for operations performed while executing messages in the VM.
+type PriceList interface {
+	// Charged once for every top-level message, before execution.
+	OnChainMessage(msgSize int) int64
+	// Charged for every invocation of an actor method, including nested sends.
+	OnMethodInvocation(value abi.TokenAmount, methodNum abi.MethodNum) int64
+
+	OnIpldGet(dataSize int) int64
+	OnIpldPut(dataSize int) int64
+
+	OnCreateActor() int64
+	OnDeleteActor() int64
+
+	OnVerifySignature(sigType crypto.SigType, planTextSize int) int64
+	OnHashing(dataSize int) int64
+	OnComputeUnsealedSectorCid(proofType abi.RegisteredSealProof, pieces []abi.PieceInfo) int64
+	OnVerifySeal(info proof.SealVerifyInfo) int64
+	OnVerifyAggregateSeals(aggregate proof.AggregateSealVerifyProofAndInfos) int64
+	OnVerifyReplicaUpdate(update proof.ReplicaUpdateInfo) int64
+	OnVerifyPost(info proof.WindowPoStVerifyInfo) int64
+	OnVerifyConsensusFault() int64
+}
+
+// ScalarPriceList is a PriceList charging a fixed base plus per-unit cost for each operation.
+type ScalarPriceList struct {
+	OnChainMessageBase    int64
+	OnChainMessagePerByte int64
+
+	SendBase                 int64
+	SendTransferFunds        int64
+	SendInvokeMethod         int64
+	IpldGetBase              int64
+	IpldGetPerByte           int64
+	IpldPutBase              int64
+	IpldPutPerByte           int64
+	CreateActor              int64
+	DeleteActor              int64
+	HashingBase              int64
+	HashingPerByte           int64
+	VerifySignatureSecp      int64
+	VerifySignatureBLS       int64
+	ComputeUnsealedBase      int64
+	VerifySealBase           int64
+	VerifyAggregateBase      int64
+	VerifyAggregatePerSector int64
+	VerifyReplicaUpdate      int64
+	VerifyPostBase           int64
+	VerifyPostPerSector      int64
+	VerifyConsensusFault     int64
+}
+
+var _ PriceList = (*ScalarPriceList)(nil)
+
+// Storage costs are scaled by this multiplier relative to compute.
+const storageGasMultiplier = 1300
+
+// DefaultPriceList approximates the mainnet price list in effect at network version 10.
+func DefaultPriceList() *ScalarPriceList {
+	return &ScalarPriceList{
+		OnChainMessageBase:    38863,
+		OnChainMessagePerByte: 1 * storageGasMultiplier,
+
+		SendBase:                 29233,
+		SendTransferFunds:        27500,
+		SendInvokeMethod:         -5377,
+		IpldGetBase:              114617,
+		IpldGetPerByte:           0,
+		IpldPutBase:              353640,
+		IpldPutPerByte:           1 * storageGasMultiplier,
+		CreateActor:              1108454 + (36+40)*storageGasMultiplier,
+		DeleteActor:              0,
+		HashingBase:              31355,
+		HashingPerByte:           0,
+		VerifySignatureSecp:      1637292,
+		VerifySignatureBLS:       16598605,
+		ComputeUnsealedBase:      98647,
+		VerifySealBase:           2000,
+		VerifyAggregateBase:      449900,
+		VerifyAggregatePerSector: 449900,
+		VerifyReplicaUpdate:      36316136,
+		VerifyPostBase:           117680921,
+		VerifyPostPerSector:      43780,
+		VerifyConsensusFault:     495422,
+	}
+}
+
+func (pl *ScalarPriceList) OnChainMessage(msgSize int) int64 {
+	return pl.OnChainMessageBase + pl.OnChainMessagePerByte*int64(msgSize)
+}
+
+func (pl *ScalarPriceList) OnMethodInvocation(value abi.TokenAmount, methodNum abi.MethodNum) int64 {
+	ret := pl.SendBase
+	if !value.NilOrZero() {
+		ret += pl.SendTransferFunds
+	}
+	if methodNum != builtin.MethodSend {
+		ret += pl.SendInvokeMethod
+	}
+	return ret
+}
+
+func (pl *ScalarPriceList) OnIpldGet(dataSize int) int64 {
+	return pl.IpldGetBase + pl.IpldGetPerByte*int64(dataSize)
+}
+
+func (pl *ScalarPriceList) OnIpldPut(dataSize int) int64 {
+	return pl.IpldPutBase + pl.IpldPutPerByte*int64(dataSize)
+}
+
+func (pl *ScalarPriceList) OnCreateActor() int64 {
+	return pl.CreateActor
+}
+
+func (pl *ScalarPriceList) OnDeleteActor() int64 {
+	return pl.DeleteActor
+}
+
+func (pl *ScalarPriceList) OnVerifySignature(sigType crypto.SigType, _ int) int64 {
+	if sigType == crypto.SigTypeBLS {
+		return pl.VerifySignatureBLS
+	}
+	return pl.VerifySignatureSecp
+}
+
+func (pl *ScalarPriceList) OnHashing(dataSize int) int64 {
+	return pl.HashingBase + pl.HashingPerByte*int64(dataSize)
+}
+
+func (pl *ScalarPriceList) OnComputeUnsealedSectorCid(_ abi.RegisteredSealProof, _ []abi.PieceInfo) int64 {
+	return pl.ComputeUnsealedBase
+}
+
+func (pl *ScalarPriceList) OnVerifySeal(_ proof.SealVerifyInfo) int64 {
+	return pl.VerifySealBase
+}
+
+func (pl *ScalarPriceList) OnVerifyAggregateSeals(aggregate proof.AggregateSealVerifyProofAndInfos) int64 {
+	return pl.VerifyAggregateBase + pl.VerifyAggregatePerSector*int64(len(aggregate.Infos))
+}
+
+func (pl *ScalarPriceList) OnVerifyReplicaUpdate(_ proof.ReplicaUpdateInfo) int64 {
+	return pl.VerifyReplicaUpdate
+}
+
+func (pl *ScalarPriceList) OnVerifyPost(info proof.WindowPoStVerifyInfo) int64 {
+	return pl.VerifyPostBase + pl.VerifyPostPerSector*int64(len(info.ChallengedSectors))
+}
+
+func (pl *ScalarPriceList) OnVerifyConsensusFault() int64 {
+	return pl.VerifyConsensusFault
+}
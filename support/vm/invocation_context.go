@@ -46,6 +46,7 @@ type invocationContext struct {
 	// Used for detecting modifications to state outside of transactions.
 	stateUsedObjs map[cbor.Marshaler]cid.Cid
 	stats         *CallStats
+	gasStart      int64 // Top-level gas used when this invocation began.
 }
 
 // Context for a top-level invocation sequence
@@ -55,6 +56,9 @@ type topLevelContext struct {
 	newActorAddressCount    uint64          // Count of calls to NewActorAddress (mutable).
 	statsSource             StatsSource     // optional source of external statistics that can be used to profile calls
 	circSupply              abi.TokenAmount // default or externally specified circulating FIL supply
+	gasLimit                int64           // Maximum gas the top-level message may use.
+	gasUsed                 int64           // Gas used so far by the top-level message and all nested invocations (mutable).
+	outOfGas                bool            // Whether any invocation has exhausted the gas limit (mutable).
 }
 
 func newInvocationContext(rt *VM, topLevel *topLevelContext, msg InternalMessage, fromActor *states.Actor, emptyObject cid.Cid) invocationContext {
@@ -70,6 +74,7 @@ func newInvocationContext(rt *VM, topLevel *topLevelContext, msg InternalMessage
 		callerValidated:  false,
 		stateUsedObjs:    map[cbor.Marshaler]cid.Cid{},
 		stats:            NewCallStats(topLevel.statsSource),
+		gasStart:         topLevel.gasUsed,
 	}
 }
 
@@ -87,6 +92,7 @@ func (ic *invocationContext) loadState(obj cbor.Unmarshaler) cid.Cid {
 	if err != nil {
 		panic(errors.Wrapf(err, "failed to load state for actor %s, CID %s", ic.msg.to, c))
 	}
+	ic.chargeGas("OnIpldGet", ic.rt.priceList.OnIpldGet(serializedSize(obj)))
 	return c
 }
 
@@ -117,10 +123,17 @@ var _ runtime.Runtime = (*invocationContext)(nil)
 // Store implements runtime.Runtime.
 func (ic *invocationContext) StoreGet(c cid.Cid, o cbor.Unmarshaler) bool {
 	sw := &storeWrapper{s: ic.rt.store, rt: ic.rt}
-	return sw.StoreGet(c, o)
+	found := sw.StoreGet(c, o)
+	size := 0
+	if found {
+		size = serializedSize(o)
+	}
+	ic.chargeGas("OnIpldGet", ic.rt.priceList.OnIpldGet(size))
+	return found
 }
 
 func (ic *invocationContext) StorePut(x cbor.Marshaler) cid.Cid {
+	ic.chargeGas("OnIpldPut", ic.rt.priceList.OnIpldPut(serializedSize(x)))
 	sw := &storeWrapper{s: ic.rt.store, rt: ic.rt}
 	return sw.StorePut(x)
 }
@@ -146,6 +159,7 @@ func (ic *invocationContext) StateCreate(obj cbor.Marshaler) {
 	if actr.Head.Defined() && !ic.emptyObject.Equals(actr.Head) {
 		ic.Abortf(exitcode.SysErrorIllegalActor, "failed to construct actor state: already initialized")
 	}
+	ic.chargeGas("OnIpldPut", ic.rt.priceList.OnIpldPut(serializedSize(obj)))
 	c, err := ic.rt.store.Put(ic.rt.ctx, obj)
 	if err != nil {
 		ic.Abortf(exitcode.ErrIllegalState, "failed to create actor state")
@@ -182,18 +196,22 @@ func (ic *invocationContext) StateTransaction(obj cbor.Er, f func()) {
 }
 
 func (ic *invocationContext) VerifySignature(signature crypto.Signature, signer address.Address, plaintext []byte) error {
+	ic.chargeGas("OnVerifySignature", ic.rt.priceList.OnVerifySignature(signature.Type, len(plaintext)))
 	return ic.Syscalls().VerifySignature(signature, signer, plaintext)
 }
 
 func (ic *invocationContext) HashBlake2b(data []byte) [32]byte {
+	ic.chargeGas("OnHashing", ic.rt.priceList.OnHashing(len(data)))
 	return ic.Syscalls().HashBlake2b(data)
 }
 
 func (ic *invocationContext) ComputeUnsealedSectorCID(reg abi.RegisteredSealProof, pieces []abi.PieceInfo) (cid.Cid, error) {
+	ic.chargeGas("OnComputeUnsealedSectorCid", ic.rt.priceList.OnComputeUnsealedSectorCid(reg, pieces))
 	return ic.Syscalls().ComputeUnsealedSectorCID(reg, pieces)
 }
 
 func (ic *invocationContext) VerifySeal(vi proof.SealVerifyInfo) error {
+	ic.chargeGas("OnVerifySeal", ic.rt.priceList.OnVerifySeal(vi))
 	return ic.Syscalls().VerifySeal(vi)
 }
 
@@ -201,11 +219,23 @@ func (ic *invocationContext) BatchVerifySeals(vis map[address.Address][]proof.Se
 	return ic.Syscalls().BatchVerifySeals(vis)
 }
 
+func (ic *invocationContext) VerifyAggregateSeals(agg proof.AggregateSealVerifyProofAndInfos) error {
+	ic.chargeGas("OnVerifyAggregateSeals", ic.rt.priceList.OnVerifyAggregateSeals(agg))
+	return ic.Syscalls().VerifyAggregateSeals(agg)
+}
+
+func (ic *invocationContext) VerifyReplicaUpdate(update proof.ReplicaUpdateInfo) error {
+	ic.chargeGas("OnVerifyReplicaUpdate", ic.rt.priceList.OnVerifyReplicaUpdate(update))
+	return ic.Syscalls().VerifyReplicaUpdate(update)
+}
+
 func (ic *invocationContext) VerifyPoSt(vi proof.WindowPoStVerifyInfo) error {
+	ic.chargeGas("OnVerifyPost", ic.rt.priceList.OnVerifyPost(vi))
 	return ic.Syscalls().VerifyPoSt(vi)
 }
 
 func (ic *invocationContext) VerifyConsensusFault(h1, h2, extra []byte) (*runtime.ConsensusFault, error) {
+	ic.chargeGas("OnVerifyConsensusFault", ic.rt.priceList.OnVerifyConsensusFault())
 	return ic.Syscalls().VerifyConsensusFault(h1, h2, extra)
 }
 
@@ -239,12 +269,20 @@ func (ic *invocationContext) GetActorCodeCID(a address.Address) (cid.Cid, bool)
 	return entry.Code, true
 }
 
-func (ic *invocationContext) GetRandomnessFromBeacon(_ crypto.DomainSeparationTag, _ abi.ChainEpoch, _ []byte) abi.Randomness {
-	return []byte("not really random")
+func (ic *invocationContext) GetRandomnessFromBeacon(tag crypto.DomainSeparationTag, epoch abi.ChainEpoch, entropy []byte) abi.Randomness {
+	return ic.getRandomness(RandomnessRequest{RandomnessBeacon, tag, epoch, entropy})
 }
 
-func (ic *invocationContext) GetRandomnessFromTickets(_ crypto.DomainSeparationTag, _ abi.ChainEpoch, _ []byte) abi.Randomness {
-	return []byte("not really random")
+func (ic *invocationContext) GetRandomnessFromTickets(tag crypto.DomainSeparationTag, epoch abi.ChainEpoch, entropy []byte) abi.Randomness {
+	return ic.getRandomness(RandomnessRequest{RandomnessTickets, tag, epoch, entropy})
+}
+
+func (ic *invocationContext) getRandomness(req RandomnessRequest) abi.Randomness {
+	if req.Epoch > ic.rt.currentEpoch {
+		ic.Abortf(exitcode.SysErrorIllegalArgument, "cannot draw %s randomness from future epoch %d at %d", req.Kind, req.Epoch, ic.rt.currentEpoch)
+	}
+	ic.rt.recordRandomnessRequest(req)
+	return ic.rt.randomness.Get(req)
 }
 
 func (ic *invocationContext) ValidateImmediateCallerAcceptAny() {
@@ -342,7 +380,9 @@ func (ic *invocationContext) Send(toAddr address.Address, methodNum abi.MethodNu
 	newCtx := newInvocationContext(ic.rt, ic.topLevel, newMsg, fromActor, ic.emptyObject)
 	ret, code := newCtx.invoke()
 
-	ic.stats.MergeSubStat(newCtx.toActor.Code, newMsg.method, newCtx.stats)
+	if newCtx.toActor != nil {
+		ic.stats.MergeSubStat(newCtx.toActor.Code, newMsg.method, newCtx.stats)
+	}
 
 	err = ret.Into(out)
 	if err != nil {
@@ -362,6 +402,8 @@ func (ic *invocationContext) CreateActor(codeID cid.Cid, addr address.Address) {
 		ic.Abortf(exitcode.SysErrorIllegalArgument, "Can only have one instance of singleton actors.")
 	}
 
+	ic.chargeGas("OnCreateActor", ic.rt.priceList.OnCreateActor())
+
 	ic.rt.Log(rt.DEBUG, "creating actor, friendly-name: %s, Exitcode: %s, addr: %s\n", builtin.ActorNameByCode(codeID), codeID, addr)
 
 	// Check existing address. If nothing there, create empty actor.
@@ -395,6 +437,7 @@ func (ic *invocationContext) DeleteActor(beneficiary address.Address) {
 	if !found {
 		ic.Abortf(exitcode.SysErrorIllegalActor, "delete non-existent actor %v", receiverActor)
 	}
+	ic.chargeGas("OnDeleteActor", ic.rt.priceList.OnDeleteActor())
 
 	// Transfer any remaining balance to the beneficiary.
 	// This looks like it could cause a problem with gas refund going to a non-existent actor, but the gas payer
@@ -416,8 +459,23 @@ func (ic *invocationContext) Context() context.Context {
 	return ic.rt.ctx
 }
 
-func (ic *invocationContext) ChargeGas(_ string, _ int64, _ int64) {
-	// no-op
+func (ic *invocationContext) ChargeGas(name string, compute int64, _ int64) {
+	// Virtual gas is not charged.
+	ic.chargeGas(name, compute)
+}
+
+// Charges gas against the top-level message's limit, aborting with SysErrOutOfGas if the limit is exceeded.
+// Once exhausted, the gas remains exhausted, so any subsequent charge by a calling actor also aborts,
+// and the top-level message fails even if a calling actor handles the nested abort.
+func (ic *invocationContext) chargeGas(name string, amount int64) {
+	if amount > ic.topLevel.gasLimit-ic.topLevel.gasUsed {
+		used := ic.topLevel.gasUsed
+		ic.topLevel.gasUsed = ic.topLevel.gasLimit
+		ic.topLevel.outOfGas = true
+		ic.Abortf(exitcode.SysErrOutOfGas, "not enough gas for %s: charge %d, used %d, limit %d",
+			name, amount, used, ic.topLevel.gasLimit)
+	}
+	ic.topLevel.gasUsed += amount
 }
 
 // Starts a new tracing span. The span must be End()ed explicitly, typically with a deferred invocation.
@@ -488,6 +546,14 @@ func (s fakeSyscalls) BatchVerifySeals(vi map[address.Address][]proof.SealVerify
 	return res, nil
 }
 
+func (s fakeSyscalls) VerifyAggregateSeals(_ proof.AggregateSealVerifyProofAndInfos) error {
+	return nil
+}
+
+func (s fakeSyscalls) VerifyReplicaUpdate(_ proof.ReplicaUpdateInfo) error {
+	return nil
+}
+
 func (s fakeSyscalls) VerifyPoSt(_ proof.WindowPoStVerifyInfo) error {
 	return nil
 }
@@ -543,12 +609,13 @@ func (ic *invocationContext) invoke() (ret returnWrapper, errcode exitcode.ExitC
 		panic(err)
 	}
 
-	ic.rt.startInvocation(&ic.msg)
+	invocation := ic.rt.startInvocation(&ic.msg, ic.stats)
 
 	// Install handler for abort, which rolls back all state changes from this and any nested invocations.
 	// This is the only path by which a non-OK exit code may be returned.
 	defer func() {
 		ic.stats.Capture()
+		ic.stats.GasUsed = ic.gasUsed()
 
 		if r := recover(); r != nil {
 			if err := ic.rt.rollback(priorRoot); err != nil {
@@ -558,7 +625,7 @@ func (ic *invocationContext) invoke() (ret returnWrapper, errcode exitcode.ExitC
 			case abort:
 				ic.rt.Log(rt.WARN, "Abort during actor execution. errMsg: %v exitCode: %d sender: %v receiver; %v method: %d value %v",
 					r, r.code, ic.msg.from, ic.msg.to, ic.msg.method, ic.msg.value)
-				ic.rt.endInvocation(r.code, abi.Empty)
+				ic.rt.endInvocation(r.code, abi.Empty, ic.gasUsed())
 				ret = returnWrapper{abi.Empty} // The Empty here should never be used, but slightly safer than zero value.
 				errcode = r.code
 				return
@@ -581,9 +648,13 @@ func (ic *invocationContext) invoke() (ret returnWrapper, errcode exitcode.ExitC
 		panic("bad Exitcode: sender address MUST be an ID address at invocation time")
 	}
 
+	// 1. charge for the invocation itself
+	ic.chargeGas("OnMethodInvocation", ic.rt.priceList.OnMethodInvocation(ic.msg.value, ic.msg.method))
+
 	// 2. load target actor
 	// Note: we replace the "to" address with the normalized version
 	ic.toActor, ic.msg.to = ic.resolveTarget(ic.msg.to)
+	invocation.Code = ic.toActor.Code
 
 	// 3. transfer funds carried by the msg
 	if !ic.msg.value.NilOrZero() {
@@ -600,7 +671,7 @@ func (ic *invocationContext) invoke() (ret returnWrapper, errcode exitcode.ExitC
 
 	// 4. if we are just sending funds, there is nothing else to do.
 	if ic.msg.method == builtin.MethodSend {
-		ic.rt.endInvocation(exitcode.Ok, abi.Empty)
+		ic.rt.endInvocation(exitcode.Ok, abi.Empty, ic.gasUsed())
 		return returnWrapper{abi.Empty}, exitcode.Ok
 	}
 
@@ -627,7 +698,7 @@ func (ic *invocationContext) invoke() (ret returnWrapper, errcode exitcode.ExitC
 	ic.checkStateObjectsUnmodified()
 
 	// 3. success!
-	ic.rt.endInvocation(exitcode.Ok, marsh)
+	ic.rt.endInvocation(exitcode.Ok, marsh, ic.gasUsed())
 	return ret, exitcode.Ok
 }
 
@@ -783,6 +854,7 @@ func (ic *invocationContext) resolveTarget(target address.Address) (*states.Acto
 }
 
 func (ic *invocationContext) replace(obj cbor.Marshaler) cid.Cid {
+	ic.chargeGas("OnIpldPut", ic.rt.priceList.OnIpldPut(serializedSize(obj)))
 	actr, found, err := ic.rt.GetActor(ic.msg.to)
 	if err != nil {
 		panic(err)
@@ -802,6 +874,11 @@ func (ic *invocationContext) replace(obj cbor.Marshaler) cid.Cid {
 	return c
 }
 
+// Gas charged since this invocation began, including nested invocations.
+func (ic *invocationContext) gasUsed() int64 {
+	return ic.topLevel.gasUsed - ic.gasStart
+}
+
 // Checks that state objects weren't modified outside of transaction.
 func (ic *invocationContext) checkStateObjectsUnmodified() {
 	for obj, expectedKey := range ic.stateUsedObjs { // nolint:nomaprange
@@ -817,6 +894,36 @@ func (ic *invocationContext) checkStateObjectsUnmodified() {
 	}
 }
 
+// Returns the length of the serialized form of a message parameter or state object, or zero
+// if it has no serialized form.
+func serializedSize(obj interface{}) int {
+	return len(serialize(obj))
+}
+
+// Returns the serialized form of a message parameter, return value or state object, or nil
+// if it has no serialized form.
+func serialize(obj interface{}) []byte {
+	switch o := obj.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return o
+	case builtin.CBORBytes:
+		return o
+	case cbor.Marshaler:
+		if v := reflect.ValueOf(o); v.Kind() == reflect.Ptr && v.IsNil() {
+			return nil
+		}
+		buf := bytes.Buffer{}
+		if err := o.MarshalCBOR(&buf); err != nil {
+			return nil
+		}
+		return buf.Bytes()
+	default:
+		return nil
+	}
+}
+
 func decodeBytes(t reflect.Type, argBytes []byte) (interface{}, error) {
 	// decode arg1 (this is the payload for the actor method)
 	v := reflect.New(t)
@@ -0,0 +1,86 @@
+package vm_test
+
+import (
+	"encoding/binary"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/crypto"
+	"github.com/minio/blake2b-simd"
+)
+
+// Identifies the chain from which randomness is drawn.
+type RandomnessKind int
+
+const (
+	RandomnessBeacon RandomnessKind = iota
+	RandomnessTickets
+)
+
+func (k RandomnessKind) String() string {
+	switch k {
+	case RandomnessBeacon:
+		return "beacon"
+	case RandomnessTickets:
+		return "tickets"
+	default:
+		return "unknown"
+	}
+}
+
+// A request for randomness made by an actor through the runtime.
+type RandomnessRequest struct {
+	Kind    RandomnessKind
+	Tag     crypto.DomainSeparationTag
+	Epoch   abi.ChainEpoch
+	Entropy []byte
+}
+
+type randomnessKey struct {
+	kind  RandomnessKind
+	epoch abi.ChainEpoch
+}
+
+// RandomnessSource provides reproducible beacon and ticket randomness to actors executing in the VM.
+// Values are derived from a seed, the chain kind, the domain separation tag, the epoch and the entropy,
+// so distinct requests receive distinct values and the same seed always produces the same values.
+// Tests may override the value for a kind of randomness at a specific epoch.
+type RandomnessSource struct {
+	seed       int64
+	registered map[randomnessKey]abi.Randomness
+}
+
+func NewRandomnessSource(seed int64) *RandomnessSource {
+	return &RandomnessSource{
+		seed:       seed,
+		registered: make(map[randomnessKey]abi.Randomness),
+	}
+}
+
+// Register sets the value returned for all requests for randomness of a kind at an epoch,
+// regardless of domain separation tag and entropy.
+func (r *RandomnessSource) Register(kind RandomnessKind, epoch abi.ChainEpoch, value abi.Randomness) {
+	r.registered[randomnessKey{kind, epoch}] = value
+}
+
+// Get returns the randomness satisfying a request.
+func (r *RandomnessSource) Get(req RandomnessRequest) abi.Randomness {
+	if value, ok := r.registered[randomnessKey{req.Kind, req.Epoch}]; ok {
+		return value
+	}
+
+	// Derive a per-epoch value for the chain, then draw from it in the same manner as the real chain.
+	var epochSeed [24]byte
+	binary.BigEndian.PutUint64(epochSeed[0:], uint64(r.seed))
+	binary.BigEndian.PutUint64(epochSeed[8:], uint64(req.Kind))
+	binary.BigEndian.PutUint64(epochSeed[16:], uint64(req.Epoch))
+	base := blake2b.Sum256(epochSeed[:])
+
+	buf := make([]byte, 8, 8+len(base)+8+len(req.Entropy))
+	binary.BigEndian.PutUint64(buf, uint64(req.Tag))
+	buf = append(buf, base[:]...)
+	buf = append(buf, make([]byte, 8)...)
+	binary.BigEndian.PutUint64(buf[8+len(base):], uint64(req.Epoch))
+	buf = append(buf, req.Entropy...)
+	out := blake2b.Sum256(buf)
+	return out[:]
+}
@@ -0,0 +1,142 @@
+package vm_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/filecoin-project/go-state-types/network"
+	block "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/specs-actors/v3/actors/util/adt"
+	"github.com/filecoin-project/specs-actors/v3/support/ipld"
+)
+
+// ReplayMessage is a top-level message to be re-executed, along with the chain context in which it was
+// originally applied and, optionally, its original outcome.
+type ReplayMessage struct {
+	From   address.Address
+	To     address.Address
+	Value  abi.TokenAmount
+	Method abi.MethodNum
+	Params []byte // CBOR-encoded parameters, empty if none.
+
+	Epoch             abi.ChainEpoch
+	CirculatingSupply abi.TokenAmount
+
+	ExpectedExitCode exitcode.ExitCode
+	ExpectedRoot     cid.Cid // State root expected after the message, or cid.Undef to skip the check.
+}
+
+// ReplayReceipt records the outcome of a replayed message.
+type ReplayReceipt struct {
+	ExitCode  exitcode.ExitCode
+	Return    []byte
+	GasUsed   int64
+	StateRoot cid.Cid // State root after the message.
+}
+
+// ReplayDivergence describes the first replayed message whose outcome differs from that expected.
+type ReplayDivergence struct {
+	Index            int // Index of the message in the replayed sequence.
+	ExpectedExitCode exitcode.ExitCode
+	ActualExitCode   exitcode.ExitCode
+	ExpectedRoot     cid.Cid
+	ActualRoot       cid.Cid
+}
+
+func (d *ReplayDivergence) String() string {
+	if d.ExpectedExitCode != d.ActualExitCode {
+		return fmt.Sprintf("message %d: exit code %s, expected %s", d.Index, d.ActualExitCode, d.ExpectedExitCode)
+	}
+	return fmt.Sprintf("message %d: state root %s, expected %s", d.Index, d.ActualRoot, d.ExpectedRoot)
+}
+
+// ReplayResult reports the outcome of replaying a sequence of messages.
+type ReplayResult struct {
+	Receipts   []ReplayReceipt   // One receipt for each message, in order.
+	Divergence *ReplayDivergence // The first divergent message, or nil if all matched expectations.
+	FinalRoot  cid.Cid
+}
+
+// Replay re-executes a sequence of messages against a state tree, starting from a state root at an epoch
+// and network version. The blocks, e.g. read from a CAR file, must include the complete state tree under
+// the starting root.
+// Each message is applied at its own epoch and circulating supply, which must not decrease
+// through the sequence. All messages are applied, even after a divergence is detected.
+func Replay(ctx context.Context, impls ActorImplLookup, blocks []block.Block, stateRoot cid.Cid, epoch abi.ChainEpoch,
+	nv network.Version, msgs []ReplayMessage) (*ReplayResult, error) {
+	bs := ipld.NewBlockStoreInMemory()
+	for _, blk := range blocks {
+		if err := bs.Put(blk); err != nil {
+			return nil, err
+		}
+	}
+	store := adt.WrapBlockStore(ctx, bs)
+
+	v, err := NewVMAtEpoch(ctx, impls, store, stateRoot, epoch)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load state root %s", stateRoot)
+	}
+	if v, err = v.WithNetworkVersion(nv); err != nil {
+		return nil, err
+	}
+	return ReplayOnVM(v, msgs)
+}
+
+// ReplayOnVM re-executes a sequence of messages against the current state of a VM.
+// The VM is advanced to each message's epoch as necessary.
+func ReplayOnVM(v *VM, msgs []ReplayMessage) (*ReplayResult, error) {
+	result := ReplayResult{Receipts: make([]ReplayReceipt, len(msgs))}
+	for i, msg := range msgs {
+		if msg.Epoch < v.GetEpoch() {
+			return nil, errors.Errorf("message %d at epoch %d precedes epoch %d", i, msg.Epoch, v.GetEpoch())
+		}
+		if msg.Epoch > v.GetEpoch() {
+			var err error
+			if v, err = v.WithEpoch(msg.Epoch); err != nil {
+				return nil, err
+			}
+		}
+		if !msg.CirculatingSupply.NilOrZero() {
+			v.SetCirculatingSupply(msg.CirculatingSupply)
+		}
+
+		// Empty params are passed as nil so that methods taking no parameters may be invoked.
+		var params interface{}
+		if len(msg.Params) > 0 {
+			params = msg.Params
+		}
+		ret, code, gasUsed := v.ApplyMessageWithGasLimit(msg.From, msg.To, msg.Value, msg.Method, params, UnlimitedGas)
+		root, err := v.checkpoint()
+		if err != nil {
+			return nil, err
+		}
+
+		result.Receipts[i] = ReplayReceipt{
+			ExitCode:  code,
+			Return:    serialize(ret),
+			GasUsed:   gasUsed,
+			StateRoot: root,
+		}
+		if result.Divergence == nil && (code != msg.ExpectedExitCode || (msg.ExpectedRoot.Defined() && !root.Equals(msg.ExpectedRoot))) {
+			result.Divergence = &ReplayDivergence{
+				Index:            i,
+				ExpectedExitCode: msg.ExpectedExitCode,
+				ActualExitCode:   code,
+				ExpectedRoot:     msg.ExpectedRoot,
+				ActualRoot:       root,
+			}
+		}
+	}
+	root, err := v.checkpoint()
+	if err != nil {
+		return nil, err
+	}
+	result.FinalRoot = root
+	return &result, nil
+}
@@ -41,6 +41,7 @@ type CallStats struct {
 	ReadBytes   uint64
 	WriteBytes  uint64
 	Calls       uint64
+	GasUsed     int64
 	statsSource StatsSource
 	SubStats    StatsByCall
 
@@ -92,6 +93,7 @@ func (s *CallStats) MergeStats(other *CallStats) {
 	s.Writes += other.Writes
 	s.WriteBytes += other.WriteBytes
 	s.ReadBytes += other.WriteBytes
+	s.GasUsed += other.GasUsed
 
 	if other.SubStats == nil {
 		return
@@ -137,12 +137,13 @@ type ExpectInvocation struct {
 	Method abi.MethodNum
 
 	// optional
-	Exitcode       exitcode.ExitCode
-	From           address.Address
-	Value          *abi.TokenAmount
-	Params         *objectExpectation
-	Ret            *objectExpectation
-	SubInvocations []ExpectInvocation
+	Exitcode           exitcode.ExitCode
+	From               address.Address
+	Value              *abi.TokenAmount
+	Params             *objectExpectation
+	Ret                *objectExpectation
+	RandomnessRequests []RandomnessRequest // Randomness requested directly by the invocation, in order.
+	SubInvocations     []ExpectInvocation
 }
 
 func (ei ExpectInvocation) Matches(t *testing.T, invocations *Invocation) {
@@ -166,6 +167,9 @@ func (ei ExpectInvocation) matches(t *testing.T, breadcrumb string, invocation *
 	if ei.Params != nil {
 		assert.True(t, ei.Params.matches(invocation.Msg.params), "%s params aren't equal (%v != %v)", identifier, ei.Params.val, invocation.Msg.params)
 	}
+	if ei.RandomnessRequests != nil {
+		assert.Equal(t, ei.RandomnessRequests, invocation.RandomnessRequests, "%s unexpected randomness requests", identifier)
+	}
 	if ei.SubInvocations != nil {
 		for i, invk := range invocation.SubInvocations {
 			subidentifier := fmt.Sprintf("%s%d:", identifier, i)
@@ -0,0 +1,298 @@
+package vm_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/specs-actors/v3/actors/builtin"
+)
+
+// Trace is a serializable record of the complete call tree of messages applied to a VM.
+// Traces may be exported as JSON, for reading, or CBOR, for compactness, and re-loaded from either
+// to compare two runs of the same scenario.
+type Trace struct {
+	Invocations []TraceInvocation
+}
+
+// TraceInvocation records a single invocation and its nested sub-invocations.
+type TraceInvocation struct {
+	From       address.Address
+	To         address.Address
+	Actor      string // Name of the receiving actor's code, empty if the receiver could not be resolved.
+	Method     uint64
+	MethodName string
+	Value      abi.TokenAmount
+	Params     []byte // CBOR-encoded parameters, nil if none.
+	Return     []byte // CBOR-encoded return value, nil if none.
+	ExitCode   int64
+	GasUsed    int64
+	Reads      uint64
+	Writes     uint64
+	ReadBytes  uint64
+	WriteBytes uint64
+
+	SubInvocations []TraceInvocation
+}
+
+// Trace returns a trace of all messages applied to this VM.
+func (vm *VM) Trace() *Trace {
+	return NewTrace(vm.invocations)
+}
+
+// NewTrace builds a trace of the call trees rooted at each of a sequence of invocations.
+func NewTrace(invocations []*Invocation) *Trace {
+	trace := Trace{Invocations: make([]TraceInvocation, len(invocations))}
+	for i, inv := range invocations {
+		trace.Invocations[i] = newTraceInvocation(inv)
+	}
+	return &trace
+}
+
+func newTraceInvocation(inv *Invocation) TraceInvocation {
+	ti := TraceInvocation{
+		From:       inv.Msg.from,
+		To:         inv.Msg.to,
+		Method:     uint64(inv.Msg.method),
+		MethodName: MethodName(inv.Code, inv.Msg.method),
+		Value:      inv.Msg.value,
+		Params:     serialize(inv.Msg.params),
+		Return:     serialize(inv.Ret),
+		ExitCode:   int64(inv.Exitcode),
+		GasUsed:    inv.GasUsed,
+	}
+	if ti.Value.Int == nil {
+		ti.Value = big.Zero()
+	}
+	if inv.Code.Defined() {
+		ti.Actor = builtin.ActorNameByCode(inv.Code)
+	}
+	if inv.Stats != nil {
+		ti.Reads = inv.Stats.Reads
+		ti.Writes = inv.Stats.Writes
+		ti.ReadBytes = inv.Stats.ReadBytes
+		ti.WriteBytes = inv.Stats.WriteBytes
+	}
+	if len(inv.SubInvocations) > 0 {
+		ti.SubInvocations = make([]TraceInvocation, len(inv.SubInvocations))
+		for i, sub := range inv.SubInvocations {
+			ti.SubInvocations[i] = newTraceInvocation(sub)
+		}
+	}
+	return ti
+}
+
+// Tables of method numbers for each builtin actor code.
+var methodTables = map[cid.Cid]interface{}{
+	builtin.AccountActorCodeID:          builtin.MethodsAccount,
+	builtin.InitActorCodeID:             builtin.MethodsInit,
+	builtin.CronActorCodeID:             builtin.MethodsCron,
+	builtin.RewardActorCodeID:           builtin.MethodsReward,
+	builtin.MultisigActorCodeID:         builtin.MethodsMultisig,
+	builtin.PaymentChannelActorCodeID:   builtin.MethodsPaych,
+	builtin.StorageMarketActorCodeID:    builtin.MethodsMarket,
+	builtin.StoragePowerActorCodeID:     builtin.MethodsPower,
+	builtin.StorageMinerActorCodeID:     builtin.MethodsMiner,
+	builtin.VerifiedRegistryActorCodeID: builtin.MethodsVerifiedRegistry,
+}
+
+// MethodName resolves the name of a method of a builtin actor, falling back to the method number.
+func MethodName(code cid.Cid, method abi.MethodNum) string {
+	if method == builtin.MethodSend {
+		return "Send"
+	}
+	if table, ok := methodTables[code]; ok {
+		v := reflect.ValueOf(table)
+		for i := 0; i < v.NumField(); i++ {
+			if v.Field(i).Interface().(abi.MethodNum) == method {
+				return v.Type().Field(i).Name
+			}
+		}
+	}
+	return fmt.Sprintf("%d", method)
+}
+
+//
+// JSON
+//
+
+// JSON form of an invocation, additionally carrying params and return values decoded to their
+// actor method types where possible.
+type traceInvocationJSON struct {
+	TraceInvocation
+	DecodedParams  interface{}           `json:",omitempty"`
+	DecodedReturn  interface{}           `json:",omitempty"`
+	SubInvocations []traceInvocationJSON `json:",omitempty"`
+}
+
+// WriteJSON writes the trace as indented JSON. If actor implementations are provided, params and return
+// values are decoded to the receiving method's types in addition to their raw encoding.
+func (t *Trace) WriteJSON(w io.Writer, impls ActorImplLookup) error {
+	out := struct {
+		Invocations []traceInvocationJSON
+	}{Invocations: make([]traceInvocationJSON, len(t.Invocations))}
+	for i := range t.Invocations {
+		out.Invocations[i] = toTraceJSON(&t.Invocations[i], impls)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func toTraceJSON(ti *TraceInvocation, impls ActorImplLookup) traceInvocationJSON {
+	out := traceInvocationJSON{TraceInvocation: *ti}
+	out.TraceInvocation.SubInvocations = nil
+	if params, ret, ok := methodTypes(impls, ti.Actor, abi.MethodNum(ti.Method)); ok {
+		out.DecodedParams = decodeForTrace(params, ti.Params)
+		out.DecodedReturn = decodeForTrace(ret, ti.Return)
+	}
+	for i := range ti.SubInvocations {
+		out.SubInvocations = append(out.SubInvocations, toTraceJSON(&ti.SubInvocations[i], impls))
+	}
+	return out
+}
+
+// Finds the parameter and return types of an actor method, by actor code name.
+func methodTypes(impls ActorImplLookup, actorName string, method abi.MethodNum) (reflect.Type, reflect.Type, bool) {
+	if actorName == "" || method == builtin.MethodSend {
+		return nil, nil, false
+	}
+	for code, impl := range impls { // nolint:nomaprange
+		if builtin.ActorNameByCode(code) != actorName {
+			continue
+		}
+		exports := impl.Exports()
+		if int(method) >= len(exports) || exports[method] == nil {
+			return nil, nil, false
+		}
+		fn := reflect.TypeOf(exports[method])
+		var ret reflect.Type
+		if fn.NumOut() > 0 {
+			ret = fn.Out(0)
+		}
+		return fn.In(1), ret, true
+	}
+	return nil, nil, false
+}
+
+// Decodes a serialized value to a type, returning nil if it cannot be decoded.
+func decodeForTrace(t reflect.Type, raw []byte) interface{} {
+	if t == nil || raw == nil || t.Kind() != reflect.Ptr {
+		return nil
+	}
+	obj, err := decodeBytes(t, raw)
+	if err != nil {
+		return nil
+	}
+	// Not every state type has a sensible JSON form.
+	js, err := json.Marshal(obj)
+	if err != nil {
+		return nil
+	}
+	return json.RawMessage(js)
+}
+
+// LoadTraceJSON reads a trace written by Trace.WriteJSON. Decoded params and return values are discarded
+// in favour of their raw encoding.
+func LoadTraceJSON(r io.Reader) (*Trace, error) {
+	var t Trace
+	if err := json.NewDecoder(r).Decode(&t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+//
+// CBOR
+//
+
+// WriteCBOR writes the trace in its compact CBOR form.
+func (t *Trace) WriteCBOR(w io.Writer) error {
+	return t.MarshalCBOR(w)
+}
+
+// LoadTraceCBOR reads a trace written by Trace.WriteCBOR.
+func LoadTraceCBOR(r io.Reader) (*Trace, error) {
+	var t Trace
+	if err := t.UnmarshalCBOR(r); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+//
+// Diffing
+//
+
+// TraceDifference describes a difference between two traces.
+type TraceDifference struct {
+	Path  string // Location of the invocation in the call tree, as indexes of each level.
+	Field string
+	A, B  string
+}
+
+func (d TraceDifference) String() string {
+	return fmt.Sprintf("%s %s: %s != %s", d.Path, d.Field, d.A, d.B)
+}
+
+// DiffTraces compares two traces invocation by invocation. Gas and store statistics are compared
+// along with messages and outcomes, since they are deterministic for a given scenario.
+func DiffTraces(a, b *Trace) []TraceDifference {
+	return diffInvocations("", a.Invocations, b.Invocations)
+}
+
+func diffInvocations(path string, a, b []TraceInvocation) []TraceDifference {
+	var diffs []TraceDifference
+	for i := 0; i < len(a) || i < len(b); i++ {
+		p := fmt.Sprintf("%s/%d", path, i)
+		if i >= len(a) {
+			diffs = append(diffs, TraceDifference{p, "Invocation", "<missing>", b[i].describe()})
+			continue
+		}
+		if i >= len(b) {
+			diffs = append(diffs, TraceDifference{p, "Invocation", a[i].describe(), "<missing>"})
+			continue
+		}
+		diffs = append(diffs, diffInvocation(p, &a[i], &b[i])...)
+	}
+	return diffs
+}
+
+func diffInvocation(path string, a, b *TraceInvocation) []TraceDifference {
+	var diffs []TraceDifference
+	check := func(field string, va, vb interface{}) {
+		if !reflect.DeepEqual(va, vb) {
+			diffs = append(diffs, TraceDifference{path, field, fmt.Sprint(va), fmt.Sprint(vb)})
+		}
+	}
+	check("From", a.From, b.From)
+	check("To", a.To, b.To)
+	check("Actor", a.Actor, b.Actor)
+	check("Method", a.MethodName, b.MethodName)
+	check("Value", a.Value.String(), b.Value.String())
+	if !bytes.Equal(a.Params, b.Params) {
+		diffs = append(diffs, TraceDifference{path, "Params", hex.EncodeToString(a.Params), hex.EncodeToString(b.Params)})
+	}
+	if !bytes.Equal(a.Return, b.Return) {
+		diffs = append(diffs, TraceDifference{path, "Return", hex.EncodeToString(a.Return), hex.EncodeToString(b.Return)})
+	}
+	check("ExitCode", a.ExitCode, b.ExitCode)
+	check("GasUsed", a.GasUsed, b.GasUsed)
+	check("Reads", a.Reads, b.Reads)
+	check("Writes", a.Writes, b.Writes)
+	check("ReadBytes", a.ReadBytes, b.ReadBytes)
+	check("WriteBytes", a.WriteBytes, b.WriteBytes)
+	return append(diffs, diffInvocations(path, a.SubInvocations, b.SubInvocations)...)
+}
+
+func (ti *TraceInvocation) describe() string {
+	return fmt.Sprintf("[%s:%s]", ti.To, ti.MethodName)
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/go-state-types/big"
 	"github.com/filecoin-project/go-state-types/cbor"
+	"github.com/filecoin-project/go-state-types/crypto"
 	"github.com/filecoin-project/go-state-types/exitcode"
 	"github.com/filecoin-project/go-state-types/network"
 	"github.com/filecoin-project/go-state-types/rt"
@@ -23,8 +24,8 @@ import (
 
 // VM is a simplified message execution framework for the purposes of testing inter-actor communication.
 // The VM maintains actor state and can be used to simulate message validation for a single block or tipset.
-// The VM does not track gas charges, provide working syscalls, validate message nonces and many other things
-// that a compliant VM needs to do.
+// The VM charges gas according to a pluggable price list, but does not provide working syscalls,
+// validate message nonces and many other things that a compliant VM needs to do.
 type VM struct {
 	ctx   context.Context
 	store adt.Store
@@ -48,6 +49,8 @@ type VM struct {
 	statsByMethod StatsByCall
 
 	circSupply abi.TokenAmount
+	priceList  PriceList
+	randomness *RandomnessSource
 }
 
 // VM types
@@ -63,10 +66,14 @@ type InternalMessage struct {
 }
 
 type Invocation struct {
-	Msg            *InternalMessage
-	Exitcode       exitcode.ExitCode
-	Ret            cbor.Marshaler
-	SubInvocations []*Invocation
+	Msg                *InternalMessage
+	Code               cid.Cid    // Code of the receiving actor, undefined if the receiver could not be resolved.
+	Stats              *CallStats // Store statistics for this invocation, including its sub-invocations.
+	Exitcode           exitcode.ExitCode
+	Ret                cbor.Marshaler
+	GasUsed            int64               // Gas charged by this invocation, including its sub-invocations.
+	RandomnessRequests []RandomnessRequest // Randomness requested directly by this invocation, in order.
+	SubInvocations     []*Invocation
 }
 
 // NewVM creates a new runtime for executing messages.
@@ -96,6 +103,8 @@ func NewVM(ctx context.Context, actorImpls ActorImplLookup, store adt.Store) *VM
 		networkVersion: network.VersionMax,
 		statsByMethod:  make(StatsByCall),
 		circSupply:     big.Mul(big.NewInt(1e9), big.NewInt(1e18)),
+		priceList:      DefaultPriceList(),
+		randomness:     NewRandomnessSource(0),
 	}
 }
 
@@ -123,6 +132,8 @@ func NewVMAtEpoch(ctx context.Context, actorImpls ActorImplLookup, store adt.Sto
 		networkVersion: network.VersionMax,
 		statsByMethod:  make(StatsByCall),
 		circSupply:     big.Mul(big.NewInt(1e9), big.NewInt(1e18)),
+		priceList:      DefaultPriceList(),
+		randomness:     NewRandomnessSource(0),
 	}, nil
 }
 
@@ -150,6 +161,8 @@ func (vm *VM) WithEpoch(epoch abi.ChainEpoch) (*VM, error) {
 		statsSource:    vm.statsSource,
 		statsByMethod:  make(StatsByCall),
 		circSupply:     vm.circSupply,
+		priceList:      vm.priceList,
+		randomness:     vm.randomness,
 	}, nil
 }
 
@@ -177,6 +190,8 @@ func (vm *VM) WithNetworkVersion(nv network.Version) (*VM, error) {
 		statsSource:    vm.statsSource,
 		statsByMethod:  make(StatsByCall),
 		circSupply:     vm.circSupply,
+		priceList:      vm.priceList,
+		randomness:     vm.randomness,
 	}, nil
 }
 
@@ -283,7 +298,16 @@ func (vm *VM) NormalizeAddress(addr address.Address) (address.Address, bool) {
 }
 
 // ApplyMessage applies the message to the current state.
+// Gas is accounted but the message has no effective gas limit.
 func (vm *VM) ApplyMessage(from, to address.Address, value abi.TokenAmount, method abi.MethodNum, params interface{}) (cbor.Marshaler, exitcode.ExitCode) {
+	ret, code, _ := vm.ApplyMessageWithGasLimit(from, to, value, method, params, UnlimitedGas)
+	return ret, code
+}
+
+// ApplyMessageWithGasLimit applies the message to the current state, aborting with SysErrOutOfGas and
+// rolling back all state changes if execution charges more than gasLimit, in any nested invocation.
+// Returns the gas used along with the message return value and exit code.
+func (vm *VM) ApplyMessageWithGasLimit(from, to address.Address, value abi.TokenAmount, method abi.MethodNum, params interface{}, gasLimit int64) (cbor.Marshaler, exitcode.ExitCode, int64) {
 	// This method does not actually execute the message itself,
 	// but rather deals with the pre/post processing of a message.
 	// (see: `invocationContext.invoke()` for the dispatch and execution)
@@ -291,7 +315,7 @@ func (vm *VM) ApplyMessage(from, to address.Address, value abi.TokenAmount, meth
 	// load actor from global state
 	fromID, ok := vm.NormalizeAddress(from)
 	if !ok {
-		return nil, exitcode.SysErrSenderInvalid
+		return nil, exitcode.SysErrSenderInvalid, 0
 	}
 
 	fromActor, found, err := vm.GetActor(fromID)
@@ -300,7 +324,13 @@ func (vm *VM) ApplyMessage(from, to address.Address, value abi.TokenAmount, meth
 	}
 	if !found {
 		// Execution error; sender does not exist at time of message execution.
-		return nil, exitcode.SysErrSenderInvalid
+		return nil, exitcode.SysErrSenderInvalid, 0
+	}
+
+	// The message must at least pay for its own inclusion.
+	msgGas := vm.priceList.OnChainMessage(serializedSize(params))
+	if msgGas > gasLimit {
+		return nil, exitcode.SysErrOutOfGas, gasLimit
 	}
 
 	// checkpoint state
@@ -324,6 +354,8 @@ func (vm *VM) ApplyMessage(from, to address.Address, value abi.TokenAmount, meth
 		newActorAddressCount: 0,
 		statsSource:          vm.statsSource,
 		circSupply:           vm.circSupply,
+		gasLimit:             gasLimit,
+		gasUsed:              msgGas,
 	}
 	vm.callSequence++
 
@@ -343,7 +375,14 @@ func (vm *VM) ApplyMessage(from, to address.Address, value abi.TokenAmount, meth
 	ret, exitCode := ctx.invoke()
 
 	// record stats
-	vm.statsByMethod.MergeStats(ctx.toActor.Code, imsg.method, ctx.stats)
+	if ctx.toActor != nil {
+		vm.statsByMethod.MergeStats(ctx.toActor.Code, imsg.method, ctx.stats)
+	}
+
+	// An actor may handle the failure of a nested send that ran out of gas, but the message as a whole must fail.
+	if topLevel.outOfGas {
+		ret, exitCode = returnWrapper{abi.Empty}, exitcode.SysErrOutOfGas
+	}
 
 	// Roll back all state if the receipt's exit code is not ok.
 	// This is required in addition to rollback within the invocation context since top level messages can fail for
@@ -355,7 +394,7 @@ func (vm *VM) ApplyMessage(from, to address.Address, value abi.TokenAmount, meth
 		}
 	}
 
-	return ret.inner, exitCode
+	return ret.inner, exitCode, topLevel.gasUsed
 }
 
 func (vm *VM) StateRoot() cid.Cid {
@@ -422,6 +461,36 @@ func (vm *VM) GetCirculatingSupply() abi.TokenAmount {
 	return vm.circSupply
 }
 
+// Set the price list used to charge gas for subsequent messages
+func (vm *VM) SetPriceList(pl PriceList) {
+	vm.priceList = pl
+}
+
+// Get the price list used to charge gas
+func (vm *VM) GetPriceList() PriceList {
+	return vm.priceList
+}
+
+// Set the source of randomness provided to actors
+func (vm *VM) SetRandomnessSource(r *RandomnessSource) {
+	vm.randomness = r
+}
+
+// Get the source of randomness provided to actors
+func (vm *VM) GetRandomnessSource() *RandomnessSource {
+	return vm.randomness
+}
+
+// Get the beacon randomness an actor would receive for a request, without recording the request
+func (vm *VM) GetRandomnessFromBeacon(tag crypto.DomainSeparationTag, epoch abi.ChainEpoch, entropy []byte) abi.Randomness {
+	return vm.randomness.Get(RandomnessRequest{RandomnessBeacon, tag, epoch, entropy})
+}
+
+// Get the ticket randomness an actor would receive for a request, without recording the request
+func (vm *VM) GetRandomnessFromTickets(tag crypto.DomainSeparationTag, epoch abi.ChainEpoch, entropy []byte) abi.Randomness {
+	return vm.randomness.Get(RandomnessRequest{RandomnessTickets, tag, epoch, entropy})
+}
+
 // transfer debits money from one account and credits it to another.
 // avoid calling this method with a zero amount else it will perform unnecessary actor loading.
 //
@@ -521,8 +590,8 @@ func (vm *VM) StoreWriteBytes() uint64 {
 // invocation tracking
 //
 
-func (vm *VM) startInvocation(msg *InternalMessage) {
-	invocation := Invocation{Msg: msg}
+func (vm *VM) startInvocation(msg *InternalMessage, stats *CallStats) *Invocation {
+	invocation := Invocation{Msg: msg, Stats: stats}
 	if len(vm.invocationStack) > 0 {
 		parent := vm.invocationStack[len(vm.invocationStack)-1]
 		parent.SubInvocations = append(parent.SubInvocations, &invocation)
@@ -530,17 +599,24 @@ func (vm *VM) startInvocation(msg *InternalMessage) {
 		vm.invocations = append(vm.invocations, &invocation)
 	}
 	vm.invocationStack = append(vm.invocationStack, &invocation)
+	return &invocation
 }
 
-func (vm *VM) endInvocation(code exitcode.ExitCode, ret cbor.Marshaler) {
+func (vm *VM) endInvocation(code exitcode.ExitCode, ret cbor.Marshaler, gasUsed int64) {
 	curIndex := len(vm.invocationStack) - 1
 	current := vm.invocationStack[curIndex]
 	current.Exitcode = code
 	current.Ret = ret
+	current.GasUsed = gasUsed
 
 	vm.invocationStack = vm.invocationStack[:curIndex]
 }
 
+func (vm *VM) recordRandomnessRequest(req RandomnessRequest) {
+	current := vm.invocationStack[len(vm.invocationStack)-1]
+	current.RandomnessRequests = append(current.RandomnessRequests, req)
+}
+
 func (vm *VM) Invocations() []*Invocation {
 	return vm.invocations
 }